@@ -150,14 +150,7 @@ func (pre *Prestate) Apply(vmConfig vm.Config, chainConfig *params.ChainConfig,
 
 		evm := vm.NewEVM(vmContext, ibs, chainConfig, vmConfig)
 		if chainConfig.IsYoloV2(vmContext.BlockNumber) {
-			ibs.AddAddressToAccessList(msg.From())
-			if dst := msg.To(); dst != nil {
-				ibs.AddAddressToAccessList(*dst)
-				// If it's a create-tx, the destination will be added inside evm.create
-			}
-			for _, addr := range evm.ActivePrecompiles() {
-				ibs.AddAddressToAccessList(addr)
-			}
+			ibs.PrepareAccessList(msg.From(), msg.To(), evm.ActivePrecompiles(), msg.AccessList())
 		}
 		snapshot := ibs.Snapshot()
 		// (ret []byte, usedGas uint64, failed bool, err error)
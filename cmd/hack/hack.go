@@ -1162,67 +1162,6 @@ func getModifiedAccounts(chaindata string) {
 	// fmt.Printf("Len(addrs)=%d\n", len(addrs))
 }
 
-type Receiver struct {
-	defaultReceiver *trie.DefaultReceiver
-	accountMap      map[string]*accounts.Account
-	storageMap      map[string][]byte
-	unfurlList      []string
-	currentIdx      int
-}
-
-func (r *Receiver) Root() common.Hash { panic("don't call me") }
-func (r *Receiver) Receive(
-	itemType trie.StreamItem,
-	accountKey []byte,
-	storageKey []byte,
-	accountValue *accounts.Account,
-	storageValue []byte,
-	hash []byte,
-	cutoff int,
-) error {
-	for r.currentIdx < len(r.unfurlList) {
-		ks := r.unfurlList[r.currentIdx]
-		k := []byte(ks)
-		var c int
-		switch itemType {
-		case trie.StorageStreamItem, trie.SHashStreamItem:
-			c = bytes.Compare(k, storageKey)
-		case trie.AccountStreamItem, trie.AHashStreamItem:
-			c = bytes.Compare(k, accountKey)
-		case trie.CutoffStreamItem:
-			c = -1
-		}
-		if c > 0 {
-			return r.defaultReceiver.Receive(itemType, accountKey, storageKey, accountValue, storageValue, hash, cutoff)
-		}
-		if len(k) > common.HashLength {
-			v := r.storageMap[ks]
-			if len(v) > 0 {
-				if err := r.defaultReceiver.Receive(trie.StorageStreamItem, nil, k, nil, v, nil, 0); err != nil {
-					return err
-				}
-			}
-		} else {
-			v := r.accountMap[ks]
-			if v != nil {
-				if err := r.defaultReceiver.Receive(trie.AccountStreamItem, k, nil, v, nil, nil, 0); err != nil {
-					return err
-				}
-			}
-		}
-		r.currentIdx++
-		if c == 0 {
-			return nil
-		}
-	}
-	// We ran out of modifications, simply pass through
-	return r.defaultReceiver.Receive(itemType, accountKey, storageKey, accountValue, storageValue, hash, cutoff)
-}
-
-func (r *Receiver) Result() trie.SubTries {
-	return r.defaultReceiver.Result()
-}
-
 func regenerate(chaindata string) error {
 	var m runtime.MemStats
 	db := ethdb.MustOpen(chaindata)
@@ -1268,137 +1207,6 @@ func regenerate(chaindata string) error {
 	return nil
 }
 
-func testGetProof(chaindata string, address common.Address, rewind int, regen bool) error {
-	if regen {
-		if err := regenerate(chaindata); err != nil {
-			return err
-		}
-	}
-	storageKeys := []string{}
-	var m runtime.MemStats
-	runtime.ReadMemStats(&m)
-	db := ethdb.MustOpen(chaindata)
-	defer db.Close()
-	headHash := rawdb.ReadHeadBlockHash(db)
-	headNumber := rawdb.ReadHeaderNumber(db, headHash)
-	block := *headNumber - uint64(rewind)
-	log.Info("GetProof", "address", address, "storage keys", len(storageKeys), "head", *headNumber, "block", block,
-		"alloc", common.StorageSize(m.Alloc), "sys", common.StorageSize(m.Sys), "numGC", int(m.NumGC))
-
-	ts := dbutils.EncodeBlockNumber(block + 1)
-	accountMap := make(map[string]*accounts.Account)
-
-	if err := changeset.Walk(db, dbutils.AccountChangeSetBucket, ts, 0, func(blockN uint64, k, v []byte) (bool, error) {
-		if blockN > *headNumber {
-			return false, nil
-		}
-
-		if _, ok := accountMap[string(k)]; !ok {
-			if len(v) > 0 {
-				var a accounts.Account
-				if innerErr := a.DecodeForStorage(v); innerErr != nil {
-					return false, innerErr
-				}
-				accountMap[string(k)] = &a
-			} else {
-				accountMap[string(k)] = nil
-			}
-		}
-		return true, nil
-	}); err != nil {
-		return err
-	}
-	runtime.ReadMemStats(&m)
-	log.Info("Constructed account map", "size", len(accountMap),
-		"alloc", common.StorageSize(m.Alloc), "sys", common.StorageSize(m.Sys), "numGC", int(m.NumGC))
-	storageMap := make(map[string][]byte)
-	if err := changeset.Walk(db, dbutils.StorageChangeSetBucket, ts, 0, func(blockN uint64, k, v []byte) (bool, error) {
-		if blockN > *headNumber {
-			return false, nil
-		}
-		if _, ok := storageMap[string(k)]; !ok {
-			storageMap[string(k)] = v
-		}
-		return true, nil
-	}); err != nil {
-		return err
-	}
-	runtime.ReadMemStats(&m)
-	log.Info("Constructed storage map", "size", len(storageMap),
-		"alloc", common.StorageSize(m.Alloc), "sys", common.StorageSize(m.Sys), "numGC", int(m.NumGC))
-	var unfurlList = make([]string, len(accountMap)+len(storageMap))
-	unfurl := trie.NewRetainList(0)
-	i := 0
-	for ks, acc := range accountMap {
-		unfurlList[i] = ks
-		i++
-		unfurl.AddKey([]byte(ks))
-		if acc != nil {
-			// Fill the code hashes
-			if acc.Incarnation > 0 && acc.IsEmptyCodeHash() {
-				if codeHash, err1 := db.Get(dbutils.ContractCodeBucket, dbutils.GenerateStoragePrefix([]byte(ks), acc.Incarnation)); err1 == nil {
-					copy(acc.CodeHash[:], codeHash)
-				} else {
-					return err1
-				}
-			}
-		}
-	}
-	for ks := range storageMap {
-		unfurlList[i] = ks
-		i++
-		unfurl.AddKey([]byte(ks))
-	}
-	rl := trie.NewRetainList(0)
-	addrHash, err := common.HashData(address[:])
-	if err != nil {
-		return err
-	}
-	rl.AddKey(addrHash[:])
-	unfurl.AddKey(addrHash[:])
-	for _, key := range storageKeys {
-		keyAsHash := common.HexToHash(key)
-		if keyHash, err1 := common.HashData(keyAsHash[:]); err1 == nil {
-			//TODO Add incarnation in the middle of this
-			trieKey := append(addrHash[:], keyHash[:]...)
-			rl.AddKey(trieKey)
-			unfurl.AddKey(trieKey)
-		} else {
-			return err1
-		}
-	}
-	sort.Strings(unfurlList)
-	runtime.ReadMemStats(&m)
-	log.Info("Constructed account unfurl lists",
-		"alloc", common.StorageSize(m.Alloc), "sys", common.StorageSize(m.Sys), "numGC", int(m.NumGC))
-	loader := trie.NewFlatDbSubTrieLoader()
-	if err = loader.Reset(db, unfurl, trie.NewRetainList(0), nil /* HashCollector */, [][]byte{nil}, []int{0}, false); err != nil {
-		return err
-	}
-	r := &Receiver{defaultReceiver: trie.NewDefaultReceiver(), unfurlList: unfurlList, accountMap: accountMap, storageMap: storageMap}
-	r.defaultReceiver.Reset(rl, nil /* HashCollector */, false)
-	loader.SetStreamReceiver(r)
-	subTries, err1 := loader.LoadSubTries()
-	if err1 != nil {
-		return err1
-	}
-	runtime.ReadMemStats(&m)
-	log.Info("Loaded subtries",
-		"alloc", common.StorageSize(m.Alloc), "sys", common.StorageSize(m.Sys), "numGC", int(m.NumGC))
-	hash, err := rawdb.ReadCanonicalHash(db, block)
-	check(err)
-	header := rawdb.ReadHeader(db, hash, block)
-	tr := trie.New(common.Hash{})
-	if err = tr.HookSubTries(subTries, [][]byte{nil}); err != nil {
-		fmt.Printf("Error hooking: %v\n", err)
-	}
-	runtime.ReadMemStats(&m)
-	log.Info("Constructed trie", "nodes", tr.NumberOfAccounts(),
-		"alloc", common.StorageSize(m.Alloc), "sys", common.StorageSize(m.Sys), "numGC", int(m.NumGC))
-	fmt.Printf("Resulting root: %x (subTrie %x), expected root: %x\n", tr.Hash(), subTries.Hashes[0], header.Root)
-	return nil
-}
-
 func changeSetStats(chaindata string, block1, block2 uint64) error {
 	db := ethdb.MustOpen(chaindata)
 	defer db.Close()
@@ -2008,11 +1816,6 @@ func main() {
 	if *action == "slice" {
 		dbSlice(*chaindata, *bucket, common.FromHex(*hash))
 	}
-	if *action == "getProof" {
-		if err := testGetProof(*chaindata, common.HexToAddress(*account), *rewind, false); err != nil {
-			fmt.Printf("Error: %v\n", err)
-		}
-	}
 	if *action == "regenerateIH" {
 		if err := regenerate(*chaindata); err != nil {
 			fmt.Printf("Error: %v\n", err)
@@ -47,6 +47,14 @@ func (scd *SentryClientDirect) SendMessageToAll(ctx context.Context, in *proto_s
 	return scd.server.SendMessageToAll(ctx, in)
 }
 
+func (scd *SentryClientDirect) PeerCount(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*proto_sentry.PeerCountReply, error) {
+	return scd.server.PeerCount(ctx, in)
+}
+
+func (scd *SentryClientDirect) Peers(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*proto_sentry.PeersReply, error) {
+	return scd.server.Peers(ctx, in)
+}
+
 // ControlClientDirect implement ControlClient interface by connecting the instance of the client directly with the corresponding
 // instance of ControlServer
 type ControlClientDirect struct {
@@ -1,12 +1,14 @@
 package download
 
 import (
+	"bytes"
 	"context"
 	"crypto/ecdsa"
 	"errors"
 	"fmt"
 	"io"
 	"math/big"
+	"math/rand"
 	"net"
 	"os"
 	"os/signal"
@@ -94,6 +96,7 @@ func makeP2PServer(
 	peerHeightMap *sync.Map,
 	peerTimeMap *sync.Map,
 	peerRwMap *sync.Map,
+	peerMap *sync.Map,
 	protocols []string,
 	coreClient proto_core.ControlClient,
 ) (*p2p.Server, error) {
@@ -130,6 +133,7 @@ func makeP2PServer(
 				peerID := peer.ID().String()
 				log.Info(fmt.Sprintf("[%s] Start with peer", peerID))
 				peerRwMap.Store(peerID, rw)
+				peerMap.Store(peerID, peer)
 				if err := runPeer(
 					ctx,
 					peerHeightMap,
@@ -150,6 +154,7 @@ func makeP2PServer(
 				peerHeightMap.Delete(peerID)
 				peerTimeMap.Delete(peerID)
 				peerRwMap.Delete(peerID)
+				peerMap.Delete(peerID)
 				return nil
 			},
 		},
@@ -524,6 +529,7 @@ func p2pServer(ctx context.Context,
 		&sentryServer.peerHeightMap,
 		&sentryServer.peerTimeMap,
 		&sentryServer.peerRwMap,
+		&sentryServer.peerMap,
 		[]string{eth.ProtocolName},
 		coreClient,
 	)
@@ -579,6 +585,7 @@ type SentryServerImpl struct {
 	peerHeightMap sync.Map
 	peerRwMap     sync.Map
 	peerTimeMap   sync.Map
+	peerMap       sync.Map
 }
 
 func (ss *SentryServerImpl) PenalizePeer(_ context.Context, req *proto_sentry.PenalizePeerRequest) (*empty.Empty, error) {
@@ -653,6 +660,68 @@ func (ss *SentryServerImpl) getBlockBodies(inreq *proto_sentry.SendMessageByMinB
 	return &proto_sentry.SentPeers{Peers: [][]byte{[]byte(peerID)}}, nil
 }
 
+// outboundMessageCodes maps the ids of the outbound messages to the eth protocol message codes
+var outboundMessageCodes = map[proto_sentry.OutboundMessageId]uint64{
+	proto_sentry.OutboundMessageId_GetBlockHeaders:            eth.GetBlockHeadersMsg,
+	proto_sentry.OutboundMessageId_GetBlockBodies:             eth.GetBlockBodiesMsg,
+	proto_sentry.OutboundMessageId_GetNodeData:                eth.GetNodeDataMsg,
+	proto_sentry.OutboundMessageId_NewBlockHashes:             eth.NewBlockHashesMsg,
+	proto_sentry.OutboundMessageId_NewBlock:                   eth.NewBlockMsg,
+	proto_sentry.OutboundMessageId_Transactions:               eth.TransactionMsg,
+	proto_sentry.OutboundMessageId_NewPooledTransactionHashes: eth.NewPooledTransactionHashesMsg,
+	proto_sentry.OutboundMessageId_GetPooledTransactions:      eth.GetPooledTransactionsMsg,
+	proto_sentry.OutboundMessageId_PooledTransactions:         eth.PooledTransactionsMsg,
+	proto_sentry.OutboundMessageId_BlockHeaders:               eth.BlockHeadersMsg,
+	proto_sentry.OutboundMessageId_BlockBodies:                eth.BlockBodiesMsg,
+	proto_sentry.OutboundMessageId_NodeData:                   eth.NodeDataMsg,
+	proto_sentry.OutboundMessageId_GetReceipts:                eth.GetReceiptsMsg,
+	proto_sentry.OutboundMessageId_Receipts:                   eth.ReceiptsMsg,
+}
+
+// sendToPeer writes the already RLP-encoded message to the given peer
+func (ss *SentryServerImpl) sendToPeer(peerID string, data *proto_sentry.OutboundMessageData) error {
+	code, ok := outboundMessageCodes[data.Id]
+	if !ok {
+		return fmt.Errorf("unknown message Id: %s", data.Id)
+	}
+	rwRaw, _ := ss.peerRwMap.Load(peerID)
+	rw, _ := rwRaw.(p2p.MsgReadWriter)
+	if rw == nil {
+		return fmt.Errorf("find rw for peer %s", peerID)
+	}
+	if err := rw.WriteMsg(p2p.Msg{Code: code, Size: uint32(len(data.Data)), Payload: bytes.NewReader(data.Data)}); err != nil {
+		return fmt.Errorf("send to peer %s: %v", peerID, err)
+	}
+	return nil
+}
+
+// sendToPeers sends the message to each of the given peers, and returns the peers that the message was sent to.
+// Failure to send to one of the peers is logged and does not prevent sending to the rest of them
+func (ss *SentryServerImpl) sendToPeers(peerIDs []string, data *proto_sentry.OutboundMessageData) (*proto_sentry.SentPeers, error) {
+	if _, ok := outboundMessageCodes[data.Id]; !ok {
+		return &proto_sentry.SentPeers{}, fmt.Errorf("unknown message Id: %s", data.Id)
+	}
+	reply := &proto_sentry.SentPeers{}
+	for _, peerID := range peerIDs {
+		if err := ss.sendToPeer(peerID, data); err != nil {
+			log.Debug("Could not send message", "id", data.Id, "error", err)
+			continue
+		}
+		reply.Peers = append(reply.Peers, []byte(peerID))
+	}
+	return reply, nil
+}
+
+// peerIDs returns the ids of all currently connected peers
+func (ss *SentryServerImpl) peerIDs() []string {
+	var peerIDs []string
+	ss.peerRwMap.Range(func(key, _ interface{}) bool {
+		peerIDs = append(peerIDs, key.(string))
+		return true
+	})
+	return peerIDs
+}
+
 func (ss *SentryServerImpl) SendMessageByMinBlock(_ context.Context, inreq *proto_sentry.SendMessageByMinBlockRequest) (*proto_sentry.SentPeers, error) {
 	switch inreq.Data.Id {
 	case proto_sentry.OutboundMessageId_GetBlockHeaders:
@@ -660,18 +729,57 @@ func (ss *SentryServerImpl) SendMessageByMinBlock(_ context.Context, inreq *prot
 	case proto_sentry.OutboundMessageId_GetBlockBodies:
 		return ss.getBlockBodies(inreq)
 	default:
-		return &proto_sentry.SentPeers{}, fmt.Errorf("not implemented for message Id: %s", inreq.Data.Id)
+		peerID, found := ss.findPeer(inreq.MinBlock)
+		if !found {
+			log.Debug("Could not find peer for request", "minBlock", inreq.MinBlock)
+			return &proto_sentry.SentPeers{}, nil
+		}
+		if err := ss.sendToPeer(peerID, inreq.Data); err != nil {
+			return &proto_sentry.SentPeers{}, err
+		}
+		return &proto_sentry.SentPeers{Peers: [][]byte{[]byte(peerID)}}, nil
 	}
 }
 
-func (ss *SentryServerImpl) SendMessageById(context.Context, *proto_sentry.SendMessageByIdRequest) (*proto_sentry.SentPeers, error) {
-	return nil, nil
+func (ss *SentryServerImpl) SendMessageById(_ context.Context, inreq *proto_sentry.SendMessageByIdRequest) (*proto_sentry.SentPeers, error) {
+	peerID := string(inreq.PeerId)
+	if err := ss.sendToPeer(peerID, inreq.Data); err != nil {
+		return &proto_sentry.SentPeers{}, err
+	}
+	return &proto_sentry.SentPeers{Peers: [][]byte{inreq.PeerId}}, nil
 }
 
-func (ss *SentryServerImpl) SendMessageToRandomPeers(context.Context, *proto_sentry.SendMessageToRandomPeersRequest) (*proto_sentry.SentPeers, error) {
-	return nil, nil
+func (ss *SentryServerImpl) SendMessageToRandomPeers(_ context.Context, inreq *proto_sentry.SendMessageToRandomPeersRequest) (*proto_sentry.SentPeers, error) {
+	peerIDs := ss.peerIDs()
+	rand.Shuffle(len(peerIDs), func(i, j int) { peerIDs[i], peerIDs[j] = peerIDs[j], peerIDs[i] })
+	if uint64(len(peerIDs)) > inreq.MaxPeers {
+		peerIDs = peerIDs[:inreq.MaxPeers]
+	}
+	return ss.sendToPeers(peerIDs, inreq.Data)
 }
 
-func (ss *SentryServerImpl) SendMessageToAll(context.Context, *proto_sentry.OutboundMessageData) (*proto_sentry.SentPeers, error) {
-	return nil, nil
+func (ss *SentryServerImpl) SendMessageToAll(_ context.Context, req *proto_sentry.OutboundMessageData) (*proto_sentry.SentPeers, error) {
+	return ss.sendToPeers(ss.peerIDs(), req)
+}
+
+func (ss *SentryServerImpl) PeerCount(context.Context, *empty.Empty) (*proto_sentry.PeerCountReply, error) {
+	return &proto_sentry.PeerCountReply{Count: uint64(len(ss.peerIDs()))}, nil
+}
+
+func (ss *SentryServerImpl) Peers(context.Context, *empty.Empty) (*proto_sentry.PeersReply, error) {
+	reply := &proto_sentry.PeersReply{}
+	ss.peerMap.Range(func(key, value interface{}) bool {
+		peerID := key.(string)
+		peer := value.(*p2p.Peer)
+		x, _ := ss.peerHeightMap.Load(peerID)
+		maxBlock, _ := x.(uint64)
+		reply.Peers = append(reply.Peers, &proto_sentry.PeerInfo{
+			PeerId:        []byte(peerID),
+			Name:          peer.Name(),
+			RemoteAddress: peer.RemoteAddr().String(),
+			MaxBlock:      maxBlock,
+		})
+		return true
+	})
+	return reply, nil
 }
@@ -23,6 +23,8 @@ type SentryClient interface {
 	SendMessageById(ctx context.Context, in *SendMessageByIdRequest, opts ...grpc.CallOption) (*SentPeers, error)
 	SendMessageToRandomPeers(ctx context.Context, in *SendMessageToRandomPeersRequest, opts ...grpc.CallOption) (*SentPeers, error)
 	SendMessageToAll(ctx context.Context, in *OutboundMessageData, opts ...grpc.CallOption) (*SentPeers, error)
+	PeerCount(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*PeerCountReply, error)
+	Peers(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*PeersReply, error)
 }
 
 type sentryClient struct {
@@ -78,6 +80,24 @@ func (c *sentryClient) SendMessageToAll(ctx context.Context, in *OutboundMessage
 	return out, nil
 }
 
+func (c *sentryClient) PeerCount(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*PeerCountReply, error) {
+	out := new(PeerCountReply)
+	err := c.cc.Invoke(ctx, "/sentry.Sentry/PeerCount", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *sentryClient) Peers(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*PeersReply, error) {
+	out := new(PeersReply)
+	err := c.cc.Invoke(ctx, "/sentry.Sentry/Peers", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // SentryServer is the server API for Sentry service.
 // All implementations must embed UnimplementedSentryServer
 // for forward compatibility
@@ -87,6 +107,8 @@ type SentryServer interface {
 	SendMessageById(context.Context, *SendMessageByIdRequest) (*SentPeers, error)
 	SendMessageToRandomPeers(context.Context, *SendMessageToRandomPeersRequest) (*SentPeers, error)
 	SendMessageToAll(context.Context, *OutboundMessageData) (*SentPeers, error)
+	PeerCount(context.Context, *emptypb.Empty) (*PeerCountReply, error)
+	Peers(context.Context, *emptypb.Empty) (*PeersReply, error)
 	mustEmbedUnimplementedSentryServer()
 }
 
@@ -109,6 +131,12 @@ func (UnimplementedSentryServer) SendMessageToRandomPeers(context.Context, *Send
 func (UnimplementedSentryServer) SendMessageToAll(context.Context, *OutboundMessageData) (*SentPeers, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method SendMessageToAll not implemented")
 }
+func (UnimplementedSentryServer) PeerCount(context.Context, *emptypb.Empty) (*PeerCountReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PeerCount not implemented")
+}
+func (UnimplementedSentryServer) Peers(context.Context, *emptypb.Empty) (*PeersReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Peers not implemented")
+}
 func (UnimplementedSentryServer) mustEmbedUnimplementedSentryServer() {}
 
 // UnsafeSentryServer may be embedded to opt out of forward compatibility for this service.
@@ -212,6 +240,42 @@ func _Sentry_SendMessageToAll_Handler(srv interface{}, ctx context.Context, dec
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Sentry_PeerCount_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(emptypb.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SentryServer).PeerCount(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/sentry.Sentry/PeerCount",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SentryServer).PeerCount(ctx, req.(*emptypb.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Sentry_Peers_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(emptypb.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SentryServer).Peers(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/sentry.Sentry/Peers",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SentryServer).Peers(ctx, req.(*emptypb.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _Sentry_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "sentry.Sentry",
 	HandlerType: (*SentryServer)(nil),
@@ -236,6 +300,14 @@ var _Sentry_serviceDesc = grpc.ServiceDesc{
 			MethodName: "SendMessageToAll",
 			Handler:    _Sentry_SendMessageToAll_Handler,
 		},
+		{
+			MethodName: "PeerCount",
+			Handler:    _Sentry_PeerCount_Handler,
+		},
+		{
+			MethodName: "Peers",
+			Handler:    _Sentry_Peers_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "sentry.proto",
@@ -0,0 +1,48 @@
+package commands
+
+import (
+	"strings"
+
+	"github.com/c2h5oh/datasize"
+	"github.com/ledgerwatch/turbo-geth/cmd/utils"
+	"github.com/ledgerwatch/turbo-geth/ethdb"
+	"github.com/ledgerwatch/turbo-geth/log"
+	"github.com/spf13/cobra"
+)
+
+var compactionBatchSizeStr string
+
+var cmdCompactBuckets = &cobra.Command{
+	Use:     "compact_buckets",
+	Short:   "rewrite the buckets into fresh ones in bounded transactions and swap their names, the readers of the database are not blocked. Other processes must not write to the buckets meanwhile",
+	Example: "go run ./cmd/integration compact_buckets --chaindata=/path/to/chaindata --bucket=PLAIN-SCS,hST",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := utils.RootContext()
+		var batchSize datasize.ByteSize
+		must(batchSize.UnmarshalText([]byte(compactionBatchSizeStr)))
+		kv := openKV(chaindata, false)
+		defer kv.Close()
+
+		buckets := strings.Split(bucket, ",")
+		compactor, err := ethdb.NewCompactor(kv, ethdb.CompactionConfig{Buckets: buckets, BatchSize: batchSize})
+		if err != nil {
+			return err
+		}
+		for _, b := range buckets {
+			if err = compactor.Compact(ctx, b); err != nil {
+				log.Error("Compaction failed", "bucket", b, "err", err)
+				return err
+			}
+		}
+		return nil
+	},
+}
+
+func init() {
+	withChaindata(cmdCompactBuckets)
+	withLmdbFlags(cmdCompactBuckets)
+	withBucket(cmdCompactBuckets)
+	cmdCompactBuckets.Flags().StringVar(&compactionBatchSizeStr, "batchSize", ethdb.CompactionDefaultBatchSize.String(), "amount of data copied by one transaction")
+
+	rootCmd.AddCommand(cmdCompactBuckets)
+}
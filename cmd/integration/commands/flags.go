@@ -24,6 +24,9 @@ var (
 	mapSizeStr         string
 	freelistReuse      int
 	migration          string
+	dryRun             bool
+	checkpoint         bool
+	down               bool
 	silkwormPath       string
 	file               string
 )
@@ -101,6 +104,18 @@ func withMigration(cmd *cobra.Command) {
 	cmd.Flags().StringVar(&migration, "migration", "", "action to apply to given migration")
 }
 
+func withDryRun(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&dryRun, "dry_run", false, "print the buckets which the migrations are going to change and their sizes, don't change anything")
+}
+
+func withCheckpoint(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&checkpoint, "checkpoint", false, "copy-compact the db into <chaindata>_checkpoint before the migrations, restore their buckets from it if they fail")
+}
+
+func withDown(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&down, "down", false, "revert the migration by its Down function instead of only removing its record")
+}
+
 func withSilkworm(cmd *cobra.Command) {
 	cmd.Flags().StringVar(&silkwormPath, "silkworm", "", "file path of libsilkworm_tg_api.so")
 	must(cmd.MarkFlagFilename("silkworm"))
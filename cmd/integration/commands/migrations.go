@@ -0,0 +1,100 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/ledgerwatch/lmdb-go/lmdb"
+	"github.com/ledgerwatch/turbo-geth/common"
+	"github.com/ledgerwatch/turbo-geth/common/dbutils"
+	"github.com/ledgerwatch/turbo-geth/ethdb"
+	"github.com/ledgerwatch/turbo-geth/log"
+	"github.com/ledgerwatch/turbo-geth/migrations"
+)
+
+// lmdbCheckpoint is the LMDB database with the copies of the buckets changed by the migrations
+type lmdbCheckpoint struct {
+	path string
+}
+
+func newLmdbCheckpoint(chaindata string) *lmdbCheckpoint {
+	return &lmdbCheckpoint{path: chaindata + "_checkpoint"}
+}
+
+// Save copies the buckets into the new LMDB env, the buckets which don't exist in db are not created there
+func (c *lmdbCheckpoint) Save(db ethdb.Database, buckets []string) error {
+	migrator, ok := db.(ethdb.BucketsMigrator)
+	if !ok {
+		return errors.New("checkpoint requires the database which can migrate buckets")
+	}
+	existing := make([]string, 0, len(buckets))
+	for _, bucket := range buckets {
+		exists, err := migrator.BucketExists(bucket)
+		if err != nil {
+			return err
+		}
+		if exists {
+			existing = append(existing, bucket)
+		}
+	}
+	if err := os.RemoveAll(c.path); err != nil {
+		return err
+	}
+	log.Info("Saving checkpoint", "path", c.path, "buckets", existing)
+	kv, err := ethdb.NewLMDB().Path(c.path).WithBucketsConfig(func(defaultBuckets dbutils.BucketsCfg) dbutils.BucketsCfg {
+		cfg := dbutils.BucketsCfg{}
+		for _, bucket := range existing {
+			cfg[bucket] = defaultBuckets[bucket]
+		}
+		return cfg
+	}).Open()
+	if err != nil {
+		return err
+	}
+	defer kv.Close()
+	for _, bucket := range existing {
+		if err = kv.Update(context.Background(), func(tx ethdb.Tx) error {
+			// the deprecated buckets are not created on open
+			if err := tx.(ethdb.BucketMigrator).CreateBucket(bucket); err != nil {
+				return err
+			}
+			c := tx.Cursor(bucket)
+			return db.Walk(bucket, nil, 0, func(k, v []byte) (bool, error) {
+				return true, c.Put(common.CopyBytes(k), common.CopyBytes(v))
+			})
+		}); err != nil {
+			return fmt.Errorf("copying bucket %s: %w", bucket, err)
+		}
+	}
+	return nil
+}
+
+func (c *lmdbCheckpoint) Open() (ethdb.KV, error) {
+	return ethdb.NewLMDB().Path(c.path).Flags(func(flags uint) uint { return flags | lmdb.Readonly }).Open()
+}
+
+func printMigrationPlans(plans []migrations.MigrationPlan) {
+	w := new(tabwriter.Writer)
+	defer w.Flush()
+	w.Init(os.Stdout, 8, 8, 0, '\t', 0)
+	if len(plans) == 0 {
+		fmt.Fprintf(w, "no pending migrations\n")
+		return
+	}
+	for _, plan := range plans {
+		fmt.Fprintf(w, "%s \t reversible: %t\n", plan.Name, plan.Reversible)
+		if len(plan.Buckets) == 0 {
+			fmt.Fprintf(w, " \t buckets are not declared\n")
+		}
+		for _, bucket := range plan.Buckets {
+			if !bucket.Exists {
+				fmt.Fprintf(w, " \t %s \t not exists\n", bucket.Name)
+				continue
+			}
+			fmt.Fprintf(w, " \t %s \t %s\n", bucket.Name, common.StorageSize(bucket.Size))
+		}
+	}
+}
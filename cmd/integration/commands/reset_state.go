@@ -103,9 +103,15 @@ func resetState(db ethdb.Database, _ context.Context) error {
 	if err := resetLogIndex(db); err != nil {
 		return err
 	}
+	if err := resetTokenTransfers(db); err != nil {
+		return err
+	}
 	if err := resetCallTraces(db); err != nil {
 		return err
 	}
+	if err := resetAccountTxs(db); err != nil {
+		return err
+	}
 	if err := resetTxLookup(db); err != nil {
 		return err
 	}
@@ -166,6 +172,9 @@ func resetExec(db rawdb.DatabaseWriter) error {
 	if err := stages.SaveStageUnwind(db, stages.Execution, 0); err != nil {
 		return err
 	}
+	if err := stages.SaveStageProgress(db, stages.Prune, 0); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -209,6 +218,20 @@ func resetLogIndex(db rawdb.DatabaseWriter) error {
 	return nil
 }
 
+func resetTokenTransfers(db rawdb.DatabaseWriter) error {
+	if err := db.(ethdb.BucketsMigrator).ClearBuckets(dbutils.TokenTransferIndex); err != nil {
+		return err
+	}
+	if err := stages.SaveStageProgress(db, stages.TokenTransfers, 0); err != nil {
+		return err
+	}
+	if err := stages.SaveStageUnwind(db, stages.TokenTransfers, 0); err != nil {
+		return err
+	}
+
+	return nil
+}
+
 func resetCallTraces(db rawdb.DatabaseWriter) error {
 	if err := db.(ethdb.BucketsMigrator).ClearBuckets(
 		dbutils.CallFromIndex,
@@ -226,6 +249,20 @@ func resetCallTraces(db rawdb.DatabaseWriter) error {
 	return nil
 }
 
+func resetAccountTxs(db rawdb.DatabaseWriter) error {
+	if err := db.(ethdb.BucketsMigrator).ClearBuckets(dbutils.AccountTxIndex); err != nil {
+		return err
+	}
+	if err := stages.SaveStageProgress(db, stages.AccountTxs, 0); err != nil {
+		return err
+	}
+	if err := stages.SaveStageUnwind(db, stages.AccountTxs, 0); err != nil {
+		return err
+	}
+
+	return nil
+}
+
 func resetTxLookup(db rawdb.DatabaseWriter) error {
 	if err := db.(ethdb.BucketsMigrator).ClearBuckets(
 		dbutils.TxLookupPrefix,
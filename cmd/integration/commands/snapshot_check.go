@@ -201,7 +201,9 @@ func snapshotCheck(ctx context.Context, db ethdb.Database, isNew bool, tmpDir st
 		stages.AccountHistoryIndex,
 		stages.StorageHistoryIndex,
 		stages.LogIndex,
+		stages.TokenTransfers,
 		stages.CallTraces,
+		stages.AccountTxs,
 		stages.TxLookup,
 		stages.TxPool,
 		stages.Finish,
@@ -16,7 +16,6 @@ import (
 	"github.com/ledgerwatch/turbo-geth/common/etl"
 	"github.com/ledgerwatch/turbo-geth/consensus/ethash"
 	"github.com/ledgerwatch/turbo-geth/core"
-	"github.com/ledgerwatch/turbo-geth/core/rawdb"
 	"github.com/ledgerwatch/turbo-geth/core/vm"
 	"github.com/ledgerwatch/turbo-geth/eth/stagedsync"
 	"github.com/ledgerwatch/turbo-geth/eth/stagedsync/stages"
@@ -125,6 +124,22 @@ var cmdLogIndex = &cobra.Command{
 	},
 }
 
+var cmdTokenTransfers = &cobra.Command{
+	Use:   "stage_token_transfers",
+	Short: "",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := utils.RootContext()
+		db := openDatabase(chaindata, true)
+		defer db.Close()
+
+		if err := stageTokenTransfers(db, ctx); err != nil {
+			log.Error("Error", "err", err)
+			return err
+		}
+		return nil
+	},
+}
+
 var cmdCallTraces = &cobra.Command{
 	Use:   "stage_call_traces",
 	Short: "",
@@ -141,6 +156,22 @@ var cmdCallTraces = &cobra.Command{
 	},
 }
 
+var cmdAccountTxs = &cobra.Command{
+	Use:   "stage_account_txs",
+	Short: "",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := utils.RootContext()
+		db := openDatabase(chaindata, true)
+		defer db.Close()
+
+		if err := stageAccountTxs(db, ctx); err != nil {
+			log.Error("Error", "err", err)
+			return err
+		}
+		return nil
+	},
+}
+
 var cmdStageTxLookup = &cobra.Command{
 	Use:   "stage_tx_lookup",
 	Short: "",
@@ -192,7 +223,7 @@ var cmdRemoveMigration = &cobra.Command{
 	Short: "",
 	RunE: func(cmd *cobra.Command, args []string) error {
 		ctx := utils.RootContext()
-		db := openDatabase(chaindata, false)
+		db := ethdb.NewObjectDatabase(openKV(chaindata, true))
 		defer db.Close()
 		if err := removeMigration(db, ctx); err != nil {
 			log.Error("Error", "err", err)
@@ -206,9 +237,13 @@ var cmdRunMigrations = &cobra.Command{
 	Use:   "run_migrations",
 	Short: "",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		db := openDatabase(chaindata, false)
+		ctx := utils.RootContext()
+		db := ethdb.NewObjectDatabase(openKV(chaindata, true))
 		defer db.Close()
-		// Nothing to do, migrations will be applied automatically
+		if err := runMigrations(db, ctx); err != nil {
+			log.Error("Error", "err", err)
+			return err
+		}
 		return nil
 	},
 }
@@ -273,6 +308,15 @@ func init() {
 
 	rootCmd.AddCommand(cmdLogIndex)
 
+	withChaindata(cmdTokenTransfers)
+	withLmdbFlags(cmdTokenTransfers)
+	withReset(cmdTokenTransfers)
+	withBlock(cmdTokenTransfers)
+	withUnwind(cmdTokenTransfers)
+	withDatadir(cmdTokenTransfers)
+
+	rootCmd.AddCommand(cmdTokenTransfers)
+
 	withChaindata(cmdCallTraces)
 	withLmdbFlags(cmdCallTraces)
 	withReset(cmdCallTraces)
@@ -282,6 +326,15 @@ func init() {
 
 	rootCmd.AddCommand(cmdCallTraces)
 
+	withChaindata(cmdAccountTxs)
+	withLmdbFlags(cmdAccountTxs)
+	withReset(cmdAccountTxs)
+	withBlock(cmdAccountTxs)
+	withUnwind(cmdAccountTxs)
+	withDatadir(cmdAccountTxs)
+
+	rootCmd.AddCommand(cmdAccountTxs)
+
 	withChaindata(cmdStageTxLookup)
 	withLmdbFlags(cmdStageTxLookup)
 	withReset(cmdStageTxLookup)
@@ -297,11 +350,17 @@ func init() {
 	withChaindata(cmdRemoveMigration)
 	withLmdbFlags(cmdRemoveMigration)
 	withMigration(cmdRemoveMigration)
+	withDatadir(cmdRemoveMigration)
+	withDryRun(cmdRemoveMigration)
+	withCheckpoint(cmdRemoveMigration)
+	withDown(cmdRemoveMigration)
 	rootCmd.AddCommand(cmdRemoveMigration)
 
 	withChaindata(cmdRunMigrations)
 	withLmdbFlags(cmdRunMigrations)
 	withDatadir(cmdRunMigrations)
+	withDryRun(cmdRunMigrations)
+	withCheckpoint(cmdRunMigrations)
 	rootCmd.AddCommand(cmdRunMigrations)
 }
 
@@ -486,6 +545,35 @@ func stageLogIndex(db ethdb.Database, ctx context.Context) error {
 	return nil
 }
 
+func stageTokenTransfers(db ethdb.Database, ctx context.Context) error {
+	tmpdir := path.Join(datadir, etl.TmpDirName)
+
+	_, bc, _, progress := newSync(ctx.Done(), db, db, nil)
+	defer bc.Stop()
+
+	if reset {
+		if err := resetTokenTransfers(db); err != nil {
+			return err
+		}
+		return nil
+	}
+	execStage := progress(stages.Execution)
+	s := progress(stages.TokenTransfers)
+	log.Info("Stage exec", "progress", execStage.BlockNumber)
+	log.Info("Stage token transfers", "progress", s.BlockNumber)
+	ch := ctx.Done()
+
+	if unwind > 0 {
+		u := &stagedsync.UnwindState{Stage: stages.TokenTransfers, UnwindPoint: s.BlockNumber - unwind}
+		return stagedsync.UnwindTokenTransfers(u, s, db, ch)
+	}
+
+	if err := stagedsync.SpawnTokenTransfers(s, db, tmpdir, ch); err != nil {
+		return err
+	}
+	return nil
+}
+
 func stageCallTraces(db ethdb.Database, ctx context.Context) error {
 	tmpdir := path.Join(datadir, etl.TmpDirName)
 
@@ -533,6 +621,48 @@ func stageCallTraces(db ethdb.Database, ctx context.Context) error {
 	return nil
 }
 
+func stageAccountTxs(db ethdb.Database, ctx context.Context) error {
+	tmpdir := path.Join(datadir, etl.TmpDirName)
+
+	_, bc, _, progress := newSync(ctx.Done(), db, db, nil)
+	defer bc.Stop()
+
+	if reset {
+		if err := resetAccountTxs(db); err != nil {
+			return err
+		}
+		return nil
+	}
+	execStage := progress(stages.Execution)
+	s := progress(stages.AccountTxs)
+	log.Info("Stage exec", "progress", execStage.BlockNumber)
+	log.Info("Stage account txs", "progress", s.BlockNumber)
+	ch := ctx.Done()
+
+	var batchSize datasize.ByteSize
+	must(batchSize.UnmarshalText([]byte(batchSizeStr)))
+	var cacheSize datasize.ByteSize
+	must(cacheSize.UnmarshalText([]byte(cacheSizeStr)))
+	if unwind > 0 {
+		u := &stagedsync.UnwindState{Stage: stages.AccountTxs, UnwindPoint: s.BlockNumber - unwind}
+		return stagedsync.UnwindAccountTxs(u, s, db, bc.Config(), bc, ch,
+			stagedsync.AccountTxsStageParams{
+				CacheSize: int(cacheSize),
+				BatchSize: int(batchSize),
+			})
+	}
+
+	if err := stagedsync.SpawnAccountTxs(s, db, bc.Config(), bc, tmpdir, ch,
+		stagedsync.AccountTxsStageParams{
+			ToBlock:   block,
+			CacheSize: int(cacheSize),
+			BatchSize: int(batchSize),
+		}); err != nil {
+		return err
+	}
+	return nil
+}
+
 func stageHistory(db ethdb.Database, ctx context.Context) error {
 	tmpdir := path.Join(datadir, etl.TmpDirName)
 
@@ -630,11 +760,40 @@ func printAppliedMigrations(db ethdb.Database, _ context.Context) error {
 	return nil
 }
 
-func removeMigration(db rawdb.DatabaseDeleter, _ context.Context) error {
-	if err := db.Delete(dbutils.Migrations, []byte(migration), nil); err != nil {
-		return err
+func runMigrations(db ethdb.Database, _ context.Context) error {
+	migrator := migrations.NewMigrator()
+	if dryRun {
+		plans, err := migrator.DryRun(db)
+		if err != nil {
+			return err
+		}
+		printMigrationPlans(plans)
+		return nil
 	}
-	return nil
+	if checkpoint {
+		migrator.Checkpoint = newLmdbCheckpoint(chaindata)
+	}
+	return migrator.Apply(db, path.Join(datadir, etl.TmpDirName))
+}
+
+func removeMigration(db ethdb.Database, _ context.Context) error {
+	migrator := migrations.NewMigrator()
+	if dryRun {
+		plan, err := migrator.RevertDryRun(db, migration)
+		if err != nil {
+			return err
+		}
+		printMigrationPlans([]migrations.MigrationPlan{plan})
+		return nil
+	}
+	if !down {
+		// only forget the migration, it's applied again on the next start
+		return db.Delete(dbutils.Migrations, []byte(migration), nil)
+	}
+	if checkpoint {
+		migrator.Checkpoint = newLmdbCheckpoint(chaindata)
+	}
+	return migrator.Revert(db, path.Join(datadir, etl.TmpDirName), migration)
 }
 
 type progressFunc func(stage stages.SyncStage) *stagedsync.StageState
@@ -4,8 +4,10 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"strings"
 	"time"
 
+	proto_sentry "github.com/ledgerwatch/turbo-geth/cmd/headers/sentry"
 	"github.com/ledgerwatch/turbo-geth/cmd/utils"
 	"github.com/ledgerwatch/turbo-geth/ethdb"
 	"github.com/ledgerwatch/turbo-geth/internal/debug"
@@ -14,10 +16,13 @@ import (
 	"github.com/ledgerwatch/turbo-geth/rpc"
 	"github.com/ledgerwatch/turbo-geth/turbo/snapshotsync"
 	"github.com/spf13/cobra"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/backoff"
 )
 
 type Flags struct {
 	PrivateApiAddr       string
+	SentryApiAddr        string
 	Chaindata            string
 	SnapshotDir          string
 	SnapshotMode         string
@@ -31,8 +36,10 @@ type Flags struct {
 	API                  []string
 	Gascap               uint64
 	MaxTraces            uint64
+	MaxTraceRange        uint64
 	TraceType            string
 	WebsocketEnabled     bool
+	GraphQLEnabled       bool
 	RpcAllowListFilePath string
 }
 
@@ -56,6 +63,7 @@ func RootCommand() (*cobra.Command, *Flags) {
 
 	cfg := &Flags{}
 	rootCmd.PersistentFlags().StringVar(&cfg.PrivateApiAddr, "private.api.addr", "127.0.0.1:9090", "private api network address, for example: 127.0.0.1:9090, empty string means not to start the listener. do not expose to public network. serves remote database interface")
+	rootCmd.PersistentFlags().StringVar(&cfg.SentryApiAddr, "sentry.api.addr", "", "sentry api network address, for example: 127.0.0.1:9091, used to report the connected peers. do not expose to public network")
 	rootCmd.PersistentFlags().StringVar(&cfg.Chaindata, "chaindata", "", "path to the database")
 	rootCmd.PersistentFlags().StringVar(&cfg.SnapshotDir, "snapshotDir", "", "path to snapshot dir(only for chaindata mode)")
 	rootCmd.PersistentFlags().StringVar(&cfg.SnapshotMode, "snapshot-mode", "", `Configures the storage mode of the app(only for chaindata mode):
@@ -74,8 +82,10 @@ func RootCommand() (*cobra.Command, *Flags) {
 	rootCmd.PersistentFlags().StringSliceVar(&cfg.API, "http.api", []string{"eth", "tg"}, "API's offered over the HTTP-RPC interface")
 	rootCmd.PersistentFlags().Uint64Var(&cfg.Gascap, "rpc.gascap", 0, "Sets a cap on gas that can be used in eth_call/estimateGas")
 	rootCmd.PersistentFlags().Uint64Var(&cfg.MaxTraces, "trace.maxtraces", 200, "Sets a limit on traces that can be returned in trace_filter")
+	rootCmd.PersistentFlags().Uint64Var(&cfg.MaxTraceRange, "trace.maxrange", 0, "Sets a limit on the number of blocks that can be scanned by trace_filter, 0 means no limit")
 	rootCmd.PersistentFlags().StringVar(&cfg.TraceType, "trace.type", "parity", "Specify the type of tracing [geth|parity*] (experimental)")
 	rootCmd.PersistentFlags().BoolVar(&cfg.WebsocketEnabled, "ws", false, "Enable Websockets")
+	rootCmd.PersistentFlags().BoolVar(&cfg.GraphQLEnabled, "graphql", false, "Enable GraphQL on the HTTP-RPC server, served on /graphql")
 	rootCmd.PersistentFlags().StringVar(&cfg.RpcAllowListFilePath, "rpc.accessList", "", "Specify granular (method-by-method) API allowlist")
 
 	if err := rootCmd.MarkPersistentFlagFilename("rpc.accessList", "json"); err != nil {
@@ -124,7 +134,25 @@ func OpenDB(cfg Flags) (ethdb.KV, ethdb.Backend, error) {
 	return db, ethBackend, err
 }
 
-func StartRpcServer(ctx context.Context, cfg Flags, rpcAPI []rpc.API) error {
+// ConnectSentry creates the client of the sentry, which knows about the connected peers. Returns nil if the
+// sentry address is not configured
+func ConnectSentry(ctx context.Context, cfg Flags) (proto_sentry.SentryClient, error) {
+	if cfg.SentryApiAddr == "" {
+		return nil, nil
+	}
+	conn, err := grpc.DialContext(ctx, cfg.SentryApiAddr,
+		grpc.WithInsecure(),
+		grpc.WithConnectParams(grpc.ConnectParams{Backoff: backoff.DefaultConfig}),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not connect to sentry: %w", err)
+	}
+	return proto_sentry.NewSentryClient(conn), nil
+}
+
+// StartRpcServer serves the APIs over HTTP, and over websockets if enabled. The GraphQL handler, if given,
+// serves the requests to /graphql
+func StartRpcServer(ctx context.Context, cfg Flags, rpcAPI []rpc.API, graphQLHandler http.Handler) error {
 	// register apis and create handler stack
 	httpEndpoint := fmt.Sprintf("%s:%d", cfg.HttpListenAddress, cfg.HttpPort)
 
@@ -146,7 +174,15 @@ func StartRpcServer(ctx context.Context, cfg Flags, rpcAPI []rpc.API) error {
 		wsHandler = srv.WebsocketHandler([]string{"*"})
 	}
 
+	if graphQLHandler != nil {
+		graphQLHandler = node.NewHTTPHandlerStack(graphQLHandler, cfg.HttpCORSDomain, cfg.HttpVirtualHost)
+	}
+
 	var handler http.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if graphQLHandler != nil && (r.URL.Path == "/graphql" || strings.HasPrefix(r.URL.Path, "/graphql/")) {
+			graphQLHandler.ServeHTTP(w, r)
+			return
+		}
 		if cfg.WebsocketEnabled && r.Method == "GET" {
 			wsHandler.ServeHTTP(w, r)
 		}
@@ -162,7 +198,7 @@ func StartRpcServer(ctx context.Context, cfg Flags, rpcAPI []rpc.API) error {
 	if cfg.TraceType != "parity" {
 		log.Info("Tracing output type: ", cfg.TraceType)
 	}
-	log.Info("HTTP endpoint opened", "url", httpEndpoint, "ws", cfg.WebsocketEnabled)
+	log.Info("HTTP endpoint opened", "url", httpEndpoint, "ws", cfg.WebsocketEnabled, "graphql", graphQLHandler != nil)
 
 	defer func() {
 		srv.Stop()
@@ -0,0 +1,94 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/ledgerwatch/turbo-geth/ethdb"
+	"github.com/ledgerwatch/turbo-geth/p2p"
+)
+
+// AdminAPI the interface for the admin_ RPC commands
+type AdminAPI interface {
+	NodeInfo(_ context.Context) (*p2p.NodeInfo, error)
+	Peers(_ context.Context) ([]*p2p.PeerInfo, error)
+}
+
+// AdminAPIImpl data structure to store things needed for admin_ commands
+type AdminAPIImpl struct {
+	ethBackend ethdb.Backend
+}
+
+// NewAdminAPI returns AdminAPIImpl instance
+func NewAdminAPI(eth ethdb.Backend) *AdminAPIImpl {
+	return &AdminAPIImpl{
+		ethBackend: eth,
+	}
+}
+
+// NodeInfo implements admin_nodeInfo. Returns the information about the p2p server of the node.
+func (api *AdminAPIImpl) NodeInfo(_ context.Context) (*p2p.NodeInfo, error) {
+	if api.ethBackend == nil {
+		// We're running in --chaindata mode or otherwise cannot get the backend
+		return nil, fmt.Errorf(NotAvailableChainData, "admin_nodeInfo")
+	}
+
+	res, err := api.ethBackend.NodeInfo()
+	if err != nil {
+		return nil, err
+	}
+
+	info := &p2p.NodeInfo{
+		ID:         res.Id,
+		Name:       res.Name,
+		Enode:      res.Enode,
+		ENR:        res.Enr,
+		IP:         res.Ip,
+		ListenAddr: res.ListenerAddr,
+	}
+	info.Ports.Discovery = int(res.DiscoveryPort)
+	info.Ports.Listener = int(res.ListenerPort)
+	if len(res.Protocols) > 0 {
+		if err = json.Unmarshal(res.Protocols, &info.Protocols); err != nil {
+			return nil, fmt.Errorf("decoding protocols of the node: %w", err)
+		}
+	}
+	return info, nil
+}
+
+// Peers implements admin_peers. Returns the information about the peers connected to the node.
+func (api *AdminAPIImpl) Peers(_ context.Context) ([]*p2p.PeerInfo, error) {
+	if api.ethBackend == nil {
+		// We're running in --chaindata mode or otherwise cannot get the backend
+		return nil, fmt.Errorf(NotAvailableChainData, "admin_peers")
+	}
+
+	res, err := api.ethBackend.Peers()
+	if err != nil {
+		return nil, err
+	}
+
+	peers := make([]*p2p.PeerInfo, 0, len(res.Peers))
+	for _, peer := range res.Peers {
+		info := &p2p.PeerInfo{
+			ID:    peer.Id,
+			Name:  peer.Name,
+			Enode: peer.Enode,
+			ENR:   peer.Enr,
+			Caps:  peer.Caps,
+		}
+		info.Network.LocalAddress = peer.ConnLocalAddr
+		info.Network.RemoteAddress = peer.ConnRemoteAddr
+		info.Network.Inbound = peer.ConnIsInbound
+		info.Network.Trusted = peer.ConnIsTrusted
+		info.Network.Static = peer.ConnIsStatic
+		if len(peer.Protocols) > 0 {
+			if err = json.Unmarshal(peer.Protocols, &info.Protocols); err != nil {
+				return nil, fmt.Errorf("decoding protocols of the peer %s: %w", peer.Id, err)
+			}
+		}
+		peers = append(peers, info)
+	}
+	return peers, nil
+}
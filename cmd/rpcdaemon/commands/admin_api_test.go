@@ -0,0 +1,154 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/ledgerwatch/turbo-geth/common"
+	"github.com/ledgerwatch/turbo-geth/ethdb"
+	"github.com/ledgerwatch/turbo-geth/ethdb/remote"
+)
+
+// testBackend serves the prepared replies instead of connecting to the node
+type testBackend struct {
+	ethdb.Backend
+	nodeInfo   *remote.NodeInfoReply
+	peers      *remote.PeersReply
+	syncStatus *remote.SyncStatusReply
+	txPool     *remote.TxPoolContentReply
+	nonces     map[common.Address]*remote.NonceReply
+	pending    *remote.PendingBlockReply
+}
+
+func (b *testBackend) NetPeerCount() (uint64, error)                      { return uint64(len(b.peers.Peers)), nil }
+func (b *testBackend) ClientVersion() (string, error)                     { return b.nodeInfo.Name, nil }
+func (b *testBackend) NodeInfo() (*remote.NodeInfoReply, error)           { return b.nodeInfo, nil }
+func (b *testBackend) Peers() (*remote.PeersReply, error)                 { return b.peers, nil }
+func (b *testBackend) SyncStatus() (*remote.SyncStatusReply, error)       { return b.syncStatus, nil }
+func (b *testBackend) TxPoolContent() (*remote.TxPoolContentReply, error) { return b.txPool, nil }
+func (b *testBackend) TxPoolStatus() (*remote.TxPoolStatusReply, error) {
+	reply := &remote.TxPoolStatusReply{}
+	for _, account := range b.txPool.Pending {
+		reply.Pending += uint64(len(account.Txs))
+	}
+	for _, account := range b.txPool.Queued {
+		reply.Queued += uint64(len(account.Txs))
+	}
+	return reply, nil
+}
+func (b *testBackend) Nonce(address common.Address) (*remote.NonceReply, error) {
+	if reply, ok := b.nonces[address]; ok {
+		return reply, nil
+	}
+	return &remote.NonceReply{}, nil
+}
+
+func (b *testBackend) PendingBlock() (*remote.PendingBlockReply, error) {
+	if b.pending == nil {
+		return &remote.PendingBlockReply{}, nil
+	}
+	return b.pending, nil
+}
+
+func TestAdminAndNetAPI(t *testing.T) {
+	backend := &testBackend{
+		nodeInfo: &remote.NodeInfoReply{
+			Id:            "b4b9",
+			Name:          "TurboGeth/v2021.01.1/linux-amd64/go1.15",
+			Enode:         "enode://b4b9@127.0.0.1:30303",
+			Ip:            "127.0.0.1",
+			DiscoveryPort: 30303,
+			ListenerPort:  30303,
+			ListenerAddr:  "[::]:30303",
+			Protocols:     []byte(`{"eth":{"network":1}}`),
+		},
+		peers: &remote.PeersReply{Peers: []*remote.PeerInfo{{
+			Id:             "a1a2",
+			Name:           "Geth/v1.9.25",
+			Enode:          "enode://a1a2@10.0.0.1:30303",
+			Caps:           []string{"eth/64", "eth/65"},
+			ConnLocalAddr:  "10.0.0.2:30303",
+			ConnRemoteAddr: "10.0.0.1:30303",
+			ConnIsInbound:  true,
+			Protocols:      []byte(`{"eth":{"version":65}}`),
+		}}},
+		syncStatus: &remote.SyncStatusReply{Syncing: true, StartingBlock: 1, CurrentBlock: 5, HighestBlock: 10, Stages: []*remote.StageProgress{
+			{Stage: "Headers", BlockNumber: 10},
+			{Stage: "Finish", BlockNumber: 5},
+		}},
+	}
+	ctx := context.Background()
+
+	admin := NewAdminAPI(backend)
+	nodeInfo, err := admin.NodeInfo(ctx)
+	if err != nil {
+		t.Fatalf("admin_nodeInfo: %v", err)
+	}
+	assertJSONEqual(t, toJSONValue(t, nodeInfo), map[string]interface{}{
+		"id": "b4b9", "name": "TurboGeth/v2021.01.1/linux-amd64/go1.15", "enode": "enode://b4b9@127.0.0.1:30303", "enr": "", "ip": "127.0.0.1",
+		"ports":      map[string]interface{}{"discovery": 30303, "listener": 30303},
+		"listenAddr": "[::]:30303",
+		"protocols":  map[string]interface{}{"eth": map[string]interface{}{"network": 1}},
+	})
+	peers, err := admin.Peers(ctx)
+	if err != nil {
+		t.Fatalf("admin_peers: %v", err)
+	}
+	assertJSONEqual(t, toJSONValue(t, peers), []interface{}{map[string]interface{}{
+		"enode": "enode://a1a2@10.0.0.1:30303", "id": "a1a2", "name": "Geth/v1.9.25", "caps": []string{"eth/64", "eth/65"},
+		"network":   map[string]interface{}{"localAddress": "10.0.0.2:30303", "remoteAddress": "10.0.0.1:30303", "inbound": true, "trusted": false, "static": false},
+		"protocols": map[string]interface{}{"eth": map[string]interface{}{"version": 65}},
+	}})
+
+	net := NewNetAPIImpl(backend, nil)
+	if count, err := net.PeerCount(ctx); err != nil || count != 1 {
+		t.Errorf("net_peerCount: expected 1, got %d (%v)", count, err)
+	}
+	if listening, err := net.Listening(ctx); err != nil || !listening {
+		t.Errorf("net_listening: expected true, got %t (%v)", listening, err)
+	}
+	backend.nodeInfo.ListenerAddr = ""
+	if listening, err := net.Listening(ctx); err != nil || listening {
+		t.Errorf("net_listening: expected false, got %t (%v)", listening, err)
+	}
+
+	if clientVersion, err := NewWeb3APIImpl(backend).ClientVersion(ctx); err != nil || clientVersion != backend.nodeInfo.Name {
+		t.Errorf("web3_clientVersion: expected %s, got %s (%v)", backend.nodeInfo.Name, clientVersion, err)
+	}
+
+	eth := NewEthAPI(nil, nil, backend, 0, nil)
+	syncing, err := eth.Syncing(ctx)
+	if err != nil {
+		t.Fatalf("eth_syncing: %v", err)
+	}
+	assertJSONEqual(t, toJSONValue(t, syncing), map[string]interface{}{
+		"startingBlock": "0x1", "currentBlock": "0x5", "highestBlock": "0xa",
+		"stages": []interface{}{
+			map[string]interface{}{"stage_name": "Headers", "block_number": "0xa"},
+			map[string]interface{}{"stage_name": "Finish", "block_number": "0x5"},
+		},
+	})
+	backend.syncStatus = &remote.SyncStatusReply{CurrentBlock: 10, HighestBlock: 10}
+	if syncing, err = eth.Syncing(ctx); err != nil || syncing != false {
+		t.Errorf("eth_syncing: expected false, got %v (%v)", syncing, err)
+	}
+
+	if _, err = NewAdminAPI(nil).NodeInfo(ctx); err == nil {
+		t.Errorf("expected error without the backend")
+	}
+}
+
+// toJSONValue round-trips v through JSON, so that its fields are marshaled in the same order as the expected maps
+func toJSONValue(t *testing.T, v interface{}) interface{} {
+	t.Helper()
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var res interface{}
+	if err = json.Unmarshal(b, &res); err != nil {
+		t.Fatal(err)
+	}
+	return res
+}
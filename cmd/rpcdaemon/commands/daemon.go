@@ -1,6 +1,7 @@
 package commands
 
 import (
+	proto_sentry "github.com/ledgerwatch/turbo-geth/cmd/headers/sentry"
 	"github.com/ledgerwatch/turbo-geth/cmd/rpcdaemon/cli"
 	"github.com/ledgerwatch/turbo-geth/cmd/rpcdaemon/filters"
 	"github.com/ledgerwatch/turbo-geth/ethdb"
@@ -8,17 +9,19 @@ import (
 )
 
 // APIList describes the list of available RPC apis
-func APIList(db ethdb.KV, eth ethdb.Backend, filters *filters.Filters, cfg cli.Flags, customAPIList []rpc.API) []rpc.API {
+func APIList(db ethdb.KV, eth ethdb.Backend, sentry proto_sentry.SentryClient, filters *filters.Filters, cfg cli.Flags, customAPIList []rpc.API) []rpc.API {
 	var defaultAPIList []rpc.API
 
 	dbReader := ethdb.NewObjectDatabase(db)
 
 	ethImpl := NewEthAPI(db, dbReader, eth, cfg.Gascap, filters)
 	tgImpl := NewTgAPI(db, dbReader)
-	netImpl := NewNetAPIImpl(eth)
+	netImpl := NewNetAPIImpl(eth, sentry)
+	adminImpl := NewAdminAPI(eth)
+	txpoolImpl := NewTxPoolAPI(eth)
 	debugImpl := NewPrivateDebugAPI(dbReader, cfg.Gascap)
 	traceImpl := NewTraceAPI(dbReader, &cfg)
-	web3Impl := NewWeb3APIImpl()
+	web3Impl := NewWeb3APIImpl(eth)
 	dbImpl := NewDBAPIImpl()   /* deprecated */
 	shhImpl := NewSHHAPIImpl() /* deprecated */
 
@@ -45,6 +48,20 @@ func APIList(db ethdb.KV, eth ethdb.Backend, filters *filters.Filters, cfg cli.F
 				Service:   NetAPI(netImpl),
 				Version:   "1.0",
 			})
+		case "admin":
+			defaultAPIList = append(defaultAPIList, rpc.API{
+				Namespace: "admin",
+				Public:    false,
+				Service:   AdminAPI(adminImpl),
+				Version:   "1.0",
+			})
+		case "txpool":
+			defaultAPIList = append(defaultAPIList, rpc.API{
+				Namespace: "txpool",
+				Public:    true,
+				Service:   TxPoolAPI(txpoolImpl),
+				Version:   "1.0",
+			})
 		case "web3":
 			defaultAPIList = append(defaultAPIList, rpc.API{
 				Namespace: "web3",
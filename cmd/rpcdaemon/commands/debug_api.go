@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 
+	jsoniter "github.com/json-iterator/go"
 	"github.com/ledgerwatch/turbo-geth/common"
 	"github.com/ledgerwatch/turbo-geth/common/changeset"
 	"github.com/ledgerwatch/turbo-geth/common/hexutil"
@@ -16,6 +17,7 @@ import (
 	"github.com/ledgerwatch/turbo-geth/internal/ethapi"
 	"github.com/ledgerwatch/turbo-geth/rpc"
 	"github.com/ledgerwatch/turbo-geth/turbo/adapter"
+	"github.com/ledgerwatch/turbo-geth/turbo/rpchelper"
 	"github.com/ledgerwatch/turbo-geth/turbo/transactions"
 )
 
@@ -27,6 +29,9 @@ type PrivateDebugAPI interface {
 	GetModifiedAccountsByNumber(ctx context.Context, startNum rpc.BlockNumber, endNum *rpc.BlockNumber) ([]common.Address, error)
 	GetModifiedAccountsByHash(_ context.Context, startHash common.Hash, endHash *common.Hash) ([]common.Address, error)
 	TraceCall(ctx context.Context, args ethapi.CallArgs, blockNrOrHash rpc.BlockNumberOrHash, config *eth.TraceConfig) (interface{}, error)
+	TraceBlockByNumber(ctx context.Context, number rpc.BlockNumber, config *eth.TraceConfig, stream *jsoniter.Stream) error
+	TraceBlockByHash(ctx context.Context, hash common.Hash, config *eth.TraceConfig, stream *jsoniter.Stream) error
+	StandardTraceBlockToFile(ctx context.Context, hash common.Hash, config *eth.StdTraceConfig) ([]string, error)
 }
 
 // PrivateDebugAPIImpl is implementation of the PrivateDebugAPI interface based on remote Db access
@@ -65,6 +70,11 @@ func (api *PrivateDebugAPIImpl) StorageRangeAt(ctx context.Context, blockHash co
 	if err != nil {
 		return StorageRangeResult{}, err
 	}
+	if number := rawdb.ReadHeaderNumber(tx, blockHash); number != nil {
+		if err = rpchelper.CheckPruned(tx, *number); err != nil {
+			return StorageRangeResult{}, err
+		}
+	}
 	_, _, _, stateReader, err := transactions.ComputeTxEnv(ctx, bc, chainConfig, cc, tx.(ethdb.HasTx).Tx(), blockHash, txIndex)
 	if err != nil {
 		return StorageRangeResult{}, err
@@ -108,6 +118,9 @@ func (api *PrivateDebugAPIImpl) AccountRange(ctx context.Context, blockNrOrHash
 		blockNumber = block.NumberU64()
 	}
 
+	if err = rpchelper.CheckPruned(tx, blockNumber); err != nil {
+		return state.IteratorDump{}, err
+	}
 	if maxResults > eth.AccountRangeMaxResults || maxResults <= 0 {
 		maxResults = eth.AccountRangeMaxResults
 	}
@@ -165,6 +178,9 @@ func (api *PrivateDebugAPIImpl) GetModifiedAccountsByNumber(ctx context.Context,
 	if startNum > endNum {
 		return nil, fmt.Errorf("start block (%d) must be less than or equal to end block (%d)", startNum, endNum)
 	}
+	if err = rpchelper.CheckPruned(tx, startNum); err != nil {
+		return nil, err
+	}
 
 	return changeset.GetModifiedAccounts(tx, startNum, endNum)
 }
@@ -201,6 +217,9 @@ func (api *PrivateDebugAPIImpl) GetModifiedAccountsByHash(ctx context.Context, s
 	if startNum > endNum {
 		return nil, fmt.Errorf("start block (%d) must be less than or equal to end block (%d)", startNum, endNum)
 	}
+	if err = rpchelper.CheckPruned(tx, startNum); err != nil {
+		return nil, err
+	}
 
 	return changeset.GetModifiedAccounts(tx, startNum, endNum)
 }
@@ -1,12 +1,19 @@
 package commands
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
 	"testing"
 
+	jsoniter "github.com/json-iterator/go"
 	"github.com/ledgerwatch/turbo-geth/common"
+	"github.com/ledgerwatch/turbo-geth/core/rawdb"
 	"github.com/ledgerwatch/turbo-geth/eth"
 	"github.com/ledgerwatch/turbo-geth/internal/ethapi"
+	"github.com/ledgerwatch/turbo-geth/rpc"
 )
 
 var debugTraceTransactionTests = []struct {
@@ -79,3 +86,133 @@ func TestTraceTransactionNoRefund(t *testing.T) {
 		}
 	}
 }
+
+// decodeBlockTraces decodes the result of debug_traceBlockByNumber/ByHash, the results of the transactions stay encoded
+func decodeBlockTraces(t *testing.T, data json.RawMessage) []*TxTraceResult {
+	t.Helper()
+	var encoded []struct {
+		Result json.RawMessage `json:"result"`
+		Error  string          `json:"error"`
+	}
+	if err := json.Unmarshal(data, &encoded); err != nil {
+		t.Fatalf("decode block traces: %v", err)
+	}
+	results := make([]*TxTraceResult, len(encoded))
+	for i, res := range encoded {
+		results[i] = &TxTraceResult{Error: res.Error}
+		if res.Result != nil {
+			results[i].Result = res.Result
+		}
+	}
+	return results
+}
+
+// streamed returns what f writes to the stream
+func streamed(f func(stream *jsoniter.Stream) error) ([]byte, error) {
+	var buf bytes.Buffer
+	stream := jsoniter.NewStream(jsoniter.ConfigDefault, &buf, 4096)
+	err := f(stream)
+	return buf.Bytes(), err
+}
+
+// decodeExecutionResult decodes the result of the transaction traced by the default tracer
+func decodeExecutionResult(t *testing.T, res *TxTraceResult) *ethapi.ExecutionResult {
+	t.Helper()
+	var er ethapi.ExecutionResult
+	if err := json.Unmarshal(res.Result.(json.RawMessage), &er); err != nil {
+		t.Fatalf("decode transaction trace: %v", err)
+	}
+	return &er
+}
+
+func TestTraceBlock(t *testing.T) {
+	db, err := createTestDb()
+	if err != nil {
+		t.Fatalf("create test db: %v", err)
+	}
+	api := NewPrivateDebugAPI(db, 0)
+	for _, tt := range debugTraceTransactionTests {
+		txn, blockHash, blockNumber, txIndex := rawdb.ReadTransaction(db, common.HexToHash(tt.txHash))
+		if txn == nil {
+			t.Fatalf("transaction %s not found", tt.txHash)
+		}
+		encoded, err1 := streamed(func(stream *jsoniter.Stream) error {
+			return api.TraceBlockByHash(context.Background(), blockHash, &eth.TraceConfig{}, stream)
+		})
+		if err1 != nil {
+			t.Fatalf("traceBlockByHash %x: %v", blockHash, err1)
+		}
+		byHash := decodeBlockTraces(t, encoded)
+		encoded, err1 = streamed(func(stream *jsoniter.Stream) error {
+			return api.TraceBlockByNumber(context.Background(), rpc.BlockNumber(blockNumber), &eth.TraceConfig{}, stream)
+		})
+		if err1 != nil {
+			t.Fatalf("traceBlockByNumber %d: %v", blockNumber, err1)
+		}
+		byNumber := decodeBlockTraces(t, encoded)
+		if len(byHash) != len(byNumber) || int(txIndex) >= len(byHash) {
+			t.Fatalf("wrong number of traces in block %d: %d by hash, %d by number", blockNumber, len(byHash), len(byNumber))
+		}
+		for _, res := range []*TxTraceResult{byHash[txIndex], byNumber[txIndex]} {
+			if res.Error != "" {
+				t.Fatalf("trace of transaction %s failed: %s", tt.txHash, res.Error)
+			}
+			er := decodeExecutionResult(t, res)
+			if er.Gas != tt.gas || er.Failed != tt.failed || er.ReturnValue != tt.returnValue {
+				t.Errorf("wrong trace of transaction %s: gas %d, failed %t, return value %s", tt.txHash, er.Gas, er.Failed, er.ReturnValue)
+			}
+		}
+
+		tracer := "callTracer"
+		encoded, err1 = streamed(func(stream *jsoniter.Stream) error {
+			return api.TraceBlockByHash(context.Background(), blockHash, &eth.TraceConfig{Tracer: &tracer}, stream)
+		})
+		if err1 != nil {
+			t.Fatalf("traceBlockByHash with callTracer %x: %v", blockHash, err1)
+		}
+		calls := decodeBlockTraces(t, encoded)
+		if len(calls) != len(byHash) || calls[txIndex].Error != "" || calls[txIndex].Result == nil {
+			t.Errorf("wrong callTracer result for transaction %s: %+v", tt.txHash, calls[txIndex])
+		}
+
+		files, err1 := api.StandardTraceBlockToFile(context.Background(), blockHash, &eth.StdTraceConfig{TxHash: txn.Hash()})
+		if err1 != nil {
+			t.Fatalf("standardTraceBlockToFile %x: %v", blockHash, err1)
+		}
+		if len(files) != 1 {
+			t.Fatalf("expected one trace file, got %d", len(files))
+		}
+		data, err1 := ioutil.ReadFile(files[0])
+		os.Remove(files[0])
+		if err1 != nil {
+			t.Fatal(err1)
+		}
+		// The last line of the standard trace is the summary of the execution
+		lines := bytes.Split(bytes.TrimSpace(data), []byte("\n"))
+		if !bytes.HasPrefix(lines[len(lines)-1], []byte(`{"output":`)) {
+			t.Errorf("standard trace of transaction %s does not end with the summary", tt.txHash)
+		}
+	}
+}
+
+func TestTraceBlockNoRefund(t *testing.T) {
+	db, err := createTestDb()
+	if err != nil {
+		t.Fatalf("create test db: %v", err)
+	}
+	api := NewPrivateDebugAPI(db, 0)
+	norefunds := true
+	for _, tt := range debugTraceTransactionNoRefundTests {
+		_, blockHash, _, txIndex := rawdb.ReadTransaction(db, common.HexToHash(tt.txHash))
+		encoded, err1 := streamed(func(stream *jsoniter.Stream) error {
+			return api.TraceBlockByHash(context.Background(), blockHash, &eth.TraceConfig{NoRefunds: &norefunds}, stream)
+		})
+		if err1 != nil {
+			t.Fatalf("traceBlockByHash %x: %v", blockHash, err1)
+		}
+		er := decodeExecutionResult(t, decodeBlockTraces(t, encoded)[txIndex])
+		if er.Gas != tt.gas || er.Failed != tt.failed || er.ReturnValue != tt.returnValue {
+			t.Errorf("wrong trace of transaction %s: gas %d, failed %t, return value %s", tt.txHash, er.Gas, er.Failed, er.ReturnValue)
+		}
+	}
+}
@@ -6,5 +6,8 @@ const NotImplemented = "the method is currently not implemented: %s"
 // NotAvailableChainData x
 const NotAvailableChainData = "the function %s is not available, please use --private.api.addr option instead of --chaindata option"
 
+// NotAvailableSentry x
+const NotAvailableSentry = "the function %s is not available, please use --sentry.api.addr option to connect to the sentry"
+
 // NotAvailableDeprecated x
 const NotAvailableDeprecated = "the method has been deprecated: %s"
@@ -6,7 +6,6 @@ import (
 	"math/big"
 
 	"github.com/ledgerwatch/turbo-geth/ethdb"
-	"github.com/ledgerwatch/turbo-geth/turbo/adapter"
 	"github.com/ledgerwatch/turbo-geth/turbo/rpchelper"
 
 	"github.com/ledgerwatch/turbo-geth/common"
@@ -21,12 +20,16 @@ func (api *APIImpl) GetBalance(ctx context.Context, address common.Address, bloc
 		return nil, fmt.Errorf("getBalance cannot open tx: %v", err1)
 	}
 	defer tx.Rollback()
-	blockNumber, _, err := rpchelper.GetBlockNumber(blockNrOrHash, tx)
+	blockNumber, _, err := rpchelper.GetBlockNumber(rpchelper.LatestInsteadOfPending(blockNrOrHash), tx)
 	if err != nil {
 		return nil, err
 	}
 
-	acc, err := rpchelper.GetAccount(tx, blockNumber, address)
+	reader, err := api.stateReader(blockNrOrHash, blockNumber, tx)
+	if err != nil {
+		return nil, err
+	}
+	acc, err := reader.ReadAccountData(address)
 	if err != nil {
 		return nil, fmt.Errorf("cant get a balance for account %q for block %v", address.String(), blockNumber)
 	}
@@ -39,18 +42,34 @@ func (api *APIImpl) GetBalance(ctx context.Context, address common.Address, bloc
 }
 
 // GetTransactionCount implements eth_getTransactionCount. Returns the number of transactions sent from an address (the nonce).
+// With the `pending` tag the transactions waiting in the pool of the node are counted too.
 func (api *APIImpl) GetTransactionCount(ctx context.Context, address common.Address, blockNrOrHash rpc.BlockNumberOrHash) (*hexutil.Uint64, error) {
+	if number, ok := blockNrOrHash.Number(); ok && number == rpc.PendingBlockNumber {
+		// The next nonce known by the transaction pool, falls back to the pending state if the pool is not available
+		if api.ethBackend != nil {
+			res, err := api.ethBackend.Nonce(address)
+			if err != nil {
+				return nil, err
+			}
+			if res.Found {
+				return (*hexutil.Uint64)(&res.Nonce), nil
+			}
+		}
+	}
 	tx, err1 := api.dbReader.Begin(ctx, ethdb.RO)
 	if err1 != nil {
 		return nil, fmt.Errorf("getTransactionCount cannot open tx: %v", err1)
 	}
 	defer tx.Rollback()
-	blockNumber, _, err := rpchelper.GetBlockNumber(blockNrOrHash, tx)
+	blockNumber, _, err := rpchelper.GetBlockNumber(rpchelper.LatestInsteadOfPending(blockNrOrHash), tx)
 	if err != nil {
 		return nil, err
 	}
 	nonce := hexutil.Uint64(0)
-	reader := adapter.NewStateReader(tx.(ethdb.HasTx).Tx(), blockNumber)
+	reader, err := api.stateReader(blockNrOrHash, blockNumber, tx)
+	if err != nil {
+		return nil, err
+	}
 	acc, err := reader.ReadAccountData(address)
 	if acc == nil || err != nil {
 		return &nonce, err
@@ -65,12 +84,15 @@ func (api *APIImpl) GetCode(ctx context.Context, address common.Address, blockNr
 		return nil, fmt.Errorf("getCode cannot open tx: %v", err1)
 	}
 	defer tx.Rollback()
-	blockNumber, _, err := rpchelper.GetBlockNumber(blockNrOrHash, tx)
+	blockNumber, _, err := rpchelper.GetBlockNumber(rpchelper.LatestInsteadOfPending(blockNrOrHash), tx)
 	if err != nil {
 		return nil, err
 	}
 
-	reader := adapter.NewStateReader(tx.(ethdb.HasTx).Tx(), blockNumber)
+	reader, err := api.stateReader(blockNrOrHash, blockNumber, tx)
+	if err != nil {
+		return nil, err
+	}
 	acc, err := reader.ReadAccountData(address)
 	if acc == nil || err != nil {
 		return hexutil.Bytes(""), nil
@@ -92,11 +114,14 @@ func (api *APIImpl) GetStorageAt(ctx context.Context, address common.Address, in
 	}
 	defer tx.Rollback()
 
-	blockNumber, _, err := rpchelper.GetBlockNumber(blockNrOrHash, tx)
+	blockNumber, _, err := rpchelper.GetBlockNumber(rpchelper.LatestInsteadOfPending(blockNrOrHash), tx)
+	if err != nil {
+		return hexutil.Encode(common.LeftPadBytes(empty[:], 32)), err
+	}
+	reader, err := api.stateReader(blockNrOrHash, blockNumber, tx)
 	if err != nil {
 		return hexutil.Encode(common.LeftPadBytes(empty[:], 32)), err
 	}
-	reader := adapter.NewStateReader(tx.(ethdb.HasTx).Tx(), blockNumber)
 	acc, err := reader.ReadAccountData(address)
 	if acc == nil || err != nil {
 		return hexutil.Encode(common.LeftPadBytes(empty[:], 32)), err
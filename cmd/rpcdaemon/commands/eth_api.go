@@ -10,12 +10,15 @@ import (
 	"github.com/ledgerwatch/turbo-geth/common/hexutil"
 	"github.com/ledgerwatch/turbo-geth/core"
 	"github.com/ledgerwatch/turbo-geth/core/rawdb"
+	"github.com/ledgerwatch/turbo-geth/core/state"
 	"github.com/ledgerwatch/turbo-geth/core/types"
 	"github.com/ledgerwatch/turbo-geth/eth/filters"
 	"github.com/ledgerwatch/turbo-geth/ethdb"
 	"github.com/ledgerwatch/turbo-geth/internal/ethapi"
 	"github.com/ledgerwatch/turbo-geth/params"
 	"github.com/ledgerwatch/turbo-geth/rpc"
+	"github.com/ledgerwatch/turbo-geth/turbo/adapter"
+	"github.com/ledgerwatch/turbo-geth/turbo/rpchelper"
 )
 
 // EthAPI is a collection of functions that are exposed in the
@@ -33,6 +36,7 @@ type EthAPI interface {
 
 	// Receipt related (see ./eth_receipts.go)
 	GetTransactionReceipt(ctx context.Context, hash common.Hash) (map[string]interface{}, error)
+	GetBlockReceipts(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) ([]map[string]interface{}, error)
 	GetLogs(ctx context.Context, crit filters.FilterCriteria) ([]*types.Log, error)
 
 	// Uncle related (see ./eth_uncles.go)
@@ -42,11 +46,12 @@ type EthAPI interface {
 	GetUncleCountByBlockHash(ctx context.Context, hash common.Hash) (*hexutil.Uint, error)
 
 	// Filter related (see ./eth_filters.go)
-	NewPendingTransactionFilter(_ context.Context) (hexutil.Uint64, error)
-	NewBlockFilter(_ context.Context) (hexutil.Uint64, error)
-	NewFilter(_ context.Context, filter interface{}) (hexutil.Uint64, error)
-	UninstallFilter(_ context.Context, index hexutil.Uint64) (bool, error)
-	GetFilterChanges(_ context.Context, index hexutil.Uint64) ([]interface{}, error)
+	NewPendingTransactionFilter(_ context.Context) (string, error)
+	NewBlockFilter(_ context.Context) (string, error)
+	NewFilter(_ context.Context, crit filters.FilterCriteria) (string, error)
+	UninstallFilter(_ context.Context, index string) (bool, error)
+	GetFilterChanges(_ context.Context, index string) ([]interface{}, error)
+	GetFilterLogs(_ context.Context, index string) ([]*types.Log, error)
 
 	// Account related (see ./eth_accounts.go)
 	Accounts(ctx context.Context) ([]common.Address, error)
@@ -65,11 +70,12 @@ type EthAPI interface {
 	// Sending related (see ./eth_call.go)
 	Call(ctx context.Context, args ethapi.CallArgs, blockNrOrHash rpc.BlockNumberOrHash, overrides *map[common.Address]ethapi.Account) (hexutil.Bytes, error)
 	EstimateGas(ctx context.Context, args ethapi.CallArgs) (hexutil.Uint64, error)
+	CreateAccessList(ctx context.Context, args ethapi.CallArgs, blockNrOrHash *rpc.BlockNumberOrHash) (*AccessListResult, error)
 	SendRawTransaction(ctx context.Context, encodedTx hexutil.Bytes) (common.Hash, error)
 	SendTransaction(_ context.Context, txObject interface{}) (common.Hash, error)
 	Sign(ctx context.Context, _ common.Address, _ hexutil.Bytes) (hexutil.Bytes, error)
 	SignTransaction(_ context.Context, txObject interface{}) (common.Hash, error)
-	GetProof(ctx context.Context, address common.Address, storageKeys []string, blockNr rpc.BlockNumber) (*interface{}, error)
+	GetProof(ctx context.Context, address common.Address, storageKeys []string, blockNrOrHash rpc.BlockNumberOrHash) (*ethapi.AccountResult, error)
 
 	// Mining related (see ./eth_mining.go)
 	Coinbase(_ context.Context) (common.Address, error)
@@ -148,22 +154,47 @@ func NewEthAPI(db ethdb.KV, dbReader ethdb.Database, eth ethdb.Backend, gascap u
 	}
 }
 
+// pendingBlock returns the pending block of the node if blockNrOrHash is the `pending` tag, see rpchelper.ReadPendingBlock
+func (api *APIImpl) pendingBlock(blockNrOrHash rpc.BlockNumberOrHash, tx ethdb.Database) (*rpchelper.PendingBlock, error) {
+	if number, ok := blockNrOrHash.Number(); !ok || number != rpc.PendingBlockNumber || api.ethBackend == nil {
+		return nil, nil
+	}
+	return rpchelper.ReadPendingBlock(api.ethBackend, tx)
+}
+
+// stateReader returns the reader of the state after blockNumber, with the changes of the pending block on top if
+// blockNrOrHash is the `pending` tag
+func (api *APIImpl) stateReader(blockNrOrHash rpc.BlockNumberOrHash, blockNumber uint64, tx ethdb.Database) (state.StateReader, error) {
+	var reader state.StateReader = adapter.NewStateReader(tx.(ethdb.HasTx).Tx(), blockNumber)
+	pending, err := api.pendingBlock(blockNrOrHash, tx)
+	if err != nil {
+		return nil, err
+	}
+	if pending != nil {
+		reader = pending.StateReader(reader)
+	}
+	return reader, nil
+}
+
 // RPCTransaction represents a transaction that will serialize to the RPC representation of a transaction
 type RPCTransaction struct {
-	BlockHash        *common.Hash    `json:"blockHash"`
-	BlockNumber      *hexutil.Big    `json:"blockNumber"`
-	From             common.Address  `json:"from"`
-	Gas              hexutil.Uint64  `json:"gas"`
-	GasPrice         *hexutil.Big    `json:"gasPrice"`
-	Hash             common.Hash     `json:"hash"`
-	Input            hexutil.Bytes   `json:"input"`
-	Nonce            hexutil.Uint64  `json:"nonce"`
-	R                *hexutil.Big    `json:"r"`
-	S                *hexutil.Big    `json:"s"`
-	To               *common.Address `json:"to"`
-	TransactionIndex *hexutil.Uint64 `json:"transactionIndex"`
-	V                *hexutil.Big    `json:"v"`
-	Value            *hexutil.Big    `json:"value"`
+	BlockHash        *common.Hash      `json:"blockHash"`
+	BlockNumber      *hexutil.Big      `json:"blockNumber"`
+	From             common.Address    `json:"from"`
+	Gas              hexutil.Uint64    `json:"gas"`
+	GasPrice         *hexutil.Big      `json:"gasPrice"`
+	Hash             common.Hash       `json:"hash"`
+	Input            hexutil.Bytes     `json:"input"`
+	Nonce            hexutil.Uint64    `json:"nonce"`
+	R                *hexutil.Big      `json:"r"`
+	S                *hexutil.Big      `json:"s"`
+	To               *common.Address   `json:"to"`
+	TransactionIndex *hexutil.Uint64   `json:"transactionIndex"`
+	Type             hexutil.Uint64    `json:"type"`
+	Accesses         *types.AccessList `json:"accessList,omitempty"`
+	ChainID          *hexutil.Big      `json:"chainId,omitempty"`
+	V                *hexutil.Big      `json:"v"`
+	Value            *hexutil.Big      `json:"value"`
 }
 
 // newRPCTransaction returns a transaction that will serialize to the RPC
@@ -171,7 +202,7 @@ type RPCTransaction struct {
 func newRPCTransaction(tx *types.Transaction, blockHash common.Hash, blockNumber uint64, index uint64) *RPCTransaction {
 	var signer types.Signer = types.FrontierSigner{}
 	if tx.Protected() {
-		signer = types.NewEIP155Signer(tx.ChainID().ToBig())
+		signer = types.LatestSignerForChainID(tx.ChainID().ToBig())
 	}
 	from, _ := types.Sender(signer, tx)
 	v, r, s := tx.RawSignatureValues()
@@ -186,6 +217,7 @@ func newRPCTransaction(tx *types.Transaction, blockHash common.Hash, blockNumber
 		R:        (*hexutil.Big)(r.ToBig()),
 		S:        (*hexutil.Big)(s.ToBig()),
 		To:       tx.To(),
+		Type:     hexutil.Uint64(tx.Type()),
 		V:        (*hexutil.Big)(v.ToBig()),
 		Value:    (*hexutil.Big)(tx.Value().ToBig()),
 	}
@@ -194,5 +226,10 @@ func newRPCTransaction(tx *types.Transaction, blockHash common.Hash, blockNumber
 		result.BlockNumber = (*hexutil.Big)(new(big.Int).SetUint64(blockNumber))
 		result.TransactionIndex = (*hexutil.Uint64)(&index)
 	}
+	if tx.Type() == types.AccessListTxType {
+		al := tx.AccessList()
+		result.Accesses = &al
+		result.ChainID = (*hexutil.Big)(tx.ChainID().ToBig())
+	}
 	return result
 }
@@ -11,7 +11,9 @@ import (
 	"github.com/ledgerwatch/turbo-geth/core"
 	"github.com/ledgerwatch/turbo-geth/core/rawdb"
 	"github.com/ledgerwatch/turbo-geth/core/state"
+	"github.com/ledgerwatch/turbo-geth/core/types"
 	"github.com/ledgerwatch/turbo-geth/core/vm"
+	"github.com/ledgerwatch/turbo-geth/crypto"
 	"github.com/ledgerwatch/turbo-geth/ethdb"
 	"github.com/ledgerwatch/turbo-geth/internal/ethapi"
 	"github.com/ledgerwatch/turbo-geth/log"
@@ -34,7 +36,12 @@ func (api *APIImpl) Call(ctx context.Context, args ethapi.CallArgs, blockNrOrHas
 		return nil, err
 	}
 
-	result, err := transactions.DoCall(ctx, args, dbtx, blockNrOrHash, overrides, api.GasCap, chainConfig)
+	pendingBlock, err := api.pendingBlock(blockNrOrHash, dbtx)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := transactions.DoCall(ctx, args, dbtx, blockNrOrHash, pendingBlock, overrides, api.GasCap, chainConfig)
 	if err != nil {
 		return nil, err
 	}
@@ -48,11 +55,10 @@ func (api *APIImpl) Call(ctx context.Context, args ethapi.CallArgs, blockNrOrHas
 }
 
 // EstimateGas implements eth_estimateGas. Returns an estimate of how much gas is necessary to allow the transaction to complete. The transaction will not be added to the blockchain.
+// The estimate is made on top of the pending block.
 func (api *APIImpl) EstimateGas(ctx context.Context, args ethapi.CallArgs) (hexutil.Uint64, error) {
-	// TODO: fixme: blockNrOrHash := rpc.BlockNumberOrHashWithNumber(rpc.PendingBlockNumber)
-	hash := rawdb.ReadHeadBlockHash(api.dbReader)
-
-	return api.DoEstimateGas(ctx, args, rpc.BlockNumberOrHash{BlockHash: &hash}, big.NewInt(0).SetUint64(api.GasCap))
+	blockNrOrHash := rpc.BlockNumberOrHashWithNumber(rpc.PendingBlockNumber)
+	return api.DoEstimateGas(ctx, args, blockNrOrHash, big.NewInt(0).SetUint64(api.GasCap))
 }
 
 func (api *APIImpl) DoEstimateGas(ctx context.Context, args ethapi.CallArgs, blockNrOrHash rpc.BlockNumberOrHash, gasCap *big.Int) (hexutil.Uint64, error) {
@@ -73,7 +79,7 @@ func (api *APIImpl) DoEstimateGas(ctx context.Context, args ethapi.CallArgs, blo
 		args.From = new(common.Address)
 	}
 
-	blockNumber, hash, err := rpchelper.GetBlockNumber(blockNrOrHash, dbtx)
+	blockNumber, hash, err := rpchelper.GetBlockNumber(rpchelper.LatestInsteadOfPending(blockNrOrHash), dbtx)
 	if err != nil {
 		return 0, err
 	}
@@ -83,9 +89,16 @@ func (api *APIImpl) DoEstimateGas(ctx context.Context, args ethapi.CallArgs, blo
 		return 0, err
 	}
 
+	pendingBlock, err := api.pendingBlock(blockNrOrHash, dbtx)
+	if err != nil {
+		return 0, err
+	}
+
 	// Determine the highest gas limit can be used during the estimation.
 	if args.Gas != nil && uint64(*args.Gas) >= params.TxGas {
 		hi = uint64(*args.Gas)
+	} else if pendingBlock != nil {
+		hi = pendingBlock.Block.GasLimit()
 	} else {
 		// Retrieve the block to act as the gas ceiling
 		header := rawdb.ReadHeader(dbtx, hash, blockNumber)
@@ -93,7 +106,10 @@ func (api *APIImpl) DoEstimateGas(ctx context.Context, args ethapi.CallArgs, blo
 	}
 	// Recap the highest gas limit with account's available balance.
 	if args.GasPrice != nil && args.GasPrice.ToInt().Uint64() != 0 {
-		ds := state.NewPlainDBState(dbtx, blockNumber)
+		var ds state.StateReader = state.NewPlainDBState(dbtx, blockNumber)
+		if pendingBlock != nil {
+			ds = pendingBlock.StateReader(state.NewPlainStateReader(dbtx))
+		}
 		state := state.New(ds)
 		if state == nil {
 			return 0, fmt.Errorf("can't get the state for %d", blockNumber)
@@ -129,7 +145,7 @@ func (api *APIImpl) DoEstimateGas(ctx context.Context, args ethapi.CallArgs, blo
 	executable := func(gas uint64) (bool, *core.ExecutionResult, error) {
 		args.Gas = (*hexutil.Uint64)(&gas)
 
-		result, err := transactions.DoCall(ctx, args, dbtx, blockNrOrHash, nil, api.GasCap, chainConfig)
+		result, err := transactions.DoCall(ctx, args, dbtx, blockNrOrHash, pendingBlock, nil, api.GasCap, chainConfig)
 		if err != nil {
 			if errors.Is(err, core.ErrIntrinsicGas) {
 				// Special case, raise gas limit
@@ -178,8 +194,100 @@ func (api *APIImpl) DoEstimateGas(ctx context.Context, args ethapi.CallArgs, blo
 	return hexutil.Uint64(hi), nil
 }
 
-// GetProof not implemented
-func (api *APIImpl) GetProof(ctx context.Context, address common.Address, storageKeys []string, blockNr rpc.BlockNumber) (*interface{}, error) {
-	var stub interface{}
-	return &stub, fmt.Errorf(NotImplemented, "eth_getProof")
+// AccessListResult is the result of eth_createAccessList
+type AccessListResult struct {
+	Accesslist *types.AccessList `json:"accessList"`
+	Error      string            `json:"error,omitempty"`
+	GasUsed    hexutil.Uint64    `json:"gasUsed"`
+}
+
+// CreateAccessList implements eth_createAccessList. Creates the EIP-2930 access list of the transaction, based on the given
+// block (the pending one by default), and returns it together with the gas used by the transaction with the list.
+func (api *APIImpl) CreateAccessList(ctx context.Context, args ethapi.CallArgs, blockNrOrHash *rpc.BlockNumberOrHash) (*AccessListResult, error) {
+	bNrOrHash := rpc.BlockNumberOrHashWithNumber(rpc.PendingBlockNumber)
+	if blockNrOrHash != nil {
+		bNrOrHash = *blockNrOrHash
+	}
+
+	dbtx, err := api.dbReader.Begin(ctx, ethdb.RO)
+	if err != nil {
+		return nil, err
+	}
+	defer dbtx.Rollback()
+
+	chainConfig, err := api.chainConfig(dbtx)
+	if err != nil {
+		return nil, err
+	}
+	pendingBlock, err := api.pendingBlock(bNrOrHash, dbtx)
+	if err != nil {
+		return nil, err
+	}
+	stateReader, header, err := transactions.CallStateReader(dbtx, bNrOrHash, pendingBlock)
+	if err != nil {
+		return nil, err
+	}
+
+	// Use zero address if sender unspecified.
+	if args.From == nil {
+		args.From = new(common.Address)
+	}
+	// The created contract is not a part of the list, as well as the sender
+	var to common.Address
+	if args.To != nil {
+		to = *args.To
+	} else {
+		to = crypto.CreateAddress(*args.From, state.New(stateReader).GetNonce(*args.From))
+	}
+	// The precompiles don't need to be added to the access list
+	precompiles := vm.NewEVM(vm.Context{BlockNumber: header.Number}, nil, chainConfig, vm.Config{}).ActivePrecompiles()
+
+	// Start with the list of the request, and expand it until the execution touches nothing new
+	var initial types.AccessList
+	if args.AccessList != nil {
+		initial = *args.AccessList
+	}
+	prevTracer := vm.NewAccessListTracer(initial, *args.From, to, precompiles)
+	for {
+		accessList := prevTracer.AccessList()
+		log.Trace("Creating access list", "input", accessList)
+		args.AccessList = &accessList
+		msg := args.ToMessage(api.GasCap)
+
+		ibs := state.New(stateReader)
+		tracer := vm.NewAccessListTracer(accessList, *args.From, to, precompiles)
+		evmCtx := transactions.GetEvmContext(msg, header, bNrOrHash.RequireCanonical, dbtx)
+		evm := vm.NewEVM(evmCtx, ibs, chainConfig, vm.Config{Debug: true, Tracer: tracer})
+		if chainConfig.IsYoloV2(header.Number) {
+			ibs.PrepareAccessList(msg.From(), msg.To(), precompiles, msg.AccessList())
+		}
+
+		res, err := core.ApplyMessage(evm, msg, new(core.GasPool).AddGas(msg.Gas()), true /* refunds */)
+		if err != nil {
+			return nil, fmt.Errorf("failed to apply transaction: %w", err)
+		}
+		if tracer.Equal(prevTracer) {
+			result := &AccessListResult{Accesslist: &accessList, GasUsed: hexutil.Uint64(res.UsedGas)}
+			if res.Err != nil {
+				result.Error = res.Err.Error()
+			}
+			return result, nil
+		}
+		prevTracer = tracer
+	}
+}
+
+// GetProof implements eth_getProof. Returns the account and storage values of the specified account including the Merkle-proof (EIP-1186).
+func (api *APIImpl) GetProof(ctx context.Context, address common.Address, storageKeys []string, blockNrOrHash rpc.BlockNumberOrHash) (*ethapi.AccountResult, error) {
+	dbtx, err := api.dbReader.Begin(ctx, ethdb.RO)
+	if err != nil {
+		return nil, err
+	}
+	defer dbtx.Rollback()
+
+	blockNumber, _, err := rpchelper.GetBlockNumber(blockNrOrHash, dbtx)
+	if err != nil {
+		return nil, err
+	}
+	return getProof(dbtx, address, storageKeys, blockNumber)
 }
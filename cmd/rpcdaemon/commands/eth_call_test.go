@@ -0,0 +1,208 @@
+package commands
+
+import (
+	"context"
+	"math/big"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/turbo-geth/accounts/abi"
+	"github.com/ledgerwatch/turbo-geth/cmd/rpcdaemon/commands/contracts"
+	"github.com/ledgerwatch/turbo-geth/common"
+	"github.com/ledgerwatch/turbo-geth/common/hexutil"
+	"github.com/ledgerwatch/turbo-geth/core/rawdb"
+	"github.com/ledgerwatch/turbo-geth/core/state"
+	"github.com/ledgerwatch/turbo-geth/core/types"
+	"github.com/ledgerwatch/turbo-geth/core/types/accounts"
+	"github.com/ledgerwatch/turbo-geth/crypto"
+	"github.com/ledgerwatch/turbo-geth/eth/stagedsync/stages"
+	"github.com/ledgerwatch/turbo-geth/ethdb"
+	"github.com/ledgerwatch/turbo-geth/ethdb/remote"
+	"github.com/ledgerwatch/turbo-geth/internal/ethapi"
+	"github.com/ledgerwatch/turbo-geth/rlp"
+	"github.com/ledgerwatch/turbo-geth/rpc"
+)
+
+func TestPendingState(t *testing.T) {
+	db, err := createTestDb()
+	if err != nil {
+		t.Fatalf("create test db: %v", err)
+	}
+	latest, err := stages.GetStageProgress(db, stages.Execution)
+	if err != nil {
+		t.Fatal(err)
+	}
+	latestHash, err := rawdb.ReadCanonicalHash(db, latest)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	theAddr, contract := common.Address{1}, common.Address{0xcc}
+	// returns the value of the storage slot 0
+	code := common.FromHex("0x60005460005260206000f3")
+	backend := &testBackend{}
+	ctx := context.Background()
+	api := NewEthAPI(db.(ethdb.HasKV).KV(), db, backend, 5000000, nil)
+	latestTag := rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
+	pendingTag := rpc.BlockNumberOrHashWithNumber(rpc.PendingBlockNumber)
+	latestBalance, err := api.GetBalance(ctx, theAddr, latestTag)
+	if err != nil {
+		t.Fatalf("eth_getBalance: %v", err)
+	}
+
+	balance := accounts.NewAccount()
+	balance.Balance.SetFromBig(new(big.Int).Add(latestBalance.ToInt(), big.NewInt(1)))
+	deployed := accounts.NewAccount()
+	deployed.Incarnation = state.FirstContractIncarnation
+	deployed.CodeHash = crypto.Keccak256Hash(code)
+	encode := func(acc accounts.Account) []byte {
+		enc := make([]byte, acc.EncodingLengthForStorage())
+		acc.EncodeForStorage(enc)
+		return enc
+	}
+	setPending := func(parentHash common.Hash) {
+		block := types.NewBlockWithHeader(&types.Header{
+			ParentHash: parentHash,
+			Number:     new(big.Int).SetUint64(latest + 1),
+			Difficulty: big.NewInt(1),
+			GasLimit:   10000000,
+		})
+		blockRlp, err := rlp.EncodeToBytes(block)
+		if err != nil {
+			t.Fatal(err)
+		}
+		backend.pending = &remote.PendingBlockReply{Found: true, BlockRlp: blockRlp, StateDiff: []*remote.AccountDiff{
+			{Address: theAddr.Bytes(), Account: encode(balance)},
+			{Address: contract.Bytes(), Account: encode(deployed), Code: code, Storage: []*remote.StorageDiff{
+				{Location: common.Hash{}.Bytes(), Value: uint256.NewInt().SetUint64(42).Bytes()},
+			}},
+		}}
+	}
+	setPending(latestHash)
+
+	if res, err := api.GetBalance(ctx, theAddr, pendingTag); err != nil || res.ToInt().Cmp(balance.Balance.ToBig()) != 0 {
+		t.Errorf("eth_getBalance: expected the pending balance %d, got %d (%v)", balance.Balance.ToBig(), res.ToInt(), err)
+	}
+	if res, err := api.GetBalance(ctx, theAddr, latestTag); err != nil || res.ToInt().Cmp(latestBalance.ToInt()) != 0 {
+		t.Errorf("eth_getBalance: expected the latest balance %d, got %d (%v)", latestBalance.ToInt(), res.ToInt(), err)
+	}
+	if res, err := api.GetCode(ctx, contract, pendingTag); err != nil || hexutil.Encode(res) != hexutil.Encode(code) {
+		t.Errorf("eth_getCode: expected the pending code %x, got %x (%v)", code, res, err)
+	}
+	slot := "0x0000000000000000000000000000000000000000000000000000000000000000"
+	if res, err := api.GetStorageAt(ctx, contract, slot, pendingTag); err != nil || res != common.BigToHash(big.NewInt(42)).Hex() {
+		t.Errorf("eth_getStorageAt: expected 42, got %s (%v)", res, err)
+	}
+	if res, err := api.GetTransactionCount(ctx, contract, pendingTag); err != nil || *res != 0 {
+		t.Errorf("eth_getTransactionCount: expected 0, got %d (%v)", *res, err)
+	}
+
+	args := ethapi.CallArgs{To: &contract}
+	res, err := api.Call(ctx, args, pendingTag, nil)
+	if err != nil {
+		t.Fatalf("eth_call: %v", err)
+	}
+	if want := common.BigToHash(big.NewInt(42)).Bytes(); hexutil.Encode(res) != hexutil.Encode(want) {
+		t.Errorf("eth_call: expected %x on top of the pending block, got %x", want, res)
+	}
+	if res, err = api.Call(ctx, args, latestTag, nil); err != nil || len(res) != 0 {
+		t.Errorf("eth_call: expected empty result on top of the latest block, got %x (%v)", res, err)
+	}
+	gas, err := api.EstimateGas(ctx, args)
+	if err != nil {
+		t.Fatalf("eth_estimateGas: %v", err)
+	}
+	// the SLOAD of the contract costs more than the plain transfer
+	if gas <= 21000 {
+		t.Errorf("eth_estimateGas: expected the estimate on top of the pending block, got %d", gas)
+	}
+
+	// the pending block which isn't built on top of the latest block of the database is ignored
+	setPending(common.Hash{1})
+	if res, err := api.GetBalance(ctx, theAddr, pendingTag); err != nil || res.ToInt().Cmp(latestBalance.ToInt()) != 0 {
+		t.Errorf("eth_getBalance: expected the latest balance %d, got %d (%v)", latestBalance.ToInt(), res.ToInt(), err)
+	}
+	if res, err = api.Call(ctx, args, pendingTag, nil); err != nil || len(res) != 0 {
+		t.Errorf("eth_call: expected empty result on top of the latest block, got %x (%v)", res, err)
+	}
+
+	// the methods which don't apply the pending block don't serve the pending tag
+	if _, err = api.GetProof(ctx, theAddr, nil, pendingTag); err == nil {
+		t.Errorf("eth_getProof: expected the pending tag to be rejected")
+	}
+}
+
+func TestCreateAccessList(t *testing.T) {
+	db, err := createTestDb()
+	if err != nil {
+		t.Fatalf("create test db: %v", err)
+	}
+	var (
+		key, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		key1, _ = crypto.HexToECDSA("49a7b37aa6f6645917e7b807e9d1c00d4fa71f18343b0d4122a4d2df64dd6fee")
+		address = crypto.PubkeyToAddress(key.PublicKey)
+		minter  = crypto.PubkeyToAddress(key1.PublicKey)
+		// the token is deployed by the third transaction of address
+		token   = crypto.CreateAddress(address, 2)
+		theAddr = common.Address{1}
+	)
+	tokenABI, err := abi.JSON(strings.NewReader(contracts.TokenABI))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mint, err := tokenABI.Pack("mint", address, big.NewInt(5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	api := NewEthAPI(db.(ethdb.HasKV).KV(), db, nil, 5000000, nil)
+	latest := rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
+
+	// totalSupply, balanceOf[address] and minter
+	balanceSlot := crypto.Keccak256Hash(common.LeftPadBytes(address.Bytes(), 32), common.LeftPadBytes([]byte{1}, 32))
+	expected := []common.Hash{{}, balanceSlot, common.BigToHash(big.NewInt(2))}
+	sort.Slice(expected, func(i, j int) bool { return expected[i].Hex() < expected[j].Hex() })
+	data := hexutil.Bytes(mint)
+	res, err := api.CreateAccessList(ctx, ethapi.CallArgs{From: &minter, To: &token, Data: &data}, &latest)
+	if err != nil {
+		t.Fatalf("eth_createAccessList: %v", err)
+	}
+	if res.Error != "" {
+		t.Fatalf("eth_createAccessList: execution failed: %s", res.Error)
+	}
+	if len(*res.Accesslist) != 1 || (*res.Accesslist)[0].Address != token {
+		t.Fatalf("expected the storage of the token only, got %v", *res.Accesslist)
+	}
+	slots := (*res.Accesslist)[0].StorageKeys
+	sort.Slice(slots, func(i, j int) bool { return slots[i].Hex() < slots[j].Hex() })
+	assertJSONEqual(t, slots, expected)
+	if res.GasUsed <= 21000 {
+		t.Errorf("expected the gas used by the call, got %d", res.GasUsed)
+	}
+
+	// the call which fails is reported with the error, the access list of the touched storage is still returned
+	res, err = api.CreateAccessList(ctx, ethapi.CallArgs{From: &address, To: &token, Data: &data}, &latest)
+	if err != nil {
+		t.Fatalf("eth_createAccessList: %v", err)
+	}
+	if res.Error == "" || len(*res.Accesslist) != 1 || len((*res.Accesslist)[0].StorageKeys) != 1 {
+		t.Errorf("expected the reverted mint reading the minter only, got %v (%s)", *res.Accesslist, res.Error)
+	}
+
+	// the plain transfer, and the call before the token is deployed don't touch anything besides the sender and the recipient
+	for _, blockNrOrHash := range []*rpc.BlockNumberOrHash{nil, &latest} {
+		res, err = api.CreateAccessList(ctx, ethapi.CallArgs{From: &address, To: &theAddr}, blockNrOrHash)
+		if err != nil {
+			t.Fatalf("eth_createAccessList: %v", err)
+		}
+		if len(*res.Accesslist) != 0 || res.GasUsed != 21000 {
+			t.Errorf("expected the empty access list and 21000 gas, got %v and %d", *res.Accesslist, res.GasUsed)
+		}
+	}
+	first := rpc.BlockNumberOrHashWithNumber(1)
+	if res, err = api.CreateAccessList(ctx, ethapi.CallArgs{From: &minter, To: &token, Data: &data}, &first); err != nil || len(*res.Accesslist) != 0 {
+		t.Errorf("expected the empty access list before the token is deployed, got %v (%v)", res, err)
+	}
+}
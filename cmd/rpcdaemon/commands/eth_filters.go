@@ -2,38 +2,138 @@ package commands
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
-	"github.com/ledgerwatch/turbo-geth/common/hexutil"
+	rpcfilters "github.com/ledgerwatch/turbo-geth/cmd/rpcdaemon/filters"
 	"github.com/ledgerwatch/turbo-geth/core/types"
+	"github.com/ledgerwatch/turbo-geth/eth/filters"
+	"github.com/ledgerwatch/turbo-geth/ethdb"
 	"github.com/ledgerwatch/turbo-geth/log"
 	"github.com/ledgerwatch/turbo-geth/rpc"
 )
 
-// NewPendingTransactionFilter new transaction filter
-func (api *APIImpl) NewPendingTransactionFilter(_ context.Context) (hexutil.Uint64, error) {
-	return 0, fmt.Errorf(NotImplemented, "eth_newPendingTransactionFilter")
+var errFilterNotFound = errors.New("filter not found")
+
+// NewPendingTransactionFilter implements eth_newPendingTransactionFilter. Creates a filter in the node, to notify when new pending transactions arrive.
+func (api *APIImpl) NewPendingTransactionFilter(_ context.Context) (string, error) {
+	if api.filters == nil {
+		return "", rpc.ErrNotificationsUnsupported
+	}
+	return api.filters.NewPendingTxFilter(), nil
 }
 
-// NewBlockFilter new transaction filter
-func (api *APIImpl) NewBlockFilter(_ context.Context) (hexutil.Uint64, error) {
-	return 0, fmt.Errorf(NotImplemented, "eth_newBlockFilter")
+// NewBlockFilter implements eth_newBlockFilter. Creates a filter in the node, to notify when a new block arrives.
+func (api *APIImpl) NewBlockFilter(_ context.Context) (string, error) {
+	if api.filters == nil {
+		return "", rpc.ErrNotificationsUnsupported
+	}
+	return api.filters.NewBlockFilter(), nil
 }
 
 // NewFilter implements eth_newFilter. Creates an arbitrary filter object, based on filter options, to notify when the state changes (logs).
-func (api *APIImpl) NewFilter(_ context.Context, filter interface{}) (hexutil.Uint64, error) {
-	return 0, fmt.Errorf(NotImplemented, "eth_newFilter")
+func (api *APIImpl) NewFilter(ctx context.Context, crit filters.FilterCriteria) (string, error) {
+	if api.filters == nil {
+		return "", rpc.ErrNotificationsUnsupported
+	}
+	if crit.BlockHash != nil {
+		return "", fmt.Errorf("blockHash is not supported by eth_newFilter")
+	}
+	if crit.FromBlock != nil && crit.ToBlock != nil && crit.FromBlock.Sign() >= 0 && crit.ToBlock.Sign() >= 0 && crit.FromBlock.Cmp(crit.ToBlock) > 0 {
+		return "", fmt.Errorf("invalid block range")
+	}
+
+	tx, err := api.dbReader.Begin(ctx, ethdb.RO)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	latest, err := getLatestBlockNumber(tx)
+	if err != nil {
+		return "", err
+	}
+	// Only the logs of the blocks arriving after the filter was created are returned by eth_getFilterChanges,
+	// older logs are available via eth_getFilterLogs
+	nextBlock := latest + 1
+	if crit.FromBlock != nil && crit.FromBlock.Sign() >= 0 && crit.FromBlock.Uint64() > nextBlock {
+		nextBlock = crit.FromBlock.Uint64()
+	}
+	return api.filters.NewLogFilter(crit, nextBlock), nil
 }
 
-// UninstallFilter new transaction filter
-func (api *APIImpl) UninstallFilter(_ context.Context, index hexutil.Uint64) (bool, error) {
-	return false, fmt.Errorf(NotImplemented, "eth_uninstallFilter")
+// UninstallFilter implements eth_uninstallFilter. Uninstalls a filter with given id.
+func (api *APIImpl) UninstallFilter(_ context.Context, index string) (bool, error) {
+	if api.filters == nil {
+		return false, rpc.ErrNotificationsUnsupported
+	}
+	return api.filters.UninstallFilter(index), nil
 }
 
-// GetFilterChanges implements eth_getFilterChanges. Polling method for a previously-created filter, which returns an array of logs which occurred since last poll.
-func (api *APIImpl) GetFilterChanges(_ context.Context, index hexutil.Uint64) ([]interface{}, error) {
-	var stub []interface{}
-	return stub, fmt.Errorf(NotImplemented, "eth_getFilterChanges")
+// GetFilterChanges implements eth_getFilterChanges. Polling method for a previously-created filter, which returns an array of logs, block hashes or transaction hashes which occurred since last poll.
+func (api *APIImpl) GetFilterChanges(ctx context.Context, index string) ([]interface{}, error) {
+	if api.filters == nil {
+		return nil, rpc.ErrNotificationsUnsupported
+	}
+	typ, ok := api.filters.FilterType(index)
+	if !ok {
+		return nil, errFilterNotFound
+	}
+
+	if typ != rpcfilters.LogsFilter {
+		hashes, ok := api.filters.PopHashes(index)
+		if !ok {
+			return nil, errFilterNotFound
+		}
+		changes := make([]interface{}, len(hashes))
+		for i, hash := range hashes {
+			changes[i] = hash
+		}
+		return changes, nil
+	}
+
+	crit, nextBlock, ok := api.filters.LogFilter(index)
+	if !ok {
+		return nil, errFilterNotFound
+	}
+	tx, err := api.dbReader.Begin(ctx, ethdb.RO)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	end, err := getLatestBlockNumber(tx)
+	if err != nil {
+		return nil, err
+	}
+	if crit.ToBlock != nil && crit.ToBlock.Sign() >= 0 && crit.ToBlock.Uint64() < end {
+		end = crit.ToBlock.Uint64()
+	}
+	if nextBlock > end {
+		return []interface{}{}, nil
+	}
+	logs, err := api.getLogs(ctx, tx, crit, nextBlock, end)
+	if err != nil {
+		return nil, err
+	}
+	api.filters.SetLogFilterNextBlock(index, end+1)
+	changes := make([]interface{}, len(logs))
+	for i, l := range logs {
+		changes[i] = l
+	}
+	return changes, nil
+}
+
+// GetFilterLogs implements eth_getFilterLogs. Returns an array of all logs matching filter with given id.
+func (api *APIImpl) GetFilterLogs(ctx context.Context, index string) ([]*types.Log, error) {
+	if api.filters == nil {
+		return nil, rpc.ErrNotificationsUnsupported
+	}
+	crit, _, ok := api.filters.LogFilter(index)
+	if !ok {
+		return nil, errFilterNotFound
+	}
+	return api.GetLogs(ctx, crit)
 }
 
 // NewHeads send a notification each time a new (header) block is appended to the chain.
@@ -46,7 +146,7 @@ func (api *APIImpl) NewHeads(ctx context.Context) (*rpc.Subscription, error) {
 	rpcSub := notifier.CreateSubscription()
 
 	go func() {
-		headers := make(chan *types.Header)
+		headers := make(chan *types.Header, rpcfilters.SubscriptionBufferSize)
 		id := api.filters.SubscribeNewHeads(headers)
 
 		for {
@@ -68,3 +168,73 @@ func (api *APIImpl) NewHeads(ctx context.Context) (*rpc.Subscription, error) {
 
 	return rpcSub, nil
 }
+
+// NewPendingTransactions send a notification each time a new transaction is added to the transaction pool.
+func (api *APIImpl) NewPendingTransactions(ctx context.Context) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		txsCh := make(chan []*types.Transaction, rpcfilters.SubscriptionBufferSize)
+		id := api.filters.SubscribePendingTxs(txsCh)
+
+		for {
+			select {
+			case txs := <-txsCh:
+				for _, t := range txs {
+					err := notifier.Notify(rpcSub.ID, t.Hash())
+					if err != nil {
+						log.Warn("error while notifying subscription", "err", err)
+					}
+				}
+			case <-rpcSub.Err():
+				api.filters.Unsubscribe(id)
+				return
+			case <-notifier.Closed():
+				api.filters.Unsubscribe(id)
+				return
+			}
+		}
+	}()
+
+	return rpcSub, nil
+}
+
+// Logs send a notification each time a new log appears matching the given criteria.
+func (api *APIImpl) Logs(ctx context.Context, crit filters.FilterCriteria) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		logsCh := make(chan []*types.Log, rpcfilters.SubscriptionBufferSize)
+		id := api.filters.SubscribeLogs(logsCh)
+
+		for {
+			select {
+			case logs := <-logsCh:
+				for _, l := range filterLogs(logs, nil, nil, crit.Addresses, crit.Topics) {
+					err := notifier.Notify(rpcSub.ID, l)
+					if err != nil {
+						log.Warn("error while notifying subscription", "err", err)
+					}
+				}
+			case <-rpcSub.Err():
+				api.filters.Unsubscribe(id)
+				return
+			case <-notifier.Closed():
+				api.filters.Unsubscribe(id)
+				return
+			}
+		}
+	}()
+
+	return rpcSub, nil
+}
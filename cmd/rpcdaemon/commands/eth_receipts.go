@@ -17,16 +17,25 @@ import (
 	"github.com/ledgerwatch/turbo-geth/ethdb"
 	"github.com/ledgerwatch/turbo-geth/ethdb/bitmapdb"
 	"github.com/ledgerwatch/turbo-geth/params"
+	"github.com/ledgerwatch/turbo-geth/rpc"
 	"github.com/ledgerwatch/turbo-geth/turbo/adapter"
+	"github.com/ledgerwatch/turbo-geth/turbo/rpchelper"
 	"github.com/ledgerwatch/turbo-geth/turbo/transactions"
 )
 
-func getReceipts(ctx context.Context, tx ethdb.Database, chainConfig *params.ChainConfig, number uint64, hash common.Hash) (types.Receipts, error) {
+// GetReceipts returns the receipts of the block, re-executing it if the receipts are not stored
+func GetReceipts(ctx context.Context, tx ethdb.Database, chainConfig *params.ChainConfig, number uint64, hash common.Hash) (types.Receipts, error) {
+	if err := rpchelper.CheckPruned(tx, number); err != nil {
+		return nil, err
+	}
 	if cached := rawdb.ReadReceipts(tx, hash, number); cached != nil {
 		return cached, nil
 	}
 
 	block := rawdb.ReadBlock(tx, hash, number)
+	if block == nil {
+		return nil, fmt.Errorf("block %d(%x) not found", number, hash)
+	}
 
 	cc := adapter.NewChainContext(tx)
 	bc := adapter.NewBlockGetter(tx)
@@ -78,15 +87,28 @@ func (api *APIImpl) GetLogs(ctx context.Context, crit filters.FilterCriteria) ([
 		}
 
 		begin = latest
-		if crit.FromBlock != nil {
+		if crit.FromBlock != nil && crit.FromBlock.Sign() >= 0 {
 			begin = crit.FromBlock.Uint64()
 		}
 		end = latest
-		if crit.ToBlock != nil {
+		if crit.ToBlock != nil && crit.ToBlock.Sign() >= 0 {
 			end = crit.ToBlock.Uint64()
 		}
 	}
 
+	return api.getLogs(ctx, tx, crit, begin, end)
+}
+
+// getLogs returns the logs matching the addresses and topics of the criteria in the blocks [begin, end].
+// Candidate blocks are found with the LogAddressIndex and LogTopicIndex bitmaps
+func (api *APIImpl) getLogs(ctx context.Context, tx ethdb.Database, crit filters.FilterCriteria, begin, end uint64) ([]*types.Log, error) {
+	var logs []*types.Log //nolint:prealloc
+	if begin <= end {
+		if err := rpchelper.CheckPruned(tx, begin); err != nil {
+			return nil, err
+		}
+	}
+
 	blockNumbers := roaring.New()
 	blockNumbers.AddRange(begin, end+1) // [min,max)
 
@@ -139,7 +161,7 @@ func (api *APIImpl) GetLogs(ctx context.Context, crit filters.FilterCriteria) ([
 		if blockHash == (common.Hash{}) {
 			return returnLogs(logs), fmt.Errorf("block not found %d", uint64(blockNToMatch))
 		}
-		receipts, err := getReceipts(ctx, tx, cc, uint64(blockNToMatch), blockHash)
+		receipts, err := GetReceipts(ctx, tx, cc, uint64(blockNToMatch), blockHash)
 		if err != nil {
 			return returnLogs(logs), err
 		}
@@ -210,18 +232,63 @@ func (api *APIImpl) GetTransactionReceipt(ctx context.Context, hash common.Hash)
 	if err != nil {
 		return nil, err
 	}
-	receipts, err := getReceipts(ctx, tx, cc, blockNumber, blockHash)
+	receipts, err := GetReceipts(ctx, tx, cc, blockNumber, blockHash)
 	if err != nil {
 		return nil, fmt.Errorf("getReceipts error: %v", err)
 	}
 	if len(receipts) <= int(txIndex) {
 		return nil, fmt.Errorf("block has less receipts than expected: %d <= %d, block: %d", len(receipts), int(txIndex), blockNumber)
 	}
-	receipt := receipts[txIndex]
+	return marshalReceipt(receipts[txIndex], txn, blockHash, blockNumber, txIndex), nil
+}
+
+// GetBlockReceipts implements eth_getBlockReceipts. Returns the receipts of all transactions in the block.
+func (api *APIImpl) GetBlockReceipts(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) ([]map[string]interface{}, error) {
+	tx, err := api.dbReader.Begin(ctx, ethdb.RO)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	cc, err := api.chainConfig(tx)
+	if err != nil {
+		return nil, err
+	}
+	return getBlockReceipts(ctx, tx, cc, blockNrOrHash)
+}
+
+// getBlockReceipts returns the receipts of the block in the same format as eth_getTransactionReceipt. The receipts
+// are read from the database if they are stored, otherwise the block is re-executed
+func getBlockReceipts(ctx context.Context, tx ethdb.Database, chainConfig *params.ChainConfig, blockNrOrHash rpc.BlockNumberOrHash) ([]map[string]interface{}, error) {
+	blockNumber, blockHash, err := rpchelper.GetBlockNumber(blockNrOrHash, tx)
+	if err != nil {
+		return nil, err
+	}
+	block := rawdb.ReadBlock(tx, blockHash, blockNumber)
+	if block == nil {
+		return nil, fmt.Errorf("block %d(%x) not found", blockNumber, blockHash)
+	}
+	receipts, err := GetReceipts(ctx, tx, chainConfig, blockNumber, blockHash)
+	if err != nil {
+		return nil, fmt.Errorf("getReceipts error: %v", err)
+	}
+	txs := block.Transactions()
+	if len(receipts) != len(txs) {
+		return nil, fmt.Errorf("block has %d receipts, but %d transactions, block: %d", len(receipts), len(txs), blockNumber)
+	}
+
+	result := make([]map[string]interface{}, 0, len(receipts))
+	for i, receipt := range receipts {
+		result = append(result, marshalReceipt(receipt, txs[i], blockHash, blockNumber, uint64(i)))
+	}
+	return result, nil
+}
 
+// marshalReceipt converts the receipt of the transaction into the format of eth_getTransactionReceipt
+func marshalReceipt(receipt *types.Receipt, txn *types.Transaction, blockHash common.Hash, blockNumber uint64, txIndex uint64) map[string]interface{} {
 	var signer types.Signer = types.FrontierSigner{}
 	if txn.Protected() {
-		signer = types.NewEIP155Signer(txn.ChainID().ToBig())
+		signer = types.LatestSignerForChainID(txn.ChainID().ToBig())
 	}
 	from, _ := types.Sender(signer, txn)
 
@@ -229,7 +296,7 @@ func (api *APIImpl) GetTransactionReceipt(ctx context.Context, hash common.Hash)
 	if receipt.Logs != nil {
 		for _, log := range receipt.Logs {
 			log.BlockNumber = blockNumber
-			log.TxHash = hash
+			log.TxHash = txn.Hash()
 			log.TxIndex = uint(txIndex)
 			log.BlockHash = blockHash
 		}
@@ -239,7 +306,7 @@ func (api *APIImpl) GetTransactionReceipt(ctx context.Context, hash common.Hash)
 	fields := map[string]interface{}{
 		"blockHash":         blockHash,
 		"blockNumber":       hexutil.Uint64(blockNumber),
-		"transactionHash":   hash,
+		"transactionHash":   txn.Hash(),
 		"transactionIndex":  hexutil.Uint64(txIndex),
 		"from":              from,
 		"to":                txn.To(),
@@ -248,6 +315,7 @@ func (api *APIImpl) GetTransactionReceipt(ctx context.Context, hash common.Hash)
 		"contractAddress":   nil,
 		"logs":              receipt.Logs,
 		"logsBloom":         types.CreateBloom(types.Receipts{receipt}),
+		"type":              hexutil.Uint(txn.Type()),
 	}
 
 	// Assign receipt status or post state.
@@ -263,7 +331,7 @@ func (api *APIImpl) GetTransactionReceipt(ctx context.Context, hash common.Hash)
 	if receipt.ContractAddress != (common.Address{}) {
 		fields["contractAddress"] = receipt.ContractAddress
 	}
-	return fields, nil
+	return fields
 }
 
 func includes(addresses []common.Address, a common.Address) bool {
@@ -0,0 +1,102 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/ledgerwatch/turbo-geth/core/rawdb"
+	"github.com/ledgerwatch/turbo-geth/eth/stagedsync/stages"
+	"github.com/ledgerwatch/turbo-geth/ethdb"
+	"github.com/ledgerwatch/turbo-geth/rpc"
+	"github.com/ledgerwatch/turbo-geth/turbo/rpchelper"
+)
+
+func TestGetBlockReceipts(t *testing.T) {
+	db, err := createTestDb()
+	if err != nil {
+		t.Fatalf("create test db: %v", err)
+	}
+	api := NewEthAPI(db.(ethdb.HasKV).KV(), db, nil, 5000000, nil)
+	tgApi := NewTgAPI(db.(ethdb.HasKV).KV(), db)
+
+	check := func(blockNum uint64) {
+		block, err1 := rawdb.ReadBlockByNumber(db, blockNum)
+		if err1 != nil {
+			t.Fatal(err1)
+		}
+		receipts, err1 := api.GetBlockReceipts(context.Background(), rpc.BlockNumberOrHashWithNumber(rpc.BlockNumber(blockNum)))
+		if err1 != nil {
+			t.Fatalf("eth_getBlockReceipts %d: %v", blockNum, err1)
+		}
+		if len(receipts) != len(block.Transactions()) {
+			t.Fatalf("wrong number of receipts in block %d: %d, expected %d", blockNum, len(receipts), len(block.Transactions()))
+		}
+		tgReceipts, err1 := tgApi.GetBlockReceipts(context.Background(), rpc.BlockNumberOrHashWithHash(block.Hash(), true))
+		if err1 != nil {
+			t.Fatalf("tg_getBlockReceipts %d: %v", blockNum, err1)
+		}
+		assertJSONEqual(t, tgReceipts, receipts)
+		for i, txn := range block.Transactions() {
+			receipt, err2 := api.GetTransactionReceipt(context.Background(), txn.Hash())
+			if err2 != nil {
+				t.Fatalf("eth_getTransactionReceipt %x: %v", txn.Hash(), err2)
+			}
+			assertJSONEqual(t, receipts[i], receipt)
+		}
+	}
+
+	for blockNum := uint64(1); blockNum <= 10; blockNum++ {
+		check(blockNum)
+	}
+
+	// Without the stored receipts, the blocks are re-executed
+	expected, err := api.GetBlockReceipts(context.Background(), rpc.BlockNumberOrHashWithNumber(5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = rawdb.DeleteReceipts(db, 5); err != nil {
+		t.Fatal(err)
+	}
+	if rawdb.HasReceipts(db, rawdb.ReadHeaderByNumber(db, 5).Hash(), 5) {
+		t.Fatalf("receipts of block 5 are not deleted")
+	}
+	receipts, err := api.GetBlockReceipts(context.Background(), rpc.BlockNumberOrHashWithNumber(5))
+	if err != nil {
+		t.Fatalf("eth_getBlockReceipts without the stored receipts: %v", err)
+	}
+	assertJSONEqual(t, receipts, expected)
+	check(5)
+
+	if _, err = api.GetBlockReceipts(context.Background(), rpc.BlockNumberOrHashWithNumber(100)); err == nil {
+		t.Errorf("expected error for the missing block")
+	}
+}
+
+func assertJSONEqual(t *testing.T, actual, expected interface{}) {
+	t.Helper()
+	actualJSON, _ := json.Marshal(actual)
+	expectedJSON, _ := json.Marshal(expected)
+	if string(actualJSON) != string(expectedJSON) {
+		t.Errorf("wrong result:\n%s\nexpected\n%s", actualJSON, expectedJSON)
+	}
+}
+
+func TestGetBlockReceiptsPruned(t *testing.T) {
+	db, err := createTestDb()
+	if err != nil {
+		t.Fatalf("create test db: %v", err)
+	}
+	if err = stages.SaveStageProgress(db, stages.Prune, 5); err != nil {
+		t.Fatal(err)
+	}
+	api := NewEthAPI(db.(ethdb.HasKV).KV(), db, nil, 5000000, nil)
+
+	if _, err = api.GetBlockReceipts(context.Background(), rpc.BlockNumberOrHashWithNumber(4)); !errors.Is(err, rpchelper.ErrBlockPruned) {
+		t.Fatalf("expected the pruned block error, got %v", err)
+	}
+	if _, err = api.GetBlockReceipts(context.Background(), rpc.BlockNumberOrHashWithNumber(5)); err != nil {
+		t.Fatal(err)
+	}
+}
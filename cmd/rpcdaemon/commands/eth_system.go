@@ -25,8 +25,43 @@ func (api *APIImpl) BlockNumber(_ context.Context) (hexutil.Uint64, error) {
 	return hexutil.Uint64(execution), nil
 }
 
+// SyncingResult is returned by eth_syncing while the node is syncing
+type SyncingResult struct {
+	StartingBlock hexutil.Uint64  `json:"startingBlock"`
+	CurrentBlock  hexutil.Uint64  `json:"currentBlock"`
+	HighestBlock  hexutil.Uint64  `json:"highestBlock"`
+	Stages        []StageProgress `json:"stages"`
+}
+
+// StageProgress is the progress of a single stage of the staged sync
+type StageProgress struct {
+	Stage       string         `json:"stage_name"`
+	BlockNumber hexutil.Uint64 `json:"block_number"`
+}
+
 // Syncing implements eth_syncing. Returns a data object detaling the status of the sync process or false if not syncing.
 func (api *APIImpl) Syncing(ctx context.Context) (interface{}, error) {
+	if api.ethBackend != nil {
+		res, err := api.ethBackend.SyncStatus()
+		if err != nil {
+			return false, err
+		}
+		if !res.Syncing {
+			return false, nil
+		}
+		result := SyncingResult{
+			StartingBlock: hexutil.Uint64(res.StartingBlock),
+			CurrentBlock:  hexutil.Uint64(res.CurrentBlock),
+			HighestBlock:  hexutil.Uint64(res.HighestBlock),
+			Stages:        make([]StageProgress, 0, len(res.Stages)),
+		}
+		for _, stage := range res.Stages {
+			result.Stages = append(result.Stages, StageProgress{Stage: stage.Stage, BlockNumber: hexutil.Uint64(stage.BlockNumber)})
+		}
+		return result, nil
+	}
+
+	// We're running in --chaindata mode, only the progress of the stages is known
 	tx, err := api.dbReader.Begin(ctx, ethdb.RO)
 	if err != nil {
 		return nil, err
@@ -47,10 +82,19 @@ func (api *APIImpl) Syncing(ctx context.Context) (interface{}, error) {
 		return false, nil
 	}
 	// Otherwise gather the block sync stats
-	return map[string]hexutil.Uint64{
-		"currentBlock": hexutil.Uint64(currentBlock),
-		"highestBlock": hexutil.Uint64(highestBlock),
-	}, nil
+	result := SyncingResult{
+		CurrentBlock: hexutil.Uint64(currentBlock),
+		HighestBlock: hexutil.Uint64(highestBlock),
+		Stages:       make([]StageProgress, 0, len(stages.AllStages)),
+	}
+	for _, stage := range stages.AllStages {
+		progress, err := stages.GetStageProgress(tx, stage)
+		if err != nil {
+			return false, err
+		}
+		result.Stages = append(result.Stages, StageProgress{Stage: string(stage), BlockNumber: hexutil.Uint64(progress)})
+	}
+	return result, nil
 }
 
 // ChainId implements eth_chainId. Returns the current ethereum chainId.
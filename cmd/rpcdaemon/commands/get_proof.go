@@ -0,0 +1,270 @@
+package commands
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/ledgerwatch/turbo-geth/common"
+	"github.com/ledgerwatch/turbo-geth/common/changeset"
+	"github.com/ledgerwatch/turbo-geth/common/dbutils"
+	"github.com/ledgerwatch/turbo-geth/common/hexutil"
+	"github.com/ledgerwatch/turbo-geth/core/rawdb"
+	"github.com/ledgerwatch/turbo-geth/core/types/accounts"
+	"github.com/ledgerwatch/turbo-geth/eth/stagedsync/stages"
+	"github.com/ledgerwatch/turbo-geth/ethdb"
+	"github.com/ledgerwatch/turbo-geth/internal/ethapi"
+	"github.com/ledgerwatch/turbo-geth/turbo/rpchelper"
+	"github.com/ledgerwatch/turbo-geth/turbo/trie"
+)
+
+// getProof builds EIP-1186 account and storage proofs for the state after the given block.
+// The trie is loaded from the hashed state and the intermediate hashes, which are always at the
+// progress of the IntermediateHashes stage. For older blocks the keys modified since then are
+// rewound with the plain changesets and overlaid on top of the stream coming from the loader.
+// Only the nodes on the paths to the requested keys are kept in memory.
+func getProof(db ethdb.Database, address common.Address, storageKeys []string, blockNumber uint64) (*ethapi.AccountResult, error) {
+	headNumber, err := stages.GetStageProgress(db, stages.IntermediateHashes)
+	if err != nil {
+		return nil, err
+	}
+	if blockNumber > headNumber {
+		return nil, fmt.Errorf("block %d is not available yet, intermediate hashes are at block %d", blockNumber, headNumber)
+	}
+	header := rawdb.ReadHeaderByNumber(db, blockNumber)
+	if header == nil {
+		return nil, fmt.Errorf("header for block %d not found", blockNumber)
+	}
+
+	accountMap, storageMap, err := rewindHashedState(db, headNumber, blockNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	// Incarnation is a part of the storage keys in the hashed state, so it has to be taken
+	// from the account as it was at the requested block
+	historicalAccount, err := rpchelper.GetAccount(db, blockNumber, address)
+	if err != nil {
+		return nil, err
+	}
+	var incarnation uint64
+	if historicalAccount != nil {
+		incarnation = historicalAccount.Incarnation
+	}
+
+	unfurlList := make([]string, 0, len(accountMap)+len(storageMap))
+	unfurl := trie.NewRetainList(0)
+	for ks := range accountMap {
+		unfurlList = append(unfurlList, ks)
+		unfurl.AddKey([]byte(ks))
+	}
+	for ks := range storageMap {
+		unfurlList = append(unfurlList, ks)
+		unfurl.AddKey([]byte(ks))
+	}
+	sort.Strings(unfurlList)
+
+	addrHash, err := common.HashData(address[:])
+	if err != nil {
+		return nil, err
+	}
+	rl := trie.NewRetainList(0)
+	rl.AddKey(addrHash[:])
+	unfurl.AddKey(addrHash[:])
+	storageTrieKeys := make([][]byte, len(storageKeys))
+	for i, key := range storageKeys {
+		keyAsHash := common.HexToHash(key)
+		keyHash, err1 := common.HashData(keyAsHash[:])
+		if err1 != nil {
+			return nil, err1
+		}
+		dbKey := dbutils.GenerateCompositeStorageKey(addrHash, incarnation, keyHash)
+		rl.AddKey(dbKey)
+		unfurl.AddKey(dbKey)
+		storageTrieKeys[i] = append(common.CopyBytes(addrHash[:]), keyHash[:]...)
+	}
+
+	loader := trie.NewFlatDBTrieLoader("eth_getProof", dbutils.CurrentStateBucket, dbutils.IntermediateTrieHashBucket)
+	if err = loader.Reset(unfurl, nil /* HashCollector */, false); err != nil {
+		return nil, err
+	}
+	loader.SetReadOnly(true)
+	aggregator := trie.NewRootHashAggregator()
+	aggregator.Reset(nil /* HashCollector */, false)
+	aggregator.SetRetainDecider(rl)
+	loader.SetStreamReceiver(newRewindReceiver(aggregator, unfurlList, accountMap, storageMap))
+	root, err := loader.CalcTrieRoot(db, nil)
+	if err != nil {
+		return nil, err
+	}
+	if root != header.Root {
+		return nil, fmt.Errorf("wrong trie root for block %d: %x, expected (from header): %x", blockNumber, root, header.Root)
+	}
+	tr := trie.New(root)
+	if err = tr.HookSubTries(aggregator.Result(), [][]byte{nil}); err != nil {
+		return nil, err
+	}
+
+	accountProof, err := tr.Prove(addrHash[:], 0, false /* storage */)
+	if err != nil {
+		return nil, err
+	}
+	storageProof := make([]ethapi.StorageResult, len(storageKeys))
+	for i, key := range storageKeys {
+		proof, err1 := tr.Prove(storageTrieKeys[i], 64 /* nibbles to get to the storage sub-trie */, true /* storage */)
+		if err1 != nil {
+			return nil, err1
+		}
+		v, _ := tr.Get(storageTrieKeys[i])
+		storageProof[i] = ethapi.StorageResult{Key: key, Value: (*hexutil.Big)(new(big.Int).SetBytes(v)), Proof: toHexSlice(proof)}
+	}
+
+	result := &ethapi.AccountResult{
+		Address:      address,
+		AccountProof: toHexSlice(accountProof),
+		Balance:      (*hexutil.Big)(new(big.Int)),
+		CodeHash:     trie.EmptyCodeHash,
+		StorageHash:  trie.EmptyRoot,
+		StorageProof: storageProof,
+	}
+	// Proof of absence is returned with the default values of the fields
+	if acc, found := tr.GetAccount(addrHash[:]); found && acc != nil {
+		result.Balance = (*hexutil.Big)(acc.Balance.ToBig())
+		result.CodeHash = acc.CodeHash
+		result.Nonce = hexutil.Uint64(acc.Nonce)
+		result.StorageHash = acc.Root
+	}
+	return result, nil
+}
+
+// rewindHashedState collects the values of all accounts and storage items modified between
+// blockNumber (exclusive) and headNumber (inclusive), as they were at blockNumber, keyed
+// the same way as in the hashed state
+func rewindHashedState(db ethdb.Database, headNumber, blockNumber uint64) (map[string]*accounts.Account, map[string][]byte, error) {
+	plainAccounts, plainStorage, err := changeset.RewindDataPlain(db, headNumber, blockNumber)
+	if err != nil {
+		return nil, nil, err
+	}
+	accountMap := make(map[string]*accounts.Account, len(plainAccounts))
+	for ks, v := range plainAccounts {
+		addrHash, err1 := common.HashData([]byte(ks))
+		if err1 != nil {
+			return nil, nil, err1
+		}
+		if len(v) == 0 {
+			accountMap[string(addrHash[:])] = nil
+			continue
+		}
+		var a accounts.Account
+		if err1 = a.DecodeForStorage(v); err1 != nil {
+			return nil, nil, err1
+		}
+		// Code hashes are not stored in the changesets for contracts, fill them in
+		if a.Incarnation > 0 && a.IsEmptyCodeHash() {
+			codeHash, err2 := db.Get(dbutils.PlainContractCodeBucket, dbutils.PlainGenerateStoragePrefix([]byte(ks), a.Incarnation))
+			if err2 != nil && !errors.Is(err2, ethdb.ErrKeyNotFound) {
+				return nil, nil, err2
+			}
+			if len(codeHash) > 0 {
+				copy(a.CodeHash[:], codeHash)
+			}
+		}
+		accountMap[string(addrHash[:])] = &a
+	}
+	storageMap := make(map[string][]byte, len(plainStorage))
+	for ks, v := range plainStorage {
+		address, incarnation, key := dbutils.PlainParseCompositeStorageKey([]byte(ks))
+		addrHash, err1 := common.HashData(address[:])
+		if err1 != nil {
+			return nil, nil, err1
+		}
+		keyHash, err1 := common.HashData(key[:])
+		if err1 != nil {
+			return nil, nil, err1
+		}
+		storageMap[string(dbutils.GenerateCompositeStorageKey(addrHash, incarnation, keyHash))] = v
+	}
+	return accountMap, storageMap, nil
+}
+
+// rewindReceiver merges the rewound values into the stream of the FlatDBTrieLoader.
+// Items coming from the database with keys present in the unfurl list are replaced by
+// their historical values, or dropped if the item did not exist at that time
+type rewindReceiver struct {
+	receiver   *trie.RootHashAggregator
+	accountMap map[string]*accounts.Account
+	storageMap map[string][]byte
+	unfurlList []string
+	unfurlHex  [][]byte // keys from the unfurlList in the nibble encoding, as used by the loader
+	currentIdx int
+}
+
+func newRewindReceiver(receiver *trie.RootHashAggregator, unfurlList []string, accountMap map[string]*accounts.Account, storageMap map[string][]byte) *rewindReceiver {
+	unfurlHex := make([][]byte, len(unfurlList))
+	for i, ks := range unfurlList {
+		trie.DecompressNibbles([]byte(ks), &unfurlHex[i])
+	}
+	return &rewindReceiver{receiver: receiver, accountMap: accountMap, storageMap: storageMap, unfurlList: unfurlList, unfurlHex: unfurlHex}
+}
+
+func (r *rewindReceiver) Root() common.Hash { return r.receiver.Root() }
+
+func (r *rewindReceiver) Receive(
+	itemType trie.StreamItem,
+	accountKey []byte,
+	storageKey []byte,
+	accountValue *accounts.Account,
+	storageValue []byte,
+	hash []byte,
+	cutoff int,
+) error {
+	for r.currentIdx < len(r.unfurlList) {
+		ks := r.unfurlList[r.currentIdx]
+		k := r.unfurlHex[r.currentIdx]
+		var c int
+		switch itemType {
+		case trie.StorageStreamItem, trie.SHashStreamItem:
+			c = bytes.Compare(k, storageKey)
+		case trie.AccountStreamItem, trie.AHashStreamItem:
+			c = bytes.Compare(k, accountKey)
+		case trie.CutoffStreamItem:
+			c = -1
+		}
+		if c > 0 {
+			return r.receiver.Receive(itemType, accountKey, storageKey, accountValue, storageValue, hash, cutoff)
+		}
+		if len(ks) > common.HashLength {
+			if v := r.storageMap[ks]; len(v) > 0 {
+				if err := r.receiver.Receive(trie.StorageStreamItem, nil, k, nil, v, nil, 0); err != nil {
+					return err
+				}
+			}
+		} else {
+			if v := r.accountMap[ks]; v != nil {
+				if err := r.receiver.Receive(trie.AccountStreamItem, k, nil, v, nil, nil, 0); err != nil {
+					return err
+				}
+			}
+		}
+		r.currentIdx++
+		if c == 0 {
+			return nil
+		}
+	}
+	// We ran out of modifications, simply pass through
+	return r.receiver.Receive(itemType, accountKey, storageKey, accountValue, storageValue, hash, cutoff)
+}
+
+func (r *rewindReceiver) Result() trie.SubTries {
+	return r.receiver.Result()
+}
+
+func toHexSlice(b [][]byte) []string {
+	r := make([]string, len(b))
+	for i := range b {
+		r[i] = hexutil.Encode(b[i])
+	}
+	return r
+}
@@ -0,0 +1,68 @@
+package commands
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ledgerwatch/turbo-geth/common"
+	"github.com/ledgerwatch/turbo-geth/common/hexutil"
+	"github.com/ledgerwatch/turbo-geth/core/rawdb"
+	"github.com/ledgerwatch/turbo-geth/crypto"
+	"github.com/ledgerwatch/turbo-geth/ethdb"
+	"github.com/ledgerwatch/turbo-geth/rpc"
+	"github.com/ledgerwatch/turbo-geth/turbo/trie"
+)
+
+func TestGetProof(t *testing.T) {
+	db, err := createTestDb()
+	if err != nil {
+		t.Fatalf("create test db: %v", err)
+	}
+	api := NewEthAPI(db.(ethdb.HasKV).KV(), db, nil, 5000000, nil)
+	key, _ := crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	address := crypto.PubkeyToAddress(key.PublicKey)
+	tokenAddr := crypto.CreateAddress(address, 2)
+	addresses := []common.Address{address, {1}, tokenAddr}
+	storageKeys := []string{"0x0", "0x1", "0x2"}
+
+	for blockNum := uint64(0); blockNum <= 10; blockNum++ {
+		header := rawdb.ReadHeaderByNumber(db, blockNum)
+		blockNrOrHash := rpc.BlockNumberOrHashWithNumber(rpc.BlockNumber(blockNum))
+		for _, addr := range addresses {
+			result, err1 := api.GetProof(context.Background(), addr, storageKeys, blockNrOrHash)
+			if err1 != nil {
+				t.Fatalf("getProof for %x at block %d: %v", addr, blockNum, err1)
+			}
+			if len(result.AccountProof) == 0 {
+				t.Fatalf("empty account proof for %x at block %d", addr, blockNum)
+			}
+			if root := crypto.Keccak256Hash(hexutil.MustDecode(result.AccountProof[0])); root != header.Root {
+				t.Errorf("wrong proof root for %x at block %d: %x, expected %x", addr, blockNum, root, header.Root)
+			}
+			balance, err1 := api.GetBalance(context.Background(), addr, blockNrOrHash)
+			if err1 != nil {
+				t.Fatalf("getBalance for %x at block %d: %v", addr, blockNum, err1)
+			}
+			if result.Balance.ToInt().Cmp(balance.ToInt()) != 0 {
+				t.Errorf("wrong balance for %x at block %d: %d, expected %d", addr, blockNum, result.Balance.ToInt(), balance.ToInt())
+			}
+			for i, storageKey := range storageKeys {
+				value, err2 := api.GetStorageAt(context.Background(), addr, storageKey, blockNrOrHash)
+				if err2 != nil {
+					t.Fatalf("getStorageAt for %x at block %d: %v", addr, blockNum, err2)
+				}
+				expected := new(big.Int).SetBytes(common.FromHex(value))
+				if result.StorageProof[i].Value.ToInt().Cmp(expected) != 0 {
+					t.Errorf("wrong storage value %s for %x at block %d: %d, expected %d", storageKey, addr, blockNum, result.StorageProof[i].Value.ToInt(), expected)
+				}
+				if result.StorageHash == trie.EmptyRoot {
+					continue
+				}
+				if root := crypto.Keccak256Hash(hexutil.MustDecode(result.StorageProof[i].Proof[0])); root != result.StorageHash {
+					t.Errorf("wrong storage proof root %s for %x at block %d: %x, expected %x", storageKey, addr, blockNum, root, result.StorageHash)
+				}
+			}
+		}
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"strconv"
 
+	"github.com/golang/protobuf/ptypes/empty"
+	proto_sentry "github.com/ledgerwatch/turbo-geth/cmd/headers/sentry"
 	"github.com/ledgerwatch/turbo-geth/common/hexutil"
 
 	"github.com/ledgerwatch/turbo-geth/ethdb"
@@ -20,19 +22,30 @@ type NetAPI interface {
 // NetAPIImpl data structure to store things needed for net_ commands
 type NetAPIImpl struct {
 	ethBackend ethdb.Backend
+	sentry     proto_sentry.SentryClient
 }
 
 // NewNetAPIImpl returns NetAPIImplImpl instance
-func NewNetAPIImpl(eth ethdb.Backend) *NetAPIImpl {
+func NewNetAPIImpl(eth ethdb.Backend, sentry proto_sentry.SentryClient) *NetAPIImpl {
 	return &NetAPIImpl{
 		ethBackend: eth,
+		sentry:     sentry,
 	}
 }
 
 // Listening implements net_listening. Returns true if client is actively listening for network connections.
-// TODO: Remove hard coded value
 func (api *NetAPIImpl) Listening(_ context.Context) (bool, error) {
-	return true, nil
+	if api.ethBackend == nil {
+		// We're running in --chaindata mode or otherwise cannot get the backend
+		return false, fmt.Errorf(NotAvailableChainData, "net_listening")
+	}
+
+	res, err := api.ethBackend.NodeInfo()
+	if err != nil {
+		return false, err
+	}
+
+	return res.ListenerAddr != "", nil
 }
 
 // Version implements net_version. Returns the current network id.
@@ -51,7 +64,24 @@ func (api *NetAPIImpl) Version(_ context.Context) (string, error) {
 }
 
 // PeerCount implements net_peerCount. Returns number of peers currently connected to the client.
-// TODO: This routine currently returns a hard coded value of '25'
-func (api *NetAPIImpl) PeerCount(_ context.Context) (hexutil.Uint, error) {
-	return hexutil.Uint(25), nil
+// The peers of the node are counted if it is available, otherwise the peers of the sentry.
+func (api *NetAPIImpl) PeerCount(ctx context.Context) (hexutil.Uint, error) {
+	if api.ethBackend != nil {
+		res, err := api.ethBackend.NetPeerCount()
+		if err != nil {
+			return 0, err
+		}
+		return hexutil.Uint(res), nil
+	}
+
+	if api.sentry == nil {
+		return 0, fmt.Errorf(NotAvailableSentry, "net_peerCount")
+	}
+
+	res, err := api.sentry.PeerCount(ctx, &empty.Empty{})
+	if err != nil {
+		return 0, err
+	}
+
+	return hexutil.Uint(res.Count), nil
 }
@@ -0,0 +1,128 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ledgerwatch/turbo-geth/common"
+	"github.com/ledgerwatch/turbo-geth/common/dbutils"
+	"github.com/ledgerwatch/turbo-geth/common/hexutil"
+	"github.com/ledgerwatch/turbo-geth/core/rawdb"
+	"github.com/ledgerwatch/turbo-geth/core/types"
+	"github.com/ledgerwatch/turbo-geth/eth/stagedsync/stages"
+	"github.com/ledgerwatch/turbo-geth/ethdb"
+	"github.com/ledgerwatch/turbo-geth/ethdb/bitmapdb"
+)
+
+const (
+	defaultAccountTransactionsCount = 100
+	maxAccountTransactionsCount     = 1000
+)
+
+// AccountTransactionsRequest represents the arguments of tg_getAccountTransactions.
+// The transactions are ordered by the block number and the index in the block, `after` is the cursor returned
+// with the previous page, the page starts with the transaction following it, and `count` limits the page size.
+type AccountTransactionsRequest struct {
+	Address   common.Address             `json:"address"`
+	FromBlock *hexutil.Uint64            `json:"fromBlock"`
+	ToBlock   *hexutil.Uint64            `json:"toBlock"`
+	After     *AccountTransactionsCursor `json:"after"`
+	Count     *uint64                    `json:"count"`
+}
+
+// AccountTransactionsCursor is the position of a transaction in the order of tg_getAccountTransactions
+type AccountTransactionsCursor struct {
+	BlockNumber      hexutil.Uint64 `json:"blockNumber"`
+	TransactionIndex hexutil.Uint   `json:"transactionIndex"`
+}
+
+// AccountTransactionsPage is the result of tg_getAccountTransactions. Next is the cursor of the last transaction
+// of the page, to be passed as `after` for the next page, it is nil if there are no more transactions
+type AccountTransactionsPage struct {
+	Transactions []*RPCTransaction          `json:"transactions"`
+	Next         *AccountTransactionsCursor `json:"next"`
+}
+
+// GetAccountTransactions implements tg_getAccountTransactions. Returns a page of the transactions which were sent
+// or received by the address, or made internal calls to it.
+// Requires the account transactions index, which is built when `a` is in --storage-mode.
+func (api *TgImpl) GetAccountTransactions(ctx context.Context, req AccountTransactionsRequest) (*AccountTransactionsPage, error) {
+	tx, err := api.dbReader.Begin(ctx, ethdb.RO)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	sm, err := ethdb.GetStorageModeFromDB(tx)
+	if err != nil {
+		return nil, err
+	}
+	if !sm.AccountTxs {
+		return nil, fmt.Errorf("the account transactions index is not built, please add `a` to --storage-mode")
+	}
+
+	// the index is only complete up to the progress of its stage
+	latest, err := stages.GetStageProgress(tx, stages.AccountTxs)
+	if err != nil {
+		return nil, err
+	}
+	fromBlock, toBlock := uint64(0), latest
+	if req.FromBlock != nil {
+		fromBlock = uint64(*req.FromBlock)
+	}
+	if req.ToBlock != nil && uint64(*req.ToBlock) < toBlock {
+		toBlock = uint64(*req.ToBlock)
+	}
+	count := uint64(defaultAccountTransactionsCount)
+	if req.Count != nil {
+		if *req.Count > maxAccountTransactionsCount {
+			return nil, fmt.Errorf("count %d exceeds the limit of %d transactions", *req.Count, maxAccountTransactionsCount)
+		}
+		count = *req.Count
+	}
+
+	page := &AccountTransactionsPage{Transactions: []*RPCTransaction{}}
+	if fromBlock > toBlock || count == 0 {
+		return page, nil
+	}
+
+	// each value of the index is blockNumber<<32 | txIndex
+	from, to := fromBlock<<32, toBlock<<32|0xFFFFFFFF
+	positions, err := bitmapdb.Get64(tx, dbutils.AccountTxIndex, req.Address[:], from, to)
+	if err != nil {
+		return nil, err
+	}
+	positions.RemoveRange(to+1, ^uint64(0))
+
+	var block *types.Block
+	var last uint64
+	it := positions.Iterator()
+	it.AdvanceIfNeeded(from)
+	if req.After != nil {
+		it.AdvanceIfNeeded(uint64(req.After.BlockNumber)<<32 | uint64(req.After.TransactionIndex) + 1)
+	}
+	for it.HasNext() {
+		position := it.Next()
+		blockNum, txIndex := position>>32, position&0xFFFFFFFF
+		if uint64(len(page.Transactions)) == count {
+			// there is one more transaction after the page
+			page.Next = &AccountTransactionsCursor{BlockNumber: hexutil.Uint64(last >> 32), TransactionIndex: hexutil.Uint(last & 0xFFFFFFFF)}
+			return page, nil
+		}
+		if block == nil || block.NumberU64() != blockNum {
+			blockHash, err := rawdb.ReadCanonicalHash(tx, blockNum)
+			if err != nil {
+				return nil, err
+			}
+			if block = rawdb.ReadBlock(tx, blockHash, blockNum); block == nil {
+				return nil, fmt.Errorf("block not found %d", blockNum)
+			}
+		}
+		if txIndex >= uint64(len(block.Transactions())) {
+			return nil, fmt.Errorf("transaction %d not found in block %d", txIndex, blockNum)
+		}
+		page.Transactions = append(page.Transactions, newRPCTransaction(block.Transactions()[txIndex], block.Hash(), blockNum, txIndex))
+		last = position
+	}
+	return page, nil
+}
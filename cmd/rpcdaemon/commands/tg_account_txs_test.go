@@ -0,0 +1,111 @@
+package commands
+
+import (
+	"context"
+	"encoding/binary"
+	"testing"
+
+	"github.com/RoaringBitmap/roaring/roaring64"
+	"github.com/ledgerwatch/turbo-geth/common"
+	"github.com/ledgerwatch/turbo-geth/common/dbutils"
+	"github.com/ledgerwatch/turbo-geth/common/hexutil"
+	"github.com/ledgerwatch/turbo-geth/core/rawdb"
+	"github.com/ledgerwatch/turbo-geth/eth/stagedsync"
+	"github.com/ledgerwatch/turbo-geth/eth/stagedsync/stages"
+	"github.com/ledgerwatch/turbo-geth/ethdb"
+)
+
+func TestGetAccountTransactions(t *testing.T) {
+	db, err := createTestDb()
+	if err != nil {
+		t.Fatalf("create test db: %v", err)
+	}
+	api := NewTgAPI(db.(ethdb.HasKV).KV(), db)
+	sender := common.HexToAddress("0x71562b71999873DB5b286dF957af199Ec94617F7")
+
+	if _, err = api.GetAccountTransactions(context.Background(), AccountTransactionsRequest{Address: sender}); err == nil {
+		t.Errorf("expected error without the account transactions index")
+	}
+
+	// All the transactions of the blocks 1, 2 and 6 are sent by the same account, they are indexed by the sender
+	var expected []*RPCTransaction
+	positions := roaring64.New()
+	for _, blockNum := range []uint64{1, 2, 6} {
+		block, err1 := rawdb.ReadBlockByNumber(db, blockNum)
+		if err1 != nil {
+			t.Fatal(err1)
+		}
+		for i, txn := range block.Transactions() {
+			expected = append(expected, newRPCTransaction(txn, block.Hash(), blockNum, uint64(i)))
+			positions.Add(stagedsync.AccountTxIndexValue(blockNum, i))
+		}
+	}
+	key := make([]byte, common.AddressLength+8)
+	copy(key, sender[:])
+	binary.BigEndian.PutUint64(key[common.AddressLength:], ^uint64(0))
+	v, err := positions.ToBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = db.Put(dbutils.AccountTxIndex, key, v); err != nil {
+		t.Fatal(err)
+	}
+	if err = db.Put(dbutils.DatabaseInfoBucket, dbutils.StorageModeAccountTxs, []byte{1}); err != nil {
+		t.Fatal(err)
+	}
+	if err = stages.SaveStageProgress(db, stages.AccountTxs, 10); err != nil {
+		t.Fatal(err)
+	}
+
+	all, err := api.GetAccountTransactions(context.Background(), AccountTransactionsRequest{Address: sender})
+	if err != nil {
+		t.Fatalf("tg_getAccountTransactions: %v", err)
+	}
+	if len(expected) != 34 {
+		t.Fatalf("wrong number of test transactions: %d", len(expected))
+	}
+	assertJSONEqual(t, all.Transactions, expected)
+	if all.Next != nil {
+		t.Errorf("expected no next cursor for the last page, got %+v", all.Next)
+	}
+
+	// the pages follow each other by the cursor, the first page ends in the middle of the block 6
+	count := uint64(10)
+	page, err := api.GetAccountTransactions(context.Background(), AccountTransactionsRequest{Address: sender, Count: &count})
+	if err != nil {
+		t.Fatalf("tg_getAccountTransactions: %v", err)
+	}
+	assertJSONEqual(t, page.Transactions, expected[:10])
+	if page.Next == nil || uint64(page.Next.BlockNumber) != 6 || page.Next.TransactionIndex != 7 {
+		t.Fatalf("wrong next cursor: %+v", page.Next)
+	}
+	var got []*RPCTransaction
+	for page.Next != nil {
+		got = append(got, page.Transactions...)
+		if page, err = api.GetAccountTransactions(context.Background(), AccountTransactionsRequest{Address: sender, After: page.Next, Count: &count}); err != nil {
+			t.Fatalf("tg_getAccountTransactions: %v", err)
+		}
+	}
+	got = append(got, page.Transactions...)
+	assertJSONEqual(t, got, expected)
+
+	fromBlock, toBlock := hexutil.Uint64(2), hexutil.Uint64(5)
+	page, err = api.GetAccountTransactions(context.Background(), AccountTransactionsRequest{Address: sender, FromBlock: &fromBlock, ToBlock: &toBlock})
+	if err != nil {
+		t.Fatalf("tg_getAccountTransactions: %v", err)
+	}
+	assertJSONEqual(t, page.Transactions, expected[1:2])
+
+	page, err = api.GetAccountTransactions(context.Background(), AccountTransactionsRequest{Address: common.Address{1}})
+	if err != nil {
+		t.Fatalf("tg_getAccountTransactions: %v", err)
+	}
+	if len(page.Transactions) != 0 {
+		t.Errorf("expected no transactions for the address which is not indexed, got %d", len(page.Transactions))
+	}
+
+	count = maxAccountTransactionsCount + 1
+	if _, err = api.GetAccountTransactions(context.Background(), AccountTransactionsRequest{Address: sender, Count: &count}); err == nil {
+		t.Errorf("expected error for count above the limit")
+	}
+}
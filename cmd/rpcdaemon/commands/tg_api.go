@@ -20,8 +20,15 @@ type TgAPI interface {
 
 	// Receipt related (see ./tg_receipts.go)
 	GetLogsByHash(ctx context.Context, hash common.Hash) ([][]*types.Log, error)
+	GetBlockReceipts(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) ([]map[string]interface{}, error)
 	//GetLogsByNumber(ctx context.Context, number rpc.BlockNumber) ([][]*types.Log, error)
 
+	// Token transfers related (see ./tg_token_transfers.go)
+	GetTokenTransfers(ctx context.Context, req TokenTransfersRequest) (*TokenTransfersPage, error)
+
+	// Account transactions related (see ./tg_account_txs.go)
+	GetAccountTransactions(ctx context.Context, req AccountTransactionsRequest) (*AccountTransactionsPage, error)
+
 	// Issuance / reward related (see ./tg_issuance.go)
 	// BlockReward(ctx context.Context, blockNr rpc.BlockNumber) (Issuance, error)
 	// UncleReward(ctx context.Context, blockNr rpc.BlockNumber) (Issuance, error)
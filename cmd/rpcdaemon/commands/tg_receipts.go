@@ -8,6 +8,7 @@ import (
 	"github.com/ledgerwatch/turbo-geth/core/rawdb"
 	"github.com/ledgerwatch/turbo-geth/core/types"
 	"github.com/ledgerwatch/turbo-geth/ethdb"
+	"github.com/ledgerwatch/turbo-geth/rpc"
 )
 
 // GetLogsByHash implements tg_getLogsByHash. Returns an array of arrays of logs generated by the transactions in the block given by the block's hash.
@@ -28,7 +29,7 @@ func (api *TgImpl) GetLogsByHash(ctx context.Context, hash common.Hash) ([][]*ty
 		return nil, err
 	}
 
-	receipts, err := getReceipts(ctx, tx, chainConfig, *number, hash)
+	receipts, err := GetReceipts(ctx, tx, chainConfig, *number, hash)
 	if err != nil {
 		return nil, fmt.Errorf("getReceipts error: %v", err)
 	}
@@ -40,6 +41,21 @@ func (api *TgImpl) GetLogsByHash(ctx context.Context, hash common.Hash) ([][]*ty
 	return logs, nil
 }
 
+// GetBlockReceipts implements tg_getBlockReceipts. Returns the receipts of all transactions in the block, the same as eth_getBlockReceipts.
+func (api *TgImpl) GetBlockReceipts(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) ([]map[string]interface{}, error) {
+	tx, err := api.dbReader.Begin(ctx, ethdb.RO)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	chainConfig, err := api.chainConfig(tx)
+	if err != nil {
+		return nil, err
+	}
+	return getBlockReceipts(ctx, tx, chainConfig, blockNrOrHash)
+}
+
 // GetLogsByNumber implements tg_getLogsByHash. Returns all the logs that appear in a block given the block's hash.
 // func (api *TgImpl) GetLogsByNumber(ctx context.Context, number rpc.BlockNumber) ([][]*types.Log, error) {
 // 	tx, err := api.db.Begin(ctx, nil, false)
@@ -53,7 +69,7 @@ func (api *TgImpl) GetLogsByHash(ctx context.Context, hash common.Hash) ([][]*ty
 // 		return nil, fmt.Errorf("block not found: %x", hash)
 // 	}
 
-// 	receipts, err := getReceipts(ctx, tx, *number, hash)
+// 	receipts, err := GetReceipts(ctx, tx, *number, hash)
 // 	if err != nil {
 // 		return nil, fmt.Errorf("getReceipts error: %v", err)
 // 	}
@@ -0,0 +1,178 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ledgerwatch/turbo-geth/common"
+	"github.com/ledgerwatch/turbo-geth/common/dbutils"
+	"github.com/ledgerwatch/turbo-geth/common/hexutil"
+	"github.com/ledgerwatch/turbo-geth/core/rawdb"
+	"github.com/ledgerwatch/turbo-geth/core/types"
+	"github.com/ledgerwatch/turbo-geth/eth/stagedsync/stages"
+	"github.com/ledgerwatch/turbo-geth/ethdb"
+	"github.com/ledgerwatch/turbo-geth/ethdb/bitmapdb"
+	"github.com/ledgerwatch/turbo-geth/turbo/rpchelper"
+)
+
+const (
+	defaultTokenTransfersCount = 100
+	maxTokenTransfersCount     = 1000
+)
+
+// TokenTransfersRequest represents the arguments of tg_getTokenTransfers.
+// The transfers are ordered by the block number and the log index, `after` is the cursor returned with the
+// previous page, the page starts with the transfer following it, and `count` limits the page size.
+type TokenTransfersRequest struct {
+	Address   common.Address        `json:"address"`
+	FromBlock *hexutil.Uint64       `json:"fromBlock"`
+	ToBlock   *hexutil.Uint64       `json:"toBlock"`
+	After     *TokenTransfersCursor `json:"after"`
+	Count     *uint64               `json:"count"`
+}
+
+// TokenTransfersCursor is the position of a transfer in the order of tg_getTokenTransfers
+type TokenTransfersCursor struct {
+	BlockNumber hexutil.Uint64 `json:"blockNumber"`
+	LogIndex    hexutil.Uint   `json:"logIndex"`
+}
+
+// TokenTransfersPage is the result of tg_getTokenTransfers. Next is the cursor of the last transfer of the page,
+// to be passed as `after` for the next page, it is nil if there are no more transfers
+type TokenTransfersPage struct {
+	Transfers []TokenTransfer       `json:"transfers"`
+	Next      *TokenTransfersCursor `json:"next"`
+}
+
+// TokenTransfer is an ERC-20 or ERC-721 transfer returned by tg_getTokenTransfers
+type TokenTransfer struct {
+	BlockNumber      hexutil.Uint64 `json:"blockNumber"`
+	BlockHash        common.Hash    `json:"blockHash"`
+	TransactionHash  common.Hash    `json:"transactionHash"`
+	TransactionIndex hexutil.Uint   `json:"transactionIndex"`
+	LogIndex         hexutil.Uint   `json:"logIndex"`
+	Token            common.Address `json:"token"`
+	From             common.Address `json:"from"`
+	To               common.Address `json:"to"`
+	Value            *hexutil.Big   `json:"value,omitempty"`   // ERC-20 only
+	TokenID          *hexutil.Big   `json:"tokenId,omitempty"` // ERC-721 only
+}
+
+// GetTokenTransfers implements tg_getTokenTransfers. Returns a page of ERC-20/721 transfers sent or received by the address.
+// Requires the token transfers index, which is built when `e` is in --storage-mode.
+func (api *TgImpl) GetTokenTransfers(ctx context.Context, req TokenTransfersRequest) (*TokenTransfersPage, error) {
+	tx, err := api.dbReader.Begin(ctx, ethdb.RO)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	sm, err := ethdb.GetStorageModeFromDB(tx)
+	if err != nil {
+		return nil, err
+	}
+	if !sm.TokenTransfers {
+		return nil, fmt.Errorf("the token transfers index is not built, please add `e` to --storage-mode")
+	}
+
+	// the index is only complete up to the progress of its stage
+	latest, err := stages.GetStageProgress(tx, stages.TokenTransfers)
+	if err != nil {
+		return nil, err
+	}
+	// the index of the pruned blocks is deleted, they are skipped unless requested explicitly
+	fromBlock, err := rpchelper.FirstNotPruned(tx)
+	if err != nil {
+		return nil, err
+	}
+	toBlock := latest
+	if req.FromBlock != nil {
+		fromBlock = uint64(*req.FromBlock)
+		if err = rpchelper.CheckPruned(tx, fromBlock); err != nil {
+			return nil, err
+		}
+	}
+	if req.ToBlock != nil && uint64(*req.ToBlock) < toBlock {
+		toBlock = uint64(*req.ToBlock)
+	}
+	if req.After != nil && uint64(req.After.BlockNumber) > fromBlock {
+		fromBlock = uint64(req.After.BlockNumber)
+	}
+	count := uint64(defaultTokenTransfersCount)
+	if req.Count != nil {
+		if *req.Count > maxTokenTransfersCount {
+			return nil, fmt.Errorf("count %d exceeds the limit of %d token transfers", *req.Count, maxTokenTransfersCount)
+		}
+		count = *req.Count
+	}
+
+	page := &TokenTransfersPage{Transfers: []TokenTransfer{}}
+	if fromBlock > toBlock || count == 0 {
+		return page, nil
+	}
+
+	blocks, err := bitmapdb.Get(tx, dbutils.TokenTransferIndex, req.Address[:], uint32(fromBlock), uint32(toBlock))
+	if err != nil {
+		return nil, err
+	}
+
+	chainConfig, err := api.chainConfig(tx)
+	if err != nil {
+		return nil, err
+	}
+	for it := blocks.Iterator(); it.HasNext(); {
+		blockNum := uint64(it.Next())
+		if blockNum < fromBlock || blockNum > toBlock {
+			continue
+		}
+		blockHash, err := rawdb.ReadCanonicalHash(tx, blockNum)
+		if err != nil {
+			return nil, err
+		}
+		if blockHash == (common.Hash{}) {
+			return nil, fmt.Errorf("block not found %d", blockNum)
+		}
+		receipts, err := GetReceipts(ctx, tx, chainConfig, blockNum, blockHash)
+		if err != nil {
+			return nil, fmt.Errorf("getReceipts error: %v", err)
+		}
+		for _, receipt := range receipts {
+			for _, l := range receipt.Logs {
+				if req.After != nil && blockNum == uint64(req.After.BlockNumber) && l.Index <= uint(req.After.LogIndex) {
+					continue
+				}
+				transfer, ok := types.DecodeTokenTransfer(l)
+				if !ok || (transfer.From != req.Address && transfer.To != req.Address) {
+					continue
+				}
+				if uint64(len(page.Transfers)) == count {
+					// there is one more transfer after the page
+					last := page.Transfers[len(page.Transfers)-1]
+					page.Next = &TokenTransfersCursor{BlockNumber: last.BlockNumber, LogIndex: last.LogIndex}
+					return page, nil
+				}
+				page.Transfers = append(page.Transfers, marshalTokenTransfer(transfer, l, blockNum, blockHash))
+			}
+		}
+	}
+	return page, nil
+}
+
+func marshalTokenTransfer(transfer *types.TokenTransfer, l *types.Log, blockNum uint64, blockHash common.Hash) TokenTransfer {
+	result := TokenTransfer{
+		BlockNumber:      hexutil.Uint64(blockNum),
+		BlockHash:        blockHash,
+		TransactionHash:  l.TxHash,
+		TransactionIndex: hexutil.Uint(l.TxIndex),
+		LogIndex:         hexutil.Uint(l.Index),
+		Token:            transfer.Token,
+		From:             transfer.From,
+		To:               transfer.To,
+	}
+	if transfer.NonFungible {
+		result.TokenID = (*hexutil.Big)(transfer.Value)
+	} else {
+		result.Value = (*hexutil.Big)(transfer.Value)
+	}
+	return result
+}
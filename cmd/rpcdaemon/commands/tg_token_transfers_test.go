@@ -0,0 +1,131 @@
+package commands
+
+import (
+	"context"
+	"encoding/binary"
+	"math/big"
+	"testing"
+
+	"github.com/RoaringBitmap/roaring"
+	"github.com/ledgerwatch/turbo-geth/common"
+	"github.com/ledgerwatch/turbo-geth/common/dbutils"
+	"github.com/ledgerwatch/turbo-geth/common/hexutil"
+	"github.com/ledgerwatch/turbo-geth/core/rawdb"
+	"github.com/ledgerwatch/turbo-geth/core/types"
+	"github.com/ledgerwatch/turbo-geth/eth/stagedsync/stages"
+	"github.com/ledgerwatch/turbo-geth/ethdb"
+)
+
+func TestGetTokenTransfers(t *testing.T) {
+	db, err := createTestDb()
+	if err != nil {
+		t.Fatalf("create test db: %v", err)
+	}
+	api := NewTgAPI(db.(ethdb.HasKV).KV(), db)
+	holder := common.HexToAddress("0x376c47978271565f56DEB45495afa69E59c16Ab2")
+	token := common.Address{0xee}
+
+	if _, err = api.GetTokenTransfers(context.Background(), TokenTransfersRequest{Address: holder}); err == nil {
+		t.Errorf("expected error without the token transfers index")
+	}
+
+	// Every transaction of the blocks 1, 2 and 6 sends the tokens from the holder, the same as
+	// the token transfers stage does, the blocks are indexed by the holder
+	var expected []TokenTransfer
+	blocks := roaring.New()
+	for _, blockNum := range []uint64{1, 2, 6} {
+		block, err1 := rawdb.ReadBlockByNumber(db, blockNum)
+		if err1 != nil {
+			t.Fatal(err1)
+		}
+		receipts := rawdb.ReadRawReceipts(db, block.Hash(), blockNum)
+		var logIndex uint
+		for i, receipt := range receipts {
+			to := common.Address{byte(i + 1)}
+			value := big.NewInt(int64(i + 1))
+			receipt.Logs = []*types.Log{
+				{Address: token, Topics: []common.Hash{common.HexToHash("0x1234"), holder.Hash()}},
+				{Address: token, Topics: []common.Hash{types.TransferEventTopic, holder.Hash(), to.Hash()}, Data: common.LeftPadBytes(value.Bytes(), 32)},
+			}
+			expected = append(expected, TokenTransfer{
+				BlockNumber:      hexutil.Uint64(blockNum),
+				BlockHash:        block.Hash(),
+				TransactionHash:  block.Transactions()[i].Hash(),
+				TransactionIndex: hexutil.Uint(i),
+				LogIndex:         hexutil.Uint(logIndex + 1),
+				Token:            token,
+				From:             holder,
+				To:               to,
+				Value:            (*hexutil.Big)(value),
+			})
+			logIndex += 2
+		}
+		if err1 = rawdb.WriteReceipts(db, blockNum, receipts); err1 != nil {
+			t.Fatal(err1)
+		}
+		blocks.Add(uint32(blockNum))
+	}
+	key := make([]byte, common.AddressLength+4)
+	copy(key, holder[:])
+	binary.BigEndian.PutUint32(key[common.AddressLength:], ^uint32(0))
+	v, err := blocks.ToBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = db.Put(dbutils.TokenTransferIndex, key, v); err != nil {
+		t.Fatal(err)
+	}
+	if err = db.Put(dbutils.DatabaseInfoBucket, dbutils.StorageModeTokenTransfers, []byte{1}); err != nil {
+		t.Fatal(err)
+	}
+	if err = stages.SaveStageProgress(db, stages.TokenTransfers, 10); err != nil {
+		t.Fatal(err)
+	}
+
+	all, err := api.GetTokenTransfers(context.Background(), TokenTransfersRequest{Address: holder})
+	if err != nil {
+		t.Fatalf("tg_getTokenTransfers: %v", err)
+	}
+	if len(expected) != 34 {
+		t.Fatalf("wrong number of test transfers: %d", len(expected))
+	}
+	assertJSONEqual(t, all, &TokenTransfersPage{Transfers: expected})
+
+	// the pages continue from the cursor of the previous one, the last page has no cursor
+	count := uint64(10)
+	var after *TokenTransfersCursor
+	for i := 0; i < len(expected); i += int(count) {
+		page, err1 := api.GetTokenTransfers(context.Background(), TokenTransfersRequest{Address: holder, After: after, Count: &count})
+		if err1 != nil {
+			t.Fatalf("tg_getTokenTransfers: %v", err1)
+		}
+		end := i + int(count)
+		if end >= len(expected) {
+			assertJSONEqual(t, page, &TokenTransfersPage{Transfers: expected[i:]})
+			break
+		}
+		last := expected[end-1]
+		after = &TokenTransfersCursor{BlockNumber: last.BlockNumber, LogIndex: last.LogIndex}
+		assertJSONEqual(t, page, &TokenTransfersPage{Transfers: expected[i:end], Next: after})
+	}
+
+	fromBlock, toBlock := hexutil.Uint64(2), hexutil.Uint64(5)
+	page, err := api.GetTokenTransfers(context.Background(), TokenTransfersRequest{Address: holder, FromBlock: &fromBlock, ToBlock: &toBlock})
+	if err != nil {
+		t.Fatalf("tg_getTokenTransfers: %v", err)
+	}
+	assertJSONEqual(t, page, &TokenTransfersPage{Transfers: expected[1:2]})
+
+	page, err = api.GetTokenTransfers(context.Background(), TokenTransfersRequest{Address: common.Address{1}})
+	if err != nil {
+		t.Fatalf("tg_getTokenTransfers: %v", err)
+	}
+	if len(page.Transfers) != 0 {
+		t.Errorf("expected no transfers for the address which is not indexed, got %d", len(page.Transfers))
+	}
+
+	count = maxTokenTransfersCount + 1
+	if _, err = api.GetTokenTransfers(context.Background(), TokenTransfersRequest{Address: holder, Count: &count}); err == nil {
+		t.Errorf("expected error for count above the limit")
+	}
+}
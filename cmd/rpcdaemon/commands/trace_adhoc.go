@@ -3,6 +3,7 @@ package commands
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"math"
 	"math/big"
@@ -22,6 +23,7 @@ import (
 	"github.com/ledgerwatch/turbo-geth/log"
 	"github.com/ledgerwatch/turbo-geth/rpc"
 	"github.com/ledgerwatch/turbo-geth/turbo/rpchelper"
+	"github.com/ledgerwatch/turbo-geth/turbo/shards"
 	"github.com/ledgerwatch/turbo-geth/turbo/transactions"
 )
 
@@ -47,15 +49,13 @@ type TraceCallParam struct {
 	Data     hexutil.Bytes   `json:"data"`
 }
 
-// TraceCallParams array of callMany structs
-type TraceCallParams []TraceCallParam
-
 // TraceCallResult is the response to `trace_call` method
 type TraceCallResult struct {
-	Output    hexutil.Bytes                        `json:"output"`
-	StateDiff map[common.Address]*StateDiffAccount `json:"stateDiff"`
-	Trace     []*ParityTrace                       `json:"trace"`
-	VmTrace   *TraceCallVmTrace                    `json:"vmTrace"`
+	Output          hexutil.Bytes                        `json:"output"`
+	StateDiff       map[common.Address]*StateDiffAccount `json:"stateDiff"`
+	Trace           []*ParityTrace                       `json:"trace"`
+	VmTrace         *TraceCallVmTrace                    `json:"vmTrace"`
+	TransactionHash *common.Hash                         `json:"transactionHash,omitempty"`
 }
 
 // StateDiffAccount is the part of `trace_call` response that is under "stateDiff" tag
@@ -88,6 +88,36 @@ type StateDiffStorage struct {
 
 // TraceCallVmTrace is the part of `trace_call` response that is under "vmTrace" tag
 type TraceCallVmTrace struct {
+	Code hexutil.Bytes `json:"code"`
+	Ops  []*VmTraceOp  `json:"ops"`
+}
+
+// VmTraceOp is one executed instruction in the vmTrace
+type VmTraceOp struct {
+	Cost int               `json:"cost"`
+	Ex   *VmTraceEx        `json:"ex"` // Effects of the instruction, nil if it failed
+	Pc   int               `json:"pc"`
+	Sub  *TraceCallVmTrace `json:"sub"` // vmTrace of the call or create made by the instruction
+}
+
+// VmTraceEx describes the effects of the execution of an instruction
+type VmTraceEx struct {
+	Mem   *VmTraceMem   `json:"mem"`
+	Push  []string      `json:"push"`
+	Store *VmTraceStore `json:"store"`
+	Used  int           `json:"used"` // Gas left after the instruction
+}
+
+// VmTraceMem is the memory region written by an instruction
+type VmTraceMem struct {
+	Data hexutil.Bytes `json:"data"`
+	Off  int           `json:"off"`
+}
+
+// VmTraceStore is the storage item written by an instruction
+type VmTraceStore struct {
+	Key string `json:"key"`
+	Val string `json:"val"`
 }
 
 // ToMessage converts CallArgs to the Message type used by the core evm
@@ -125,7 +155,7 @@ func (args *TraceCallParam) ToMessage(globalGasCap uint64) types.Message {
 		input = args.Data
 	}
 
-	msg := types.NewMessage(addr, args.To, 0 /* nonce */, value, gas, gasPrice, input, false /* checkNonce */)
+	msg := types.NewMessage(addr, args.To, 0 /* nonce */, value, gas, gasPrice, input, nil /* accessList */, false /* checkNonce */)
 	return msg
 }
 
@@ -135,6 +165,19 @@ type OeTracer struct {
 	traceAddr  []int
 	traceStack []*ParityTrace
 	precompile bool // Whether the last CaptureStart was called with `precompile = true`
+	// vmTrace is only produced when r.VmTrace is set
+	vmFrames []*vmTraceFrame
+}
+
+// vmTraceFrame is the vmTrace of a call being executed. The effects of an instruction are only known
+// when the next instruction of the same call starts, so the last instruction is kept here until then
+type vmTraceFrame struct {
+	trace      *TraceCallVmTrace
+	lastOp     *VmTraceOp
+	lastOpCode vm.OpCode
+	memOff     uint64 // Memory region written by the last instruction
+	memLen     uint64
+	memory     *vm.Memory // Memory of the call, to finish the last instruction when the call exits
 }
 
 func (ot *OeTracer) CaptureStart(depth int, from common.Address, to common.Address, precompile bool, create bool, calltype vm.CallType, input []byte, gas uint64, value *big.Int) error {
@@ -142,6 +185,20 @@ func (ot *OeTracer) CaptureStart(depth int, from common.Address, to common.Addre
 		ot.precompile = true
 		return nil
 	}
+	if ot.r.VmTrace != nil {
+		vmTrace := ot.r.VmTrace
+		if depth > 0 {
+			vmTrace = &TraceCallVmTrace{Ops: []*VmTraceOp{}}
+			if parent := ot.vmFrames[len(ot.vmFrames)-1]; parent.lastOp != nil {
+				parent.lastOp.Sub = vmTrace
+				if create {
+					// Unlike for calls, the gas given to the created contract is not a part of the cost reported to the tracer
+					parent.lastOp.Cost += int(gas)
+				}
+			}
+		}
+		ot.vmFrames = append(ot.vmFrames, &vmTraceFrame{trace: vmTrace})
+	}
 	if gas > 500000000 {
 		gas = 500000001 - (0x8000000000000000 - gas)
 	}
@@ -212,6 +269,14 @@ func (ot *OeTracer) CaptureEnd(depth int, output []byte, gasUsed uint64, t time.
 		ot.precompile = false
 		return nil
 	}
+	if ot.r.VmTrace != nil {
+		frame := ot.vmFrames[len(ot.vmFrames)-1]
+		if frame.lastOp != nil && frame.lastOp.Ex != nil {
+			// The stack is already released, but the instructions which end the call push nothing
+			finishVmTraceOp(frame, uint64(frame.lastOp.Ex.Used), frame.memory, nil)
+		}
+		ot.vmFrames = ot.vmFrames[:len(ot.vmFrames)-1]
+	}
 	//fmt.Printf("CaptureEnd depth %d, output %x, gasUsed %d, err %v\n", depth, output, gasUsed, err)
 	if depth == 0 {
 		ot.r.Output = common.CopyBytes(output)
@@ -262,9 +327,81 @@ func (ot *OeTracer) CaptureEnd(depth int, output []byte, gasUsed uint64, t time.
 }
 
 func (ot *OeTracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, st *stack.Stack, retst *stack.ReturnStack, rData []byte, contract *vm.Contract, opDepth int, err error) error {
+	if ot.r.VmTrace == nil || len(ot.vmFrames) == 0 {
+		return nil
+	}
+	frame := ot.vmFrames[len(ot.vmFrames)-1]
+	if frame.trace.Code == nil {
+		frame.trace.Code = common.CopyBytes(contract.Code)
+	}
+	if frame.lastOp != nil && frame.lastOp.Ex != nil {
+		finishVmTraceOp(frame, gas, memory, st)
+	}
+
+	vmOp := &VmTraceOp{Cost: int(cost), Pc: int(pc)}
+	frame.trace.Ops = append(frame.trace.Ops, vmOp)
+	frame.lastOp = vmOp
+	frame.lastOpCode = op
+	frame.memOff, frame.memLen = 0, 0
+	frame.memory = memory
+	if gas < cost || err != nil {
+		// The instruction fails before it is executed, so it has no effects
+		return nil
+	}
+	vmOp.Ex = &VmTraceEx{Push: []string{}, Used: int(gas - cost)}
+	switch op {
+	case vm.MSTORE, vm.MLOAD:
+		frame.memOff, frame.memLen = st.Back(0).Uint64(), 32
+	case vm.MSTORE8:
+		frame.memOff, frame.memLen = st.Back(0).Uint64(), 1
+	case vm.CALLDATACOPY, vm.CODECOPY, vm.RETURNDATACOPY:
+		frame.memOff, frame.memLen = st.Back(0).Uint64(), st.Back(2).Uint64()
+	case vm.EXTCODECOPY:
+		frame.memOff, frame.memLen = st.Back(1).Uint64(), st.Back(3).Uint64()
+	case vm.CALL, vm.CALLCODE:
+		frame.memOff, frame.memLen = st.Back(5).Uint64(), st.Back(6).Uint64()
+	case vm.DELEGATECALL, vm.STATICCALL:
+		frame.memOff, frame.memLen = st.Back(4).Uint64(), st.Back(5).Uint64()
+	case vm.SSTORE:
+		vmOp.Ex.Store = &VmTraceStore{Key: st.Back(0).String(), Val: st.Back(1).String()}
+	}
 	return nil
 }
 
+// finishVmTraceOp fills in the stack items pushed and the memory written by the last instruction of the frame,
+// now that the next instruction is about to be executed or the frame exits. st is nil when the frame exits
+func finishVmTraceOp(frame *vmTraceFrame, gas uint64, memory *vm.Memory, st *stack.Stack) {
+	var pushed int
+	op := frame.lastOpCode
+	switch {
+	case op >= vm.PUSH1 && op <= vm.PUSH32:
+		pushed = 1
+	case op >= vm.DUP1 && op <= vm.DUP16:
+		pushed = int(op-vm.DUP1) + 2
+	case op >= vm.SWAP1 && op <= vm.SWAP16:
+		pushed = int(op-vm.SWAP1) + 2
+	}
+	switch op {
+	case vm.ADD, vm.MUL, vm.SUB, vm.DIV, vm.SDIV, vm.MOD, vm.SMOD, vm.ADDMOD, vm.MULMOD, vm.EXP, vm.SIGNEXTEND,
+		vm.LT, vm.GT, vm.SLT, vm.SGT, vm.EQ, vm.ISZERO, vm.AND, vm.OR, vm.XOR, vm.NOT, vm.BYTE, vm.SHL, vm.SHR, vm.SAR,
+		vm.SHA3, vm.ADDRESS, vm.BALANCE, vm.ORIGIN, vm.CALLER, vm.CALLVALUE, vm.CALLDATALOAD, vm.CALLDATASIZE,
+		vm.CODESIZE, vm.GASPRICE, vm.EXTCODESIZE, vm.RETURNDATASIZE, vm.EXTCODEHASH, vm.BLOCKHASH, vm.COINBASE,
+		vm.TIMESTAMP, vm.NUMBER, vm.DIFFICULTY, vm.GASLIMIT, vm.CHAINID, vm.SELFBALANCE, vm.MLOAD, vm.SLOAD,
+		vm.PC, vm.MSIZE, vm.GAS:
+		pushed = 1
+	case vm.CALL, vm.CALLCODE, vm.DELEGATECALL, vm.STATICCALL, vm.CREATE, vm.CREATE2:
+		pushed = 1
+		// Gas left after the call is only known when it returns
+		frame.lastOp.Ex.Used = int(gas)
+	}
+	for i := pushed - 1; i >= 0 && st != nil; i-- {
+		frame.lastOp.Ex.Push = append(frame.lastOp.Ex.Push, st.Back(i).String())
+	}
+	if frame.memLen > 0 && frame.memOff+frame.memLen <= uint64(memory.Len()) {
+		frame.lastOp.Ex.Mem = &VmTraceMem{Data: memory.GetCopy(frame.memOff, frame.memLen), Off: int(frame.memOff)}
+	}
+}
+
 func (ot *OeTracer) CaptureFault(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *stack.Stack, rst *stack.ReturnStack, contract *vm.Contract, opDepth int, err error) error {
 	//fmt.Printf("CaptureFault depth %d\n", opDepth)
 	return nil
@@ -435,6 +572,27 @@ func (sd *StateDiff) CompareStates(initialIbs, ibs *state.IntraBlockState) {
 
 const callTimeout = 5 * time.Minute
 
+// TraceCallManyParam is a single call of trace_callMany, sent as the two element array [call, traceTypes]
+type TraceCallManyParam struct {
+	Call       TraceCallParam
+	TraceTypes []string
+}
+
+// UnmarshalJSON decodes the [call, traceTypes] pair
+func (p *TraceCallManyParam) UnmarshalJSON(input []byte) error {
+	var raw []json.RawMessage
+	if err := json.Unmarshal(input, &raw); err != nil {
+		return err
+	}
+	if len(raw) != 2 {
+		return fmt.Errorf("expected [call, traceTypes], got %d elements", len(raw))
+	}
+	if err := json.Unmarshal(raw[0], &p.Call); err != nil {
+		return err
+	}
+	return json.Unmarshal(raw[1], &p.TraceTypes)
+}
+
 // Call implements trace_call.
 func (api *TraceAPIImpl) Call(ctx context.Context, args TraceCallParam, traceTypes []string, blockNrOrHash *rpc.BlockNumberOrHash) (*TraceCallResult, error) {
 	dbtx, err := api.dbReader.Begin(ctx, ethdb.RO)
@@ -443,30 +601,190 @@ func (api *TraceAPIImpl) Call(ctx context.Context, args TraceCallParam, traceTyp
 	}
 	defer dbtx.Rollback()
 
-	chainConfig, err := api.chainConfig(dbtx)
+	if blockNrOrHash == nil {
+		var num = rpc.LatestBlockNumber
+		blockNrOrHash = &rpc.BlockNumberOrHash{BlockNumber: &num}
+	}
+	stateReader, header, err := callStateAt(dbtx, *blockNrOrHash)
 	if err != nil {
 		return nil, err
 	}
 
+	msgs := []types.Message{args.ToMessage(api.gasCap)}
+	results, err := api.doCallMany(ctx, dbtx, stateReader, header, msgs, [][]string{traceTypes}, blockNrOrHash.RequireCanonical)
+	if err != nil {
+		return nil, err
+	}
+	return results[0], nil
+}
+
+// CallMany implements trace_callMany. The calls are executed one after another, each of them on top of the state changes made by the previous ones.
+func (api *TraceAPIImpl) CallMany(ctx context.Context, calls []TraceCallManyParam, blockNrOrHash *rpc.BlockNumberOrHash) ([]*TraceCallResult, error) {
+	dbtx, err := api.dbReader.Begin(ctx, ethdb.RO)
+	if err != nil {
+		return nil, err
+	}
+	defer dbtx.Rollback()
+
 	if blockNrOrHash == nil {
 		var num = rpc.LatestBlockNumber
 		blockNrOrHash = &rpc.BlockNumberOrHash{BlockNumber: &num}
 	}
-	blockNumber, hash, err := rpchelper.GetBlockNumber(*blockNrOrHash, dbtx)
+	stateReader, header, err := callStateAt(dbtx, *blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+
+	msgs := make([]types.Message, len(calls))
+	traceTypes := make([][]string, len(calls))
+	for i, call := range calls {
+		msgs[i] = call.Call.ToMessage(api.gasCap)
+		traceTypes[i] = call.TraceTypes
+	}
+	return api.doCallMany(ctx, dbtx, stateReader, header, msgs, traceTypes, blockNrOrHash.RequireCanonical)
+}
+
+// RawTransaction implements trace_rawTransaction. The transaction is executed on top of the latest block.
+func (api *TraceAPIImpl) RawTransaction(ctx context.Context, encodedTx hexutil.Bytes, traceTypes []string) (*TraceCallResult, error) {
+	txn := new(types.Transaction)
+	if err := txn.UnmarshalBinary(encodedTx); err != nil {
+		return nil, err
+	}
+
+	dbtx, err := api.dbReader.Begin(ctx, ethdb.RO)
+	if err != nil {
+		return nil, err
+	}
+	defer dbtx.Rollback()
+
+	chainConfig, err := api.chainConfig(dbtx)
+	if err != nil {
+		return nil, err
+	}
+	stateReader, header, err := callStateAt(dbtx, rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber))
+	if err != nil {
+		return nil, err
+	}
+	msg, err := txn.AsMessage(types.MakeSigner(chainConfig, header.Number))
+	if err != nil {
+		return nil, err
+	}
+
+	results, err := api.doCallMany(ctx, dbtx, stateReader, header, []types.Message{msg}, [][]string{traceTypes}, false /* requireCanonical */)
+	if err != nil {
+		return nil, err
+	}
+	return results[0], nil
+}
+
+// ReplayBlockTransactions implements trace_replayBlockTransactions.
+func (api *TraceAPIImpl) ReplayBlockTransactions(ctx context.Context, blockNr rpc.BlockNumber, traceTypes []string) ([]*TraceCallResult, error) {
+	dbtx, err := api.dbReader.Begin(ctx, ethdb.RO)
 	if err != nil {
 		return nil, err
 	}
+	defer dbtx.Rollback()
+
+	blockNumber, hash, err := rpchelper.GetBlockNumber(rpc.BlockNumberOrHashWithNumber(blockNr), dbtx)
+	if err != nil {
+		return nil, err
+	}
+	block := rawdb.ReadBlock(dbtx, hash, blockNumber)
+	if block == nil {
+		return nil, fmt.Errorf("block %d(%x) not found", blockNumber, hash)
+	}
+
+	blockTraceTypes := make([][]string, len(block.Transactions()))
+	for i := range blockTraceTypes {
+		blockTraceTypes[i] = traceTypes
+	}
+	results, err := api.replayBlockTransactions(ctx, dbtx, block, blockTraceTypes)
+	if err != nil {
+		return nil, err
+	}
+	for i, txn := range block.Transactions() {
+		txHash := txn.Hash()
+		results[i].TransactionHash = &txHash
+	}
+	return results, nil
+}
+
+// ReplayTransaction implements trace_replayTransaction.
+func (api *TraceAPIImpl) ReplayTransaction(ctx context.Context, txHash common.Hash, traceTypes []string) (*TraceCallResult, error) {
+	dbtx, err := api.dbReader.Begin(ctx, ethdb.RO)
+	if err != nil {
+		return nil, err
+	}
+	defer dbtx.Rollback()
+
+	txn, blockHash, blockNumber, txIndex := rawdb.ReadTransaction(dbtx, txHash)
+	if txn == nil {
+		return nil, fmt.Errorf("transaction %#x not found", txHash)
+	}
+	block := rawdb.ReadBlock(dbtx, blockHash, blockNumber)
+	if block == nil {
+		return nil, fmt.Errorf("block %d(%x) not found", blockNumber, blockHash)
+	}
+
+	// The transactions before the requested one are executed without tracing
+	blockTraceTypes := make([][]string, txIndex+1)
+	blockTraceTypes[txIndex] = traceTypes
+	results, err := api.replayBlockTransactions(ctx, dbtx, block, blockTraceTypes)
+	if err != nil {
+		return nil, err
+	}
+	return results[txIndex], nil
+}
+
+// replayBlockTransactions re-executes the first len(traceTypes) transactions of the block on top of the state of its parent
+func (api *TraceAPIImpl) replayBlockTransactions(ctx context.Context, dbtx ethdb.Database, block *types.Block, traceTypes [][]string) ([]*TraceCallResult, error) {
+	if len(traceTypes) == 0 {
+		return []*TraceCallResult{}, nil
+	}
+	chainConfig, err := api.chainConfig(dbtx)
+	if err != nil {
+		return nil, err
+	}
+	signer := types.MakeSigner(chainConfig, block.Number())
+	msgs := make([]types.Message, len(traceTypes))
+	for i, txn := range block.Transactions()[:len(traceTypes)] {
+		if msgs[i], err = txn.AsMessage(signer); err != nil {
+			return nil, fmt.Errorf("transaction %x: %w", txn.Hash(), err)
+		}
+	}
+	if err = rpchelper.CheckPruned(dbtx, block.NumberU64()); err != nil {
+		return nil, err
+	}
+	// Block with transactions is never the genesis, so there is always the parent state
+	stateReader := state.NewPlainDBState(dbtx, block.NumberU64()-1)
+	return api.doCallMany(ctx, dbtx, stateReader, block.Header(), msgs, traceTypes, true /* requireCanonical */)
+}
+
+// callStateAt returns the state after the given block and its header
+func callStateAt(dbtx ethdb.Database, blockNrOrHash rpc.BlockNumberOrHash) (state.StateReader, *types.Header, error) {
+	blockNumber, hash, err := rpchelper.GetBlockNumber(blockNrOrHash, dbtx)
+	if err != nil {
+		return nil, nil, err
+	}
 	var stateReader state.StateReader
 	if num, ok := blockNrOrHash.Number(); ok && num == rpc.LatestBlockNumber {
 		stateReader = state.NewPlainStateReader(dbtx)
 	} else {
 		stateReader = state.NewPlainDBState(dbtx, blockNumber)
 	}
-	ibs := state.New(stateReader)
-
 	header := rawdb.ReadHeader(dbtx, hash, blockNumber)
 	if header == nil {
-		return nil, fmt.Errorf("block %d(%x) not found", blockNumber, hash)
+		return nil, nil, fmt.Errorf("block %d(%x) not found", blockNumber, hash)
+	}
+	return stateReader, header, nil
+}
+
+// doCallMany executes the messages one after another in the context of the given header. Each message sees the state
+// left by the previous ones. The state changes are kept in memory on top of the stateReader and never written to the database.
+func (api *TraceAPIImpl) doCallMany(ctx context.Context, dbtx ethdb.Database, stateReader state.StateReader, header *types.Header, msgs []types.Message, traceTypes [][]string, requireCanonical bool) ([]*TraceCallResult, error) {
+	chainConfig, err := api.chainConfig(dbtx)
+	if err != nil {
+		return nil, err
 	}
 
 	// Setup context so it may be cancelled the call has completed
@@ -482,102 +800,87 @@ func (api *TraceAPIImpl) Call(ctx context.Context, args TraceCallParam, traceTyp
 	// this makes sure resources are cleaned up.
 	defer cancel()
 
-	traceResult := &TraceCallResult{}
-	var traceTypeTrace, traceTypeStateDiff, traceTypeVmTrace bool
-	for _, traceType := range traceTypes {
-		switch traceType {
-		case TraceTypeTrace:
-			traceTypeTrace = true
-		case TraceTypeStateDiff:
-			traceTypeStateDiff = true
-		case TraceTypeVmTrace:
-			traceTypeVmTrace = true
-		default:
-			return nil, fmt.Errorf("unrecognized trace type: %s", traceType)
+	stateCache := shards.NewStateCache(32, 0 /* no limit */)
+	cachedReader := state.NewCachedReader(stateReader, stateCache)
+	noop := state.NewNoopWriter()
+	cachedWriter := state.NewCachedWriter(noop, stateCache)
+	rulesCtx := chainConfig.WithEIPsFlags(ctx, header.Number)
+
+	results := make([]*TraceCallResult, len(msgs))
+	for i, msg := range msgs {
+		traceResult := &TraceCallResult{}
+		var traceTypeTrace, traceTypeStateDiff, traceTypeVmTrace bool
+		for _, traceType := range traceTypes[i] {
+			switch traceType {
+			case TraceTypeTrace:
+				traceTypeTrace = true
+			case TraceTypeStateDiff:
+				traceTypeStateDiff = true
+			case TraceTypeVmTrace:
+				traceTypeVmTrace = true
+			default:
+				return nil, fmt.Errorf("unrecognized trace type: %s", traceType)
+			}
+		}
+		var ot OeTracer
+		if traceTypeTrace || traceTypeVmTrace {
+			ot.r = traceResult
+			ot.traceAddr = []int{}
+		}
+		if traceTypeVmTrace {
+			traceResult.VmTrace = &TraceCallVmTrace{Ops: []*VmTraceOp{}}
 		}
-	}
-	var ot OeTracer
-	if traceTypeTrace {
-		ot.r = traceResult
-		ot.traceAddr = []int{}
-	}
 
-	// Get a new instance of the EVM.
-	msg := args.ToMessage(api.gasCap)
+		ibs := state.New(cachedReader)
+		// Get a new instance of the EVM.
+		evmCtx := transactions.GetEvmContext(msg, header, requireCanonical, dbtx)
+		evm := vm.NewEVM(evmCtx, ibs, chainConfig, vm.Config{Debug: traceTypeTrace || traceTypeVmTrace, Tracer: &ot})
 
-	evmCtx := transactions.GetEvmContext(msg, header, blockNrOrHash.RequireCanonical, dbtx)
+		// Wait for the context to be done and cancel the evm. Even if the
+		// EVM has finished, cancelling may be done (repeatedly)
+		go func() {
+			<-ctx.Done()
+			evm.Cancel()
+		}()
 
-	evm := vm.NewEVM(evmCtx, ibs, chainConfig, vm.Config{Debug: traceTypeTrace, Tracer: &ot})
+		if chainConfig.IsYoloV2(header.Number) {
+			ibs.PrepareAccessList(msg.From(), msg.To(), evm.ActivePrecompiles(), msg.AccessList())
+		}
 
-	// Wait for the context to be done and cancel the evm. Even if the
-	// EVM has finished, cancelling may be done (repeatedly)
-	go func() {
-		<-ctx.Done()
-		evm.Cancel()
-	}()
+		gp := new(core.GasPool).AddGas(msg.Gas())
+		execResult, err := core.ApplyMessage(evm, msg, gp, true /* refunds */)
+		if err != nil {
+			return nil, fmt.Errorf("call %d: %w", i, err)
+		}
+		// If the timer caused an abort, return an appropriate error message
+		if evm.Cancelled() {
+			return nil, fmt.Errorf("execution aborted (timeout = %v)", callTimeout)
+		}
+		traceResult.Output = execResult.ReturnData
+		if !traceTypeTrace {
+			// The calls are traced anyway when only vmTrace is requested
+			traceResult.Trace = nil
+		}
 
-	gp := new(core.GasPool).AddGas(msg.Gas())
-	var execResult *core.ExecutionResult
-	execResult, err = core.ApplyMessage(evm, msg, gp, true /* refunds */)
-	if err != nil {
-		return nil, err
-	}
-	traceResult.Output = execResult.ReturnData
-	if traceTypeStateDiff {
-		sdMap := make(map[common.Address]*StateDiffAccount)
-		traceResult.StateDiff = sdMap
-		sd := &StateDiff{sdMap: sdMap}
-		if err = ibs.CommitBlock(ctx, sd); err != nil {
+		if traceTypeStateDiff {
+			sdMap := make(map[common.Address]*StateDiffAccount)
+			traceResult.StateDiff = sdMap
+			sd := &StateDiff{sdMap: sdMap}
+			if err = ibs.FinalizeTx(rulesCtx, sd); err != nil {
+				return nil, err
+			}
+			// Create initial IntraBlockState, we will compare it with ibs (IntraBlockState after the transaction).
+			// The cache does not have the changes of this message yet, so it gives the state before it
+			initialIbs := state.New(cachedReader)
+			sd.CompareStates(initialIbs, ibs)
+		} else if err = ibs.FinalizeTx(rulesCtx, noop); err != nil {
 			return nil, err
 		}
-		// Create initial IntraBlockState, we will compare it with ibs (IntraBlockState after the transaction)
-		initialIbs := state.New(stateReader)
-		sd.CompareStates(initialIbs, ibs)
-	}
-	if traceTypeVmTrace {
-		return nil, fmt.Errorf("vmTrace not implemented yet")
-	}
-
-	// If the timer caused an abort, return an appropriate error message
-	if evm.Cancelled() {
-		return nil, fmt.Errorf("execution aborted (timeout = %v)", callTimeout)
+		// Make the changes visible to the next messages
+		if err = ibs.CommitBlock(rulesCtx, cachedWriter); err != nil {
+			return nil, err
+		}
+		results[i] = traceResult
 	}
-
-	return traceResult, nil
-}
-
-// TODO(tjayrush) - try to use a concrete type here
-// TraceCallManyParam array of callMany structs
-// type TraceCallManyParam struct {
-// 	obj        TraceCallParam
-// 	traceTypes []string
-// }
-
-// TraceCallManyParams array of callMany structs
-// type TraceCallManyParams struct {
-// 	things []TraceCallManyParam
-// }
-
-// CallMany implements trace_callMany.
-func (api *TraceAPIImpl) CallMany(ctx context.Context, calls []interface{}, blockNr *rpc.BlockNumberOrHash) ([]interface{}, error) {
-	var stub []interface{}
-	return stub, fmt.Errorf(NotImplemented, "trace_callMany")
-}
-
-// RawTransaction implements trace_rawTransaction.
-func (api *TraceAPIImpl) RawTransaction(ctx context.Context, txHash common.Hash, traceTypes []string) ([]interface{}, error) {
-	var stub []interface{}
-	return stub, fmt.Errorf(NotImplemented, "trace_rawTransaction")
-}
-
-// ReplayBlockTransactions implements trace_replayBlockTransactions.
-func (api *TraceAPIImpl) ReplayBlockTransactions(ctx context.Context, blockNr rpc.BlockNumber, traceTypes []string) ([]interface{}, error) {
-	var stub []interface{}
-	return stub, fmt.Errorf(NotImplemented, "trace_replayBlockTransactions")
-}
-
-// ReplayTransaction implements trace_replayTransaction.
-func (api *TraceAPIImpl) ReplayTransaction(ctx context.Context, txHash common.Hash, traceTypes []string) ([]interface{}, error) {
-	var stub []interface{}
-	return stub, fmt.Errorf(NotImplemented, "trace_replayTransaction")
+	return results, nil
 }
@@ -0,0 +1,191 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/ledgerwatch/turbo-geth/cmd/rpcdaemon/cli"
+	"github.com/ledgerwatch/turbo-geth/common"
+	"github.com/ledgerwatch/turbo-geth/common/hexutil"
+	"github.com/ledgerwatch/turbo-geth/core/rawdb"
+	"github.com/ledgerwatch/turbo-geth/core/state"
+	"github.com/ledgerwatch/turbo-geth/core/vm"
+	"github.com/ledgerwatch/turbo-geth/core/vm/runtime"
+	"github.com/ledgerwatch/turbo-geth/ethdb"
+	"github.com/ledgerwatch/turbo-geth/rpc"
+)
+
+func TestReplayTransaction(t *testing.T) {
+	db, err := createTestDb()
+	if err != nil {
+		t.Fatalf("create test db: %v", err)
+	}
+	api := NewTraceAPI(db, &cli.Flags{})
+	traceTypes := []string{TraceTypeTrace, TraceTypeStateDiff}
+	for blockNum := uint64(1); blockNum <= 10; blockNum++ {
+		results, err1 := api.ReplayBlockTransactions(context.Background(), rpc.BlockNumber(blockNum), traceTypes)
+		if err1 != nil {
+			t.Fatalf("replayBlockTransactions %d: %v", blockNum, err1)
+		}
+		block, err1 := rawdb.ReadBlockByNumber(db, blockNum)
+		if err1 != nil {
+			t.Fatal(err1)
+		}
+		if len(results) != len(block.Transactions()) {
+			t.Fatalf("wrong number of results for block %d: %d, expected %d", blockNum, len(results), len(block.Transactions()))
+		}
+		for i, txn := range block.Transactions() {
+			if *results[i].TransactionHash != txn.Hash() {
+				t.Errorf("wrong transaction hash in block %d at %d: %x", blockNum, i, *results[i].TransactionHash)
+			}
+			if len(results[i].Trace) == 0 || len(results[i].StateDiff) == 0 {
+				t.Errorf("missing trace or stateDiff for transaction %x", txn.Hash())
+			}
+			// Replaying a single transaction has to give the same result as replaying it within the block
+			result, err2 := api.ReplayTransaction(context.Background(), txn.Hash(), traceTypes)
+			if err2 != nil {
+				t.Fatalf("replayTransaction %x: %v", txn.Hash(), err2)
+			}
+			result.TransactionHash = results[i].TransactionHash
+			expected, _ := json.Marshal(results[i])
+			actual, _ := json.Marshal(result)
+			if string(actual) != string(expected) {
+				t.Errorf("replayTransaction %x:\n%s\nexpected\n%s", txn.Hash(), actual, expected)
+			}
+		}
+	}
+}
+
+func TestCallMany(t *testing.T) {
+	db, err := createTestDb()
+	if err != nil {
+		t.Fatalf("create test db: %v", err)
+	}
+	api := NewTraceAPI(db, &cli.Flags{})
+	from := common.Address{1}
+	to := common.Address{2}
+	value := (*hexutil.Big)(big.NewInt(1000))
+	call := TraceCallParam{From: &from, To: &to, Value: value}
+	calls := []TraceCallManyParam{
+		{Call: call, TraceTypes: []string{TraceTypeStateDiff}},
+		{Call: call, TraceTypes: []string{TraceTypeTrace, TraceTypeStateDiff}},
+	}
+	latest := rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
+	results, err := api.CallMany(context.Background(), calls, &latest)
+	if err != nil {
+		t.Fatalf("callMany: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("wrong number of results: %d", len(results))
+	}
+	if results[0].Trace != nil || len(results[1].Trace) != 1 {
+		t.Errorf("trace must only be present when requested")
+	}
+	// The second call sees the balance left by the first one
+	first := results[0].StateDiff[to].Balance.(map[string]*hexutil.Big)["+"]
+	second := results[1].StateDiff[to].Balance.(map[string]*StateDiffBalance)["*"]
+	if first.ToInt().Cmp(second.From.ToInt()) != 0 {
+		t.Errorf("state did not carry over between calls: %d, expected %d", second.From.ToInt(), first.ToInt())
+	}
+	if new(big.Int).Sub(second.To.ToInt(), second.From.ToInt()).Cmp(value.ToInt()) != 0 {
+		t.Errorf("wrong balance change in the second call: %d -> %d", second.From.ToInt(), second.To.ToInt())
+	}
+}
+
+func TestReplayVmTrace(t *testing.T) {
+	db, err := createTestDb()
+	if err != nil {
+		t.Fatalf("create test db: %v", err)
+	}
+	api := NewTraceAPI(db, &cli.Flags{})
+	var withOps, withSub int
+	for blockNum := uint64(1); blockNum <= 10; blockNum++ {
+		results, err1 := api.ReplayBlockTransactions(context.Background(), rpc.BlockNumber(blockNum), []string{TraceTypeVmTrace})
+		if err1 != nil {
+			t.Fatalf("replayBlockTransactions %d: %v", blockNum, err1)
+		}
+		for _, result := range results {
+			if result.Trace != nil {
+				t.Errorf("trace must only be present when requested")
+			}
+			if result.VmTrace == nil {
+				t.Fatalf("missing vmTrace for transaction %x", *result.TransactionHash)
+			}
+			if len(result.VmTrace.Ops) == 0 {
+				continue
+			}
+			withOps++
+			if len(result.VmTrace.Code) == 0 {
+				t.Errorf("missing code in vmTrace for transaction %x", *result.TransactionHash)
+			}
+			for _, op := range result.VmTrace.Ops {
+				if op.Sub != nil {
+					withSub++
+				}
+			}
+		}
+	}
+	if withOps == 0 || withSub == 0 {
+		t.Errorf("expected instructions and sub-traces in vmTrace, got %d transactions with instructions and %d sub-traces", withOps, withSub)
+	}
+}
+
+func TestVmTraceEffects(t *testing.T) {
+	db := ethdb.NewMemDatabase()
+	defer db.Close()
+	ibs := state.New(state.NewTrieDbState(common.Hash{}, db, 0))
+	child := common.HexToAddress("0xc0")
+	// PUSH1 0x2a PUSH1 0 MSTORE PUSH1 0x20 PUSH1 0 RETURN
+	ibs.SetCode(child, common.FromHex("0x602a60005260206000f3"))
+	// PUSH1 0x20 PUSH1 0 PUSH1 0 PUSH1 0 PUSH1 0 PUSH1 0xc0 GAS CALL PUSH1 0x2a, then the implicit STOP
+	code := common.FromHex("0x6020600060006000600060c05af1602a")
+
+	ot := OeTracer{r: &TraceCallResult{VmTrace: &TraceCallVmTrace{Ops: []*VmTraceOp{}}}}
+	cfg := &runtime.Config{State: ibs, GasLimit: 1000000, EVMConfig: vm.Config{Debug: true, Tracer: &ot}}
+	if _, _, err := runtime.Execute(code, nil, cfg, 0); err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+
+	word := hexutil.Encode(common.LeftPadBytes([]byte{0x2a}, 32))
+	type effects struct {
+		Push []string
+		Mem  *VmTraceMem
+	}
+	collect := func(trace *TraceCallVmTrace) []effects {
+		var res []effects
+		for _, op := range trace.Ops {
+			if op.Ex == nil {
+				t.Fatalf("instruction at %d has no effects", op.Pc)
+			}
+			res = append(res, effects{Push: op.Ex.Push, Mem: op.Ex.Mem})
+		}
+		return res
+	}
+	assertJSONEqual(t, collect(ot.r.VmTrace), []effects{
+		{Push: []string{"0x20"}},
+		{Push: []string{"0x0"}},
+		{Push: []string{"0x0"}},
+		{Push: []string{"0x0"}},
+		{Push: []string{"0x0"}},
+		{Push: []string{"0xc0"}},
+		{Push: []string{hexutil.EncodeUint64(uint64(ot.r.VmTrace.Ops[6].Ex.Used))}},
+		{Push: []string{"0x1"}, Mem: &VmTraceMem{Data: common.FromHex(word), Off: 0}},
+		{Push: []string{"0x2a"}},
+		{Push: []string{}},
+	})
+	sub := ot.r.VmTrace.Ops[7].Sub
+	if sub == nil {
+		t.Fatalf("missing vmTrace of the call")
+	}
+	assertJSONEqual(t, collect(sub), []effects{
+		{Push: []string{"0x2a"}},
+		{Push: []string{"0x0"}},
+		{Push: []string{}, Mem: &VmTraceMem{Data: common.FromHex(word), Off: 0}},
+		{Push: []string{"0x20"}},
+		{Push: []string{"0x0"}},
+		// the last instruction is finished when the call exits
+		{Push: []string{}},
+	})
+}
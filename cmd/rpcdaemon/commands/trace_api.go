@@ -13,11 +13,11 @@ import (
 // TraceAPI RPC interface into tracing API
 type TraceAPI interface {
 	// Ad-hoc (see ./trace_adhoc.go)
-	ReplayBlockTransactions(ctx context.Context, blockNr rpc.BlockNumber, traceTypes []string) ([]interface{}, error)
-	ReplayTransaction(ctx context.Context, txHash common.Hash, traceTypes []string) ([]interface{}, error)
+	ReplayBlockTransactions(ctx context.Context, blockNr rpc.BlockNumber, traceTypes []string) ([]*TraceCallResult, error)
+	ReplayTransaction(ctx context.Context, txHash common.Hash, traceTypes []string) (*TraceCallResult, error)
 	Call(ctx context.Context, call TraceCallParam, types []string, blockNr *rpc.BlockNumberOrHash) (*TraceCallResult, error)
-	CallMany(ctx context.Context, calls []interface{}, blockNr *rpc.BlockNumberOrHash) ([]interface{}, error)
-	RawTransaction(ctx context.Context, txHash common.Hash, traceTypes []string) ([]interface{}, error)
+	CallMany(ctx context.Context, calls []TraceCallManyParam, blockNr *rpc.BlockNumberOrHash) ([]*TraceCallResult, error)
+	RawTransaction(ctx context.Context, encodedTx hexutil.Bytes, traceTypes []string) (*TraceCallResult, error)
 
 	// Filtering (see ./trace_filtering.go)
 	Transaction(ctx context.Context, txHash common.Hash) (ParityTraces, error)
@@ -29,19 +29,21 @@ type TraceAPI interface {
 // TraceAPIImpl is implementation of the TraceAPI interface based on remote Db access
 type TraceAPIImpl struct {
 	*BaseAPI
-	dbReader  ethdb.Database
-	maxTraces uint64
-	traceType string
-	gasCap    uint64
+	dbReader      ethdb.Database
+	maxTraces     uint64
+	maxTraceRange uint64
+	traceType     string
+	gasCap        uint64
 }
 
 // NewTraceAPI returns NewTraceAPI instance
 func NewTraceAPI(dbReader ethdb.Database, cfg *cli.Flags) *TraceAPIImpl {
 	return &TraceAPIImpl{
-		BaseAPI:   &BaseAPI{},
-		dbReader:  dbReader,
-		maxTraces: cfg.MaxTraces,
-		traceType: cfg.TraceType,
-		gasCap:    cfg.Gascap,
+		BaseAPI:       &BaseAPI{},
+		dbReader:      dbReader,
+		maxTraces:     cfg.MaxTraces,
+		maxTraceRange: cfg.MaxTraceRange,
+		traceType:     cfg.TraceType,
+		gasCap:        cfg.Gascap,
 	}
 }
@@ -1,22 +1,25 @@
 package commands
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
-	"sort"
 
+	"github.com/RoaringBitmap/roaring"
+	"github.com/holiman/uint256"
 	"github.com/ledgerwatch/turbo-geth/common"
 	"github.com/ledgerwatch/turbo-geth/common/dbutils"
 	"github.com/ledgerwatch/turbo-geth/common/hexutil"
 	"github.com/ledgerwatch/turbo-geth/consensus/ethash"
 	"github.com/ledgerwatch/turbo-geth/core/rawdb"
+	"github.com/ledgerwatch/turbo-geth/core/types"
 	"github.com/ledgerwatch/turbo-geth/eth"
 	"github.com/ledgerwatch/turbo-geth/ethdb"
 	"github.com/ledgerwatch/turbo-geth/ethdb/bitmapdb"
+	"github.com/ledgerwatch/turbo-geth/params"
 	"github.com/ledgerwatch/turbo-geth/rpc"
 	"github.com/ledgerwatch/turbo-geth/turbo/adapter"
+	"github.com/ledgerwatch/turbo-geth/turbo/rpchelper"
 	"github.com/ledgerwatch/turbo-geth/turbo/transactions"
 )
 
@@ -71,277 +74,298 @@ func (api *TraceAPIImpl) Get(ctx context.Context, txHash common.Hash, indicies [
 
 // Block implements trace_block
 func (api *TraceAPIImpl) Block(ctx context.Context, blockNr rpc.BlockNumber) (ParityTraces, error) {
-	blockNum, err := getBlockNumber(blockNr, api.dbReader)
+	dbtx, err := api.dbReader.Begin(ctx, ethdb.RO)
 	if err != nil {
 		return nil, err
 	}
-	bn := hexutil.Uint64(blockNum)
-	var req TraceFilterRequest
-	req.FromBlock = &bn
-	req.ToBlock = &bn
-	req.FromAddress = nil
-	req.ToAddress = nil
-	req.After = nil
-	req.Count = nil
+	defer dbtx.Rollback()
 
-	traces, err := api.Filter(ctx, req)
+	blockNum, err := getBlockNumber(blockNr, dbtx)
 	if err != nil {
 		return nil, err
 	}
-	return traces, err
+	traces := ParityTraces{}
+	if err = api.filterTraces(ctx, dbtx, TraceFilterRequest{}, blockNum, blockNum, func(trace *ParityTrace) bool {
+		traces = append(traces, *trace)
+		return true
+	}); err != nil {
+		return nil, err
+	}
+	return traces, nil
 }
 
+// filterWindow is the number of blocks for which the call indices are loaded at once
+const filterWindow = 1_000_000
+
 // Filter implements trace_filter
-// TODO(tjayrush): Eventually, we will need to protect ourselves from 'large' queries. Parity crashes when a range query of a very large size
-// is sent. We need to protect ourselves with maxTraces. It may already be done
+// The blocks are replayed one by one and only the requested page of traces is kept in memory. With the address filter,
+// only the blocks found in the call indices are replayed. The first `after` matching traces are skipped and at most
+// `count` traces are returned. Without `count`, the query fails if more than `trace.maxtraces` traces match
 func (api *TraceAPIImpl) Filter(ctx context.Context, req TraceFilterRequest) (ParityTraces, error) {
-	tx, err1 := api.dbReader.Begin(ctx, ethdb.RO)
-	if err1 != nil {
-		return nil, fmt.Errorf("traceFilter cannot open tx: %v", err1)
+	dbtx, err := api.dbReader.Begin(ctx, ethdb.RO)
+	if err != nil {
+		return nil, fmt.Errorf("traceFilter cannot open tx: %v", err)
 	}
-	defer tx.Rollback()
-
-	var filteredHashes []common.Hash
-	// TODO(tjayrush): Parity intersperses block/uncle reward traces with transaction call traces. We need to be able to tell the
-	// difference. I do that with this boolean array. This will be re-written shortly. For now, we use this simple boolean flag.
-	// Eventually, we will fill the ParityTrace array directly as we go (and we can probably even do better than that)
-	var traceTypes []bool
-	var maxTracesCount uint64
-	var offset uint64
-	var skipped uint64
-
-	sort.Slice(req.FromAddress, func(i int, j int) bool {
-		return bytes.Compare(req.FromAddress[i].Bytes(), req.FromAddress[j].Bytes()) == -1
-	})
-
-	sort.Slice(req.ToAddress, func(i int, j int) bool {
-		return bytes.Compare(req.ToAddress[i].Bytes(), req.ToAddress[j].Bytes()) == -1
-	})
+	defer dbtx.Rollback()
 
+	headNumber := rawdb.ReadHeaderNumber(dbtx, rawdb.ReadHeadHeaderHash(dbtx))
+	if headNumber == nil {
+		return nil, fmt.Errorf("head header not found")
+	}
 	var fromBlock uint64
-	var toBlock uint64
-	if req.FromBlock == nil {
-		fromBlock = 0
-	} else {
+	if req.FromBlock != nil {
 		fromBlock = uint64(*req.FromBlock)
+	} else if fromBlock, err = rpchelper.FirstNotPruned(dbtx); err != nil {
+		return nil, err
 	}
-
-	if req.ToBlock == nil {
-		headNumber := rawdb.ReadHeaderNumber(tx, rawdb.ReadHeadHeaderHash(tx))
-		toBlock = *headNumber
-	} else {
+	toBlock := *headNumber
+	if req.ToBlock != nil && uint64(*req.ToBlock) < toBlock {
 		toBlock = uint64(*req.ToBlock)
 	}
-
 	if fromBlock > toBlock {
-		// TODO(tjayrush): Parity reports no error in this case it simply returns an empty response
-		return nil, nil //fmt.Errorf("invalid parameters: toBlock must be greater than fromBlock")
+		// Parity reports no error in this case, it simply returns an empty response
+		return ParityTraces{}, nil
 	}
-
-	if req.Count == nil {
-		maxTracesCount = api.maxTraces
-	} else {
-		maxTracesCount = *req.Count
+	if api.maxTraceRange > 0 && toBlock-fromBlock >= api.maxTraceRange {
+		return nil, fmt.Errorf("block range %d-%d is too large, the limit is %d blocks", fromBlock, toBlock, api.maxTraceRange)
 	}
 
-	if req.After == nil {
-		offset = 0
-	} else {
-		offset = *req.After
+	var after uint64
+	if req.After != nil {
+		after = *req.After
 	}
-
-	if req.FromAddress != nil || req.ToAddress != nil { // use address history index to retrieve matching transactions
-		var historyFilter []*common.Address
-		isFromAddress := req.FromAddress != nil
-		if isFromAddress {
-			historyFilter = req.FromAddress
-		} else {
-			historyFilter = req.ToAddress
+	limit := api.maxTraces
+	if req.Count != nil {
+		if api.maxTraces > 0 && *req.Count > api.maxTraces {
+			return nil, fmt.Errorf("count %d exceeds the limit of %d traces", *req.Count, api.maxTraces)
 		}
+		limit = *req.Count
+	}
 
-		for _, addr := range historyFilter {
-
-			addrBytes := addr.Bytes()
-			blockNumbers, err := retrieveHistory(tx, addr, fromBlock, toBlock)
-			if err != nil {
-				return nil, err
-			}
-
-			for _, num := range blockNumbers {
-				block, err := rawdb.ReadBlockByNumber(tx, num)
-				if err != nil {
-					return nil, err
-				}
-				senders := rawdb.ReadSenders(tx, block.Hash(), num)
-				for i, txn := range block.Transactions() {
-					if uint64(len(filteredHashes)) == maxTracesCount {
-						if uint64(len(filteredHashes)) == api.maxTraces {
-							return nil, fmt.Errorf("too many traces found")
-						}
-						return nil, nil
-					}
-
-					var to *common.Address
-					if txn.To() == nil {
-						to = &common.Address{}
-					} else {
-						to = txn.To()
-					}
-
-					if isFromAddress {
-						if !isAddressInFilter(to, req.ToAddress) {
-							continue
-						}
-						if bytes.Equal(senders[i].Bytes(), addrBytes) {
-							filteredHashes = append(filteredHashes, txn.Hash())
-							traceTypes = append(traceTypes, false)
-						}
-					} else if bytes.Equal(to.Bytes(), addrBytes) {
-						if skipped < offset {
-							skipped++
-							continue
-						}
-						filteredHashes = append(filteredHashes, txn.Hash())
-						traceTypes = append(traceTypes, false)
-					}
-				}
-				// TODO(tjayrush): Parity does not (for some unknown reason) include blockReward traces here
+	traces := ParityTraces{}
+	var skipped uint64
+	var tooMany bool
+	if err = api.filterTraces(ctx, dbtx, req, fromBlock, toBlock, func(trace *ParityTrace) bool {
+		if skipped < after {
+			skipped++
+			return true
+		}
+		if limit > 0 || req.Count != nil {
+			if uint64(len(traces)) == limit {
+				tooMany = true
+				return false
 			}
 		}
-	} else if req.FromBlock != nil || req.ToBlock != nil { // iterate over blocks
+		traces = append(traces, *trace)
+		// With the explicit count, there is no need to look further
+		return req.Count == nil || uint64(len(traces)) < limit
+	}); err != nil {
+		return nil, err
+	}
+	if tooMany && req.Count == nil {
+		return nil, fmt.Errorf("too many traces found, the limit is %d, use `after` and `count` to page through the results", api.maxTraces)
+	}
+	return traces, nil
+}
 
-		for blockNum := fromBlock; blockNum < toBlock+1; blockNum++ {
-			block, err := rawdb.ReadBlockByNumber(tx, blockNum)
-			if err != nil {
-				return nil, err
+// filterTraces passes the traces of the blocks [fromBlock, toBlock] matching the addresses of the request to the emit
+// function, in the order they appear in the chain. It stops as soon as emit returns false or the context is cancelled
+func (api *TraceAPIImpl) filterTraces(ctx context.Context, dbtx ethdb.Database, req TraceFilterRequest, fromBlock, toBlock uint64, emit func(*ParityTrace) bool) error {
+	if err := rpchelper.CheckPruned(dbtx, fromBlock); err != nil {
+		return err
+	}
+	chainConfig, err := api.chainConfig(dbtx)
+	if err != nil {
+		return err
+	}
+	filter := newTraceAddressFilter(req)
+
+	for start := fromBlock; start <= toBlock; start += filterWindow {
+		end := toBlock
+		if toBlock-start >= filterWindow {
+			end = start + filterWindow - 1
+		}
+		blocks, err := filter.blocks(dbtx, start, end)
+		if err != nil {
+			return err
+		}
+		for it := blocks.Iterator(); it.HasNext(); {
+			if err = ctx.Err(); err != nil {
+				return err
 			}
-			for _, txn := range block.Transactions() {
-				if uint64(len(filteredHashes)) == maxTracesCount {
-					if uint64(len(filteredHashes)) == api.maxTraces {
-						return nil, fmt.Errorf("too many traces found")
-					}
-					return nil, nil
-				}
-				if skipped < offset {
-					skipped++
-					continue
-				}
-				filteredHashes = append(filteredHashes, txn.Hash())
-				traceTypes = append(traceTypes, false)
+			more, err := api.filterBlockTraces(ctx, dbtx, chainConfig, uint64(it.Next()), filter, emit)
+			if err != nil {
+				return err
 			}
-			// TODO(tjayrush): Need much, much better testing surrounding offset and count especially when including block rewards
-			if skipped < offset {
-				skipped++
-				continue
+			if !more {
+				return nil
 			}
-			// We need to intersperse block reward traces to match Parity
-			filteredHashes = append(filteredHashes, block.Hash())
-			traceTypes = append(traceTypes, true)
 		}
-	} else {
-		return nil, fmt.Errorf("invalid parameters")
+		if end == toBlock {
+			break
+		}
 	}
+	return nil
+}
 
-	getter := adapter.NewBlockGetter(tx)
-	chainContext := adapter.NewChainContext(tx)
-	genesis, err := rawdb.ReadBlockByNumber(tx, 0)
+// filterBlockTraces replays the transactions of the block and emits the matching traces followed by the matching reward traces.
+// It returns false if the emit function asked to stop
+func (api *TraceAPIImpl) filterBlockTraces(ctx context.Context, dbtx ethdb.Database, chainConfig *params.ChainConfig, blockNum uint64, filter *traceAddressFilter, emit func(*ParityTrace) bool) (bool, error) {
+	hash, err := rawdb.ReadCanonicalHash(dbtx, blockNum)
 	if err != nil {
-		return nil, err
+		return false, err
 	}
-	genesisHash := genesis.Hash()
-	chainConfig, err := rawdb.ReadChainConfig(tx, genesisHash)
-	if err != nil {
-		return nil, err
+	block := rawdb.ReadBlock(dbtx, hash, blockNum)
+	if block == nil {
+		return false, fmt.Errorf("block %d(%x) not found", blockNum, hash)
 	}
-	traceType := "callTracer" // nolint: goconst
-	traces := ParityTraces{}
 
-	for i, txOrBlockHash := range filteredHashes {
-		if traceTypes[i] {
-			// In this case, we're processing a block (or uncle) reward trace. The hash is a block hash
-			// Because Geth does not return blockReward or uncleReward traces, we must create them here
-			block, err := rawdb.ReadBlockByHash(tx, txOrBlockHash)
-			if err != nil {
-				return nil, err
-			}
-			minerReward, uncleRewards := ethash.AccumulateRewards(chainConfig, block.Header(), block.Uncles())
-			fmt.Printf("%v\n", minerReward)
-			var tr ParityTrace
-			//tr.Action.Author = strings.ToLower(block.Coinbase().String())
-			//tr.Action.RewardType = "block" // goconst
-			//tr.Action.Value = minerReward.String()
-			tr.BlockHash = &common.Hash{}
-			copy(tr.BlockHash[:], block.Hash().Bytes())
-			tr.BlockNumber = new(uint64)
-			*tr.BlockNumber = block.NumberU64()
-			tr.Type = "reward" // nolint: goconst
-			traces = append(traces, tr)
-			for i, uncle := range block.Uncles() {
-				fmt.Printf("%v\n", uncle)
-				if i < len(uncleRewards) {
-					var tr ParityTrace
-					//tr.Action.Author = strings.ToLower(uncle.Coinbase.String())
-					//tr.Action.RewardType = "uncle" // goconst
-					//tr.Action.Value = uncleRewards[i].String()
-					tr.BlockHash = &common.Hash{}
-					copy(tr.BlockHash[:], block.Hash().Bytes())
-					tr.BlockNumber = new(uint64)
-					*tr.BlockNumber = block.NumberU64()
-					tr.Type = "reward" // nolint: goconst
-					traces = append(traces, tr)
-				}
-			}
-		} else {
-			// In this case, we're processing a transaction hash
-			txn, blockHash, blockNumber, txIndex := rawdb.ReadTransaction(tx, txOrBlockHash)
-			msg, vmctx, ibs, _, err := transactions.ComputeTxEnv(ctx, getter, chainConfig, chainContext, tx.(ethdb.HasTx).Tx(), blockHash, txIndex)
-			if err != nil {
-				return nil, err
-			}
-			trace, err := transactions.TraceTx(ctx, msg, vmctx, ibs, &eth.TraceConfig{Tracer: &traceType}, chainConfig)
-			if err != nil {
-				return nil, err
+	traceTypes := make([][]string, len(block.Transactions()))
+	for i := range traceTypes {
+		traceTypes[i] = []string{TraceTypeTrace}
+	}
+	results, err := api.replayBlockTransactions(ctx, dbtx, block, traceTypes)
+	if err != nil {
+		return false, err
+	}
+	for i, result := range results {
+		txHash := block.Transactions()[i].Hash()
+		txPosition := uint64(i)
+		for _, trace := range result.Trace {
+			if !filter.matchTrace(trace) {
+				continue
 			}
-			traceJSON, ok := trace.(json.RawMessage)
-			if !ok {
-				return nil, fmt.Errorf("unknown type in trace_filter")
+			trace.BlockHash = &hash
+			trace.BlockNumber = &blockNum
+			trace.TransactionHash = &txHash
+			trace.TransactionPosition = &txPosition
+			if !emit(trace) {
+				return false, nil
 			}
-			var gethTrace GethTrace
-			jsonStr, _ := traceJSON.MarshalJSON()
-			json.Unmarshal(jsonStr, &gethTrace) // nolint errcheck
-			converted := api.convertToParityTrace(gethTrace, blockHash, blockNumber, txn, txIndex, []int{})
-			traces = append(traces, converted...)
 		}
 	}
-	return traces, nil
+
+	// Block and uncle rewards do not exist in proof-of-authority chains and the genesis block
+	if chainConfig.Clique != nil || blockNum == 0 {
+		return true, nil
+	}
+	minerReward, uncleRewards := ethash.AccumulateRewards(chainConfig, block.Header(), block.Uncles())
+	if !emitReward(block, block.Coinbase(), "block", &minerReward, filter, emit) {
+		return false, nil
+	}
+	for i, uncle := range block.Uncles() {
+		if i < len(uncleRewards) && !emitReward(block, uncle.Coinbase, "uncle", &uncleRewards[i], filter, emit) {
+			return false, nil
+		}
+	}
+	return true, nil
 }
 
-func retrieveHistory(tx ethdb.Getter, addr *common.Address, fromBlock uint64, toBlock uint64) ([]uint64, error) {
-	blocks, err := bitmapdb.Get(tx, dbutils.AccountsHistoryBucket, addr.Bytes(), uint32(fromBlock), uint32(toBlock+1))
-	if err != nil {
-		return nil, err
+func emitReward(block *types.Block, author common.Address, rewardType string, value *uint256.Int, filter *traceAddressFilter, emit func(*ParityTrace) bool) bool {
+	if !filter.matchReward(author) {
+		return true
+	}
+	action := &RewardTraceAction{Author: author, RewardType: rewardType}
+	action.Value.ToInt().Set(value.ToBig())
+	hash := block.Hash()
+	blockNum := block.NumberU64()
+	return emit(&ParityTrace{
+		Action:       action,
+		BlockHash:    &hash,
+		BlockNumber:  &blockNum,
+		TraceAddress: []int{},
+		Type:         "reward",
+	})
+}
+
+// traceAddressFilter selects the traces by the addresses of trace_filter request. Empty set of addresses matches any address
+type traceAddressFilter struct {
+	froms map[common.Address]struct{}
+	tos   map[common.Address]struct{}
+}
+
+func newTraceAddressFilter(req TraceFilterRequest) *traceAddressFilter {
+	filter := &traceAddressFilter{
+		froms: make(map[common.Address]struct{}, len(req.FromAddress)),
+		tos:   make(map[common.Address]struct{}, len(req.ToAddress)),
 	}
-	blocks.RemoveRange(fromBlock, toBlock+1)
-	return toU64(blocks.ToArray()), nil
+	for _, addr := range req.FromAddress {
+		if addr != nil {
+			filter.froms[*addr] = struct{}{}
+		}
+	}
+	for _, addr := range req.ToAddress {
+		if addr != nil {
+			filter.tos[*addr] = struct{}{}
+		}
+	}
+	return filter
 }
 
-func toU64(in []uint32) []uint64 {
-	out := make([]uint64, len(in))
-	for i := range in {
-		out[i] = uint64(in[i])
+// blocks returns the numbers of the blocks [from, to] which may contain the matching traces
+func (f *traceAddressFilter) blocks(db ethdb.Getter, from, to uint64) (*roaring.Bitmap, error) {
+	all := roaring.New()
+	all.AddRange(from, to+1)
+	if len(f.froms) == 0 && len(f.tos) == 0 {
+		return all, nil
+	}
+	blocks := all
+	if len(f.froms) > 0 {
+		froms, err := indexedBlocks(db, dbutils.CallFromIndex, f.froms, from, to)
+		if err != nil {
+			return nil, err
+		}
+		blocks = roaring.And(blocks, froms)
 	}
-	return out
+	if len(f.tos) > 0 {
+		tos, err := indexedBlocks(db, dbutils.CallToIndex, f.tos, from, to)
+		if err != nil {
+			return nil, err
+		}
+		blocks = roaring.And(blocks, tos)
+	}
+	return blocks, nil
 }
 
-func isAddressInFilter(addr *common.Address, filter []*common.Address) bool {
-	if filter == nil {
+func indexedBlocks(db ethdb.Getter, bucket string, addrs map[common.Address]struct{}, from, to uint64) (*roaring.Bitmap, error) {
+	blocks := roaring.New()
+	for addr := range addrs {
+		bm, err := bitmapdb.Get(db, bucket, addr.Bytes(), uint32(from), uint32(to))
+		if err != nil {
+			return nil, err
+		}
+		blocks.Or(bm)
+	}
+	return blocks, nil
+}
+
+func (f *traceAddressFilter) match(set map[common.Address]struct{}, addr common.Address) bool {
+	if len(set) == 0 {
 		return true
 	}
-	i := sort.Search(len(filter), func(i int) bool {
-		return bytes.Equal(filter[i].Bytes(), addr.Bytes())
-	})
+	_, ok := set[addr]
+	return ok
+}
+
+func (f *traceAddressFilter) matchTrace(trace *ParityTrace) bool {
+	var from, to common.Address
+	switch action := trace.Action.(type) {
+	case *CallTraceAction:
+		from, to = action.From, action.To
+	case *CreateTraceAction:
+		from = action.From
+		if result, ok := trace.Result.(*CreateTraceResult); ok && result.Address != nil {
+			to = *result.Address
+		}
+	case *SuicideTraceAction:
+		from, to = action.Address, action.RefundAddress
+	}
+	return f.match(f.froms, from) && f.match(f.tos, to)
+}
 
-	return i != len(filter)
+// matchReward - rewards have no sender, so they never match the `fromAddress` filter
+func (f *traceAddressFilter) matchReward(author common.Address) bool {
+	return len(f.froms) == 0 && f.match(f.tos, author)
 }
 
 // getTransactionTraces - returns the traces for a single transaction. Used by trace_get and trace_transaction.
@@ -365,6 +389,12 @@ func (api *TraceAPIImpl) getTransactionTraces(tx ethdb.Database, ctx context.Con
 	traceType := "callTracer" // nolint: goconst
 
 	txn, blockHash, blockNumber, txIndex := rawdb.ReadTransaction(tx, txHash)
+	if txn == nil {
+		return nil, fmt.Errorf("transaction %#x not found", txHash)
+	}
+	if err = rpchelper.CheckPruned(tx, blockNumber); err != nil {
+		return nil, err
+	}
 	msg, vmctx, ibs, _, err := transactions.ComputeTxEnv(ctx, getter, chainConfig, chainContext, tx.(ethdb.HasTx).Tx(), blockHash, txIndex)
 	if err != nil {
 		return nil, err
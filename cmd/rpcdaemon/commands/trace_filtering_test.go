@@ -0,0 +1,201 @@
+package commands
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"testing"
+
+	"github.com/RoaringBitmap/roaring"
+	"github.com/ledgerwatch/turbo-geth/cmd/rpcdaemon/cli"
+	"github.com/ledgerwatch/turbo-geth/common"
+	"github.com/ledgerwatch/turbo-geth/common/dbutils"
+	"github.com/ledgerwatch/turbo-geth/common/hexutil"
+	"github.com/ledgerwatch/turbo-geth/rpc"
+)
+
+func TestFilterPaging(t *testing.T) {
+	db, err := createTestDb()
+	if err != nil {
+		t.Fatalf("create test db: %v", err)
+	}
+	api := NewTraceAPI(db, &cli.Flags{})
+	fromBlock, toBlock := hexutil.Uint64(1), hexutil.Uint64(10)
+	all, err := api.Filter(context.Background(), TraceFilterRequest{FromBlock: &fromBlock, ToBlock: &toBlock})
+	if err != nil {
+		t.Fatalf("trace_filter: %v", err)
+	}
+
+	// trace_filter over the range has to give the same traces as trace_block for each block
+	var blocks ParityTraces
+	var rewards int
+	for blockNum := uint64(1); blockNum <= 10; blockNum++ {
+		traces, err1 := api.Block(context.Background(), rpc.BlockNumber(blockNum))
+		if err1 != nil {
+			t.Fatalf("trace_block %d: %v", blockNum, err1)
+		}
+		blocks = append(blocks, traces...)
+		for _, trace := range traces {
+			if trace.Type == "reward" {
+				rewards++
+			}
+		}
+	}
+	assertTracesEqual(t, all, blocks)
+	if rewards < 10 {
+		t.Errorf("expected at least one reward trace per block, got %d", rewards)
+	}
+
+	for _, count := range []uint64{0, 1, 3} {
+		for after := uint64(0); after <= uint64(len(all)); after++ {
+			after, count := after, count
+			page, err1 := api.Filter(context.Background(), TraceFilterRequest{FromBlock: &fromBlock, ToBlock: &toBlock, After: &after, Count: &count})
+			if err1 != nil {
+				t.Fatalf("trace_filter after %d count %d: %v", after, count, err1)
+			}
+			end := after + count
+			if end > uint64(len(all)) {
+				end = uint64(len(all))
+			}
+			assertTracesEqual(t, page, all[after:end])
+		}
+	}
+}
+
+func TestFilterLimits(t *testing.T) {
+	db, err := createTestDb()
+	if err != nil {
+		t.Fatalf("create test db: %v", err)
+	}
+	fromBlock, toBlock := hexutil.Uint64(1), hexutil.Uint64(10)
+	req := TraceFilterRequest{FromBlock: &fromBlock, ToBlock: &toBlock}
+
+	api := NewTraceAPI(db, &cli.Flags{MaxTraces: 2})
+	if _, err = api.Filter(context.Background(), req); err == nil {
+		t.Errorf("expected too many traces error")
+	}
+	count := uint64(3)
+	if _, err = api.Filter(context.Background(), TraceFilterRequest{FromBlock: &fromBlock, ToBlock: &toBlock, Count: &count}); err == nil {
+		t.Errorf("expected error for count above the limit")
+	}
+	count = 2
+	traces, err := api.Filter(context.Background(), TraceFilterRequest{FromBlock: &fromBlock, ToBlock: &toBlock, Count: &count})
+	if err != nil {
+		t.Fatalf("trace_filter: %v", err)
+	}
+	if len(traces) != 2 {
+		t.Errorf("expected 2 traces, got %d", len(traces))
+	}
+
+	api = NewTraceAPI(db, &cli.Flags{MaxTraceRange: 5})
+	if _, err = api.Filter(context.Background(), req); err == nil {
+		t.Errorf("expected error for the block range above the limit")
+	}
+	toBlock = 5
+	if _, err = api.Filter(context.Background(), req); err != nil {
+		t.Errorf("trace_filter within the range limit: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err = api.Filter(ctx, req); err == nil {
+		t.Errorf("expected error for the cancelled context")
+	}
+}
+
+func TestFilterAddresses(t *testing.T) {
+	db, err := createTestDb()
+	if err != nil {
+		t.Fatalf("create test db: %v", err)
+	}
+	api := NewTraceAPI(db, &cli.Flags{})
+	fromBlock, toBlock := hexutil.Uint64(1), hexutil.Uint64(10)
+	all, err := api.Filter(context.Background(), TraceFilterRequest{FromBlock: &fromBlock, ToBlock: &toBlock})
+	if err != nil {
+		t.Fatalf("trace_filter: %v", err)
+	}
+
+	// Build the call indices the same way as the call traces stage does
+	froms := map[common.Address]*roaring.Bitmap{}
+	tos := map[common.Address]*roaring.Bitmap{}
+	index := func(m map[common.Address]*roaring.Bitmap, addr common.Address, blockNum uint64) {
+		if _, ok := m[addr]; !ok {
+			m[addr] = roaring.New()
+		}
+		m[addr].Add(uint32(blockNum))
+	}
+	for i := range all {
+		trace := &all[i]
+		switch action := trace.Action.(type) {
+		case *CallTraceAction:
+			index(froms, action.From, *trace.BlockNumber)
+			index(tos, action.To, *trace.BlockNumber)
+		case *CreateTraceAction:
+			index(froms, action.From, *trace.BlockNumber)
+			index(tos, *trace.Result.(*CreateTraceResult).Address, *trace.BlockNumber)
+		case *RewardTraceAction:
+			index(tos, action.Author, *trace.BlockNumber)
+		}
+	}
+	writeIndex := func(bucket string, m map[common.Address]*roaring.Bitmap) {
+		for addr, bm := range m {
+			key := make([]byte, common.AddressLength+4)
+			copy(key, addr[:])
+			binary.BigEndian.PutUint32(key[common.AddressLength:], ^uint32(0))
+			v, err1 := bm.ToBytes()
+			if err1 != nil {
+				t.Fatal(err1)
+			}
+			if err1 = db.Put(bucket, key, v); err1 != nil {
+				t.Fatal(err1)
+			}
+		}
+	}
+	writeIndex(dbutils.CallFromIndex, froms)
+	writeIndex(dbutils.CallToIndex, tos)
+
+	check := func(req TraceFilterRequest) {
+		req.FromBlock, req.ToBlock = &fromBlock, &toBlock
+		filter := newTraceAddressFilter(req)
+		var expected ParityTraces
+		for i := range all {
+			if action, ok := all[i].Action.(*RewardTraceAction); ok {
+				if filter.matchReward(action.Author) {
+					expected = append(expected, all[i])
+				}
+			} else if filter.matchTrace(&all[i]) {
+				expected = append(expected, all[i])
+			}
+		}
+		if len(expected) == 0 {
+			t.Fatalf("no traces match %v %v", req.FromAddress, req.ToAddress)
+		}
+		traces, err1 := api.Filter(context.Background(), req)
+		if err1 != nil {
+			t.Fatalf("trace_filter: %v", err1)
+		}
+		assertTracesEqual(t, traces, expected)
+	}
+	for addr := range froms {
+		addr := addr
+		check(TraceFilterRequest{FromAddress: []*common.Address{&addr}})
+	}
+	for addr := range tos {
+		addr := addr
+		check(TraceFilterRequest{ToAddress: []*common.Address{&addr}})
+	}
+	call := all[0].Action.(*CallTraceAction)
+	check(TraceFilterRequest{FromAddress: []*common.Address{&call.From}, ToAddress: []*common.Address{&call.To}})
+}
+
+func assertTracesEqual(t *testing.T, actual, expected ParityTraces) {
+	t.Helper()
+	if len(actual) == 0 && len(expected) == 0 {
+		return
+	}
+	actualJSON, _ := json.Marshal(actual)
+	expectedJSON, _ := json.Marshal(expected)
+	if string(actualJSON) != string(expectedJSON) {
+		t.Errorf("wrong traces:\n%s\nexpected\n%s", actualJSON, expectedJSON)
+	}
+}
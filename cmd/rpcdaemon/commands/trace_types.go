@@ -91,6 +91,12 @@ type SuicideTraceAction struct {
 	Balance       hexutil.Big    `json:"balance"`
 }
 
+type RewardTraceAction struct {
+	Author     common.Address `json:"author"`
+	RewardType string         `json:"rewardType"`
+	Value      hexutil.Big    `json:"value"`
+}
+
 type CreateTraceResult struct {
 	// Do not change the ordering of these fields -- allows for easier comparison with other clients
 	Address *common.Address `json:"address,omitempty"`
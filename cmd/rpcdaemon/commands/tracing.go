@@ -1,15 +1,23 @@
 package commands
 
 import (
+	"bufio"
 	"context"
 	"fmt"
+	"io/ioutil"
+	"os"
 
+	jsoniter "github.com/json-iterator/go"
 	"github.com/ledgerwatch/turbo-geth/common"
+	"github.com/ledgerwatch/turbo-geth/core"
 	"github.com/ledgerwatch/turbo-geth/core/rawdb"
 	"github.com/ledgerwatch/turbo-geth/core/state"
+	"github.com/ledgerwatch/turbo-geth/core/types"
+	"github.com/ledgerwatch/turbo-geth/core/vm"
 	"github.com/ledgerwatch/turbo-geth/eth"
 	"github.com/ledgerwatch/turbo-geth/ethdb"
 	"github.com/ledgerwatch/turbo-geth/internal/ethapi"
+	"github.com/ledgerwatch/turbo-geth/log"
 	"github.com/ledgerwatch/turbo-geth/rpc"
 	"github.com/ledgerwatch/turbo-geth/turbo/adapter"
 	"github.com/ledgerwatch/turbo-geth/turbo/rpchelper"
@@ -25,10 +33,13 @@ func (api *PrivateDebugAPIImpl) TraceTransaction(ctx context.Context, hash commo
 	defer tx.Rollback()
 
 	// Retrieve the transaction and assemble its EVM context
-	txn, blockHash, _, txIndex := rawdb.ReadTransaction(tx, hash)
+	txn, blockHash, blockNumber, txIndex := rawdb.ReadTransaction(tx, hash)
 	if txn == nil {
 		return nil, fmt.Errorf("transaction %#x not found", hash)
 	}
+	if err = rpchelper.CheckPruned(tx, blockNumber); err != nil {
+		return nil, err
+	}
 	getter := adapter.NewBlockGetter(tx)
 	chainContext := adapter.NewChainContext(tx)
 
@@ -77,3 +88,173 @@ func (api *PrivateDebugAPIImpl) TraceCall(ctx context.Context, args ethapi.CallA
 	// Trace the transaction and return
 	return transactions.TraceTx(ctx, msg, evmCtx, ibs, config, chainConfig)
 }
+
+// TxTraceResult is the result of a single transaction trace in the block trace
+type TxTraceResult struct {
+	Result interface{} `json:"result,omitempty"` // Trace results produced by the tracer
+	Error  string      `json:"error,omitempty"`  // Trace failure produced by the tracer
+}
+
+// TraceBlockByNumber implements debug_traceBlockByNumber. Returns Geth style traces of all transactions in the block.
+func (api *PrivateDebugAPIImpl) TraceBlockByNumber(ctx context.Context, number rpc.BlockNumber, config *eth.TraceConfig, stream *jsoniter.Stream) error {
+	return api.traceBlock(ctx, rpc.BlockNumberOrHashWithNumber(number), config, stream)
+}
+
+// TraceBlockByHash implements debug_traceBlockByHash. Returns Geth style traces of all transactions in the block.
+func (api *PrivateDebugAPIImpl) TraceBlockByHash(ctx context.Context, hash common.Hash, config *eth.TraceConfig, stream *jsoniter.Stream) error {
+	return api.traceBlock(ctx, rpc.BlockNumberOrHashWithHash(hash, true), config, stream)
+}
+
+// traceBlock executes the block once, and traces each of its transactions with the configured tracer. The results are
+// written to the stream as the JSON array of TxTraceResult, each result is flushed as soon as it's produced
+func (api *PrivateDebugAPIImpl) traceBlock(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash, config *eth.TraceConfig, stream *jsoniter.Stream) error {
+	tx, err := api.dbReader.Begin(ctx, ethdb.RO)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	block, err := readBlock(tx, blockNrOrHash)
+	if err != nil {
+		return err
+	}
+	chainConfig, err := api.chainConfig(tx)
+	if err != nil {
+		return err
+	}
+
+	stream.WriteArrayStart()
+	if err = transactions.TraceBlock(ctx, block, chainConfig, adapter.NewChainContext(tx), adapter.NewBlockGetter(tx), tx.(ethdb.HasTx).Tx(), config, func(txIndex int, result interface{}, err error) error {
+		if txIndex > 0 {
+			stream.WriteMore()
+		}
+		if err != nil {
+			stream.WriteVal(&TxTraceResult{Error: err.Error()})
+		} else {
+			stream.WriteVal(&TxTraceResult{Result: result})
+		}
+		if stream.Error != nil {
+			return stream.Error
+		}
+		return stream.Flush()
+	}); err != nil {
+		return err
+	}
+	stream.WriteArrayEnd()
+	return stream.Flush()
+}
+
+// StandardTraceBlockToFile implements debug_standardTraceBlockToFile. Dumps the standard JSON traces of the transactions
+// in the block, or only of the transaction given in the config, into the temporary files on the rpcdaemon host, and
+// returns the names of the files. The files are removed if the tracing fails.
+func (api *PrivateDebugAPIImpl) StandardTraceBlockToFile(ctx context.Context, hash common.Hash, config *eth.StdTraceConfig) ([]string, error) {
+	tx, err := api.dbReader.Begin(ctx, ethdb.RO)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	block, err := readBlock(tx, rpc.BlockNumberOrHashWithHash(hash, true))
+	if err != nil {
+		return nil, err
+	}
+	chainConfig, err := api.chainConfig(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		logConfig vm.LogConfig
+		txHash    common.Hash
+	)
+	if config != nil {
+		if config.LogConfig != nil {
+			logConfig = *config.LogConfig
+		}
+		txHash = config.TxHash
+	}
+	logConfig.Debug = true
+	// If we're tracing a single transaction, make sure it's present
+	if txHash != (common.Hash{}) && block.Transaction(txHash) == nil {
+		return nil, fmt.Errorf("transaction %#x not found in block", txHash)
+	}
+
+	parent := rawdb.ReadBlock(tx, block.ParentHash(), block.NumberU64()-1)
+	if parent == nil {
+		return nil, fmt.Errorf("parent %#x not found", block.ParentHash())
+	}
+	ibs, reader := adapter.ComputeIntraBlockState(tx.(ethdb.HasTx).Tx(), parent)
+	chainContext := adapter.NewChainContext(tx)
+	signer := types.MakeSigner(chainConfig, block.Number())
+
+	var dumps []string
+	for i, txn := range block.Transactions() {
+		select {
+		default:
+		case <-ctx.Done():
+			removeFiles(dumps)
+			return nil, ctx.Err()
+		}
+		ibs.Prepare(txn.Hash(), block.Hash(), i)
+		msg, _ := txn.AsMessage(signer)
+		vmctx := core.NewEVMContext(msg, block.Header(), chainContext, nil)
+
+		var (
+			vmConf vm.Config
+			dump   *os.File
+			writer *bufio.Writer
+		)
+		if txHash == (common.Hash{}) || txn.Hash() == txHash {
+			// Generate a unique temporary file to dump it into
+			prefix := fmt.Sprintf("block_%#x-%d-%#x-", block.Hash().Bytes()[:4], i, txn.Hash().Bytes()[:4])
+			if dump, err = ioutil.TempFile(os.TempDir(), prefix); err != nil {
+				removeFiles(dumps)
+				return nil, err
+			}
+			dumps = append(dumps, dump.Name())
+			writer = bufio.NewWriter(dump)
+			vmConf = vm.Config{Debug: true, Tracer: vm.NewJSONLogger(&logConfig, writer)}
+		}
+		vmenv := vm.NewEVM(vmctx, ibs, chainConfig, vmConf)
+		_, err = core.ApplyMessage(vmenv, msg, new(core.GasPool).AddGas(msg.Gas()), true /* refunds */)
+		if writer != nil {
+			_ = writer.Flush()
+		}
+		if dump != nil {
+			_ = dump.Close()
+			log.Info("Wrote standard trace", "file", dump.Name())
+		}
+		if err != nil {
+			removeFiles(dumps)
+			return nil, fmt.Errorf("transaction %x failed: %v", txn.Hash(), err)
+		}
+		// Only delete empty objects if EIP158/161 (a.k.a Spurious Dragon) is in effect
+		_ = ibs.FinalizeTx(chainConfig.WithEIPsFlags(context.Background(), block.Number()), reader)
+		if txn.Hash() == txHash {
+			break
+		}
+	}
+	return dumps, nil
+}
+
+// removeFiles removes the trace files created before the failure
+func removeFiles(names []string) {
+	for _, name := range names {
+		if err := os.Remove(name); err != nil {
+			log.Warn("Failed to remove standard trace", "file", name, "err", err)
+		}
+	}
+}
+
+// readBlock returns the block given by the number or hash, or an error if there is no such block
+func readBlock(tx ethdb.Database, blockNrOrHash rpc.BlockNumberOrHash) (*types.Block, error) {
+	blockNumber, hash, err := rpchelper.GetBlockNumber(blockNrOrHash, tx)
+	if err != nil {
+		return nil, err
+	}
+	block := rawdb.ReadBlock(tx, hash, blockNumber)
+	if block == nil {
+		return nil, fmt.Errorf("block %d(%x) not found", blockNumber, hash)
+	}
+	return block, nil
+}
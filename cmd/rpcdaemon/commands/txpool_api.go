@@ -0,0 +1,150 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ledgerwatch/turbo-geth/common"
+	"github.com/ledgerwatch/turbo-geth/common/hexutil"
+	"github.com/ledgerwatch/turbo-geth/core/types"
+	"github.com/ledgerwatch/turbo-geth/ethdb"
+	"github.com/ledgerwatch/turbo-geth/ethdb/remote"
+)
+
+// TxPoolAPI the interface for the txpool_ RPC commands
+type TxPoolAPI interface {
+	Content(ctx context.Context) (map[string]map[string]map[string]*RPCTransaction, error)
+	Status(ctx context.Context) (map[string]hexutil.Uint, error)
+	Inspect(ctx context.Context) (map[string]map[string]map[string]string, error)
+	NonceGaps(ctx context.Context, address common.Address) ([]NonceGap, error)
+}
+
+// TxPoolAPIImpl data structure to store things needed for txpool_ commands
+type TxPoolAPIImpl struct {
+	ethBackend ethdb.Backend
+}
+
+// NewTxPoolAPI returns TxPoolAPIImpl instance
+func NewTxPoolAPI(eth ethdb.Backend) *TxPoolAPIImpl {
+	return &TxPoolAPIImpl{
+		ethBackend: eth,
+	}
+}
+
+// NonceGap is an inclusive range of the nonces missing before the queued transactions of an account
+type NonceGap struct {
+	From hexutil.Uint64 `json:"from"`
+	To   hexutil.Uint64 `json:"to"`
+}
+
+// Content implements txpool_content. Returns the pending and queued transactions of the pool, grouped by the sender and the nonce.
+func (api *TxPoolAPIImpl) Content(_ context.Context) (map[string]map[string]map[string]*RPCTransaction, error) {
+	content := map[string]map[string]map[string]*RPCTransaction{
+		"pending": make(map[string]map[string]*RPCTransaction),
+		"queued":  make(map[string]map[string]*RPCTransaction),
+	}
+	err := api.walkContent("txpool_content", func(section string, account common.Address, txs []*types.Transaction) {
+		dump := make(map[string]*RPCTransaction, len(txs))
+		for _, tx := range txs {
+			dump[fmt.Sprintf("%d", tx.Nonce())] = newRPCTransaction(tx, common.Hash{}, 0, 0)
+		}
+		content[section][account.Hex()] = dump
+	})
+	if err != nil {
+		return nil, err
+	}
+	return content, nil
+}
+
+// Status implements txpool_status. Returns the number of pending and queued transactions in the pool.
+func (api *TxPoolAPIImpl) Status(_ context.Context) (map[string]hexutil.Uint, error) {
+	if api.ethBackend == nil {
+		// We're running in --chaindata mode or otherwise cannot get the backend
+		return nil, fmt.Errorf(NotAvailableChainData, "txpool_status")
+	}
+
+	res, err := api.ethBackend.TxPoolStatus()
+	if err != nil {
+		return nil, err
+	}
+	return map[string]hexutil.Uint{
+		"pending": hexutil.Uint(res.Pending),
+		"queued":  hexutil.Uint(res.Queued),
+	}, nil
+}
+
+// Inspect implements txpool_inspect. Returns the pending and queued transactions of the pool flattened
+// into the short textual summaries.
+func (api *TxPoolAPIImpl) Inspect(_ context.Context) (map[string]map[string]map[string]string, error) {
+	content := map[string]map[string]map[string]string{
+		"pending": make(map[string]map[string]string),
+		"queued":  make(map[string]map[string]string),
+	}
+	// Define a formatter to flatten a transaction into a string
+	var format = func(tx *types.Transaction) string {
+		if to := tx.To(); to != nil {
+			return fmt.Sprintf("%s: %v wei + %v gas × %v wei", tx.To().Hex(), tx.Value(), tx.Gas(), tx.GasPrice())
+		}
+		return fmt.Sprintf("contract creation: %v wei + %v gas × %v wei", tx.Value(), tx.Gas(), tx.GasPrice())
+	}
+	err := api.walkContent("txpool_inspect", func(section string, account common.Address, txs []*types.Transaction) {
+		dump := make(map[string]string, len(txs))
+		for _, tx := range txs {
+			dump[fmt.Sprintf("%d", tx.Nonce())] = format(tx)
+		}
+		content[section][account.Hex()] = dump
+	})
+	if err != nil {
+		return nil, err
+	}
+	return content, nil
+}
+
+// NonceGaps implements txpool_nonceGaps. Returns the nonces missing between the next nonce of the account and
+// its queued transactions, these transactions can't be executed until the gaps are filled. Turbo-geth only.
+func (api *TxPoolAPIImpl) NonceGaps(_ context.Context, address common.Address) ([]NonceGap, error) {
+	if api.ethBackend == nil {
+		// We're running in --chaindata mode or otherwise cannot get the backend
+		return nil, fmt.Errorf(NotAvailableChainData, "txpool_nonceGaps")
+	}
+
+	res, err := api.ethBackend.Nonce(address)
+	if err != nil {
+		return nil, err
+	}
+	if !res.Found {
+		return nil, fmt.Errorf("the transaction pool of the node is not started")
+	}
+	gaps := make([]NonceGap, 0, len(res.Gaps))
+	for _, gap := range res.Gaps {
+		gaps = append(gaps, NonceGap{From: hexutil.Uint64(gap.From), To: hexutil.Uint64(gap.To)})
+	}
+	return gaps, nil
+}
+
+// walkContent calls f for the decoded transactions of each account in the "pending" and "queued" sections of the pool
+func (api *TxPoolAPIImpl) walkContent(method string, f func(section string, account common.Address, txs []*types.Transaction)) error {
+	if api.ethBackend == nil {
+		// We're running in --chaindata mode or otherwise cannot get the backend
+		return fmt.Errorf(NotAvailableChainData, method)
+	}
+
+	res, err := api.ethBackend.TxPoolContent()
+	if err != nil {
+		return err
+	}
+	for section, accounts := range map[string][]*remote.AccountTransactions{"pending": res.Pending, "queued": res.Queued} {
+		for _, account := range accounts {
+			txs := make([]*types.Transaction, 0, len(account.Txs))
+			for _, b := range account.Txs {
+				tx := new(types.Transaction)
+				if err = tx.UnmarshalBinary(b); err != nil {
+					return fmt.Errorf("decoding transaction of %x: %w", account.Address, err)
+				}
+				txs = append(txs, tx)
+			}
+			f(section, common.BytesToAddress(account.Address), txs)
+		}
+	}
+	return nil
+}
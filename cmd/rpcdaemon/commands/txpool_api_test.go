@@ -0,0 +1,127 @@
+package commands
+
+import (
+	"context"
+	"testing"
+
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/turbo-geth/common"
+	"github.com/ledgerwatch/turbo-geth/common/hexutil"
+	"github.com/ledgerwatch/turbo-geth/core/types"
+	"github.com/ledgerwatch/turbo-geth/crypto"
+	"github.com/ledgerwatch/turbo-geth/ethdb"
+	"github.com/ledgerwatch/turbo-geth/ethdb/remote"
+	"github.com/ledgerwatch/turbo-geth/rpc"
+)
+
+func TestTxPoolAPI(t *testing.T) {
+	key, _ := crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	sender := crypto.PubkeyToAddress(key.PublicKey)
+	to := common.Address{1}
+	signTx := func(nonce uint64, to *common.Address) *types.Transaction {
+		var tx *types.Transaction
+		if to == nil {
+			tx = types.NewContractCreation(nonce, uint256.NewInt().SetUint64(1), 100000, uint256.NewInt().SetUint64(2), nil)
+		} else {
+			tx = types.NewTransaction(nonce, *to, uint256.NewInt().SetUint64(1), 21000, uint256.NewInt().SetUint64(2), nil)
+		}
+		signed, err := types.SignTx(tx, types.HomesteadSigner{}, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return signed
+	}
+	encode := func(txs ...*types.Transaction) [][]byte {
+		var res [][]byte
+		for _, tx := range txs {
+			b, err := tx.MarshalBinary()
+			if err != nil {
+				t.Fatal(err)
+			}
+			res = append(res, b)
+		}
+		return res
+	}
+	tx0, tx1, tx3 := signTx(0, &to), signTx(1, nil), signTx(3, &to)
+	backend := &testBackend{
+		txPool: &remote.TxPoolContentReply{
+			Pending: []*remote.AccountTransactions{{Address: sender.Bytes(), Txs: encode(tx0, tx1)}},
+			Queued:  []*remote.AccountTransactions{{Address: sender.Bytes(), Txs: encode(tx3)}},
+		},
+		nonces: map[common.Address]*remote.NonceReply{
+			sender: {Found: true, Nonce: 2, Gaps: []*remote.NonceGap{{From: 2, To: 2}}},
+		},
+	}
+	ctx := context.Background()
+	api := NewTxPoolAPI(backend)
+
+	content, err := api.Content(ctx)
+	if err != nil {
+		t.Fatalf("txpool_content: %v", err)
+	}
+	assertJSONEqual(t, content, map[string]map[string]map[string]*RPCTransaction{
+		"pending": {sender.Hex(): {
+			"0": newRPCTransaction(tx0, common.Hash{}, 0, 0),
+			"1": newRPCTransaction(tx1, common.Hash{}, 0, 0),
+		}},
+		"queued": {sender.Hex(): {
+			"3": newRPCTransaction(tx3, common.Hash{}, 0, 0),
+		}},
+	})
+
+	status, err := api.Status(ctx)
+	if err != nil {
+		t.Fatalf("txpool_status: %v", err)
+	}
+	assertJSONEqual(t, status, map[string]hexutil.Uint{"pending": 2, "queued": 1})
+
+	inspect, err := api.Inspect(ctx)
+	if err != nil {
+		t.Fatalf("txpool_inspect: %v", err)
+	}
+	assertJSONEqual(t, inspect, map[string]map[string]map[string]string{
+		"pending": {sender.Hex(): {
+			"0": to.Hex() + ": 1 wei + 21000 gas × 2 wei",
+			"1": "contract creation: 1 wei + 100000 gas × 2 wei",
+		}},
+		"queued": {sender.Hex(): {
+			"3": to.Hex() + ": 1 wei + 21000 gas × 2 wei",
+		}},
+	})
+
+	gaps, err := api.NonceGaps(ctx, sender)
+	if err != nil {
+		t.Fatalf("txpool_nonceGaps: %v", err)
+	}
+	assertJSONEqual(t, gaps, []NonceGap{{From: 2, To: 2}})
+	if _, err = api.NonceGaps(ctx, to); err == nil {
+		t.Errorf("expected error when the pool doesn't know the nonce")
+	}
+
+	// eth_getTransactionCount with the `pending` tag takes the nonce from the pool, and falls back to the latest state
+	db, err := createTestDb()
+	if err != nil {
+		t.Fatalf("create test db: %v", err)
+	}
+	eth := NewEthAPI(db.(ethdb.HasKV).KV(), db, backend, 5000000, nil)
+	pending := rpc.BlockNumberOrHashWithNumber(rpc.PendingBlockNumber)
+	nonce, err := eth.GetTransactionCount(ctx, sender, pending)
+	if err != nil {
+		t.Fatalf("eth_getTransactionCount: %v", err)
+	}
+	if *nonce != 2 {
+		t.Errorf("expected the pending nonce 2, got %d", *nonce)
+	}
+	latest, err := eth.GetTransactionCount(ctx, sender, rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber))
+	if err != nil {
+		t.Fatalf("eth_getTransactionCount: %v", err)
+	}
+	delete(backend.nonces, sender)
+	nonce, err = eth.GetTransactionCount(ctx, sender, pending)
+	if err != nil {
+		t.Fatalf("eth_getTransactionCount: %v", err)
+	}
+	if *nonce != *latest || *latest == 0 {
+		t.Errorf("expected the latest nonce %d, got %d", *latest, *nonce)
+	}
+}
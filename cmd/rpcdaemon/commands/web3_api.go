@@ -6,6 +6,7 @@ import (
 	"github.com/ledgerwatch/turbo-geth/common"
 	"github.com/ledgerwatch/turbo-geth/common/hexutil"
 	"github.com/ledgerwatch/turbo-geth/crypto"
+	"github.com/ledgerwatch/turbo-geth/ethdb"
 	"github.com/ledgerwatch/turbo-geth/params"
 )
 
@@ -17,17 +18,23 @@ type Web3API interface {
 
 type Web3APIImpl struct {
 	*BaseAPI
+	ethBackend ethdb.Backend
 }
 
 // NewWeb3APIImpl returns Web3APIImpl instance
-func NewWeb3APIImpl() *Web3APIImpl {
+func NewWeb3APIImpl(eth ethdb.Backend) *Web3APIImpl {
 	return &Web3APIImpl{
-		BaseAPI: &BaseAPI{},
+		BaseAPI:    &BaseAPI{},
+		ethBackend: eth,
 	}
 }
 
 // ClientVersion implements web3_clientVersion. Returns the current client version.
+// The version of the node is returned if it is available, otherwise the version of the rpcdaemon.
 func (api *Web3APIImpl) ClientVersion(_ context.Context) (string, error) {
+	if api.ethBackend != nil {
+		return api.ethBackend.ClientVersion()
+	}
 	return common.MakeName("TurboGeth", params.VersionWithCommit(gitCommit, "")), nil
 }
 
@@ -7,23 +7,57 @@ import (
 	"sync"
 	"time"
 
+	"github.com/ledgerwatch/turbo-geth/common"
 	"github.com/ledgerwatch/turbo-geth/core/types"
+	"github.com/ledgerwatch/turbo-geth/eth/filters"
 	"github.com/ledgerwatch/turbo-geth/ethdb"
 	"github.com/ledgerwatch/turbo-geth/ethdb/remote"
 	"github.com/ledgerwatch/turbo-geth/ethdb/remote/remotedbserver"
 	"github.com/ledgerwatch/turbo-geth/log"
 )
 
+// filterTimeout is how long a polling filter is kept after its last use
+const filterTimeout = 5 * time.Minute
+
+// SubscriptionBufferSize is the capacity of the channels given to the Subscribe methods. The events are sent
+// without blocking, so a slow subscriber misses the events which don't fit into its channel
+const SubscriptionBufferSize = 256
+
+// FilterType distinguishes polling filters created by eth_newFilter, eth_newBlockFilter and eth_newPendingTransactionFilter
+type FilterType int
+
+const (
+	LogsFilter = FilterType(iota)
+	BlocksFilter
+	PendingTxsFilter
+)
+
+// pollFilter is a filter polled over eth_getFilterChanges
+type pollFilter struct {
+	typ      FilterType
+	deadline time.Time
+	// block and pending tx filters accumulate hashes between polls
+	hashes []common.Hash
+	// log filters remember the criteria and the first block which was not yet returned
+	crit      filters.FilterCriteria
+	nextBlock uint64
+}
+
 type Filters struct {
 	mu sync.RWMutex
 
-	headsSubs map[string]chan *types.Header
+	headsSubs      map[string]chan *types.Header
+	pendingTxsSubs map[string]chan []*types.Transaction
+	logsSubs       map[string]chan []*types.Log
+
+	pollMu      sync.Mutex
+	pollFilters map[string]*pollFilter
 }
 
 func New(ethBackend ethdb.Backend) *Filters {
 	log.Info("rpc filters: subscribing to tg events")
 
-	ff := &Filters{headsSubs: make(map[string]chan *types.Header)}
+	ff := newFilters()
 
 	go func() {
 		var err error
@@ -36,9 +70,20 @@ func New(ethBackend ethdb.Backend) *Filters {
 		}
 	}()
 
+	go ff.timeoutLoop()
+
 	return ff
 }
 
+func newFilters() *Filters {
+	return &Filters{
+		headsSubs:      make(map[string]chan *types.Header),
+		pendingTxsSubs: make(map[string]chan []*types.Transaction),
+		logsSubs:       make(map[string]chan []*types.Log),
+		pollFilters:    make(map[string]*pollFilter),
+	}
+}
+
 func (ff *Filters) SubscribeNewHeads(out chan *types.Header) string {
 	ff.mu.Lock()
 	defer ff.mu.Unlock()
@@ -47,32 +92,233 @@ func (ff *Filters) SubscribeNewHeads(out chan *types.Header) string {
 	return id
 }
 
+func (ff *Filters) SubscribePendingTxs(out chan []*types.Transaction) string {
+	ff.mu.Lock()
+	defer ff.mu.Unlock()
+	id := generateSubscriptionID()
+	ff.pendingTxsSubs[id] = out
+	return id
+}
+
+// SubscribeLogs delivers all new logs, filtering them is up to the subscriber
+func (ff *Filters) SubscribeLogs(out chan []*types.Log) string {
+	ff.mu.Lock()
+	defer ff.mu.Unlock()
+	id := generateSubscriptionID()
+	ff.logsSubs[id] = out
+	return id
+}
+
 func (ff *Filters) Unsubscribe(id string) {
 	ff.mu.Lock()
 	defer ff.mu.Unlock()
 	delete(ff.headsSubs, id)
+	delete(ff.pendingTxsSubs, id)
+	delete(ff.logsSubs, id)
+}
+
+// NewLogFilter installs a polling log filter. nextBlock is the first block whose logs are returned by the first poll
+func (ff *Filters) NewLogFilter(crit filters.FilterCriteria, nextBlock uint64) string {
+	return ff.addPollFilter(&pollFilter{typ: LogsFilter, crit: crit, nextBlock: nextBlock})
+}
+
+// NewBlockFilter installs a polling filter which collects the hashes of new blocks
+func (ff *Filters) NewBlockFilter() string {
+	return ff.addPollFilter(&pollFilter{typ: BlocksFilter})
+}
+
+// NewPendingTxFilter installs a polling filter which collects the hashes of new pending transactions
+func (ff *Filters) NewPendingTxFilter() string {
+	return ff.addPollFilter(&pollFilter{typ: PendingTxsFilter})
+}
+
+func (ff *Filters) addPollFilter(f *pollFilter) string {
+	ff.pollMu.Lock()
+	defer ff.pollMu.Unlock()
+	id := generateSubscriptionID()
+	f.deadline = time.Now().Add(filterTimeout)
+	ff.pollFilters[id] = f
+	return id
+}
+
+// UninstallFilter removes the polling filter, returns false if there was no such filter
+func (ff *Filters) UninstallFilter(id string) bool {
+	ff.pollMu.Lock()
+	defer ff.pollMu.Unlock()
+	_, ok := ff.pollFilters[id]
+	delete(ff.pollFilters, id)
+	return ok
+}
+
+// FilterType returns the type of the polling filter and extends its lifetime
+func (ff *Filters) FilterType(id string) (FilterType, bool) {
+	ff.pollMu.Lock()
+	defer ff.pollMu.Unlock()
+	f, ok := ff.pollFilters[id]
+	if !ok {
+		return 0, false
+	}
+	f.deadline = time.Now().Add(filterTimeout)
+	return f.typ, true
+}
+
+// PopHashes returns the hashes collected by the block or pending tx filter since the previous call
+func (ff *Filters) PopHashes(id string) ([]common.Hash, bool) {
+	ff.pollMu.Lock()
+	defer ff.pollMu.Unlock()
+	f, ok := ff.pollFilters[id]
+	if !ok || f.typ == LogsFilter {
+		return nil, false
+	}
+	hashes := f.hashes
+	f.hashes = nil
+	f.deadline = time.Now().Add(filterTimeout)
+	return hashes, true
+}
+
+// LogFilter returns the criteria of the log filter and the first block which was not yet returned by it
+func (ff *Filters) LogFilter(id string) (filters.FilterCriteria, uint64, bool) {
+	ff.pollMu.Lock()
+	defer ff.pollMu.Unlock()
+	f, ok := ff.pollFilters[id]
+	if !ok || f.typ != LogsFilter {
+		return filters.FilterCriteria{}, 0, false
+	}
+	f.deadline = time.Now().Add(filterTimeout)
+	return f.crit, f.nextBlock, true
+}
+
+// SetLogFilterNextBlock moves the log filter forward after its logs were returned
+func (ff *Filters) SetLogFilterNextBlock(id string, nextBlock uint64) {
+	ff.pollMu.Lock()
+	defer ff.pollMu.Unlock()
+	if f, ok := ff.pollFilters[id]; ok && f.typ == LogsFilter && nextBlock > f.nextBlock {
+		f.nextBlock = nextBlock
+	}
+}
+
+// rewindLogFilters makes the log filters return the logs of the blocks after unwindPoint again
+func (ff *Filters) rewindLogFilters(unwindPoint uint64) {
+	ff.pollMu.Lock()
+	defer ff.pollMu.Unlock()
+	for _, f := range ff.pollFilters {
+		if f.typ == LogsFilter && f.nextBlock > unwindPoint+1 {
+			f.nextBlock = unwindPoint + 1
+		}
+	}
+}
+
+func (ff *Filters) appendHashes(typ FilterType, hashes ...common.Hash) {
+	ff.pollMu.Lock()
+	defer ff.pollMu.Unlock()
+	for _, f := range ff.pollFilters {
+		if f.typ == typ {
+			f.hashes = append(f.hashes, hashes...)
+		}
+	}
+}
+
+// timeoutLoop removes the polling filters which were not used for longer than filterTimeout
+func (ff *Filters) timeoutLoop() {
+	ticker := time.NewTicker(filterTimeout)
+	defer ticker.Stop()
+	for now := range ticker.C {
+		ff.removeExpired(now)
+	}
+}
+
+func (ff *Filters) removeExpired(now time.Time) {
+	ff.pollMu.Lock()
+	defer ff.pollMu.Unlock()
+	for id, f := range ff.pollFilters {
+		if now.After(f.deadline) {
+			delete(ff.pollFilters, id)
+		}
+	}
 }
 
 func (ff *Filters) OnNewEvent(event *remote.SubscribeReply) {
 	ff.mu.RLock()
 	defer ff.mu.RUnlock()
 
-	if remotedbserver.RpcEventType(event.Type) != remotedbserver.EventTypeHeader {
+	var err error
+	switch remotedbserver.RpcEventType(event.Type) {
+	case remotedbserver.EventTypeHeader:
+		err = ff.onNewHeader(event.Data)
+	case remotedbserver.EventTypePendingTxs:
+		err = ff.onNewPendingTxs(event.Data)
+	case remotedbserver.EventTypeLogs:
+		err = ff.onNewLogs(event.Data)
+	case remotedbserver.EventTypeUnwind:
+		err = ff.onUnwind(event.Data)
+	default:
 		log.Warn("rpc filters: unsupported event type", "type", event.Type)
 		return
 	}
-
-	payload := event.Data
-	var header types.Header
-	err := json.Unmarshal(payload, &header)
 	if err != nil {
 		// ignoring what we can't unmarshal
-		log.Warn("rpc filters, unprocessable payload", "err", err)
-	} else {
-		for _, v := range ff.headsSubs {
-			v <- &header
+		log.Warn("rpc filters, unprocessable payload", "type", event.Type, "err", err)
+	}
+}
+
+func (ff *Filters) onNewHeader(payload []byte) error {
+	var header types.Header
+	if err := json.Unmarshal(payload, &header); err != nil {
+		return err
+	}
+	ff.appendHashes(BlocksFilter, header.Hash())
+	for id, v := range ff.headsSubs {
+		select {
+		case v <- &header:
+		default:
+			log.Warn("rpc filters: subscriber is too slow, dropping the header", "id", id)
 		}
 	}
+	return nil
+}
+
+func (ff *Filters) onNewPendingTxs(payload []byte) error {
+	var txs []*types.Transaction
+	if err := json.Unmarshal(payload, &txs); err != nil {
+		return err
+	}
+	hashes := make([]common.Hash, len(txs))
+	for i, tx := range txs {
+		hashes[i] = tx.Hash()
+	}
+	ff.appendHashes(PendingTxsFilter, hashes...)
+	for id, v := range ff.pendingTxsSubs {
+		select {
+		case v <- txs:
+		default:
+			log.Warn("rpc filters: subscriber is too slow, dropping the pending transactions", "id", id)
+		}
+	}
+	return nil
+}
+
+func (ff *Filters) onNewLogs(payload []byte) error {
+	var logs []*types.Log
+	if err := json.Unmarshal(payload, &logs); err != nil {
+		return err
+	}
+	for id, v := range ff.logsSubs {
+		select {
+		case v <- logs:
+		default:
+			log.Warn("rpc filters: subscriber is too slow, dropping the logs", "id", id)
+		}
+	}
+	return nil
+}
+
+func (ff *Filters) onUnwind(payload []byte) error {
+	var header types.Header
+	if err := json.Unmarshal(payload, &header); err != nil {
+		return err
+	}
+	ff.rewindLogFilters(header.Number.Uint64())
+	return nil
 }
 
 func generateSubscriptionID() string {
@@ -0,0 +1,138 @@
+package filters
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/turbo-geth/common"
+	"github.com/ledgerwatch/turbo-geth/core/types"
+	"github.com/ledgerwatch/turbo-geth/eth/filters"
+	"github.com/ledgerwatch/turbo-geth/ethdb/remote"
+	"github.com/ledgerwatch/turbo-geth/ethdb/remote/remotedbserver"
+)
+
+func TestPollFilters(t *testing.T) {
+	ff := newFilters()
+	blocksID := ff.NewBlockFilter()
+	pendingID := ff.NewPendingTxFilter()
+	logsID := ff.NewLogFilter(filters.FilterCriteria{}, 5)
+
+	header := &types.Header{Number: big.NewInt(5), Difficulty: big.NewInt(1)}
+	payload, err := json.Marshal(header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ff.OnNewEvent(&remote.SubscribeReply{Type: uint64(remotedbserver.EventTypeHeader), Data: payload})
+
+	hashes, ok := ff.PopHashes(blocksID)
+	if !ok || len(hashes) != 1 || hashes[0] != header.Hash() {
+		t.Fatalf("unexpected block filter changes: %x", hashes)
+	}
+	if hashes, _ = ff.PopHashes(blocksID); len(hashes) != 0 {
+		t.Fatalf("block filter changes were not reset: %x", hashes)
+	}
+	if hashes, ok = ff.PopHashes(pendingID); !ok || len(hashes) != 0 {
+		t.Fatalf("unexpected pending tx filter changes: %x", hashes)
+	}
+	if _, ok = ff.PopHashes(logsID); ok {
+		t.Fatalf("log filter must not return hashes")
+	}
+
+	ff.SetLogFilterNextBlock(logsID, 7)
+	if _, next, ok := ff.LogFilter(logsID); !ok || next != 7 {
+		t.Fatalf("unexpected next block of the log filter: %d", next)
+	}
+
+	if !ff.UninstallFilter(pendingID) {
+		t.Fatalf("pending tx filter was not installed")
+	}
+	if _, ok = ff.FilterType(pendingID); ok {
+		t.Fatalf("pending tx filter was not uninstalled")
+	}
+
+	ff.removeExpired(time.Now().Add(filterTimeout + time.Second))
+	if _, ok = ff.FilterType(blocksID); ok {
+		t.Fatalf("block filter did not expire")
+	}
+	if _, ok = ff.FilterType(logsID); ok {
+		t.Fatalf("log filter did not expire")
+	}
+}
+
+func TestFilterEvents(t *testing.T) {
+	ff := newFilters()
+	pendingID := ff.NewPendingTxFilter()
+	logsID := ff.NewLogFilter(filters.FilterCriteria{}, 10)
+
+	logsCh := make(chan []*types.Log, 1)
+	ff.SubscribeLogs(logsCh)
+
+	txn := types.NewTransaction(0, common.Address{1}, uint256.NewInt().SetUint64(1), 21000, uint256.NewInt(), nil)
+	payload, err := json.Marshal([]*types.Transaction{txn})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ff.OnNewEvent(&remote.SubscribeReply{Type: uint64(remotedbserver.EventTypePendingTxs), Data: payload})
+	if hashes, _ := ff.PopHashes(pendingID); len(hashes) != 1 || hashes[0] != txn.Hash() {
+		t.Fatalf("unexpected pending tx filter changes: %x", hashes)
+	}
+
+	logs := []*types.Log{{Address: common.Address{2}, Topics: []common.Hash{{3}}, Data: []byte{}, BlockNumber: 9}}
+	if payload, err = json.Marshal(logs); err != nil {
+		t.Fatal(err)
+	}
+	ff.OnNewEvent(&remote.SubscribeReply{Type: uint64(remotedbserver.EventTypeLogs), Data: payload})
+	if received := <-logsCh; len(received) != 1 || received[0].Address != logs[0].Address {
+		t.Fatalf("unexpected logs: %v", received)
+	}
+
+	header := &types.Header{Number: big.NewInt(7), Difficulty: big.NewInt(1)}
+	if payload, err = json.Marshal(header); err != nil {
+		t.Fatal(err)
+	}
+	ff.OnNewEvent(&remote.SubscribeReply{Type: uint64(remotedbserver.EventTypeUnwind), Data: payload})
+	if _, next, _ := ff.LogFilter(logsID); next != 8 {
+		t.Fatalf("log filter was not rewound: %d", next)
+	}
+}
+
+func TestSlowSubscriber(t *testing.T) {
+	ff := newFilters()
+	// nobody reads the channel
+	id := ff.SubscribeNewHeads(make(chan *types.Header))
+
+	payload, err := json.Marshal(&types.Header{Number: big.NewInt(1), Difficulty: big.NewInt(1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ff.OnNewEvent(&remote.SubscribeReply{Type: uint64(remotedbserver.EventTypeHeader), Data: payload})
+		ff.Unsubscribe(id)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the event is blocked by the slow subscriber")
+	}
+}
+
+func TestRemovedLogs(t *testing.T) {
+	ff := newFilters()
+	logsCh := make(chan []*types.Log, 1)
+	ff.SubscribeLogs(logsCh)
+
+	// the logs of the unwound blocks come with the removed flag
+	payload, err := json.Marshal([]*types.Log{{Address: common.Address{2}, Topics: []common.Hash{}, Data: []byte{}, BlockNumber: 9, Removed: true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ff.OnNewEvent(&remote.SubscribeReply{Type: uint64(remotedbserver.EventTypeLogs), Data: payload})
+	if received := <-logsCh; len(received) != 1 || !received[0].Removed {
+		t.Fatalf("unexpected logs: %v", received)
+	}
+}
@@ -0,0 +1,241 @@
+package graphql
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/turbo-geth/common"
+	"github.com/ledgerwatch/turbo-geth/consensus/ethash"
+	"github.com/ledgerwatch/turbo-geth/core"
+	"github.com/ledgerwatch/turbo-geth/core/types"
+	"github.com/ledgerwatch/turbo-geth/core/types/accounts"
+	"github.com/ledgerwatch/turbo-geth/core/vm"
+	"github.com/ledgerwatch/turbo-geth/crypto"
+	"github.com/ledgerwatch/turbo-geth/eth/stagedsync"
+	"github.com/ledgerwatch/turbo-geth/ethdb"
+	"github.com/ledgerwatch/turbo-geth/ethdb/remote"
+	"github.com/ledgerwatch/turbo-geth/params"
+	"github.com/ledgerwatch/turbo-geth/rlp"
+)
+
+var (
+	testKey, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	testAddress = crypto.PubkeyToAddress(testKey.PublicKey)
+	testTo      = common.Address{1}
+)
+
+// createTestDb generates 3 blocks with one value transfer in each of them
+func createTestDb(t *testing.T) (ethdb.Database, []*types.Block) {
+	db := ethdb.NewMemDatabase()
+	gspec := &core.Genesis{
+		Config: params.TestChainConfig,
+		Alloc:  core.GenesisAlloc{testAddress: {Balance: big.NewInt(1000000000000000000)}},
+	}
+	genesis := gspec.MustCommit(db)
+	signer := types.HomesteadSigner{}
+	blocks, _, err := core.GenerateChain(gspec.Config, genesis, ethash.NewFaker(), db, 3, func(i int, block *core.BlockGen) {
+		tx, err := types.SignTx(types.NewTransaction(block.TxNonce(testAddress), testTo, uint256.NewInt().SetUint64(1000), params.TxGas, new(uint256.Int), nil), signer, testKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		block.AddTx(tx)
+	}, false /* intermediateHashes */)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = stagedsync.InsertBlocksInStages(db, ethdb.DefaultStorageMode, gspec.Config, &vm.Config{}, ethash.NewFaker(), blocks, true /* rootCheck */); err != nil {
+		t.Fatal(err)
+	}
+	return db, blocks
+}
+
+func query(t *testing.T, handler http.Handler, q string, result interface{}) {
+	body, _ := json.Marshal(map[string]string{"query": q})
+	req := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(string(body)))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("query %s: status %d", q, rec.Code)
+	}
+	var resp struct {
+		Data   json.RawMessage
+		Errors []struct{ Message string }
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("query %s: %v", q, err)
+	}
+	if len(resp.Errors) > 0 {
+		t.Fatalf("query %s: %v", q, resp.Errors)
+	}
+	if err := json.Unmarshal(resp.Data, result); err != nil {
+		t.Fatalf("query %s: %v", q, err)
+	}
+}
+
+func TestGraphQL(t *testing.T) {
+	db, blocks := createTestDb(t)
+	defer db.Close()
+	handler, err := NewHandler(db.(ethdb.HasKV).KV(), nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var blockResult struct {
+		Block struct {
+			Number       string
+			Hash         common.Hash
+			Parent       struct{ Hash common.Hash }
+			Transactions []struct {
+				Hash   common.Hash
+				From   struct{ Address common.Address }
+				To     struct{ Address common.Address }
+				Status string
+			}
+		}
+	}
+	query(t, handler, `{block(number: 2){number hash parent{hash} transactions{hash from{address} to{address} status}}}`, &blockResult)
+	block := blockResult.Block
+	if block.Number != "0x2" || block.Hash != blocks[1].Hash() || block.Parent.Hash != blocks[0].Hash() {
+		t.Errorf("wrong block: %+v", block)
+	}
+	if len(block.Transactions) != 1 {
+		t.Fatalf("wrong number of transactions: %d", len(block.Transactions))
+	}
+	txn := block.Transactions[0]
+	if txn.Hash != blocks[1].Transactions()[0].Hash() || txn.From.Address != testAddress || txn.To.Address != testTo || txn.Status != "0x1" {
+		t.Errorf("wrong transaction: %+v", txn)
+	}
+
+	var txResult struct {
+		Transaction struct {
+			Block struct{ Number string }
+			Index int
+		}
+	}
+	query(t, handler, fmt.Sprintf(`{transaction(hash: "%s"){block{number} index}}`, txn.Hash.Hex()), &txResult)
+	if txResult.Transaction.Block.Number != "0x2" || txResult.Transaction.Index != 0 {
+		t.Errorf("wrong transaction location: %+v", txResult.Transaction)
+	}
+
+	var accountResult struct {
+		Block struct {
+			Account struct {
+				Balance          string
+				TransactionCount string
+			}
+		}
+	}
+	query(t, handler, fmt.Sprintf(`{block(number: 1){account(address: "%s"){balance transactionCount}}}`, testTo.Hex()), &accountResult)
+	if accountResult.Block.Account.Balance != "0x3e8" || accountResult.Block.Account.TransactionCount != "0x0" {
+		t.Errorf("wrong account at block 1: %+v", accountResult.Block.Account)
+	}
+	query(t, handler, fmt.Sprintf(`{block{account(address: "%s"){balance}}}`, testTo.Hex()), &accountResult)
+	if accountResult.Block.Account.Balance != "0xbb8" {
+		t.Errorf("wrong latest balance: %s", accountResult.Block.Account.Balance)
+	}
+
+	var callResult struct {
+		Block struct {
+			Call        struct{ Status string }
+			EstimateGas string
+		}
+	}
+	call := fmt.Sprintf(`{from: "%s", to: "%s", value: "0x1"}`, testAddress.Hex(), testTo.Hex())
+	query(t, handler, fmt.Sprintf(`{block{call(data: %s){status} estimateGas(data: %s)}}`, call, call), &callResult)
+	if callResult.Block.Call.Status != "0x1" || callResult.Block.EstimateGas != "0x5208" {
+		t.Errorf("wrong call result: %+v", callResult.Block)
+	}
+
+	var blocksResult struct {
+		Blocks []struct{ Number string }
+	}
+	query(t, handler, `{blocks(from: 1){number}}`, &blocksResult)
+	if len(blocksResult.Blocks) != 3 {
+		t.Errorf("wrong number of blocks: %d", len(blocksResult.Blocks))
+	}
+
+	var chainResult struct {
+		ChainID string
+	}
+	query(t, handler, `{chainID}`, &chainResult)
+	if chainResult.ChainID != "0x1" {
+		t.Errorf("wrong chain id: %s", chainResult.ChainID)
+	}
+
+	var missing struct {
+		Block *struct{ Number string }
+	}
+	query(t, handler, `{block(number: 100){number}}`, &missing)
+	if missing.Block != nil {
+		t.Errorf("expected no block, got %+v", missing.Block)
+	}
+}
+
+// pendingBackend returns the pending block, the other methods of ethdb.Backend are not used by graphql
+type pendingBackend struct {
+	ethdb.Backend
+	pending *remote.PendingBlockReply
+}
+
+func (b *pendingBackend) PendingBlock() (*remote.PendingBlockReply, error) {
+	return b.pending, nil
+}
+
+func TestGraphQLPending(t *testing.T) {
+	db, blocks := createTestDb(t)
+	defer db.Close()
+
+	var result struct {
+		Pending struct {
+			TransactionCount int
+			Transactions     []struct{ Hash common.Hash }
+			Account          struct{ Balance string }
+		}
+	}
+	q := fmt.Sprintf(`{pending{transactionCount transactions{hash} account(address: "%s"){balance}}}`, testTo.Hex())
+
+	// without the pending block the pending state is the latest one
+	handler, err := NewHandler(db.(ethdb.HasKV).KV(), nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	query(t, handler, q, &result)
+	if result.Pending.TransactionCount != 0 || len(result.Pending.Transactions) != 0 || result.Pending.Account.Balance != "0xbb8" {
+		t.Errorf("wrong pending state without the pending block: %+v", result.Pending)
+	}
+
+	latest := blocks[len(blocks)-1]
+	tx, err := types.SignTx(types.NewTransaction(3, testTo, uint256.NewInt().SetUint64(1000), params.TxGas, new(uint256.Int), nil), types.HomesteadSigner{}, testKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block := types.NewBlock(&types.Header{ParentHash: latest.Hash(), Number: new(big.Int).Add(latest.Number(), big.NewInt(1))}, []*types.Transaction{tx}, nil, nil)
+	blockRlp, err := rlp.EncodeToBytes(block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	account := accounts.NewAccount()
+	account.Balance.SetUint64(4000)
+	enc := make([]byte, account.EncodingLengthForStorage())
+	account.EncodeForStorage(enc)
+	backend := &pendingBackend{pending: &remote.PendingBlockReply{Found: true, BlockRlp: blockRlp, StateDiff: []*remote.AccountDiff{
+		{Address: testTo.Bytes(), Account: enc},
+	}}}
+	if handler, err = NewHandler(db.(ethdb.HasKV).KV(), backend, 0); err != nil {
+		t.Fatal(err)
+	}
+	query(t, handler, q, &result)
+	if result.Pending.TransactionCount != 1 || len(result.Pending.Transactions) != 1 || result.Pending.Transactions[0].Hash != tx.Hash() {
+		t.Errorf("wrong pending transactions: %+v", result.Pending)
+	}
+	if result.Pending.Account.Balance != "0xfa0" {
+		t.Errorf("wrong pending balance: %s", result.Pending.Account.Balance)
+	}
+}
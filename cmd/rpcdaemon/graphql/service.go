@@ -0,0 +1,36 @@
+package graphql
+
+import (
+	"net/http"
+
+	"github.com/graph-gophers/graphql-go"
+	"github.com/graph-gophers/graphql-go/relay"
+	"github.com/ledgerwatch/turbo-geth/cmd/rpcdaemon/commands"
+	"github.com/ledgerwatch/turbo-geth/ethdb"
+	ethgraphql "github.com/ledgerwatch/turbo-geth/graphql"
+)
+
+// NewHandler returns the handler which answers GraphQL queries on /graphql and serves
+// the interactive query browser on /graphql/ui. The queries are resolved with the same
+// database access as the rpcdaemon commands, so it works both with remote and local database
+func NewHandler(db ethdb.KV, ethBackend ethdb.Backend, gasCap uint64) (http.Handler, error) {
+	dbReader := ethdb.NewObjectDatabase(db)
+	be := &backend{
+		db:         dbReader,
+		ethBackend: ethBackend,
+		api:        commands.NewEthAPI(db, dbReader, ethBackend, gasCap, nil),
+	}
+	q := Resolver{be}
+
+	s, err := graphql.ParseSchema(ethgraphql.Schema, &q)
+	if err != nil {
+		return nil, err
+	}
+	h := be.withQueryTx(&relay.Handler{Schema: s})
+
+	mux := http.NewServeMux()
+	mux.Handle("/graphql/ui", ethgraphql.GraphiQL{})
+	mux.Handle("/graphql", h)
+	mux.Handle("/graphql/", h)
+	return mux, nil
+}
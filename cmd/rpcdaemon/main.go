@@ -1,11 +1,13 @@
 package main
 
 import (
+	"net/http"
 	"os"
 
 	"github.com/ledgerwatch/turbo-geth/cmd/rpcdaemon/cli"
 	"github.com/ledgerwatch/turbo-geth/cmd/rpcdaemon/commands"
 	"github.com/ledgerwatch/turbo-geth/cmd/rpcdaemon/filters"
+	"github.com/ledgerwatch/turbo-geth/cmd/rpcdaemon/graphql"
 	"github.com/ledgerwatch/turbo-geth/cmd/utils"
 	"github.com/ledgerwatch/turbo-geth/common/fdlimit"
 	"github.com/ledgerwatch/turbo-geth/log"
@@ -30,7 +32,21 @@ func main() {
 			log.Info("filters are not supported in chaindata mode")
 		}
 
-		return cli.StartRpcServer(cmd.Context(), *cfg, commands.APIList(db, backend, ff, *cfg, nil))
+		sentry, err := cli.ConnectSentry(cmd.Context(), *cfg)
+		if err != nil {
+			log.Error("Could not connect to sentry", "error", err)
+			return err
+		}
+
+		var graphQLHandler http.Handler
+		if cfg.GraphQLEnabled {
+			if graphQLHandler, err = graphql.NewHandler(db, backend, cfg.Gascap); err != nil {
+				log.Error("Could not create GraphQL handler", "error", err)
+				return err
+			}
+		}
+
+		return cli.StartRpcServer(cmd.Context(), *cfg, commands.APIList(db, backend, sentry, ff, *cfg, nil), graphQLHandler)
 	}
 
 	if err := cmd.ExecuteContext(utils.RootContext()); err != nil {
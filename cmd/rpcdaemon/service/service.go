@@ -3,13 +3,13 @@ package service
 import (
 	"github.com/ledgerwatch/turbo-geth/cmd/rpcdaemon/cli"
 	"github.com/ledgerwatch/turbo-geth/cmd/rpcdaemon/commands"
-	"github.com/ledgerwatch/turbo-geth/core"
+	"github.com/ledgerwatch/turbo-geth/eth/ethbackend"
 	"github.com/ledgerwatch/turbo-geth/ethdb"
 	"github.com/ledgerwatch/turbo-geth/node"
 )
 
-func New(db ethdb.HasKV, ethereum core.Backend, stack *node.Node) {
-	apis := commands.APIList(db.KV(), core.NewEthBackend(ethereum), nil, cli.Flags{API: []string{"eth", "debug"}}, nil)
+func New(db ethdb.HasKV, ethereum ethbackend.Backend, stack *node.Node) {
+	apis := commands.APIList(db.KV(), ethbackend.New(ethereum), nil, nil, cli.Flags{API: []string{"eth", "debug"}}, nil)
 
 	stack.RegisterAPIs(apis)
 }
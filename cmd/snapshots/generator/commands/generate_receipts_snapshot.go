@@ -0,0 +1,137 @@
+package commands
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ledgerwatch/turbo-geth/common"
+	"github.com/ledgerwatch/turbo-geth/common/dbutils"
+	"github.com/ledgerwatch/turbo-geth/core/rawdb"
+	"github.com/ledgerwatch/turbo-geth/ethdb"
+	"github.com/ledgerwatch/turbo-geth/log"
+	"github.com/ledgerwatch/turbo-geth/turbo/snapshotsync"
+)
+
+func init() {
+	withChaindata(generateReceiptsSnapshotCmd)
+	withSnapshotFile(generateReceiptsSnapshotCmd)
+	withSnapshotData(generateReceiptsSnapshotCmd)
+	withBlock(generateReceiptsSnapshotCmd)
+	rootCmd.AddCommand(generateReceiptsSnapshotCmd)
+
+}
+
+var generateReceiptsSnapshotCmd = &cobra.Command{
+	Use:     "receipts",
+	Short:   "Generate receipts snapshot",
+	Example: "go run cmd/snapshots/generator/main.go receipts --block 11000000 --chaindata /media/b00ris/nvme/snapshotsync/tg/chaindata/ --snapshotDir /media/b00ris/nvme/snapshotsync/tg/snapshots/ --snapshotMode \"hb\" --snapshot /media/b00ris/nvme/snapshots/receipts_test",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return ReceiptsSnapshot(cmd.Context(), chaindata, snapshotFile, block, snapshotDir, snapshotMode)
+	},
+}
+
+func ReceiptsSnapshot(ctx context.Context, dbPath, snapshotPath string, toBlock uint64, snapshotDir string, snapshotMode string) error {
+	kv := ethdb.NewLMDB().Path(dbPath).MustOpen()
+	var err error
+	if snapshotDir != "" {
+		var mode snapshotsync.SnapshotMode
+		mode, err = snapshotsync.SnapshotModeFromString(snapshotMode)
+		if err != nil {
+			return err
+		}
+
+		kv, err = snapshotsync.WrapBySnapshotsFromDir(kv, snapshotDir, mode)
+		if err != nil {
+			return err
+		}
+	}
+
+	snKV := ethdb.NewLMDB().WithBucketsConfig(func(defaultBuckets dbutils.BucketsCfg) dbutils.BucketsCfg {
+		return dbutils.BucketsCfg{
+			dbutils.BlockReceiptsPrefix:        dbutils.BucketConfigItem{},
+			dbutils.Log:                        dbutils.BucketConfigItem{},
+			dbutils.ReceiptsSnapshotInfoBucket: dbutils.BucketConfigItem{},
+		}
+	}).Path(snapshotPath).MustOpen()
+
+	db := ethdb.NewObjectDatabase(kv)
+	snDB := ethdb.NewObjectDatabase(snKV)
+
+	t := time.Now()
+	hash, err := rawdb.ReadCanonicalHash(db, toBlock)
+	if err != nil {
+		return fmt.Errorf("getting canonical hash for block %d: %v", toBlock, err)
+	}
+	if hash == (common.Hash{}) {
+		return fmt.Errorf("block %d is not found", toBlock)
+	}
+
+	// receipts are keyed by the block number, logs by the block number and the transaction index
+	for _, bucket := range []string{dbutils.BlockReceiptsPrefix, dbutils.Log} {
+		if err = copyBlocksRange(ctx, db, snDB, bucket, 1, toBlock); err != nil {
+			return err
+		}
+	}
+
+	err = snDB.Put(dbutils.ReceiptsSnapshotInfoBucket, []byte(dbutils.SnapshotReceiptsHeadNumber), big.NewInt(0).SetUint64(toBlock).Bytes())
+	if err != nil {
+		log.Crit("SnapshotReceiptsHeadNumber error", "err", err)
+		return err
+	}
+	err = snDB.Put(dbutils.ReceiptsSnapshotInfoBucket, []byte(dbutils.SnapshotReceiptsHeadHash), hash.Bytes())
+	if err != nil {
+		log.Crit("SnapshotReceiptsHeadHash error", "err", err)
+		return err
+	}
+	snDB.Close()
+	err = os.Remove(snapshotPath + "/lock.mdb")
+	if err != nil {
+		log.Warn("Remove lock", "err", err)
+		return err
+	}
+
+	log.Info("Finished", "duration", time.Since(t))
+	return nil
+}
+
+// copyBlocksRange copies the records of the bucket from db to snDB, which keys start with the block numbers from..to
+func copyBlocksRange(ctx context.Context, db, snDB ethdb.Database, bucket string, from, to uint64) error {
+	chunkFile := 30000
+	tuples := make(ethdb.MultiPutTuples, 0, chunkFile*3+100)
+	err := db.Walk(bucket, dbutils.EncodeBlockNumber(from), 0, func(k, v []byte) (bool, error) {
+		if common.IsCanceled(ctx) {
+			return false, common.ErrStopped
+		}
+		blockNum := binary.BigEndian.Uint64(k[:8])
+		if blockNum > to {
+			return false, nil
+		}
+		tuples = append(tuples, []byte(bucket), common.CopyBytes(k), common.CopyBytes(v))
+		if len(tuples) >= chunkFile {
+			log.Info("Committed", "bucket", bucket, "block", blockNum)
+			if _, err := snDB.MultiPut(tuples...); err != nil {
+				log.Crit("Multiput error", "err", err)
+				return false, err
+			}
+			tuples = tuples[:0]
+		}
+		return true, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(tuples) > 0 {
+		if _, err = snDB.MultiPut(tuples...); err != nil {
+			log.Crit("Multiput error", "err", err)
+			return err
+		}
+	}
+	return nil
+}
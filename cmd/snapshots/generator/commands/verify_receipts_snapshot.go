@@ -0,0 +1,119 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ledgerwatch/lmdb-go/lmdb"
+	"github.com/ledgerwatch/turbo-geth/common"
+	"github.com/ledgerwatch/turbo-geth/common/dbutils"
+	"github.com/ledgerwatch/turbo-geth/core/rawdb"
+	"github.com/ledgerwatch/turbo-geth/ethdb"
+	"github.com/ledgerwatch/turbo-geth/log"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	withChaindata(verifyReceiptsSnapshotCmd)
+	withSnapshotFile(verifyReceiptsSnapshotCmd)
+
+	rootCmd.AddCommand(verifyReceiptsSnapshotCmd)
+}
+
+var verifyReceiptsSnapshotCmd = &cobra.Command{
+	Use:     "verify_receipts",
+	Short:   "Verify receipts snapshot",
+	Example: "go run cmd/snapshots/generator/main.go verify_receipts --snapshot /media/b00ris/nvme/snapshots/receipts/ --chaindata /media/b00ris/nvme/backup/snapshotsync/tg/chaindata/ ",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return VerifyReceiptsSnapshot(cmd.Context(), chaindata, snapshotFile)
+	},
+}
+
+// VerifyReceiptsSnapshot checks that the receipts snapshot is the exact copy of the receipts and logs of the canonical
+// blocks of the database, up to the head of the snapshot
+func VerifyReceiptsSnapshot(ctx context.Context, dbPath, snapshotPath string) error {
+	db, err := ethdb.Open(dbPath, true)
+	if err != nil {
+		return fmt.Errorf("open err: %w", err)
+	}
+	defer db.Close()
+
+	snKV := ethdb.NewLMDB().WithBucketsConfig(func(defaultBuckets dbutils.BucketsCfg) dbutils.BucketsCfg {
+		return dbutils.BucketsCfg{
+			dbutils.BlockReceiptsPrefix:        dbutils.BucketConfigItem{},
+			dbutils.Log:                        dbutils.BucketConfigItem{},
+			dbutils.ReceiptsSnapshotInfoBucket: dbutils.BucketConfigItem{},
+		}
+	}).Path(snapshotPath).Flags(func(flags uint) uint { return flags | lmdb.Readonly }).MustOpen()
+	snDB := ethdb.NewObjectDatabase(snKV)
+	defer snDB.Close()
+
+	headNumberBytes, err := snDB.Get(dbutils.ReceiptsSnapshotInfoBucket, []byte(dbutils.SnapshotReceiptsHeadNumber))
+	if err != nil {
+		return fmt.Errorf("reading snapshot head number: %w", err)
+	}
+	headHashBytes, err := snDB.Get(dbutils.ReceiptsSnapshotInfoBucket, []byte(dbutils.SnapshotReceiptsHeadHash))
+	if err != nil {
+		return fmt.Errorf("reading snapshot head hash: %w", err)
+	}
+	headNumber := big.NewInt(0).SetBytes(headNumberBytes).Uint64()
+	hash, err := rawdb.ReadCanonicalHash(db, headNumber)
+	if err != nil {
+		return err
+	}
+	if hash != common.BytesToHash(headHashBytes) {
+		return fmt.Errorf("snapshot head %d(%x) is not canonical, expected %x", headNumber, headHashBytes, hash)
+	}
+
+	t := time.Now()
+	for _, bucket := range []string{dbutils.BlockReceiptsPrefix, dbutils.Log} {
+		if err = compareBlocksRange(ctx, db, snDB, bucket, 1, headNumber); err != nil {
+			return err
+		}
+	}
+	log.Info("Receipts snapshot is valid", "head", headNumber, "duration", time.Since(t))
+	return nil
+}
+
+// compareBlocksRange checks that the records of the bucket with the block numbers from..to are the same in db and snDB
+func compareBlocksRange(ctx context.Context, db, snDB ethdb.Database, bucket string, from, to uint64) error {
+	var expected uint64
+	err := db.Walk(bucket, dbutils.EncodeBlockNumber(from), 0, func(k, v []byte) (bool, error) {
+		if common.IsCanceled(ctx) {
+			return false, common.ErrStopped
+		}
+		if binary.BigEndian.Uint64(k[:8]) > to {
+			return false, nil
+		}
+		expected++
+		snV, err := snDB.Get(bucket, k)
+		if err != nil && !errors.Is(err, ethdb.ErrKeyNotFound) {
+			return false, err
+		}
+		if !bytes.Equal(v, snV) {
+			return false, fmt.Errorf("%s: mismatch for the key %x", bucket, k)
+		}
+		return true, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	var got uint64
+	err = snDB.Walk(bucket, nil, 0, func(k, v []byte) (bool, error) {
+		got++
+		return true, nil
+	})
+	if err != nil {
+		return err
+	}
+	if got != expected {
+		return fmt.Errorf("%s: expected %d records in the snapshot, got %d", bucket, expected, got)
+	}
+	return nil
+}
@@ -33,7 +33,7 @@ func Seed(ctx context.Context, datadir string) error {
 		cfg.DataDir + "/headers",
 		cfg.DataDir + "/bodies",
 		cfg.DataDir + "/state",
-		//cfg.DataDir+"/receipts",
+		cfg.DataDir + "/receipts",
 	}
 
 	cl, err := torrent.NewClient(cfg)
@@ -40,24 +40,31 @@ func main() {
 	}
 }
 
+// defining a custom stage ID
+var customStageID = stages.SyncStage("ch.torquem.demo.tgcustom.CUSTOM_STAGE")
+
 func syncStages(ctx *cli.Context) stagedsync.StageBuilders {
-	return append(
-		stagedsync.DefaultStages(), // adding all default stages
-		stagedsync.StageBuilder{ // adding our custom stage
-			ID: stages.SyncStage("ch.torquem.demo.tgcustom.CUSTOM_STAGE"),
+	// adding all default stages and our custom stage right after the execution
+	return stagedsync.DefaultStages().MustInsertAfter(stages.Execution,
+		stagedsync.StageBuilder{
+			ID: customStageID,
 			Build: func(world stagedsync.StageParameters) *stagedsync.Stage {
 				return &stagedsync.Stage{
-					ID:          stages.SyncStage("ch.torquem.demo.tgcustom.CUSTOM_STAGE"),
+					ID:          customStageID,
 					Description: "Custom Stage",
 					ExecFunc: func(s *stagedsync.StageState, _ stagedsync.Unwinder) error {
-						fmt.Println("hello from the custom stage", ctx.String(flag.Name))
+						fmt.Println("hello from the custom stage", ctx.String(flag.Name), "tmpdir", world.TmpDir())
 						val, err := world.TX.Get(customBucketName, []byte("test"))
 						fmt.Println("val", string(val), "err", err)
 						if err := world.TX.Put(customBucketName, []byte("test"), []byte(ctx.String(flag.Name))); err != nil {
 							return err
 						}
-						s.Done()
-						return nil
+						// progress of the custom stage is stored the same way as for the default stages
+						executionAt, err := s.ExecutionAt(world.TX)
+						if err != nil {
+							return err
+						}
+						return s.DoneAndUpdate(world.TX, executionAt)
 					},
 					UnwindFunc: func(u *stagedsync.UnwindState, s *stagedsync.StageState) error {
 						fmt.Println("hello from the custom stage unwind", ctx.String(flag.Name))
@@ -68,16 +75,17 @@ func syncStages(ctx *cli.Context) stagedsync.StageBuilders {
 					},
 				}
 			},
-		},
-	)
+		})
 }
 
 // turbo-geth main function
 func runTurboGeth(ctx *cli.Context) {
 	// creating a staged sync with our new stage
+	stageList := syncStages(ctx)
 	sync := stagedsync.New(
-		syncStages(ctx),
-		stagedsync.DefaultUnwindOrder(),
+		stageList,
+		// our stage is unwound first, before the execution it depends on
+		stagedsync.DefaultUnwindOrder().InsertStage(stageList.Index(customStageID)),
 		stagedsync.OptionalParameters{
 			StateReaderBuilder: func(db ethdb.Database) state.StateReader {
 				// put your custom caching code here
@@ -174,6 +174,36 @@ func Truncate(tx ethdb.Tx, from uint64) error {
 	return nil
 }
 
+// Prune deletes the plain changesets of the blocks [from, to)
+func Prune(tx ethdb.Tx, from, to uint64) error {
+	keyStart := dbutils.EncodeBlockNumber(from)
+
+	for _, bucket := range []string{dbutils.PlainAccountChangeSetBucket, dbutils.PlainStorageChangeSetBucket} {
+		if err := pruneBucket(tx, bucket, keyStart, to); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func pruneBucket(tx ethdb.Tx, bucket string, keyStart []byte, to uint64) error {
+	c := tx.CursorDupSort(bucket)
+	defer c.Close()
+	for k, _, err := c.Seek(keyStart); k != nil; k, _, err = c.NextNoDup() {
+		if err != nil {
+			return err
+		}
+		if binary.BigEndian.Uint64(k) >= to {
+			break
+		}
+		err = c.DeleteCurrentDuplicates()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 var Mapper = map[string]struct {
 	IndexBucket   string
 	WalkerAdapter func(cursor ethdb.CursorDupSort) Walker
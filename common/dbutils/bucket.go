@@ -2,6 +2,7 @@ package dbutils
 
 import (
 	"bytes"
+	"fmt"
 	"sort"
 	"strings"
 
@@ -100,11 +101,14 @@ var (
 	IntermediateTrieHashBucketOld1 = "iTh"
 
 	// DatabaseInfoBucket is used to store information about data layout.
-	DatabaseInfoBucket        = "DBINFO"
-	SnapshotInfoBucket        = "SNINFO"
-	HeadersSnapshotInfoBucket = "hSNINFO"
-	BodiesSnapshotInfoBucket  = "bSNINFO"
-	StateSnapshotInfoBucket   = "sSNINFO"
+	DatabaseInfoBucket         = "DBINFO"
+	SnapshotInfoBucket         = "SNINFO"
+	HeadersSnapshotInfoBucket  = "hSNINFO"
+	BodiesSnapshotInfoBucket   = "bSNINFO"
+	StateSnapshotInfoBucket    = "sSNINFO"
+	ReceiptsSnapshotInfoBucket = "rSNINFO"
+	// bucket + 0x00 + key - the keys hidden from the snapshots by ethdb.DeletedValue in the main database
+	SnapshotDeletedKeysBucket = "SNDELETED"
 
 	// databaseVerisionKey tracks the current database version.
 	DatabaseVerisionKey = "DatabaseVersion"
@@ -136,6 +140,15 @@ var (
 	CallFromIndex = "call_from_index"
 	CallToIndex   = "call_to_index"
 
+	// TokenTransferIndex - has the same format as LogAddressIndex
+	// Stores bitmap index - in which block numbers some address sent or received ERC-20/721 tokens (Transfer event)
+	TokenTransferIndex = "token_transfer_index"
+
+	// AccountTxIndex - has the same format as the history indices (roaring64 chunks with 8 bytes suffix)
+	// Stores bitmap index - which transactions sent, received or made internal calls to some address,
+	// each value is blockNumber<<32 | txIndex
+	AccountTxIndex = "account_tx_index"
+
 	TxLookupPrefix  = "l" // txLookupPrefix + hash -> transaction/receipt lookup metadata
 	BloomBitsPrefix = "B" // bloomBitsPrefix + bit (uint16 big endian) + section (uint64 big endian) + hash -> bloom bits
 
@@ -188,13 +201,34 @@ var (
 	StorageModeTxIndex = []byte("smTxIndex")
 	//StorageModeCallTraces - does not build index of call traces
 	StorageModeCallTraces = []byte("smCallTraces")
+	//StorageModeTokenTransfers - does node build index of ERC-20/721 transfers
+	StorageModeTokenTransfers = []byte("smTokenTransfers")
+	//StorageModeAccountTxs - does node build index of transactions by the addresses they touch
+	StorageModeAccountTxs = []byte("smAccountTxs")
+	//StorageModePruneDistance - how many recent blocks of history, receipts and call traces the node keeps (0 - keep everything)
+	StorageModePruneDistance = []byte("smPruneDistance")
+	// PruneIndexCursorPrefix + bucket - the key of the bitmap index from which the next run of the prune stage continues
+	PruneIndexCursorPrefix = "PruneIndexCursor_"
 
 	HeadHeaderKey = "LastHeader"
 
-	SnapshotHeadersHeadNumber = "SnapshotLastHeaderNumber"
-	SnapshotHeadersHeadHash   = "SnapshotLastHeaderHash"
-	SnapshotBodyHeadNumber    = "SnapshotLastBodyNumber"
-	SnapshotBodyHeadHash      = "SnapshotLastBodyHash"
+	SnapshotHeadersHeadNumber  = "SnapshotLastHeaderNumber"
+	SnapshotHeadersHeadHash    = "SnapshotLastHeaderHash"
+	SnapshotBodyHeadNumber     = "SnapshotLastBodyNumber"
+	SnapshotBodyHeadHash       = "SnapshotLastBodyHash"
+	SnapshotReceiptsHeadNumber = "SnapshotLastReceiptsNumber"
+	SnapshotReceiptsHeadHash   = "SnapshotLastReceiptsHash"
+	SnapshotStateHeadNumber    = "SnapshotLastStateNumber"
+	SnapshotStateHeadHash      = "SnapshotLastStateHash"
+
+	// SnapshotProducedBlockPrefix + snapshot type - the block of the last snapshot produced by the node itself
+	SnapshotProducedBlockPrefix = "SnapshotProducedBlock_"
+
+	// CompactionSwappedKey - comma separated list of the compactable buckets whose names are swapped with their
+	// compaction pairs, see CompactionPair
+	CompactionSwappedKey = []byte("CompactionSwapped")
+	// CompactionProgressPrefix + bucket - the last key copied into the compaction pair by the unfinished compaction
+	CompactionProgressPrefix = "CompactionProgress_"
 )
 
 // Metrics
@@ -246,8 +280,12 @@ var Buckets = []string{
 	HeadersSnapshotInfoBucket,
 	BodiesSnapshotInfoBucket,
 	StateSnapshotInfoBucket,
+	ReceiptsSnapshotInfoBucket,
+	SnapshotDeletedKeysBucket,
 	CallFromIndex,
 	CallToIndex,
+	TokenTransferIndex,
+	AccountTxIndex,
 	Log,
 	Sequence,
 	EthTx,
@@ -262,6 +300,24 @@ var DeprecatedBuckets = []string{
 	IntermediateTrieHashBucketOld1,
 }
 
+// CompactableBuckets - buckets which can be compacted online: the bucket is rewritten into its compaction pair,
+// then their names are swapped. Buckets with AutoDupSortKeysConversion are not supported
+var CompactableBuckets = []string{
+	PlainAccountChangeSetBucket,
+	PlainStorageChangeSetBucket,
+	AccountsHistoryBucket,
+	StorageHistoryBucket,
+	LogTopicIndex,
+	LogAddressIndex,
+	CallFromIndex,
+	CallToIndex,
+}
+
+// CompactionPair - the bucket with the same configuration into which the compactable bucket is rewritten
+func CompactionPair(bucket string) string {
+	return bucket + "-compaction"
+}
+
 type CustomComparator string
 
 const (
@@ -370,13 +426,29 @@ func UpdateBucketsList(newBucketCfg BucketsCfg) {
 	reinit()
 }
 
+// RegisterBuckets adds the custom buckets, e.g. the ones used by custom sync stages, to the list of buckets
+// which are created when the database is opened. It has to be called before the database is opened.
+// Overriding the existing buckets is not supported.
+func RegisterBuckets(customBuckets BucketsCfg) error {
+	newBucketsCfg := make(BucketsCfg, len(BucketsConfigs)+len(customBuckets))
+	for k, v := range BucketsConfigs {
+		newBucketsCfg[k] = v
+	}
+	for k, v := range customBuckets {
+		if _, ok := newBucketsCfg[k]; ok {
+			return fmt.Errorf("overriding existing buckets is not supported (bucket key=%s)", k)
+		}
+		newBucketsCfg[k] = v
+	}
+	UpdateBucketsList(newBucketsCfg)
+	return nil
+}
+
 func init() {
 	reinit()
 }
 
 func reinit() {
-	sortBuckets()
-
 	for _, name := range Buckets {
 		_, ok := BucketsConfigs[name]
 		if !ok {
@@ -384,6 +456,21 @@ func reinit() {
 		}
 	}
 
+	for _, name := range CompactableBuckets {
+		cfg, ok := BucketsConfigs[name]
+		if !ok {
+			continue
+		}
+		pair := CompactionPair(name)
+		if _, ok = BucketsConfigs[pair]; ok {
+			continue
+		}
+		BucketsConfigs[pair] = cfg
+		Buckets = append(Buckets, pair)
+	}
+
+	sortBuckets()
+
 	for _, name := range DeprecatedBuckets {
 		_, ok := BucketsConfigs[name]
 		if !ok {
@@ -1,9 +1,15 @@
 package core
 
 import (
+	"context"
+
+	"github.com/holiman/uint256"
 	"github.com/ledgerwatch/turbo-geth/common"
+	"github.com/ledgerwatch/turbo-geth/core/state"
 	"github.com/ledgerwatch/turbo-geth/core/types"
+	"github.com/ledgerwatch/turbo-geth/core/types/accounts"
 	"github.com/ledgerwatch/turbo-geth/ethdb/remote"
+	"github.com/ledgerwatch/turbo-geth/params"
 	"github.com/ledgerwatch/turbo-geth/rlp"
 )
 
@@ -15,6 +21,10 @@ type Backend interface {
 	TxPool() *TxPool
 	Etherbase() (common.Address, error)
 	NetVersion() (uint64, error)
+	NetPeerCount() (uint64, error)
+	ClientVersion() (string, error)
+	ChainConfig() *params.ChainConfig
+	PendingBlockAndState() (*types.Block, *state.IntraBlockState)
 }
 
 func NewEthBackend(eth Backend) *EthBackend {
@@ -23,7 +33,7 @@ func NewEthBackend(eth Backend) *EthBackend {
 
 func (back *EthBackend) AddLocal(signedtx []byte) ([]byte, error) {
 	tx := new(types.Transaction)
-	if err := rlp.DecodeBytes(signedtx, tx); err != nil {
+	if err := tx.UnmarshalBinary(signedtx); err != nil {
 		return common.Hash{}.Bytes(), err
 	}
 
@@ -34,3 +44,117 @@ func (back *EthBackend) Subscribe(func(*remote.SubscribeReply)) error {
 	// do nothing
 	return nil
 }
+
+func (back *EthBackend) TxPoolContent() (*remote.TxPoolContentReply, error) {
+	pending, queued := back.TxPool().Content()
+	reply := &remote.TxPoolContentReply{}
+	var err error
+	if reply.Pending, err = marshalAccountTransactions(pending); err != nil {
+		return nil, err
+	}
+	if reply.Queued, err = marshalAccountTransactions(queued); err != nil {
+		return nil, err
+	}
+	return reply, nil
+}
+
+func marshalAccountTransactions(content map[common.Address]types.Transactions) ([]*remote.AccountTransactions, error) {
+	res := make([]*remote.AccountTransactions, 0, len(content))
+	for addr, txs := range content {
+		account := &remote.AccountTransactions{Address: common.CopyBytes(addr[:]), Txs: make([][]byte, 0, len(txs))}
+		for _, tx := range txs {
+			b, err := tx.MarshalBinary()
+			if err != nil {
+				return nil, err
+			}
+			account.Txs = append(account.Txs, b)
+		}
+		res = append(res, account)
+	}
+	return res, nil
+}
+
+func (back *EthBackend) TxPoolStatus() (*remote.TxPoolStatusReply, error) {
+	pending, queued := back.TxPool().Stats()
+	return &remote.TxPoolStatusReply{Pending: uint64(pending), Queued: uint64(queued)}, nil
+}
+
+// Nonce returns the next nonce of the account known by the transaction pool, and the gaps which block its queued transactions
+func (back *EthBackend) Nonce(address common.Address) (*remote.NonceReply, error) {
+	pool := back.TxPool()
+	if !pool.IsStarted() {
+		return &remote.NonceReply{Found: false}, nil
+	}
+	reply := &remote.NonceReply{Found: true, Nonce: pool.Nonce(address)}
+	for _, gap := range pool.NonceGaps(address) {
+		reply.Gaps = append(reply.Gaps, &remote.NonceGap{From: gap.From, To: gap.To})
+	}
+	return reply, nil
+}
+
+// PendingBlock returns the block which the miner is working on, and the changes it makes on top of the latest state
+func (back *EthBackend) PendingBlock() (*remote.PendingBlockReply, error) {
+	block, ibs := back.PendingBlockAndState()
+	if block == nil || ibs == nil {
+		return &remote.PendingBlockReply{Found: false}, nil
+	}
+	blockRlp, err := rlp.EncodeToBytes(block)
+	if err != nil {
+		return nil, err
+	}
+	// the copy keeps all the objects modified by the block dirty, so committing it writes the whole diff
+	diff := &pendingStateDiff{accounts: map[common.Address]*remote.AccountDiff{}}
+	ctx := back.ChainConfig().WithEIPsFlags(context.Background(), block.Number())
+	if err = ibs.Copy().CommitBlock(ctx, diff); err != nil {
+		return nil, err
+	}
+	return &remote.PendingBlockReply{Found: true, BlockRlp: blockRlp, StateDiff: diff.list}, nil
+}
+
+// pendingStateDiff records the writes of the pending state
+type pendingStateDiff struct {
+	accounts map[common.Address]*remote.AccountDiff
+	list     []*remote.AccountDiff
+}
+
+func (d *pendingStateDiff) account(address common.Address) *remote.AccountDiff {
+	if diff, ok := d.accounts[address]; ok {
+		return diff
+	}
+	diff := &remote.AccountDiff{Address: common.CopyBytes(address[:])}
+	d.accounts[address] = diff
+	d.list = append(d.list, diff)
+	return diff
+}
+
+func (d *pendingStateDiff) UpdateAccountData(_ context.Context, address common.Address, _, account *accounts.Account) error {
+	diff := d.account(address)
+	diff.Deleted = false
+	diff.Account = make([]byte, account.EncodingLengthForStorage())
+	account.EncodeForStorage(diff.Account)
+	return nil
+}
+
+func (d *pendingStateDiff) UpdateAccountCode(address common.Address, _ uint64, _ common.Hash, code []byte) error {
+	d.account(address).Code = common.CopyBytes(code)
+	return nil
+}
+
+func (d *pendingStateDiff) DeleteAccount(_ context.Context, address common.Address, _ *accounts.Account) error {
+	diff := d.account(address)
+	diff.Deleted = true
+	diff.Account, diff.Code, diff.Storage = nil, nil, nil
+	return nil
+}
+
+func (d *pendingStateDiff) WriteAccountStorage(_ context.Context, address common.Address, _ uint64, key *common.Hash, _, value *uint256.Int) error {
+	diff := d.account(address)
+	diff.Storage = append(diff.Storage, &remote.StorageDiff{Location: common.CopyBytes(key[:]), Value: value.Bytes()})
+	return nil
+}
+
+func (d *pendingStateDiff) CreateContract(address common.Address) error {
+	// the storage of the previous incarnation is not visible anymore
+	d.account(address).Storage = nil
+	return nil
+}
@@ -0,0 +1,75 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/turbo-geth/common"
+	"github.com/ledgerwatch/turbo-geth/core/state"
+	"github.com/ledgerwatch/turbo-geth/core/types/accounts"
+	"github.com/ledgerwatch/turbo-geth/ethdb"
+	"github.com/ledgerwatch/turbo-geth/ethdb/remote"
+	"github.com/ledgerwatch/turbo-geth/params"
+)
+
+func TestPendingStateDiff(t *testing.T) {
+	db := ethdb.NewMemDatabase()
+	defer db.Close()
+	ctx := params.AllEthashProtocolChanges.WithEIPsFlags(context.Background(), common.Big1)
+
+	// the account which is destroyed by the pending block
+	destroyed := common.Address{2}
+	ibs := state.New(state.NewPlainStateReader(db))
+	ibs.CreateAccount(destroyed, true)
+	ibs.SetCode(destroyed, []byte{1})
+	ibs.AddBalance(destroyed, uint256.NewInt().SetUint64(1))
+	if err := ibs.CommitBlock(ctx, state.NewPlainStateWriter(db, db, 0)); err != nil {
+		t.Fatal(err)
+	}
+
+	sender, contract := common.Address{1}, common.Address{3}
+	key := common.Hash{4}
+	ibs = state.New(state.NewPlainStateReader(db))
+	ibs.AddBalance(sender, uint256.NewInt().SetUint64(100))
+	ibs.CreateAccount(contract, true)
+	ibs.SetCode(contract, []byte{5, 6})
+	ibs.SetState(contract, &key, *uint256.NewInt().SetUint64(7))
+	ibs.Suicide(destroyed)
+	// the changes of the finalized transactions are a part of the diff too
+	if err := ibs.FinalizeTx(ctx, state.NewNoopWriter()); err != nil {
+		t.Fatal(err)
+	}
+
+	diff := &pendingStateDiff{accounts: map[common.Address]*remote.AccountDiff{}}
+	if err := ibs.Copy().CommitBlock(ctx, diff); err != nil {
+		t.Fatal(err)
+	}
+	if len(diff.list) != 3 {
+		t.Fatalf("expected 3 accounts in the diff, got %d", len(diff.list))
+	}
+	decode := func(address common.Address) *accounts.Account {
+		t.Helper()
+		acc := new(accounts.Account)
+		if err := acc.DecodeForStorage(diff.accounts[address].Account); err != nil {
+			t.Fatal(err)
+		}
+		return acc
+	}
+	if balance := decode(sender).Balance.Uint64(); balance != 100 {
+		t.Errorf("expected the balance 100, got %d", balance)
+	}
+	if acc := decode(contract); acc.Incarnation != state.FirstContractIncarnation {
+		t.Errorf("expected the incarnation %d, got %d", state.FirstContractIncarnation, acc.Incarnation)
+	}
+	if code := diff.accounts[contract].Code; !bytes.Equal(code, []byte{5, 6}) {
+		t.Errorf("expected the code 0506, got %x", code)
+	}
+	if storage := diff.accounts[contract].Storage; len(storage) != 1 || !bytes.Equal(storage[0].Location, key[:]) || !bytes.Equal(storage[0].Value, []byte{7}) {
+		t.Errorf("unexpected storage %v", storage)
+	}
+	if d := diff.accounts[destroyed]; !d.Deleted || d.Account != nil {
+		t.Errorf("expected the account %x to be deleted, got %v", destroyed, d)
+	}
+}
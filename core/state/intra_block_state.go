@@ -929,6 +929,32 @@ func (sdb *IntraBlockState) clearJournalAndRefund() {
 	sdb.refund = 0
 }
 
+// PrepareAccessList handles the preparatory steps for executing a state transition with
+// regards to both EIP-2929 and EIP-2930:
+//
+// - Add sender to access list (2929)
+// - Add destination to access list (2929)
+// - Add precompiles to access list (2929)
+// - Add the contents of the optional tx access list (2930)
+//
+// This method should only be called if Yolov2/Berlin/2929+2930 is applicable at the current number.
+func (sdb *IntraBlockState) PrepareAccessList(sender common.Address, dst *common.Address, precompiles []common.Address, list types.AccessList) {
+	sdb.AddAddressToAccessList(sender)
+	if dst != nil {
+		sdb.AddAddressToAccessList(*dst)
+		// If it's a create-tx, the destination will be added inside evm.create
+	}
+	for _, addr := range precompiles {
+		sdb.AddAddressToAccessList(addr)
+	}
+	for _, el := range list {
+		sdb.AddAddressToAccessList(el.Address)
+		for _, key := range el.StorageKeys {
+			sdb.AddSlotToAccessList(el.Address, key)
+		}
+	}
+}
+
 // AddAddressToAccessList adds the given address to the access list
 func (sdb *IntraBlockState) AddAddressToAccessList(addr common.Address) {
 	if sdb.accessList.AddAddress(addr) {
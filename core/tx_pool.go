@@ -219,6 +219,7 @@ type TxPool struct {
 	mu           sync.RWMutex
 
 	istanbul bool // Fork indicator whether we are in the istanbul stage.
+	eip2718  bool // Fork indicator whether we are using EIP-2718 type transactions.
 
 	pendingNonces *txNoncer              // Pending state tracking virtual nonces
 	currentState  *state.IntraBlockState // Current state in the blockchain head
@@ -260,7 +261,7 @@ func NewTxPool(config TxPoolConfig, chainconfig *params.ChainConfig, chaindb *et
 		config:         config,
 		chainconfig:    chainconfig,
 		chaindb:        chaindb,
-		signer:         types.NewEIP155Signer(chainconfig.ChainID),
+		signer:         types.LatestSigner(chainconfig),
 		pending:        make(map[common.Address]*txList),
 		queue:          make(map[common.Address]*txList),
 		beats:          make(map[common.Address]time.Time),
@@ -388,7 +389,9 @@ func (pool *TxPool) resetHead(blockGasLimit uint64, blockNumber uint64) {
 	pool.currentState = state.New(state.NewPlainStateReader(pool.chaindb))
 	pool.pendingNonces = newTxNoncer(pool.currentState)
 	pool.currentMaxGas = blockGasLimit
-	pool.istanbul = pool.chainconfig.IsIstanbul(big.NewInt(int64(blockNumber + 1)))
+	next := big.NewInt(int64(blockNumber + 1))
+	pool.istanbul = pool.chainconfig.IsIstanbul(next)
+	pool.eip2718 = pool.chainconfig.IsYoloV2(next)
 }
 
 func (pool *TxPool) ResetHead(blockGasLimit uint64, blockNumber uint64) {
@@ -508,6 +511,34 @@ func (pool *TxPool) Pending() (map[common.Address]types.Transactions, error) {
 	return pending, nil
 }
 
+// NonceGap is an inclusive range of the nonces missing before the queued transactions of an account
+type NonceGap struct {
+	From, To uint64
+}
+
+// NonceGaps returns the nonces which are missing between the next nonce of the account and its queued
+// transactions. The queued transactions can't be executed until these gaps are filled.
+func (pool *TxPool) NonceGaps(addr common.Address) []NonceGap {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	list, ok := pool.queue[addr]
+	if !ok {
+		return nil
+	}
+	var gaps []NonceGap
+	next := pool.pendingNonces.get(addr)
+	for _, tx := range list.Flatten() {
+		if nonce := tx.Nonce(); nonce > next {
+			gaps = append(gaps, NonceGap{From: next, To: nonce - 1})
+		}
+		if tx.Nonce() >= next {
+			next = tx.Nonce() + 1
+		}
+	}
+	return gaps
+}
+
 // Locals retrieves the accounts currently considered local by the pool.
 func (pool *TxPool) Locals() []common.Address {
 	pool.mu.Lock()
@@ -535,6 +566,10 @@ func (pool *TxPool) local() map[common.Address]types.Transactions {
 // validateTx checks whether a transaction is valid according to the consensus
 // rules and adheres to some heuristic limits of the local node (price and size).
 func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
+	// Accept only legacy transactions until EIP-2718/2930 activates.
+	if !pool.eip2718 && tx.Type() != types.LegacyTxType {
+		return ErrTxTypeNotSupported
+	}
 	// Reject transactions over defined size to prevent DOS attacks
 	if uint64(tx.Size()) > txMaxSize {
 		return ErrOversizedData
@@ -568,7 +603,7 @@ func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
 		return ErrInsufficientFunds
 	}
 	// Ensure the transaction has more gas than the basic tx fee.
-	intrGas, err := IntrinsicGas(tx.Data(), tx.To() == nil, true, pool.istanbul)
+	intrGas, err := IntrinsicGas(tx.Data(), tx.AccessList(), tx.To() == nil, true, pool.istanbul)
 	if err != nil {
 		return err
 	}
@@ -24,6 +24,7 @@ import (
 	"math/big"
 	"math/rand"
 	"os"
+	"reflect"
 	"runtime"
 	"testing"
 	"time"
@@ -315,6 +316,27 @@ func TestTransactionQueue2(t *testing.T) {
 	}
 }
 
+func TestTransactionNonceGaps(t *testing.T) {
+	pool, key, clear := setupTxPool()
+	defer clear()
+	from := crypto.PubkeyToAddress(key.PublicKey)
+	pool.currentState.AddBalance(from, uint256.NewInt().SetUint64(1000))
+
+	if gaps := pool.NonceGaps(from); len(gaps) != 0 {
+		t.Errorf("expected no gaps without the queued transactions, got %v", gaps)
+	}
+	for _, nonce := range []uint64{0, 3, 4, 7} {
+		tx := transaction(nonce, 100, key)
+		pool.enqueueTx(tx.Hash(), tx)
+	}
+	pool.promoteExecutables([]common.Address{from})
+
+	expected := []NonceGap{{From: 1, To: 2}, {From: 5, To: 6}}
+	if gaps := pool.NonceGaps(from); !reflect.DeepEqual(gaps, expected) {
+		t.Errorf("wrong nonce gaps: have %v, want %v", gaps, expected)
+	}
+}
+
 func TestTransactionChainFork(t *testing.T) {
 	pool, key, clear := setupTxPool()
 	defer clear()
@@ -0,0 +1,76 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"github.com/holiman/uint256"
+
+	"github.com/ledgerwatch/turbo-geth/common"
+)
+
+// LegacyTx is the transaction data of regular Ethereum transactions.
+type LegacyTx struct {
+	Nonce    uint64          // nonce of sender account
+	GasPrice uint256.Int     // wei per gas
+	Gas      uint64          // gas limit
+	To       *common.Address `rlp:"nil"` // nil means contract creation
+	Value    uint256.Int     // wei amount
+	Data     []byte          // contract invocation input data
+	V, R, S  uint256.Int     // signature values
+}
+
+// copy creates a deep copy of the transaction data and initializes all fields.
+func (tx *LegacyTx) copy() TxData {
+	cpy := &LegacyTx{
+		Nonce:    tx.Nonce,
+		GasPrice: tx.GasPrice,
+		Gas:      tx.Gas,
+		To:       tx.To,
+		Value:    tx.Value,
+		Data:     common.CopyBytes(tx.Data),
+		V:        tx.V,
+		R:        tx.R,
+		S:        tx.S,
+	}
+	if tx.To != nil {
+		to := *tx.To
+		cpy.To = &to
+	}
+	return cpy
+}
+
+// accessors for innerTx.
+
+func (tx *LegacyTx) txType() byte              { return LegacyTxType }
+func (tx *LegacyTx) chainID() *uint256.Int     { return deriveChainID(&tx.V) }
+func (tx *LegacyTx) accessList() AccessList    { return nil }
+func (tx *LegacyTx) data() []byte              { return tx.Data }
+func (tx *LegacyTx) gas() uint64               { return tx.Gas }
+func (tx *LegacyTx) gasPrice() *uint256.Int    { return &tx.GasPrice }
+func (tx *LegacyTx) value() *uint256.Int       { return &tx.Value }
+func (tx *LegacyTx) nonce() uint64             { return tx.Nonce }
+func (tx *LegacyTx) to() *common.Address       { return tx.To }
+func (tx *LegacyTx) protected() bool           { return isProtectedV(&tx.V) }
+func (tx *LegacyTx) rawSignatureValues() (v, r, s *uint256.Int) {
+	return &tx.V, &tx.R, &tx.S
+}
+
+func (tx *LegacyTx) setSignatureValues(chainID, v, r, s *uint256.Int) {
+	tx.V.Set(v)
+	tx.R.Set(r)
+	tx.S.Set(s)
+}
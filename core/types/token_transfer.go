@@ -0,0 +1,43 @@
+package types
+
+import (
+	"math/big"
+
+	"github.com/ledgerwatch/turbo-geth/common"
+)
+
+// TransferEventTopic is the topic of the `Transfer(address,address,uint256)` event,
+// which is emitted both by ERC-20 and ERC-721 tokens
+var TransferEventTopic = common.HexToHash("0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef")
+
+// TokenTransfer is a decoded ERC-20 or ERC-721 Transfer event
+type TokenTransfer struct {
+	Token common.Address // address of the token contract
+	From  common.Address
+	To    common.Address
+	// Value is the amount transferred for ERC-20 and the token id for ERC-721
+	Value *big.Int
+	// NonFungible is true for the ERC-721 transfers
+	NonFungible bool
+}
+
+// DecodeTokenTransfer decodes the log if it is an ERC-20 or ERC-721 Transfer event.
+// ERC-20 has the amount in the data, while ERC-721 has the token id as the indexed third topic.
+func DecodeTokenTransfer(l *Log) (*TokenTransfer, bool) {
+	if len(l.Topics) == 0 || l.Topics[0] != TransferEventTopic {
+		return nil, false
+	}
+	transfer := &TokenTransfer{Token: l.Address}
+	switch {
+	case len(l.Topics) == 3 && len(l.Data) == 32:
+		transfer.Value = new(big.Int).SetBytes(l.Data)
+	case len(l.Topics) == 4 && len(l.Data) == 0:
+		transfer.Value = l.Topics[3].Big()
+		transfer.NonFungible = true
+	default:
+		return nil, false
+	}
+	transfer.From = common.BytesToAddress(l.Topics[1][:])
+	transfer.To = common.BytesToAddress(l.Topics[2][:])
+	return transfer, true
+}
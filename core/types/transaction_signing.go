@@ -39,6 +39,8 @@ var (
 func MakeSigner(config *params.ChainConfig, blockNumber *big.Int) Signer {
 	var signer Signer
 	switch {
+	case config.IsYoloV2(blockNumber):
+		signer = NewEIP2930Signer(config.ChainID)
 	case config.IsEIP155(blockNumber):
 		signer = NewEIP155Signer(config.ChainID)
 	case config.IsHomestead(blockNumber):
@@ -49,6 +51,38 @@ func MakeSigner(config *params.ChainConfig, blockNumber *big.Int) Signer {
 	return signer
 }
 
+// LatestSigner returns the 'most permissive' Signer available for the given chain
+// configuration. Specifically, this enables support of EIP-155 replay protection and
+// EIP-2930 access list transactions when their respective forks are scheduled to occur at
+// any block number in the chain config.
+//
+// Use this in transaction-handling code where the current block number is unknown. If you
+// have the current block number available, use MakeSigner instead.
+func LatestSigner(config *params.ChainConfig) Signer {
+	if config.ChainID != nil {
+		if config.YoloV2Block != nil {
+			return NewEIP2930Signer(config.ChainID)
+		}
+		if config.EIP155Block != nil {
+			return NewEIP155Signer(config.ChainID)
+		}
+	}
+	return HomesteadSigner{}
+}
+
+// LatestSignerForChainID returns the 'most permissive' Signer available for the given
+// chain ID. Specifically, this enables support of EIP-155 replay protection and EIP-2930
+// access list transactions.
+//
+// Use this in transaction-handling code where the current block number and fork
+// configuration are unknown. If you have a ChainConfig, use LatestSigner instead.
+func LatestSignerForChainID(chainID *big.Int) Signer {
+	if chainID == nil {
+		return HomesteadSigner{}
+	}
+	return NewEIP2930Signer(chainID)
+}
+
 // SignTx signs the transaction using the given signer and private key
 func SignTx(tx *Transaction, s Signer, prv *ecdsa.PrivateKey) (*Transaction, error) {
 	h := s.Hash(tx)
@@ -97,6 +131,87 @@ type Signer interface {
 	ChainID() *uint256.Int
 }
 
+// EIP2930Signer implements Signer using the EIP-2930 rules: it accepts access list
+// transactions along with the legacy ones, which are handled by the EIP155Signer.
+type EIP2930Signer struct{ EIP155Signer }
+
+// NewEIP2930Signer returns a signer that accepts EIP-2930 access list transactions,
+// EIP-155 replay protected transactions, and legacy Homestead transactions.
+func NewEIP2930Signer(chainId *big.Int) EIP2930Signer {
+	return EIP2930Signer{NewEIP155Signer(chainId)}
+}
+
+func (s EIP2930Signer) Equal(s2 Signer) bool {
+	x, ok := s2.(EIP2930Signer)
+	return ok && x.chainID.Cmp(s.chainID) == 0
+}
+
+func (s EIP2930Signer) Sender(tx *Transaction) (common.Address, error) {
+	return s.SenderWithContext(secp256k1.DefaultContext, tx)
+}
+
+func (s EIP2930Signer) SenderWithContext(context *secp256k1.Context, tx *Transaction) (common.Address, error) {
+	switch tx.Type() {
+	case LegacyTxType:
+		return s.EIP155Signer.SenderWithContext(context, tx)
+	case AccessListTxType:
+		if !tx.inner.chainID().Eq(s.chainID) {
+			return common.Address{}, ErrInvalidChainId
+		}
+		// ACL txs are defined to use 0 and 1 as their recovery id, add
+		// 27 to become equivalent to unprotected Homestead signatures.
+		V, R, S := tx.RawSignatureValues()
+		return recoverPlain(context, s.Hash(tx), R, S, new(uint256.Int).Add(V, u256.Num27), true)
+	default:
+		return common.Address{}, ErrTxTypeNotSupported
+	}
+}
+
+// SignatureValues returns signature values. This signature
+// needs to be in the [R || S || V] format where V is 0 or 1.
+func (s EIP2930Signer) SignatureValues(tx *Transaction, sig []byte) (R, S, V *uint256.Int, err error) {
+	switch txdata := tx.inner.(type) {
+	case *LegacyTx:
+		return s.EIP155Signer.SignatureValues(tx, sig)
+	case *AccessListTx:
+		// Check that chain ID of tx matches the signer. We also accept ID zero here,
+		// because it indicates that the chain ID was not specified in the tx.
+		if txdata.ChainID.Sign() != 0 && !txdata.ChainID.Eq(s.chainID) {
+			return nil, nil, nil, ErrInvalidChainId
+		}
+		R, S, _ = decodeSignature(sig)
+		V = new(uint256.Int).SetUint64(uint64(sig[64]))
+		return R, S, V, nil
+	default:
+		return nil, nil, nil, ErrTxTypeNotSupported
+	}
+}
+
+// Hash returns the hash to be signed by the sender.
+// It does not uniquely identify the transaction.
+func (s EIP2930Signer) Hash(tx *Transaction) common.Hash {
+	switch tx.Type() {
+	case AccessListTxType:
+		return prefixedRlpHash(
+			tx.Type(),
+			[]interface{}{
+				s.chainID,
+				tx.inner.nonce(),
+				tx.inner.gasPrice(),
+				tx.inner.gas(),
+				tx.inner.to(),
+				tx.inner.value(),
+				tx.inner.data(),
+				tx.inner.accessList(),
+			})
+	default:
+		// This _should_ not happen, but in case someone sends in a bad
+		// json struct via RPC, it's probably more prudent to return an
+		// empty hash instead of killing the node with a panic
+		return s.EIP155Signer.Hash(tx)
+	}
+}
+
 // EIP155Transaction implements Signer using the EIP155 rules.
 type EIP155Signer struct {
 	chainID, chainIDMul *uint256.Int
@@ -123,20 +238,27 @@ func (s EIP155Signer) Sender(tx *Transaction) (common.Address, error) {
 }
 
 func (s EIP155Signer) SenderWithContext(context *secp256k1.Context, tx *Transaction) (common.Address, error) {
+	if tx.Type() != LegacyTxType {
+		return common.Address{}, ErrTxTypeNotSupported
+	}
 	if !tx.Protected() {
-		return HomesteadSigner{}.Sender(tx)
+		return HomesteadSigner{}.SenderWithContext(context, tx)
 	}
-	if !tx.ChainID().Eq(s.chainID) {
+	if !tx.inner.chainID().Eq(s.chainID) {
 		return common.Address{}, ErrInvalidChainId
 	}
-	V := new(uint256.Int).Sub(&tx.data.V, s.chainIDMul)
+	V, R, S := tx.RawSignatureValues()
+	V = new(uint256.Int).Sub(V, s.chainIDMul)
 	V.Sub(V, u256.Num8)
-	return recoverPlain(context, s.Hash(tx), &tx.data.R, &tx.data.S, V, true)
+	return recoverPlain(context, s.Hash(tx), R, S, V, true)
 }
 
 // SignatureValues returns signature values. This signature
 // needs to be in the [R || S || V] format where V is 0 or 1.
 func (s EIP155Signer) SignatureValues(tx *Transaction, sig []byte) (R, S, V *uint256.Int, err error) {
+	if tx.Type() != LegacyTxType {
+		return nil, nil, nil, ErrTxTypeNotSupported
+	}
 	R, S, V, err = HomesteadSigner{}.SignatureValues(tx, sig)
 	if err != nil {
 		return nil, nil, nil, err
@@ -152,12 +274,12 @@ func (s EIP155Signer) SignatureValues(tx *Transaction, sig []byte) (R, S, V *uin
 // It does not uniquely identify the transaction.
 func (s EIP155Signer) Hash(tx *Transaction) common.Hash {
 	return rlpHash([]interface{}{
-		tx.data.AccountNonce,
-		tx.data.Price,
-		tx.data.GasLimit,
-		tx.data.Recipient,
-		tx.data.Amount,
-		tx.data.Payload,
+		tx.inner.nonce(),
+		tx.inner.gasPrice(),
+		tx.inner.gas(),
+		tx.inner.to(),
+		tx.inner.value(),
+		tx.inner.data(),
 		s.chainID, uint(0), uint(0),
 	})
 }
@@ -186,7 +308,11 @@ func (hs HomesteadSigner) Sender(tx *Transaction) (common.Address, error) {
 }
 
 func (hs HomesteadSigner) SenderWithContext(context *secp256k1.Context, tx *Transaction) (common.Address, error) {
-	return recoverPlain(context, hs.Hash(tx), &tx.data.R, &tx.data.S, &tx.data.V, true)
+	if tx.Type() != LegacyTxType {
+		return common.Address{}, ErrTxTypeNotSupported
+	}
+	V, R, S := tx.RawSignatureValues()
+	return recoverPlain(context, hs.Hash(tx), R, S, V, true)
 }
 
 type FrontierSigner struct{}
@@ -199,12 +325,10 @@ func (fs FrontierSigner) Equal(s2 Signer) bool {
 // SignatureValues returns signature values. This signature
 // needs to be in the [R || S || V] format where V is 0 or 1.
 func (fs FrontierSigner) SignatureValues(tx *Transaction, sig []byte) (r, s, v *uint256.Int, err error) {
-	if len(sig) != crypto.SignatureLength {
-		panic(fmt.Sprintf("wrong size for signature: got %d, want %d", len(sig), crypto.SignatureLength))
+	if tx.Type() != LegacyTxType {
+		return nil, nil, nil, ErrTxTypeNotSupported
 	}
-	r = new(uint256.Int).SetBytes(sig[:32])
-	s = new(uint256.Int).SetBytes(sig[32:64])
-	v = new(uint256.Int).SetBytes([]byte{sig[64] + 27})
+	r, s, v = decodeSignature(sig)
 	return r, s, v, nil
 }
 
@@ -212,12 +336,12 @@ func (fs FrontierSigner) SignatureValues(tx *Transaction, sig []byte) (r, s, v *
 // It does not uniquely identify the transaction.
 func (fs FrontierSigner) Hash(tx *Transaction) common.Hash {
 	return rlpHash([]interface{}{
-		tx.data.AccountNonce,
-		tx.data.Price,
-		tx.data.GasLimit,
-		tx.data.Recipient,
-		tx.data.Amount,
-		tx.data.Payload,
+		tx.inner.nonce(),
+		tx.inner.gasPrice(),
+		tx.inner.gas(),
+		tx.inner.to(),
+		tx.inner.value(),
+		tx.inner.data(),
 	})
 }
 
@@ -226,13 +350,27 @@ func (fs FrontierSigner) Sender(tx *Transaction) (common.Address, error) {
 }
 
 func (fs FrontierSigner) SenderWithContext(context *secp256k1.Context, tx *Transaction) (common.Address, error) {
-	return recoverPlain(context, fs.Hash(tx), &tx.data.R, &tx.data.S, &tx.data.V, false)
+	if tx.Type() != LegacyTxType {
+		return common.Address{}, ErrTxTypeNotSupported
+	}
+	V, R, S := tx.RawSignatureValues()
+	return recoverPlain(context, fs.Hash(tx), R, S, V, false)
 }
 
 func (fs FrontierSigner) ChainID() *uint256.Int {
 	return u256.Num0
 }
 
+func decodeSignature(sig []byte) (r, s, v *uint256.Int) {
+	if len(sig) != crypto.SignatureLength {
+		panic(fmt.Sprintf("wrong size for signature: got %d, want %d", len(sig), crypto.SignatureLength))
+	}
+	r = new(uint256.Int).SetBytes(sig[:32])
+	s = new(uint256.Int).SetBytes(sig[32:64])
+	v = new(uint256.Int).SetBytes([]byte{sig[64] + 27})
+	return r, s, v
+}
+
 func recoverPlain(context *secp256k1.Context, sighash common.Hash, R, S, Vb *uint256.Int, homestead bool) (common.Address, error) {
 	if Vb.BitLen() > 8 {
 		return common.Address{}, ErrInvalidSig
@@ -20,6 +20,7 @@ import (
 	"bytes"
 	"crypto/ecdsa"
 	"encoding/json"
+	"math/big"
 	"testing"
 	"time"
 
@@ -52,6 +53,23 @@ var (
 		HomesteadSigner{},
 		common.Hex2Bytes("98ff921201554726367d2be8c804a7ff89ccf285ebc57dff8ae4c44b9c19ac4a8887321be575c8095f789dd4c743dfe42c1820f9231f98a962b210e3ac2452a301"),
 	)
+
+	testAddr = common.HexToAddress("b94f5374fce5edbc8e2a8697c15331677e6ebf0b")
+
+	emptyEip2718Tx = NewTx(&AccessListTx{
+		ChainID:  *u256.Num1,
+		Nonce:    3,
+		To:       &testAddr,
+		Value:    *uint256.NewInt().SetUint64(10),
+		Gas:      25000,
+		GasPrice: *u256.Num1,
+		Data:     common.FromHex("5544"),
+	})
+
+	signedEip2718Tx, _ = emptyEip2718Tx.WithSignature(
+		NewEIP2930Signer(big.NewInt(1)),
+		common.Hex2Bytes("c9519f4f2b30335884581971573fadf60c6204f59a911df35ee8a540456b266032f1e8e2c5dd761f9e4f88f41c8310aeaba26a8bfcdacfedfa12ec3862d3752101"),
+	)
 )
 
 func TestTransactionSigHash(t *testing.T) {
@@ -75,6 +93,89 @@ func TestTransactionEncode(t *testing.T) {
 	}
 }
 
+func TestEIP2718TransactionSigHash(t *testing.T) {
+	s := NewEIP2930Signer(big.NewInt(1))
+	if s.Hash(emptyEip2718Tx) != common.HexToHash("49b486f0ec0a60dfbbca2d30cb07c9e8ffb2a2ff41f29a1ab6737475f6ff69f3") {
+		t.Errorf("empty EIP-2718 transaction hash mismatch, got %x", s.Hash(emptyEip2718Tx))
+	}
+	if s.Hash(signedEip2718Tx) != common.HexToHash("49b486f0ec0a60dfbbca2d30cb07c9e8ffb2a2ff41f29a1ab6737475f6ff69f3") {
+		t.Errorf("signed EIP-2718 transaction hash mismatch, got %x", s.Hash(signedEip2718Tx))
+	}
+}
+
+func TestEIP2718TransactionEncode(t *testing.T) {
+	// RLP representation
+	{
+		have, err := rlp.EncodeToBytes(signedEip2718Tx)
+		if err != nil {
+			t.Fatalf("encode error: %v", err)
+		}
+		want := common.FromHex("b86601f8630103018261a894b94f5374fce5edbc8e2a8697c15331677e6ebf0b0a825544c001a0c9519f4f2b30335884581971573fadf60c6204f59a911df35ee8a540456b2660a032f1e8e2c5dd761f9e4f88f41c8310aeaba26a8bfcdacfedfa12ec3862d37521")
+		if !bytes.Equal(have, want) {
+			t.Errorf("encoded RLP mismatch, got %x", have)
+		}
+		var decoded Transaction
+		if err := rlp.DecodeBytes(have, &decoded); err != nil {
+			t.Fatalf("decode error: %v", err)
+		}
+		if decoded.Hash() != signedEip2718Tx.Hash() {
+			t.Errorf("decoded RLP hash mismatch, got %x", decoded.Hash())
+		}
+	}
+	// Binary representation
+	{
+		have, err := signedEip2718Tx.MarshalBinary()
+		if err != nil {
+			t.Fatalf("encode error: %v", err)
+		}
+		want := common.FromHex("01f8630103018261a894b94f5374fce5edbc8e2a8697c15331677e6ebf0b0a825544c001a0c9519f4f2b30335884581971573fadf60c6204f59a911df35ee8a540456b2660a032f1e8e2c5dd761f9e4f88f41c8310aeaba26a8bfcdacfedfa12ec3862d37521")
+		if !bytes.Equal(have, want) {
+			t.Errorf("encoded binary mismatch, got %x", have)
+		}
+		var decoded Transaction
+		if err := decoded.UnmarshalBinary(have); err != nil {
+			t.Fatalf("decode error: %v", err)
+		}
+		if decoded.Type() != AccessListTxType {
+			t.Errorf("wrong decoded type %d", decoded.Type())
+		}
+		if decoded.Hash() != crypto.Keccak256Hash(want) {
+			t.Errorf("decoded binary hash mismatch, got %x", decoded.Hash())
+		}
+	}
+}
+
+func TestEIP2930Signer(t *testing.T) {
+	key, _ := crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	addr := crypto.PubkeyToAddress(key.PublicKey)
+	signer := NewEIP2930Signer(big.NewInt(1))
+	tx := NewTx(&AccessListTx{
+		ChainID:    *u256.Num1,
+		Nonce:      1,
+		To:         &testAddr,
+		Gas:        50000,
+		GasPrice:   *u256.Num1,
+		AccessList: AccessList{{Address: testAddr, StorageKeys: []common.Hash{{0}, {1}}}},
+	})
+	signed, err := SignTx(tx, signer, key)
+	if err != nil {
+		t.Fatalf("could not sign transaction: %v", err)
+	}
+	from, err := Sender(signer, signed)
+	if err != nil {
+		t.Fatalf("could not recover sender: %v", err)
+	}
+	if from != addr {
+		t.Errorf("wrong sender %x, expected %x", from, addr)
+	}
+	if _, err := NewEIP155Signer(big.NewInt(1)).Sender(signed); err != ErrTxTypeNotSupported {
+		t.Errorf("expected %v from EIP155 signer, got %v", ErrTxTypeNotSupported, err)
+	}
+	if _, err := NewEIP2930Signer(big.NewInt(2)).Sender(signed); err == nil {
+		t.Errorf("expected chain id mismatch error")
+	}
+}
+
 func decodeTx(data []byte) (*Transaction, error) {
 	var tx Transaction
 	t, err := &tx, rlp.Decode(bytes.NewReader(data), &tx)
@@ -50,6 +50,7 @@ import (
 	"github.com/ledgerwatch/turbo-geth/core"
 	"github.com/ledgerwatch/turbo-geth/core/bloombits"
 	"github.com/ledgerwatch/turbo-geth/core/rawdb"
+	"github.com/ledgerwatch/turbo-geth/core/state"
 	"github.com/ledgerwatch/turbo-geth/core/types"
 	"github.com/ledgerwatch/turbo-geth/core/vm"
 	"github.com/ledgerwatch/turbo-geth/eth/downloader"
@@ -104,7 +105,9 @@ type Ethereum struct {
 	p2pServer     *p2p.Server
 	txPoolStarted bool
 
-	torrentClient *bittorrent.Client
+	torrentClient    *bittorrent.Client
+	snapshotProducer *snapshotsync.Producer
+	compactor        *ethdb.Compactor
 
 	lock sync.RWMutex // Protects the variadic fields (e.g. gas price and etherbase)
 }
@@ -280,6 +283,27 @@ func New(stack *node.Node, config *Config) (*Ethereum, error) {
 		}
 	}
 
+	var snapshotsDir string
+	if config.SnapshotProducer.Enabled() {
+		if config.SyncMode != downloader.StagedSync {
+			return nil, errors.New("snapshot production requires staged sync")
+		}
+		snapshotsDir, err = stack.Config().ResolvePath("snapshots")
+		if err != nil {
+			return nil, err
+		}
+		if torrentClient == nil {
+			torrentClient, err = bittorrent.New(snapshotsDir, config.SnapshotSeeding)
+			if err != nil {
+				return nil, err
+			}
+		}
+		// the produced snapshots replace the ones of the read path
+		if _, ok := chainDb.KV().(*ethdb.SnapshotKV2); !ok {
+			chainDb.SetKV(ethdb.NewSnapshot2KV().DB(chainDb.KV()).MustOpen())
+		}
+	}
+
 	eth := &Ethereum{
 		config:         config,
 		chainDb:        chainDb,
@@ -314,6 +338,11 @@ func New(stack *node.Node, config *Config) (*Ethereum, error) {
 		}
 	}
 
+	// The blocks can't be unwound below the pruned ones, so the pruned blocks have to be older than any possible reorg
+	if config.StorageMode.PruneDistance != 0 && config.StorageMode.PruneDistance < params.FullImmutabilityThreshold {
+		return nil, fmt.Errorf("prune distance %d is shorter than the max reorg depth %d", config.StorageMode.PruneDistance, params.FullImmutabilityThreshold)
+	}
+
 	err = ethdb.SetStorageModeIfNotExist(chainDb, config.StorageMode)
 	if err != nil {
 		return nil, err
@@ -324,7 +353,21 @@ func New(stack *node.Node, config *Config) (*Ethereum, error) {
 		return nil, err
 	}
 	if !reflect.DeepEqual(sm, config.StorageMode) {
-		return nil, errors.New("mode is " + config.StorageMode.ToString() + " original mode is " + sm.ToString())
+		return nil, fmt.Errorf("mode is %s (prune distance %d) original mode is %s (prune distance %d)",
+			config.StorageMode.ToString(), config.StorageMode.PruneDistance, sm.ToString(), sm.PruneDistance)
+	}
+
+	if config.SnapshotProducer.Enabled() {
+		eth.snapshotProducer, err = snapshotsync.NewProducer(chainDb, snapshotsDir, tmpdir, torrentClient, config.SnapshotProducer)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if config.Compaction.Enabled() {
+		eth.compactor, err = ethdb.NewCompactor(chainDb.KV(), config.Compaction)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	vmConfig, cacheConfig := BlockchainRuntimeConfig(config)
@@ -408,12 +451,12 @@ func New(stack *node.Node, config *Config) (*Ethereum, error) {
 			if err != nil {
 				return nil, err
 			}
-			eth.privateAPI, err = remotedbserver.StartGrpc(chainDb.KV(), eth, stack.Config().PrivateApiAddr, &creds, remoteEvents)
+			eth.privateAPI, err = remotedbserver.StartGrpc(chainDb.KV(), eth, stack.Config().PrivateApiAddr, &creds, remoteEvents, stack.Config().PrivateApiWritableBuckets)
 			if err != nil {
 				return nil, err
 			}
 		} else {
-			eth.privateAPI, err = remotedbserver.StartGrpc(chainDb.KV(), eth, stack.Config().PrivateApiAddr, nil, remoteEvents)
+			eth.privateAPI, err = remotedbserver.StartGrpc(chainDb.KV(), eth, stack.Config().PrivateApiAddr, nil, remoteEvents, stack.Config().PrivateApiWritableBuckets)
 			if err != nil {
 				return nil, err
 			}
@@ -782,6 +825,15 @@ func (s *Ethereum) IsListening() bool                  { return true } // Always
 func (s *Ethereum) EthVersion() int                    { return int(ProtocolVersions[0]) }
 func (s *Ethereum) NetVersion() (uint64, error)        { return s.networkID, nil }
 func (s *Ethereum) Downloader() *downloader.Downloader { return s.protocolManager.downloader }
+func (s *Ethereum) NetPeerCount() (uint64, error)      { return uint64(s.p2pServer.PeerCount()), nil }
+func (s *Ethereum) ClientVersion() (string, error)     { return s.p2pServer.Name, nil }
+func (s *Ethereum) P2PNodeInfo() *p2p.NodeInfo         { return s.p2pServer.NodeInfo() }
+func (s *Ethereum) P2PPeersInfo() []*p2p.PeerInfo      { return s.p2pServer.PeersInfo() }
+func (s *Ethereum) ChainConfig() *params.ChainConfig   { return s.blockchain.Config() }
+func (s *Ethereum) PendingBlockAndState() (*types.Block, *state.IntraBlockState) {
+	block, ibs, _ := s.miner.Pending()
+	return block, ibs
+}
 func (s *Ethereum) SyncProgress() ethereum.SyncProgress {
 	return s.protocolManager.downloader.Progress()
 }
@@ -811,6 +863,15 @@ func (s *Ethereum) Protocols() []p2p.Protocol {
 func (s *Ethereum) Start() error {
 	s.startEthEntryUpdate(s.p2pServer.LocalNode())
 
+	if s.snapshotProducer != nil {
+		if err := s.snapshotProducer.Start(); err != nil {
+			return err
+		}
+	}
+	if s.compactor != nil {
+		s.compactor.Start()
+	}
+
 	// Figure out a max peers count based on the server limits
 	maxPeers := s.p2pServer.MaxPeers
 	withTxPool := s.config.SyncMode != downloader.StagedSync
@@ -870,6 +931,12 @@ func (s *Ethereum) Stop() error {
 	if err := s.StopTxPool(); err != nil {
 		log.Warn("error while stopping transaction pool", "err", err)
 	}
+	if s.snapshotProducer != nil {
+		s.snapshotProducer.Stop()
+	}
+	if s.compactor != nil {
+		s.compactor.Stop()
+	}
 	s.miner.Stop()
 	s.blockchain.Stop()
 	s.engine.Close()
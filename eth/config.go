@@ -132,6 +132,10 @@ type Config struct {
 	BatchSize       datasize.ByteSize // Batch size for execution stage
 	SnapshotMode    snapshotsync.SnapshotMode
 	SnapshotSeeding bool
+	// Snapshots which the node produces by itself from the synced blocks
+	SnapshotProducer snapshotsync.ProducerConfig
+	// Buckets which are compacted in the background to reclaim the free pages of the database
+	Compaction ethdb.CompactionConfig
 
 	// Address to connect to external snapshot downloader
 	// empty if you want to use internal bittorrent snapshot downloader
@@ -605,8 +605,11 @@ func (d *Downloader) syncWithPeer(p *peerConnection, hash common.Hash, blockNumb
 				return nil
 			}
 			log.Info("Commit cycle")
-			_, errCommit := tx.Commit()
-			return errCommit
+			if _, errCommit := tx.Commit(); errCommit != nil {
+				return errCommit
+			}
+			d.stagedSyncState.Committed()
+			return nil
 		})
 
 		err = d.stagedSyncState.Run(d.stateDB, writeDB)
@@ -622,6 +625,7 @@ func (d *Downloader) syncWithPeer(p *peerConnection, hash common.Hash, blockNumb
 			_, errTx := tx.Commit()
 			if errTx == nil {
 				log.Info("Commit cycle", "in", time.Since(commitStart))
+				d.stagedSyncState.Committed()
 			}
 			return errTx
 		}
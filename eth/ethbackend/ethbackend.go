@@ -0,0 +1,112 @@
+// Package ethbackend converts the p2p and sync state of the node to the replies of the ETHBACKEND interface.
+// It's kept out of core, because the state comes from the packages which depend on core.
+package ethbackend
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+
+	ethereum "github.com/ledgerwatch/turbo-geth"
+	"github.com/ledgerwatch/turbo-geth/core"
+	"github.com/ledgerwatch/turbo-geth/eth/stagedsync/stages"
+	"github.com/ledgerwatch/turbo-geth/ethdb"
+	"github.com/ledgerwatch/turbo-geth/ethdb/remote"
+	"github.com/ledgerwatch/turbo-geth/p2p"
+)
+
+// Backend is the node: core.Backend together with its p2p server and the progress of the sync
+type Backend interface {
+	core.Backend
+	P2PNodeInfo() *p2p.NodeInfo
+	P2PPeersInfo() []*p2p.PeerInfo
+	SyncProgress() ethereum.SyncProgress
+	ChainKV() ethdb.KV
+}
+
+// EthBackend implements ethdb.Backend on top of the node in the same process
+type EthBackend struct {
+	*core.EthBackend
+	eth Backend
+}
+
+func New(eth Backend) *EthBackend {
+	return &EthBackend{EthBackend: core.NewEthBackend(eth), eth: eth}
+}
+
+func (back *EthBackend) NodeInfo() (*remote.NodeInfoReply, error) {
+	info := back.eth.P2PNodeInfo()
+	protocols, err := json.Marshal(info.Protocols)
+	if err != nil {
+		return nil, err
+	}
+	return &remote.NodeInfoReply{
+		Id:            info.ID,
+		Name:          info.Name,
+		Enode:         info.Enode,
+		Enr:           info.ENR,
+		Ip:            info.IP,
+		DiscoveryPort: uint32(info.Ports.Discovery),
+		ListenerPort:  uint32(info.Ports.Listener),
+		ListenerAddr:  info.ListenAddr,
+		Protocols:     protocols,
+	}, nil
+}
+
+func (back *EthBackend) Peers() (*remote.PeersReply, error) {
+	peers := back.eth.P2PPeersInfo()
+	reply := &remote.PeersReply{Peers: make([]*remote.PeerInfo, 0, len(peers))}
+	for _, peer := range peers {
+		protocols, err := json.Marshal(peer.Protocols)
+		if err != nil {
+			return nil, err
+		}
+		reply.Peers = append(reply.Peers, &remote.PeerInfo{
+			Id:             peer.ID,
+			Name:           peer.Name,
+			Enode:          peer.Enode,
+			Enr:            peer.ENR,
+			Caps:           peer.Caps,
+			ConnLocalAddr:  peer.Network.LocalAddress,
+			ConnRemoteAddr: peer.Network.RemoteAddress,
+			ConnIsInbound:  peer.Network.Inbound,
+			ConnIsTrusted:  peer.Network.Trusted,
+			ConnIsStatic:   peer.Network.Static,
+			Protocols:      protocols,
+		})
+	}
+	return reply, nil
+}
+
+// SyncStatus combines the progress of the stages with the highest block known by the downloader.
+// The node is syncing while the last stage hasn't reached the highest block.
+func (back *EthBackend) SyncStatus() (*remote.SyncStatusReply, error) {
+	reply := &remote.SyncStatusReply{Stages: make([]*remote.StageProgress, 0, len(stages.AllStages))}
+	// all the stages are read in one transaction, so they don't move while being read
+	if err := back.eth.ChainKV().View(context.Background(), func(tx ethdb.Tx) error {
+		for _, stage := range stages.AllStages {
+			progress, err := stages.GetStageProgressTx(tx, stage)
+			if err != nil {
+				return err
+			}
+			reply.Stages = append(reply.Stages, &remote.StageProgress{Stage: string(stage), BlockNumber: progress})
+			switch {
+			case bytes.Equal(stage, stages.Headers):
+				reply.HighestBlock = progress
+			case bytes.Equal(stage, stages.Finish):
+				reply.CurrentBlock = progress
+			}
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	progress := back.eth.SyncProgress()
+	reply.StartingBlock = progress.StartingBlock
+	if progress.HighestBlock > reply.HighestBlock {
+		reply.HighestBlock = progress.HighestBlock
+	}
+	reply.Syncing = reply.CurrentBlock < reply.HighestBlock
+	return reply, nil
+}
@@ -0,0 +1,48 @@
+package ethbackend
+
+import (
+	"testing"
+
+	ethereum "github.com/ledgerwatch/turbo-geth"
+	"github.com/ledgerwatch/turbo-geth/eth/stagedsync/stages"
+	"github.com/ledgerwatch/turbo-geth/ethdb"
+	"github.com/stretchr/testify/require"
+)
+
+// testBackend provides the sync state, the other methods of Backend are not used by SyncStatus
+type testBackend struct {
+	Backend
+	kv       ethdb.KV
+	progress ethereum.SyncProgress
+}
+
+func (b *testBackend) ChainKV() ethdb.KV                   { return b.kv }
+func (b *testBackend) SyncProgress() ethereum.SyncProgress { return b.progress }
+
+func TestSyncStatus(t *testing.T) {
+	require, db := require.New(t), ethdb.NewMemDatabase()
+	defer db.Close()
+	require.NoError(stages.SaveStageProgress(db, stages.Headers, 10))
+	require.NoError(stages.SaveStageProgress(db, stages.Execution, 7))
+	require.NoError(stages.SaveStageProgress(db, stages.Finish, 5))
+
+	eth := &testBackend{kv: db.KV(), progress: ethereum.SyncProgress{StartingBlock: 1, HighestBlock: 8}}
+	reply, err := New(eth).SyncStatus()
+	require.NoError(err)
+	require.True(reply.Syncing)
+	require.Equal(uint64(1), reply.StartingBlock)
+	require.Equal(uint64(5), reply.CurrentBlock)
+	// the headers are ahead of the downloader
+	require.Equal(uint64(10), reply.HighestBlock)
+	require.Equal(len(stages.AllStages), len(reply.Stages))
+	for _, stage := range reply.Stages {
+		if stage.Stage == string(stages.Execution) {
+			require.Equal(uint64(7), stage.BlockNumber)
+		}
+	}
+
+	require.NoError(stages.SaveStageProgress(db, stages.Finish, 10))
+	reply, err = New(eth).SyncStatus()
+	require.NoError(err)
+	require.False(reply.Syncing)
+}
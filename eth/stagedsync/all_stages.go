@@ -219,6 +219,23 @@ func createStageBuilders(blocks []*types.Block, blockNum uint64, checkRoot bool)
 				}
 			},
 		},
+		{
+			ID: stages.TokenTransfers,
+			Build: func(world StageParameters) *Stage {
+				return &Stage{
+					ID:                  stages.TokenTransfers,
+					Description:         "Generate token transfers index",
+					Disabled:            !world.storageMode.TokenTransfers,
+					DisabledDescription: "Enable by adding `e` to --storage-mode",
+					ExecFunc: func(s *StageState, u Unwinder) error {
+						return SpawnTokenTransfers(s, world.TX, world.tmpdir, world.QuitCh)
+					},
+					UnwindFunc: func(u *UnwindState, s *StageState) error {
+						return UnwindTokenTransfers(u, s, world.TX, world.QuitCh)
+					},
+				}
+			},
+		},
 		{
 			ID: stages.CallTraces,
 			Build: func(world StageParameters) *Stage {
@@ -244,6 +261,31 @@ func createStageBuilders(blocks []*types.Block, blockNum uint64, checkRoot bool)
 				}
 			},
 		},
+		{
+			ID: stages.AccountTxs,
+			Build: func(world StageParameters) *Stage {
+				return &Stage{
+					ID:                  stages.AccountTxs,
+					Description:         "Generate account transactions index",
+					Disabled:            !world.storageMode.AccountTxs,
+					DisabledDescription: "Enable by adding `a` to --storage-mode",
+					ExecFunc: func(s *StageState, u Unwinder) error {
+						return SpawnAccountTxs(s, world.TX, world.chainConfig, world.chainContext, world.tmpdir, world.QuitCh,
+							AccountTxsStageParams{
+								CacheSize: world.cacheSize,
+								BatchSize: world.batchSize,
+							})
+					},
+					UnwindFunc: func(u *UnwindState, s *StageState) error {
+						return UnwindAccountTxs(u, s, world.TX, world.chainConfig, world.chainContext, world.QuitCh,
+							AccountTxsStageParams{
+								CacheSize: world.cacheSize,
+								BatchSize: world.batchSize,
+							})
+					},
+				}
+			},
+		},
 		{
 			ID: stages.TxLookup,
 			Build: func(world StageParameters) *Stage {
@@ -312,7 +354,7 @@ func SetHead(db ethdb.Database, config *params.ChainConfig, vmConfig *vm.Config,
 	cc := &core.TinyChainContext{}
 	cc.SetDB(nil)
 	cc.SetEngine(engine)
-	stagedSync := New(stageBuilders, []int{0, 1, 2, 3, 5, 4, 6, 7, 8, 9, 10, 11}, OptionalParameters{})
+	stagedSync := New(stageBuilders, []int{0, 1, 2, 3, 5, 4, 6, 7, 8, 9, 10, 11, 12, 13}, OptionalParameters{})
 	syncState, err1 := stagedSync.Prepare(
 		nil,
 		config,
@@ -399,7 +441,7 @@ func InsertBlocksInStages(db ethdb.Database, storageMode ethdb.StorageMode, conf
 	cc := &core.TinyChainContext{}
 	cc.SetDB(nil)
 	cc.SetEngine(engine)
-	stagedSync := New(stageBuilders, []int{0, 1, 2, 3, 5, 4, 6, 7, 8, 9, 10, 11}, OptionalParameters{})
+	stagedSync := New(stageBuilders, []int{0, 1, 2, 3, 5, 4, 6, 7, 8, 9, 10, 11, 12, 13}, OptionalParameters{})
 	syncState, err2 := stagedSync.Prepare(
 		nil,
 		config,
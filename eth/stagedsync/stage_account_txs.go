@@ -0,0 +1,351 @@
+package stagedsync
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+	"runtime"
+	"time"
+
+	"github.com/RoaringBitmap/roaring/roaring64"
+	"github.com/c2h5oh/datasize"
+	"github.com/ledgerwatch/turbo-geth/common"
+	"github.com/ledgerwatch/turbo-geth/common/dbutils"
+	"github.com/ledgerwatch/turbo-geth/common/etl"
+	"github.com/ledgerwatch/turbo-geth/consensus/misc"
+	"github.com/ledgerwatch/turbo-geth/core"
+	"github.com/ledgerwatch/turbo-geth/core/rawdb"
+	"github.com/ledgerwatch/turbo-geth/core/state"
+	"github.com/ledgerwatch/turbo-geth/core/vm"
+	"github.com/ledgerwatch/turbo-geth/core/vm/stack"
+	"github.com/ledgerwatch/turbo-geth/ethdb"
+	"github.com/ledgerwatch/turbo-geth/ethdb/bitmapdb"
+	"github.com/ledgerwatch/turbo-geth/log"
+	"github.com/ledgerwatch/turbo-geth/params"
+	"github.com/ledgerwatch/turbo-geth/turbo/shards"
+)
+
+type AccountTxsStageParams struct {
+	ToBlock   uint64 // not setting this params means no limit
+	BatchSize int
+	CacheSize int
+}
+
+// SpawnAccountTxs builds the index of the transactions by the addresses they touch: the sender, the recipient
+// (or the created contract) and the targets of the internal calls. The blocks are re-executed with a tracer
+// to find the internal calls.
+func SpawnAccountTxs(s *StageState, db ethdb.Database, chainConfig *params.ChainConfig, chainContext core.ChainContext, tmpdir string, quit <-chan struct{}, params AccountTxsStageParams) error {
+	var tx ethdb.DbWithPendingMutations
+	var useExternalTx bool
+	if hasTx, ok := db.(ethdb.HasTx); ok && hasTx.Tx() != nil {
+		tx = db.(ethdb.DbWithPendingMutations)
+		useExternalTx = true
+	} else {
+		var err error
+		tx, err = db.Begin(context.Background(), ethdb.RW)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+	}
+
+	endBlock, err := s.ExecutionAt(tx)
+	if params.ToBlock > 0 && params.ToBlock < endBlock {
+		endBlock = params.ToBlock
+	}
+	logPrefix := s.state.LogPrefix()
+	if err != nil {
+		return fmt.Errorf("%s: getting last executed block: %w", logPrefix, err)
+	}
+	if endBlock == s.BlockNumber {
+		s.Done()
+		return nil
+	}
+
+	if err := promoteAccountTxs(logPrefix, tx, s.BlockNumber+1, endBlock, chainConfig, chainContext, bitmapsBufLimit, bitmapsFlushEvery, tmpdir, quit, params); err != nil {
+		return err
+	}
+
+	if err := s.DoneAndUpdate(tx, endBlock); err != nil {
+		return err
+	}
+	if !useExternalTx {
+		if _, err := tx.Commit(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// AccountTxIndexValue encodes the position of the transaction as a value of the AccountTxIndex bitmaps
+func AccountTxIndexValue(blockNum uint64, txIndex int) uint64 {
+	return blockNum<<32 | uint64(txIndex)
+}
+
+func promoteAccountTxs(logPrefix string, tx ethdb.Database, startBlock, endBlock uint64, chainConfig *params.ChainConfig, chainContext core.ChainContext, bufLimit datasize.ByteSize, flushEvery time.Duration, tmpdir string, quit <-chan struct{}, params AccountTxsStageParams) error {
+	logEvery := time.NewTicker(logInterval)
+	defer logEvery.Stop()
+
+	accounts := map[string]*roaring64.Bitmap{}
+	collector := etl.NewCollector(tmpdir, etl.NewSortableBuffer(etl.BufferOptimalSize))
+
+	checkFlushEvery := time.NewTicker(flushEvery)
+	defer checkFlushEvery.Stop()
+
+	var cache = shards.NewStateCache(32, params.CacheSize)
+
+	prev := startBlock
+	for blockNum := startBlock; blockNum <= endBlock; blockNum++ {
+		if err := common.Stopped(quit); err != nil {
+			return err
+		}
+
+		select {
+		default:
+		case <-logEvery.C:
+			var m runtime.MemStats
+			runtime.ReadMemStats(&m)
+			speed := float64(blockNum-prev) / float64(logInterval/time.Second)
+			prev = blockNum
+
+			log.Info(fmt.Sprintf("[%s] Progress", logPrefix), "number", blockNum,
+				"blk/second", speed, "cache writes", common.StorageSize(cache.WriteSize()), "cache read", common.StorageSize(cache.ReadSize()),
+				"alloc", common.StorageSize(m.Alloc),
+				"sys", common.StorageSize(m.Sys),
+				"numGC", int(m.NumGC))
+		case <-checkFlushEvery.C:
+			if needFlush64(accounts, bufLimit) {
+				if err := flushBitmaps64(collector, accounts); err != nil {
+					return fmt.Errorf("[%s] %w", logPrefix, err)
+				}
+
+				accounts = map[string]*roaring64.Bitmap{}
+			}
+		}
+
+		found, err := traceAccountTxs(tx, blockNum, chainConfig, chainContext, cache, params.BatchSize, func(txIndex int, addrs map[common.Address]struct{}) {
+			for addr := range addrs {
+				m, ok := accounts[string(addr[:])]
+				if !ok {
+					m = roaring64.New()
+					a := addr // To copy addr
+					accounts[string(a[:])] = m
+				}
+				m.Add(AccountTxIndexValue(blockNum, txIndex))
+			}
+		})
+		if err != nil {
+			return fmt.Errorf("[%s] %w", logPrefix, err)
+		}
+		if !found {
+			break
+		}
+	}
+
+	if err := flushBitmaps64(collector, accounts); err != nil {
+		return fmt.Errorf("[%s] %w", logPrefix, err)
+	}
+
+	var currentBitmap = roaring64.New()
+	var buf = bytes.NewBuffer(nil)
+
+	lastChunkKey := make([]byte, 128)
+	var loaderFunc = func(k []byte, v []byte, table etl.CurrentTableReader, next etl.LoadNextFunc) error {
+		if _, err := currentBitmap.ReadFrom(bytes.NewReader(v)); err != nil {
+			return err
+		}
+
+		lastChunkKey = lastChunkKey[:len(k)+8]
+		copy(lastChunkKey, k)
+		binary.BigEndian.PutUint64(lastChunkKey[len(k):], ^uint64(0))
+		lastChunkBytes, err := table.Get(lastChunkKey)
+		if err != nil && !errors.Is(err, ethdb.ErrKeyNotFound) {
+			return fmt.Errorf("%s: find last chunk failed: %w", logPrefix, err)
+		}
+		if len(lastChunkBytes) > 0 {
+			lastChunk := roaring64.New()
+			_, err = lastChunk.ReadFrom(bytes.NewReader(lastChunkBytes))
+			if err != nil {
+				return fmt.Errorf("%s: couldn't read last account txs chunk: %w, len(lastChunkBytes)=%d", logPrefix, err, len(lastChunkBytes))
+			}
+
+			currentBitmap.Or(lastChunk) // merge last existing chunk from db - next loop will overwrite it
+		}
+		if err = bitmapdb.WalkChunkWithKeys64(k, currentBitmap, bitmapdb.ChunkLimit, func(chunkKey []byte, chunk *roaring64.Bitmap) error {
+			buf.Reset()
+			if _, err = chunk.WriteTo(buf); err != nil {
+				return err
+			}
+			return next(k, chunkKey, buf.Bytes())
+		}); err != nil {
+			return err
+		}
+		currentBitmap.Clear()
+		return nil
+	}
+
+	if err := collector.Load(logPrefix, tx, dbutils.AccountTxIndex, loaderFunc, etl.TransformArgs{Quit: quit}); err != nil {
+		return fmt.Errorf("[%s] %w", logPrefix, err)
+	}
+	return nil
+}
+
+func UnwindAccountTxs(u *UnwindState, s *StageState, db ethdb.Database, chainConfig *params.ChainConfig, chainContext core.ChainContext, quitCh <-chan struct{}, params AccountTxsStageParams) error {
+	var tx ethdb.DbWithPendingMutations
+	var useExternalTx bool
+	if hasTx, ok := db.(ethdb.HasTx); ok && hasTx.Tx() != nil {
+		tx = db.(ethdb.DbWithPendingMutations)
+		useExternalTx = true
+	} else {
+		var err error
+		tx, err = db.Begin(context.Background(), ethdb.RW)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+	}
+
+	logPrefix := s.state.LogPrefix()
+	if err := unwindAccountTxs(logPrefix, tx, s.BlockNumber, u.UnwindPoint, chainConfig, chainContext, quitCh, params); err != nil {
+		return fmt.Errorf("[%s] %w", logPrefix, err)
+	}
+
+	if err := u.Done(tx); err != nil {
+		return fmt.Errorf("%s: %w", logPrefix, err)
+	}
+
+	if !useExternalTx {
+		if _, err := tx.Commit(); err != nil {
+			return fmt.Errorf("[%s] %w", logPrefix, err)
+		}
+	}
+
+	return nil
+}
+
+func unwindAccountTxs(logPrefix string, db ethdb.Database, from, to uint64, chainConfig *params.ChainConfig, chainContext core.ChainContext, quitCh <-chan struct{}, params AccountTxsStageParams) error {
+	accounts := map[string]struct{}{}
+
+	var cache = shards.NewStateCache(32, params.CacheSize)
+	for blockNum := to + 1; blockNum <= from; blockNum++ {
+		if err := common.Stopped(quitCh); err != nil {
+			return err
+		}
+
+		found, err := traceAccountTxs(db, blockNum, chainConfig, chainContext, cache, params.BatchSize, func(_ int, addrs map[common.Address]struct{}) {
+			for addr := range addrs {
+				a := addr // To copy addr
+				accounts[string(a[:])] = struct{}{}
+			}
+		})
+		if err != nil {
+			return err
+		}
+		if !found {
+			break
+		}
+	}
+
+	// the values of the bitmaps are AccountTxIndexValue, so everything starting from the block to+1 is removed
+	return truncateBitmaps64(db, dbutils.AccountTxIndex, accounts, AccountTxIndexValue(to+1, 0)-1)
+}
+
+// traceAccountTxs re-executes the block on top of the historical state and calls f for each of its transactions
+// with the addresses the transaction touched. Returns false if the block is not found.
+func traceAccountTxs(db ethdb.Database, blockNum uint64, chainConfig *params.ChainConfig, chainContext core.ChainContext, cache *shards.StateCache, batchSize int, f func(txIndex int, addrs map[common.Address]struct{})) (bool, error) {
+	blockHash, err := rawdb.ReadCanonicalHash(db, blockNum)
+	if err != nil {
+		return false, fmt.Errorf("getting canonical blockhash for block %d: %v", blockNum, err)
+	}
+	block := rawdb.ReadBlock(db, blockHash, blockNum)
+	if block == nil {
+		return false, nil
+	}
+	senders := rawdb.ReadSenders(db, blockHash, blockNum)
+	block.Body().SendersToTxs(senders)
+
+	stateReader := state.NewCachedReader(state.NewPlainDBState(db, blockNum-1), cache)
+	stateWriter := state.NewCachedWriter(state.NewNoopWriter(), cache)
+
+	ibs := state.New(stateReader)
+	header := block.Header()
+	usedGas := new(uint64)
+	gp := new(core.GasPool).AddGas(block.GasLimit())
+	if chainConfig.DAOForkSupport && chainConfig.DAOForkBlock != nil && chainConfig.DAOForkBlock.Cmp(block.Number()) == 0 {
+		misc.ApplyDAOHardFork(ibs)
+	}
+
+	tracer := NewAccountTxsTracer()
+	vmConfig := vm.Config{Debug: true, NoReceipts: true, Tracer: tracer}
+	noop := state.NewNoopWriter()
+	for i, txn := range block.Transactions() {
+		tracer.addrs = map[common.Address]struct{}{}
+		if i < len(senders) {
+			tracer.addrs[senders[i]] = struct{}{}
+		}
+		if to := txn.To(); to != nil {
+			tracer.addrs[*to] = struct{}{}
+		}
+		if _, err = core.ApplyTransaction(chainConfig, chainContext, nil, gp, ibs, noop, header, txn, usedGas, vmConfig); err != nil {
+			return false, fmt.Errorf("tx %x failed: %w", txn.Hash(), err)
+		}
+		f(i, tracer.addrs)
+	}
+
+	chainContext.Engine().Finalize(chainConfig, header, ibs, block.Transactions(), block.Uncles())
+	if err = ibs.CommitBlock(chainConfig.WithEIPsFlags(context.Background(), header.Number), stateWriter); err != nil {
+		return false, fmt.Errorf("committing block %d failed: %w", blockNum, err)
+	}
+
+	if cache.WriteSize() >= batchSize {
+		start := time.Now()
+		writes := cache.PrepareWrites()
+		log.Info("PrepareWrites", "in", time.Since(start))
+		start = time.Now()
+		cache.TurnWritesToReads(writes)
+		log.Info("TurnWritesToReads", "in", time.Since(start))
+	}
+	return true, nil
+}
+
+// AccountTxsTracer collects all the addresses touched by the calls, creates and self-destructs of a transaction
+type AccountTxsTracer struct {
+	addrs map[common.Address]struct{}
+}
+
+func NewAccountTxsTracer() *AccountTxsTracer {
+	return &AccountTxsTracer{
+		addrs: make(map[common.Address]struct{}),
+	}
+}
+
+func (at *AccountTxsTracer) CaptureStart(depth int, from common.Address, to common.Address, precompile bool, create bool, calltype vm.CallType, input []byte, gas uint64, value *big.Int) error {
+	at.addrs[from] = struct{}{}
+	if !precompile {
+		at.addrs[to] = struct{}{}
+	}
+	return nil
+}
+func (at *AccountTxsTracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *stack.Stack, _ *stack.ReturnStack, rData []byte, contract *vm.Contract, depth int, err error) error {
+	return nil
+}
+func (at *AccountTxsTracer) CaptureFault(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *stack.Stack, _ *stack.ReturnStack, contract *vm.Contract, depth int, err error) error {
+	return nil
+}
+func (at *AccountTxsTracer) CaptureEnd(depth int, output []byte, gasUsed uint64, t time.Duration, err error) error {
+	return nil
+}
+func (at *AccountTxsTracer) CaptureSelfDestruct(from common.Address, to common.Address, value *big.Int) {
+	at.addrs[from] = struct{}{}
+	at.addrs[to] = struct{}{}
+}
+func (at *AccountTxsTracer) CaptureAccountRead(account common.Address) error {
+	return nil
+}
+func (at *AccountTxsTracer) CaptureAccountWrite(account common.Address) error {
+	return nil
+}
@@ -0,0 +1,111 @@
+package stagedsync
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/turbo-geth/common"
+	"github.com/ledgerwatch/turbo-geth/common/dbutils"
+	"github.com/ledgerwatch/turbo-geth/consensus/ethash"
+	"github.com/ledgerwatch/turbo-geth/core"
+	"github.com/ledgerwatch/turbo-geth/core/rawdb"
+	"github.com/ledgerwatch/turbo-geth/core/types"
+	"github.com/ledgerwatch/turbo-geth/core/vm"
+	"github.com/ledgerwatch/turbo-geth/crypto"
+	"github.com/ledgerwatch/turbo-geth/ethdb"
+	"github.com/ledgerwatch/turbo-geth/ethdb/bitmapdb"
+	"github.com/ledgerwatch/turbo-geth/params"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccountTxs(t *testing.T) {
+	require := require.New(t)
+
+	var (
+		key, _      = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		sender      = crypto.PubkeyToAddress(key.PublicKey)
+		receiver    = common.HexToAddress("0x1000")
+		caller      = common.HexToAddress("0x2000")
+		callee      = common.HexToAddress("0x2001")
+		destructor  = common.HexToAddress("0x3000")
+		beneficiary = common.HexToAddress("0x3001")
+		signer      = types.HomesteadSigner{}
+	)
+	// caller: CALL(gas, callee, 0, 0, 0, 0, 0); destructor: SELFDESTRUCT(beneficiary)
+	callerCode := append(append(common.FromHex("60006000600060006000"+"73"), callee[:]...), 0x5a, 0xf1, 0x00)
+	destructorCode := append(append([]byte{0x73}, beneficiary[:]...), 0xff)
+	gspec := &core.Genesis{
+		Config: params.AllEthashProtocolChanges,
+		Alloc: core.GenesisAlloc{
+			sender:     {Balance: big.NewInt(params.Ether)},
+			caller:     {Balance: new(big.Int), Code: callerCode},
+			destructor: {Balance: big.NewInt(1), Code: destructorCode},
+		},
+	}
+
+	db := ethdb.NewMemDatabase()
+	defer db.Close()
+	_, genesisHash, _, err := core.SetupGenesisBlock(db, gspec, true, false)
+	require.NoError(err)
+	genesis := rawdb.ReadBlock(db, genesisHash, 0)
+	engine := ethash.NewFaker()
+
+	var nonce uint64
+	send := func(block *core.BlockGen, to common.Address, gas uint64) {
+		tx, err1 := types.SignTx(types.NewTransaction(nonce, to, uint256.NewInt().SetUint64(1000), gas, new(uint256.Int), nil), signer, key)
+		require.NoError(err1)
+		block.AddTx(tx)
+		nonce++
+	}
+	blocks, _, err := core.GenerateChain(gspec.Config, genesis, engine, db, 3, func(i int, block *core.BlockGen) {
+		switch i {
+		case 0:
+			send(block, receiver, 21000)
+			send(block, caller, 100000)
+		case 1:
+			send(block, destructor, 100000)
+		case 2:
+			send(block, receiver, 21000)
+		}
+	}, true)
+	require.NoError(err)
+
+	sm := ethdb.DefaultStorageMode
+	sm.AccountTxs = true
+	_, err = InsertBlocksInStages(db, sm, gspec.Config, &vm.Config{}, engine, blocks, true /* rootCheck */)
+	require.NoError(err)
+
+	check := func(db ethdb.Getter, account common.Address, expected ...uint64) {
+		t.Helper()
+		m, err := bitmapdb.Get64(db, dbutils.AccountTxIndex, account[:], 0, ^uint64(0))
+		require.NoError(err)
+		if len(expected) == 0 {
+			require.True(m.IsEmpty(), account.Hex())
+			return
+		}
+		require.Equal(expected, m.ToArray(), account.Hex())
+	}
+	check(db, sender, AccountTxIndexValue(1, 0), AccountTxIndexValue(1, 1), AccountTxIndexValue(2, 0), AccountTxIndexValue(3, 0))
+	check(db, receiver, AccountTxIndexValue(1, 0), AccountTxIndexValue(3, 0))
+	check(db, caller, AccountTxIndexValue(1, 1))
+	check(db, callee, AccountTxIndexValue(1, 1))
+	check(db, destructor, AccountTxIndexValue(2, 0))
+	check(db, beneficiary, AccountTxIndexValue(2, 0))
+
+	// Unwind test
+	tx, err := db.Begin(context.Background(), ethdb.RW)
+	require.NoError(err)
+	defer tx.Rollback()
+	cc := &core.TinyChainContext{}
+	cc.SetDB(nil)
+	cc.SetEngine(engine)
+	err = unwindAccountTxs("logPrefix", tx, 3, 1, gspec.Config, cc, nil, AccountTxsStageParams{BatchSize: 1024, CacheSize: 1024})
+	require.NoError(err)
+	check(tx, sender, AccountTxIndexValue(1, 0), AccountTxIndexValue(1, 1))
+	check(tx, receiver, AccountTxIndexValue(1, 0))
+	check(tx, caller, AccountTxIndexValue(1, 1))
+	check(tx, destructor)
+	check(tx, beneficiary)
+}
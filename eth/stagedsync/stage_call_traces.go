@@ -18,6 +18,7 @@ import (
 	"github.com/ledgerwatch/turbo-geth/core"
 	"github.com/ledgerwatch/turbo-geth/core/rawdb"
 	"github.com/ledgerwatch/turbo-geth/core/state"
+	"github.com/ledgerwatch/turbo-geth/core/types"
 	"github.com/ledgerwatch/turbo-geth/core/vm"
 	"github.com/ledgerwatch/turbo-geth/core/vm/stack"
 	"github.com/ledgerwatch/turbo-geth/ethdb"
@@ -157,6 +158,7 @@ func promoteCallTraces(logPrefix string, tx ethdb.Database, startBlock, endBlock
 		if _, err := core.ExecuteBlockEphemerally(chainConfig, vmConfig, chainContext, engine, block, stateReader, stateWriter); err != nil {
 			return fmt.Errorf("[%s] %w", logPrefix, err)
 		}
+		tracer.captureRewards(chainConfig, block)
 		for addr := range tracer.froms {
 			m, ok := froms[string(addr[:])]
 			if !ok {
@@ -301,6 +303,7 @@ func unwindCallTraces(logPrefix string, db ethdb.Database, from, to uint64, chai
 		if _, err = core.ExecuteBlockEphemerally(chainConfig, vmConfig, chainContext, engine, block, stateReader, stateWriter); err != nil {
 			return fmt.Errorf("exec block: %w", err)
 		}
+		tracer.captureRewards(chainConfig, block)
 		if cache.WriteSize() >= params.BatchSize {
 			start := time.Now()
 			writes := cache.PrepareWrites()
@@ -341,6 +344,8 @@ func NewCallTracer() *CallTracer {
 }
 
 func (ct *CallTracer) CaptureStart(depth int, from common.Address, to common.Address, precompile bool, create bool, calltype vm.CallType, input []byte, gas uint64, value *big.Int) error {
+	ct.froms[from] = struct{}{}
+	ct.tos[to] = struct{}{}
 	return nil
 }
 func (ct *CallTracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *stack.Stack, _ *stack.ReturnStack, rData []byte, contract *vm.Contract, depth int, err error) error {
@@ -354,6 +359,8 @@ func (ct *CallTracer) CaptureEnd(depth int, output []byte, gasUsed uint64, t tim
 	return nil
 }
 func (ct *CallTracer) CaptureSelfDestruct(from common.Address, to common.Address, value *big.Int) {
+	ct.froms[from] = struct{}{}
+	ct.tos[to] = struct{}{}
 }
 func (ct *CallTracer) CaptureAccountRead(account common.Address) error {
 	return nil
@@ -361,3 +368,14 @@ func (ct *CallTracer) CaptureAccountRead(account common.Address) error {
 func (ct *CallTracer) CaptureAccountWrite(account common.Address) error {
 	return nil
 }
+
+// captureRewards indexes the receivers of the block and uncle rewards, so that trace_filter can find the reward traces
+func (ct *CallTracer) captureRewards(chainConfig *params.ChainConfig, block *types.Block) {
+	if chainConfig.Clique != nil {
+		return
+	}
+	ct.tos[block.Coinbase()] = struct{}{}
+	for _, uncle := range block.Uncles() {
+		ct.tos[uncle.Coinbase] = struct{}{}
+	}
+}
@@ -4,10 +4,13 @@ import (
 	"fmt"
 
 	"github.com/ledgerwatch/turbo-geth/core/rawdb"
+	"github.com/ledgerwatch/turbo-geth/core/types"
 	"github.com/ledgerwatch/turbo-geth/ethdb"
 	"github.com/ledgerwatch/turbo-geth/log"
 )
 
+// NotifyRpcDaemon sends the canonical headers of the blocks [from, to] and their logs to the rpc daemon.
+// Logs are only sent if the receipts are stored (`r` in --storage-mode)
 func NotifyRpcDaemon(from, to uint64, notifier ChainEventNotifier, db ethdb.Database) error {
 	if notifier == nil {
 		log.Warn("rpc notifier is not set, rpc daemon won't be updated about headers")
@@ -23,6 +26,65 @@ func NotifyRpcDaemon(from, to uint64, notifier ChainEventNotifier, db ethdb.Data
 			return fmt.Errorf("could not find canonical header for hash: %x number: %d", hash, i)
 		}
 		notifier.OnNewHeader(header)
+
+		var logs []*types.Log
+		for _, receipt := range rawdb.ReadReceipts(db, hash, i) {
+			logs = append(logs, receipt.Logs...)
+		}
+		if len(logs) > 0 {
+			notifier.OnNewLogs(logs)
+		}
+	}
+	return nil
+}
+
+// ReadRemovedLogs reads the logs of the canonical blocks [from, to], which are being unwound, with the removed flag,
+// from the newest block to the oldest. It is called before their receipts are deleted, the logs are sent by
+// NotifyRpcDaemonRemovedLogs once the unwind is committed
+func ReadRemovedLogs(from, to uint64, db ethdb.Database) ([][]*types.Log, error) {
+	var removed [][]*types.Log
+	for i := to; i >= from && i > 0; i-- {
+		hash, err := rawdb.ReadCanonicalHash(db, i)
+		if err != nil {
+			return nil, err
+		}
+		var logs []*types.Log
+		for _, receipt := range rawdb.ReadReceipts(db, hash, i) {
+			for _, l := range receipt.Logs {
+				l.Removed = true
+				logs = append(logs, l)
+			}
+		}
+		if len(logs) > 0 {
+			removed = append(removed, logs)
+		}
+	}
+	return removed, nil
+}
+
+// NotifyRpcDaemonRemovedLogs sends the logs read by ReadRemovedLogs to the rpc daemon, one block at a time
+func NotifyRpcDaemonRemovedLogs(removed [][]*types.Log, notifier ChainEventNotifier) {
+	if notifier == nil {
+		return
+	}
+	for _, logs := range removed {
+		notifier.OnNewLogs(logs)
+	}
+}
+
+// NotifyRpcDaemonUnwind lets the rpc daemon know that the blocks after unwindPoint are not canonical anymore
+func NotifyRpcDaemonUnwind(unwindPoint uint64, notifier ChainEventNotifier, db ethdb.Database) error {
+	if notifier == nil {
+		return nil
+	}
+	hash, err := rawdb.ReadCanonicalHash(db, unwindPoint)
+	if err != nil {
+		return err
+	}
+	header := rawdb.ReadHeader(db, hash, unwindPoint)
+	if header == nil {
+		return fmt.Errorf("could not find canonical header for hash: %x number: %d", hash, unwindPoint)
 	}
+	notifier.OnUnwind(header)
 	return nil
 }
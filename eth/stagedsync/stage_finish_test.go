@@ -0,0 +1,61 @@
+package stagedsync
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/turbo-geth/common"
+	"github.com/ledgerwatch/turbo-geth/core/rawdb"
+	"github.com/ledgerwatch/turbo-geth/core/types"
+	"github.com/ledgerwatch/turbo-geth/ethdb"
+)
+
+type testNotifier struct {
+	logs [][]*types.Log
+}
+
+func (n *testNotifier) OnNewHeader(*types.Header)            {}
+func (n *testNotifier) OnNewPendingTxs([]*types.Transaction) {}
+func (n *testNotifier) OnNewLogs(logs []*types.Log)          { n.logs = append(n.logs, logs) }
+func (n *testNotifier) OnUnwind(*types.Header)               {}
+
+func TestNotifyRpcDaemonRemovedLogs(t *testing.T) {
+	db := ethdb.NewMemDatabase()
+	defer db.Close()
+	for i := uint64(1); i <= 3; i++ {
+		header := &types.Header{Number: big.NewInt(int64(i)), Difficulty: big.NewInt(1)}
+		hash := header.Hash()
+		rawdb.WriteHeader(context.Background(), db, header)
+		if err := rawdb.WriteCanonicalHash(db, hash, i); err != nil {
+			t.Fatal(err)
+		}
+		txn := types.NewTransaction(i, common.Address{1}, uint256.NewInt(), 21000, uint256.NewInt(), nil)
+		if err := rawdb.WriteBody(db, hash, i, &types.Body{Transactions: []*types.Transaction{txn}}); err != nil {
+			t.Fatal(err)
+		}
+		rawdb.WriteSenders(context.Background(), db, hash, i, []common.Address{{2}})
+		receipts := types.Receipts{{Status: types.ReceiptStatusSuccessful, Logs: []*types.Log{{Address: common.Address{byte(i)}, Topics: []common.Hash{}, Data: []byte{}}}}}
+		if err := rawdb.WriteReceipts(db, i, receipts); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	removed, err := ReadRemovedLogs(2, 3, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	notifier := &testNotifier{}
+	NotifyRpcDaemonRemovedLogs(removed, notifier)
+	if len(notifier.logs) != 2 {
+		t.Fatalf("expected the logs of 2 blocks, got %d", len(notifier.logs))
+	}
+	// the newest block goes first
+	for i, blockNum := range []uint64{3, 2} {
+		logs := notifier.logs[i]
+		if len(logs) != 1 || logs[0].BlockNumber != blockNum || logs[0].Address != (common.Address{byte(blockNum)}) || !logs[0].Removed {
+			t.Fatalf("unexpected logs of the block %d: %+v", blockNum, logs)
+		}
+	}
+}
@@ -0,0 +1,260 @@
+package stagedsync
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/RoaringBitmap/roaring"
+	"github.com/ledgerwatch/turbo-geth/common"
+	"github.com/ledgerwatch/turbo-geth/common/changeset"
+	"github.com/ledgerwatch/turbo-geth/common/dbutils"
+	"github.com/ledgerwatch/turbo-geth/common/etl"
+	"github.com/ledgerwatch/turbo-geth/core/rawdb"
+	"github.com/ledgerwatch/turbo-geth/ethdb"
+	"github.com/ledgerwatch/turbo-geth/ethdb/bitmapdb"
+	"github.com/ledgerwatch/turbo-geth/log"
+)
+
+// SpawnPruneStage deletes the changesets, history indexes, receipts and call traces of the blocks
+// which are further than `storageMode.PruneDistance` blocks away from the last executed block.
+// The progress of the stage is the first block which is not pruned yet.
+func SpawnPruneStage(s *StageState, db ethdb.Database, storageMode ethdb.StorageMode, tmpdir string, quitCh <-chan struct{}) error {
+	var tx ethdb.DbWithPendingMutations
+	var useExternalTx bool
+	if hasTx, ok := db.(ethdb.HasTx); ok && hasTx.Tx() != nil {
+		tx = db.(ethdb.DbWithPendingMutations)
+		useExternalTx = true
+	} else {
+		var err error
+		tx, err = db.Begin(context.Background(), ethdb.RW)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+	}
+
+	logPrefix := s.state.LogPrefix()
+	executionAt, err := s.ExecutionAt(tx)
+	if err != nil {
+		return fmt.Errorf("%s: getting last executed block: %w", logPrefix, err)
+	}
+	if storageMode.PruneDistance == 0 || executionAt <= storageMode.PruneDistance {
+		s.Done()
+		return nil
+	}
+	pruneTo := executionAt - storageMode.PruneDistance
+	if pruneTo <= s.BlockNumber {
+		s.Done()
+		return nil
+	}
+
+	log.Info(fmt.Sprintf("[%s] Pruning", logPrefix), "from", s.BlockNumber, "to", pruneTo)
+	for from := s.BlockNumber; from < pruneTo; from += pruneBlocksPerTx {
+		to := from + pruneBlocksPerTx
+		if to > pruneTo {
+			to = pruneTo
+		}
+		if err := pruneBlocks(logPrefix, tx, storageMode, from, to, tmpdir, quitCh); err != nil {
+			return err
+		}
+		if err := s.Update(tx, to); err != nil {
+			return err
+		}
+		if !useExternalTx {
+			if err := tx.CommitAndBegin(context.Background()); err != nil {
+				return err
+			}
+		}
+	}
+	if err := pruneIndexes(logPrefix, tx, storageMode, pruneTo, quitCh); err != nil {
+		return err
+	}
+
+	if err := s.DoneAndUpdate(tx, pruneTo); err != nil {
+		return err
+	}
+	if !useExternalTx {
+		if _, err := tx.Commit(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UnwindPruneStage refuses to unwind below the first block which is not pruned, because the changesets
+// needed to unwind the state of the pruned blocks are deleted. The pruned data isn't restored, so the progress is kept.
+// The Prune stage is the first one to unwind, so the unwind fails before any other stage is touched.
+func UnwindPruneStage(u *UnwindState, s *StageState, db ethdb.Database) error {
+	if u.UnwindPoint+1 < s.BlockNumber {
+		logPrefix := s.state.LogPrefix()
+		log.Error(fmt.Sprintf("[%s] Unwind is below the pruned blocks, the node can't follow this chain and needs to be resynced", logPrefix),
+			"unwindPoint", u.UnwindPoint, "firstNotPruned", s.BlockNumber)
+		return fmt.Errorf("%s: can't unwind to block %d, the blocks before %d are pruned", logPrefix, u.UnwindPoint, s.BlockNumber)
+	}
+	return u.Skip(db)
+}
+
+// PruneBlocks deletes the data of the blocks [from, to) which is kept according to the storage mode.
+// History indexes are pruned before the changesets, because the keys to prune are taken from them.
+func PruneBlocks(logPrefix string, tx ethdb.Database, storageMode ethdb.StorageMode, from, to uint64, tmpdir string, quitCh <-chan struct{}) error {
+	log.Info(fmt.Sprintf("[%s] Pruning", logPrefix), "from", from, "to", to)
+	if err := pruneBlocks(logPrefix, tx, storageMode, from, to, tmpdir, quitCh); err != nil {
+		return err
+	}
+	return pruneIndexes(logPrefix, tx, storageMode, to, quitCh)
+}
+
+// pruneBlocksPerTx - the amount of the blocks whose history, changesets and receipts are pruned in one transaction
+var pruneBlocksPerTx uint64 = 10_000
+
+// pruneBlocks deletes the history, changesets and receipts of the blocks [from, to)
+func pruneBlocks(logPrefix string, tx ethdb.Database, storageMode ethdb.StorageMode, from, to uint64, tmpdir string, quitCh <-chan struct{}) error {
+	if storageMode.History {
+		if err := pruneHistory(logPrefix, tx, dbutils.PlainAccountChangeSetBucket, from, to, tmpdir, quitCh); err != nil {
+			return fmt.Errorf("[%s] prune account history: %w", logPrefix, err)
+		}
+		if err := pruneHistory(logPrefix, tx, dbutils.PlainStorageChangeSetBucket, from, to, tmpdir, quitCh); err != nil {
+			return fmt.Errorf("[%s] prune storage history: %w", logPrefix, err)
+		}
+	}
+	if err := changeset.Prune(tx.(ethdb.HasTx).Tx(), from, to); err != nil {
+		return fmt.Errorf("[%s] prune changesets: %w", logPrefix, err)
+	}
+	if storageMode.Receipts {
+		if err := rawdb.DeleteOlderReceipts(tx, from, to); err != nil {
+			return fmt.Errorf("[%s] prune receipts: %w", logPrefix, err)
+		}
+	}
+	return nil
+}
+
+// pruneIndexes prunes the blocks before `to` from the bitmap indexes which can't be pruned by the keys
+// of the pruned blocks, see pruneBitmapIndex
+func pruneIndexes(logPrefix string, tx ethdb.Database, storageMode ethdb.StorageMode, to uint64, quitCh <-chan struct{}) error {
+	if storageMode.CallTraces {
+		if err := pruneBitmapIndex(logPrefix, tx, dbutils.CallFromIndex, to, quitCh); err != nil {
+			return fmt.Errorf("[%s] prune call traces: %w", logPrefix, err)
+		}
+		if err := pruneBitmapIndex(logPrefix, tx, dbutils.CallToIndex, to, quitCh); err != nil {
+			return fmt.Errorf("[%s] prune call traces: %w", logPrefix, err)
+		}
+	}
+	if storageMode.TokenTransfers {
+		if err := pruneBitmapIndex(logPrefix, tx, dbutils.TokenTransferIndex, to, quitCh); err != nil {
+			return fmt.Errorf("[%s] prune token transfers: %w", logPrefix, err)
+		}
+	}
+	return nil
+}
+
+// pruneHistory prunes the history index of the keys changed in the blocks [from, to). The keys are collected
+// by the ETL collector, because the range may hold a big part of the state
+func pruneHistory(logPrefix string, db ethdb.Database, csBucket string, from, to uint64, tmpdir string, quitCh <-chan struct{}) error {
+	logEvery := time.NewTicker(30 * time.Second)
+	defer logEvery.Stop()
+
+	collector := etl.NewCollector(tmpdir, etl.NewOldestEntryBuffer(etl.BufferOptimalSize))
+	if err := changeset.Walk(db, csBucket, dbutils.EncodeBlockNumber(from), 0, func(blockN uint64, k, v []byte) (bool, error) {
+		if blockN >= to {
+			return false, nil
+		}
+		if err := common.Stopped(quitCh); err != nil {
+			return false, err
+		}
+		select {
+		default:
+		case <-logEvery.C:
+			log.Info(fmt.Sprintf("[%s] Progress", logPrefix), "bucket", csBucket, "number", blockN)
+		}
+		if err := collector.Collect(dbutils.CompositeKeyWithoutIncarnation(k), nil); err != nil {
+			return false, err
+		}
+		return true, nil
+	}); err != nil {
+		return err
+	}
+
+	indexBucket := changeset.Mapper[csBucket].IndexBucket
+	var prevKey []byte
+	return collector.Load(logPrefix, db, "", func(k []byte, _ []byte, _ etl.CurrentTableReader, _ etl.LoadNextFunc) error {
+		// the same key may come from the different files of the collector
+		if bytes.Equal(k, prevKey) {
+			return nil
+		}
+		prevKey = common.CopyBytes(k)
+		if err := bitmapdb.PruneRange64(db, indexBucket, k, to); err != nil {
+			return fmt.Errorf("fail PruneRange: bucket=%s, %w", indexBucket, err)
+		}
+		return nil
+	}, etl.TransformArgs{Quit: quitCh})
+}
+
+// pruneIndexKeysPerRun - the amount of the keys of a bitmap index which one run of the prune stage visits
+var pruneIndexKeysPerRun = 100_000
+
+// pruneBitmapIndex prunes the next pruneIndexKeysPerRun keys of the bitmap index, continuing from the key saved by the
+// previous run. The addresses seen in the pruned blocks can't be found without re-executing them (call traces) or once
+// their receipts are pruned (token transfers), so the index is swept in portions instead of walking it whole every run
+func pruneBitmapIndex(logPrefix string, db ethdb.Database, bucket string, to uint64, quitCh <-chan struct{}) error {
+	logEvery := time.NewTicker(30 * time.Second)
+	defer logEvery.Stop()
+
+	cursorKey := []byte(dbutils.PruneIndexCursorPrefix + bucket)
+	startKey, err := db.Get(dbutils.DatabaseInfoBucket, cursorKey)
+	if err != nil && !errors.Is(err, ethdb.ErrKeyNotFound) {
+		return err
+	}
+
+	var keys [][]byte
+	var prevKey, nextKey []byte
+	visited := 0
+	if err = db.Walk(bucket, startKey, 0, func(k, v []byte) (bool, error) {
+		if err = common.Stopped(quitCh); err != nil {
+			return false, err
+		}
+		select {
+		default:
+		case <-logEvery.C:
+			log.Info(fmt.Sprintf("[%s] Progress", logPrefix), "bucket", bucket, "key", fmt.Sprintf("%x", k))
+		}
+		key := k[:len(k)-4]
+		if bytes.Equal(key, prevKey) {
+			return true, nil
+		}
+		if visited == pruneIndexKeysPerRun {
+			nextKey = common.CopyBytes(key)
+			return false, nil
+		}
+		visited++
+		prevKey = common.CopyBytes(key)
+		// The first shard of the key holds its smallest block numbers
+		if uint64(binary.BigEndian.Uint32(k[len(k)-4:])) < to {
+			keys = append(keys, prevKey)
+			return true, nil
+		}
+		bm := roaring.New()
+		if _, err = bm.ReadFrom(bytes.NewReader(v)); err != nil {
+			return false, err
+		}
+		if bm.GetCardinality() > 0 && uint64(bm.Minimum()) < to {
+			keys = append(keys, prevKey)
+		}
+		return true, nil
+	}); err != nil {
+		return err
+	}
+
+	for _, k := range keys {
+		if err = bitmapdb.PruneRange(db, bucket, k, uint32(to)); err != nil {
+			return fmt.Errorf("fail PruneRange: bucket=%s, %w", bucket, err)
+		}
+	}
+	if nextKey == nil { // the end of the index, the next run starts from the beginning
+		return db.Delete(dbutils.DatabaseInfoBucket, cursorKey, nil)
+	}
+	return db.Put(dbutils.DatabaseInfoBucket, cursorKey, nextKey)
+}
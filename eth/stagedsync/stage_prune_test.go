@@ -0,0 +1,144 @@
+package stagedsync
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/RoaringBitmap/roaring"
+	"github.com/ledgerwatch/turbo-geth/common"
+	"github.com/ledgerwatch/turbo-geth/common/changeset"
+	"github.com/ledgerwatch/turbo-geth/common/dbutils"
+	"github.com/ledgerwatch/turbo-geth/eth/stagedsync/stages"
+	"github.com/ledgerwatch/turbo-geth/ethdb"
+	"github.com/ledgerwatch/turbo-geth/ethdb/bitmapdb"
+)
+
+func TestPruneBlocks_History(t *testing.T) {
+	for _, csBucket := range []string{dbutils.PlainAccountChangeSetBucket, dbutils.PlainStorageChangeSetBucket} {
+		csBucket := csBucket
+		t.Run(csBucket, func(t *testing.T) {
+			db := ethdb.NewMemDatabase()
+			defer db.Close()
+			tx, err := db.Begin(context.Background(), ethdb.RW)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer tx.Rollback()
+
+			addrs, expected := generateTestData(t, tx, csBucket, 2100)
+			indexBucket := changeset.Mapper[csBucket].IndexBucket
+			if err = promoteHistory("logPrefix", tx, csBucket, 0, 2100, 10, time.Millisecond, getTmpDir(), nil); err != nil {
+				t.Fatal(err)
+			}
+
+			cutSlice := func(arr []uint64, from uint64) []uint64 {
+				var res []uint64
+				for _, v := range arr {
+					if v >= from {
+						res = append(res, v)
+					}
+				}
+				return res
+			}
+
+			sm := ethdb.StorageMode{History: true, PruneDistance: 100}
+			for _, pruneTo := range []uint64{1000, 1999, 2050} {
+				if err = PruneBlocks("logPrefix", tx, sm, 0, pruneTo, getTmpDir(), nil); err != nil {
+					t.Fatal(err)
+				}
+				for _, addr := range addrs {
+					expected[string(addr)] = cutSlice(expected[string(addr)], pruneTo)
+					checkIndex(t, tx, indexBucket, addr, expected[string(addr)])
+				}
+
+				var first uint64
+				var found bool
+				if err = changeset.Walk(tx, csBucket, nil, 0, func(blockN uint64, _, _ []byte) (bool, error) {
+					first, found = blockN, true
+					return false, nil
+				}); err != nil {
+					t.Fatal(err)
+				}
+				if !found || first != pruneTo {
+					t.Fatalf("expected first changeset at block %d, got %d (found %t)", pruneTo, first, found)
+				}
+			}
+		})
+	}
+}
+
+func TestPruneBitmapIndex(t *testing.T) {
+	defer func(v int) { pruneIndexKeysPerRun = v }(pruneIndexKeysPerRun)
+	pruneIndexKeysPerRun = 2
+
+	db := ethdb.NewMemDatabase()
+	defer db.Close()
+	var addrs [][]byte
+	for i := byte(0); i < 5; i++ {
+		addr := common.Address{i}
+		addrs = append(addrs, addr[:])
+		bm := roaring.BitmapOf(1, 2, 3, 7, 8)
+		var buf bytes.Buffer
+		if _, err := bm.WriteTo(&buf); err != nil {
+			t.Fatal(err)
+		}
+		if err := db.Put(dbutils.CallFromIndex, append(common.CopyBytes(addr[:]), 0xff, 0xff, 0xff, 0xff), buf.Bytes()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	minBlock := func(addr []byte) uint32 {
+		t.Helper()
+		bm, err := bitmapdb.Get(db, dbutils.CallFromIndex, addr, 0, 100)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return bm.Minimum()
+	}
+
+	// each run prunes the next 2 keys, the cursor is removed at the end of the index
+	for run, pruned := range []int{2, 4, 5} {
+		if err := pruneBitmapIndex("logPrefix", db, dbutils.CallFromIndex, 5, nil); err != nil {
+			t.Fatal(err)
+		}
+		for i, addr := range addrs {
+			expected := uint32(1)
+			if i < pruned {
+				expected = 7
+			}
+			if m := minBlock(addr); m != expected {
+				t.Fatalf("run %d: expected the first block %d of the address %d, got %d", run, expected, i, m)
+			}
+		}
+	}
+	if err := pruneBitmapIndex("logPrefix", db, dbutils.CallFromIndex, 8, nil); err != nil {
+		t.Fatal(err)
+	}
+	if m := minBlock(addrs[0]); m != 8 {
+		t.Fatalf("expected the sweep to start over, got the first block %d", m)
+	}
+}
+
+func TestUnwindPruneStage(t *testing.T) {
+	db := ethdb.NewMemDatabase()
+	defer db.Close()
+	if err := stages.SaveStageProgress(db, stages.Prune, 100); err != nil {
+		t.Fatal(err)
+	}
+	s := &StageState{Stage: stages.Prune, BlockNumber: 100}
+
+	if err := UnwindPruneStage(&UnwindState{Stage: stages.Prune, UnwindPoint: 98}, s, db); err == nil {
+		t.Fatal("expected the unwind below the pruned blocks to fail")
+	}
+	if err := UnwindPruneStage(&UnwindState{Stage: stages.Prune, UnwindPoint: 99}, s, db); err != nil {
+		t.Fatal(err)
+	}
+	progress, err := stages.GetStageProgress(db, stages.Prune)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if progress != 100 {
+		t.Fatalf("expected the progress to be kept, got %d", progress)
+	}
+}
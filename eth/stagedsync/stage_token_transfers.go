@@ -0,0 +1,235 @@
+package stagedsync
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"runtime"
+	"time"
+
+	"github.com/RoaringBitmap/roaring"
+	"github.com/c2h5oh/datasize"
+	"github.com/ledgerwatch/turbo-geth/common"
+	"github.com/ledgerwatch/turbo-geth/common/dbutils"
+	"github.com/ledgerwatch/turbo-geth/common/etl"
+	"github.com/ledgerwatch/turbo-geth/core/types"
+	"github.com/ledgerwatch/turbo-geth/ethdb"
+	"github.com/ledgerwatch/turbo-geth/ethdb/cbor"
+	"github.com/ledgerwatch/turbo-geth/log"
+)
+
+// SpawnTokenTransfers builds the index of ERC-20/721 transfers by holder (both the sender and the recipient)
+// out of the logs stored by the execution stage
+func SpawnTokenTransfers(s *StageState, db ethdb.Database, tmpdir string, quit <-chan struct{}) error {
+	var tx ethdb.DbWithPendingMutations
+	var useExternalTx bool
+	if hasTx, ok := db.(ethdb.HasTx); ok && hasTx.Tx() != nil {
+		tx = db.(ethdb.DbWithPendingMutations)
+		useExternalTx = true
+	} else {
+		var err error
+		tx, err = db.Begin(context.Background(), ethdb.RW)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+	}
+
+	endBlock, err := s.ExecutionAt(tx)
+	logPrefix := s.state.LogPrefix()
+	if err != nil {
+		return fmt.Errorf("%s: token transfers: getting last executed block: %w", logPrefix, err)
+	}
+	sm, err := ethdb.GetStorageModeFromDB(tx)
+	if err != nil {
+		return err
+	}
+	if !sm.Receipts {
+		// the logs are written by the execution only together with the receipts, the index would be silently empty
+		return fmt.Errorf("%s: token transfers index requires receipts, the database has storage mode %q", logPrefix, sm.ToString())
+	}
+	if endBlock == s.BlockNumber {
+		s.Done()
+		return nil
+	}
+
+	start := s.BlockNumber
+	if start > 0 {
+		start++
+	}
+
+	if err := promoteTokenTransfers(logPrefix, tx, start, bitmapsBufLimit, bitmapsFlushEvery, tmpdir, quit); err != nil {
+		return err
+	}
+
+	if err := s.DoneAndUpdate(tx, endBlock); err != nil {
+		return err
+	}
+	if !useExternalTx {
+		if _, err := tx.Commit(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func promoteTokenTransfers(logPrefix string, db ethdb.Database, start uint64, bufLimit datasize.ByteSize, flushEvery time.Duration, tmpdir string, quit <-chan struct{}) error {
+	logEvery := time.NewTicker(30 * time.Second)
+	defer logEvery.Stop()
+
+	tx := db.(ethdb.HasTx).Tx()
+	holders := map[string]*roaring.Bitmap{}
+	logs := tx.Cursor(dbutils.Log)
+	defer logs.Close()
+	checkFlushEvery := time.NewTicker(flushEvery)
+	defer checkFlushEvery.Stop()
+
+	collector := etl.NewCollector(tmpdir, etl.NewSortableBuffer(etl.BufferOptimalSize))
+
+	reader := bytes.NewReader(nil)
+
+	for k, v, err := logs.Seek(dbutils.LogKey(start, 0)); k != nil; k, v, err = logs.Next() {
+		if err != nil {
+			return err
+		}
+
+		if err := common.Stopped(quit); err != nil {
+			return err
+		}
+		blockNum := binary.BigEndian.Uint64(k[:8])
+
+		select {
+		default:
+		case <-logEvery.C:
+			var m runtime.MemStats
+			runtime.ReadMemStats(&m)
+			log.Info(fmt.Sprintf("[%s] Progress", logPrefix), "number", blockNum, "alloc", common.StorageSize(m.Alloc), "sys", common.StorageSize(m.Sys))
+		case <-checkFlushEvery.C:
+			if needFlush(holders, bufLimit) {
+				if err := flushBitmaps(collector, holders); err != nil {
+					return err
+				}
+				holders = map[string]*roaring.Bitmap{}
+			}
+		}
+
+		var ll types.Logs
+		reader.Reset(v)
+		if err := cbor.Unmarshal(&ll, reader); err != nil {
+			return fmt.Errorf("%s: receipt unmarshal failed: %w, block=%d", logPrefix, err, blockNum)
+		}
+
+		for _, l := range ll {
+			transfer, ok := types.DecodeTokenTransfer(l)
+			if !ok {
+				continue
+			}
+			for _, holder := range []common.Address{transfer.From, transfer.To} {
+				holderStr := string(holder.Bytes())
+				m, ok := holders[holderStr]
+				if !ok {
+					m = roaring.New()
+					holders[holderStr] = m
+				}
+				m.Add(uint32(blockNum))
+			}
+		}
+	}
+
+	if err := flushBitmaps(collector, holders); err != nil {
+		return err
+	}
+
+	var currentBitmap = roaring.New()
+	var buf = bytes.NewBuffer(nil)
+
+	lastChunkKey := make([]byte, 128)
+	var loaderFunc = func(k []byte, v []byte, table etl.CurrentTableReader, next etl.LoadNextFunc) error {
+		lastChunkKey = lastChunkKey[:len(k)+4]
+		copy(lastChunkKey, k)
+		binary.BigEndian.PutUint32(lastChunkKey[len(k):], ^uint32(0))
+		lastChunkBytes, err := table.Get(lastChunkKey)
+		if err != nil && !errors.Is(err, ethdb.ErrKeyNotFound) {
+			return fmt.Errorf("%s: find last chunk failed: %w", logPrefix, err)
+		}
+
+		lastChunk := roaring.New()
+		if len(lastChunkBytes) > 0 {
+			_, err = lastChunk.FromBuffer(lastChunkBytes)
+			if err != nil {
+				return fmt.Errorf("%s: couldn't read last token transfers chunk: %w, len(lastChunkBytes)=%d", logPrefix, err, len(lastChunkBytes))
+			}
+		}
+
+		if _, err := currentBitmap.FromBuffer(v); err != nil {
+			return err
+		}
+		currentBitmap.Or(lastChunk) // merge last existing chunk from db - next loop will overwrite it
+		return SendBitmapsByChunks(k, currentBitmap, buf, next)
+	}
+
+	return collector.Load(logPrefix, db, dbutils.TokenTransferIndex, loaderFunc, etl.TransformArgs{Quit: quit})
+}
+
+func UnwindTokenTransfers(u *UnwindState, s *StageState, db ethdb.Database, quitCh <-chan struct{}) error {
+	var tx ethdb.DbWithPendingMutations
+	var useExternalTx bool
+	if hasTx, ok := db.(ethdb.HasTx); ok && hasTx.Tx() != nil {
+		tx = db.(ethdb.DbWithPendingMutations)
+		useExternalTx = true
+	} else {
+		var err error
+		tx, err = db.Begin(context.Background(), ethdb.RW)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+	}
+
+	logPrefix := s.state.LogPrefix()
+	if err := unwindTokenTransfers(logPrefix, tx, u.UnwindPoint, quitCh); err != nil {
+		return err
+	}
+
+	if err := u.Done(tx); err != nil {
+		return fmt.Errorf("%s: %w", logPrefix, err)
+	}
+
+	if !useExternalTx {
+		if _, err := tx.Commit(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func unwindTokenTransfers(logPrefix string, db ethdb.Database, to uint64, quitCh <-chan struct{}) error {
+	holders := map[string]struct{}{}
+
+	start := dbutils.EncodeBlockNumber(to + 1)
+	if err := db.Walk(dbutils.Log, start, 0, func(k, v []byte) (bool, error) {
+		if err := common.Stopped(quitCh); err != nil {
+			return false, err
+		}
+		var logs types.Logs
+		if err := cbor.Unmarshal(&logs, bytes.NewReader(v)); err != nil {
+			return false, fmt.Errorf("%s: receipt unmarshal failed: %w, block=%d", logPrefix, err, binary.BigEndian.Uint64(k))
+		}
+
+		for _, l := range logs {
+			if transfer, ok := types.DecodeTokenTransfer(l); ok {
+				holders[string(transfer.From.Bytes())] = struct{}{}
+				holders[string(transfer.To.Bytes())] = struct{}{}
+			}
+		}
+		return true, nil
+	}); err != nil {
+		return err
+	}
+
+	return truncateBitmaps(db, dbutils.TokenTransferIndex, holders, to)
+}
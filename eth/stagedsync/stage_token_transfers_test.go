@@ -0,0 +1,98 @@
+package stagedsync
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ledgerwatch/turbo-geth/common"
+	"github.com/ledgerwatch/turbo-geth/common/dbutils"
+	"github.com/ledgerwatch/turbo-geth/core/rawdb"
+	"github.com/ledgerwatch/turbo-geth/core/types"
+	"github.com/ledgerwatch/turbo-geth/ethdb"
+	"github.com/ledgerwatch/turbo-geth/ethdb/bitmapdb"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenTransfers(t *testing.T) {
+	require := require.New(t)
+
+	db := ethdb.NewMemDatabase()
+	defer db.Close()
+	tx, err := db.Begin(context.Background(), ethdb.RW)
+	require.NoError(err)
+	defer tx.Rollback()
+
+	token := common.HexToAddress("0x376c47978271565f56DEB45495afa69E59c16Ab2")
+	holder1, holder2, holder3 := common.HexToAddress("0x1"), common.HexToAddress("0x2"), common.HexToAddress("0x3")
+	erc20 := func(from, to common.Address, value int64) *types.Log {
+		return &types.Log{
+			Address: token,
+			Topics:  []common.Hash{types.TransferEventTopic, from.Hash(), to.Hash()},
+			Data:    common.LeftPadBytes(big.NewInt(value).Bytes(), 32),
+		}
+	}
+	erc721 := func(from, to common.Address, id int64) *types.Log {
+		return &types.Log{
+			Address: token,
+			Topics:  []common.Hash{types.TransferEventTopic, from.Hash(), to.Hash(), common.BigToHash(big.NewInt(id))},
+		}
+	}
+
+	transfer, ok := types.DecodeTokenTransfer(erc20(holder1, holder2, 5))
+	require.True(ok)
+	require.Equal(types.TokenTransfer{Token: token, From: holder1, To: holder2, Value: big.NewInt(5)}, *transfer)
+	transfer, ok = types.DecodeTokenTransfer(erc721(holder1, holder2, 7))
+	require.True(ok)
+	require.Equal(types.TokenTransfer{Token: token, From: holder1, To: holder2, Value: big.NewInt(7), NonFungible: true}, *transfer)
+	// other events and the transfers with an unexpected layout are skipped
+	_, ok = types.DecodeTokenTransfer(&types.Log{Address: token, Topics: []common.Hash{common.HexToHash("0x1234"), holder1.Hash(), holder2.Hash()}, Data: make([]byte, 32)})
+	require.False(ok)
+	_, ok = types.DecodeTokenTransfer(&types.Log{Address: token, Topics: []common.Hash{types.TransferEventTopic, holder1.Hash()}, Data: make([]byte, 32)})
+	require.False(ok)
+
+	err = rawdb.AppendReceipts(tx, 1, types.Receipts{{Logs: []*types.Log{erc20(holder1, holder2, 5)}}})
+	require.NoError(err)
+	err = rawdb.AppendReceipts(tx, 2, types.Receipts{{Logs: []*types.Log{erc721(holder2, holder3, 7)}}})
+	require.NoError(err)
+	err = rawdb.AppendReceipts(tx, 3, types.Receipts{{Logs: []*types.Log{{Address: token, Topics: []common.Hash{common.HexToHash("0x1234"), holder1.Hash()}}}}})
+	require.NoError(err)
+
+	err = promoteTokenTransfers("logPrefix", tx, 0, 10, time.Millisecond, "", nil)
+	require.NoError(err)
+
+	check := func(holder common.Address, expected ...uint32) {
+		t.Helper()
+		m, err := bitmapdb.Get(tx, dbutils.TokenTransferIndex, holder[:], 0, 10_000_000)
+		require.NoError(err)
+		require.Equal(len(expected), int(m.GetCardinality()))
+		for _, blockNum := range expected {
+			require.True(m.Contains(blockNum))
+		}
+	}
+	check(holder1, 1)
+	check(holder2, 1, 2)
+	check(holder3, 2)
+	check(token)
+
+	// Incremental run merges with the existing chunks
+	err = rawdb.AppendReceipts(tx, 4, types.Receipts{{Logs: []*types.Log{erc20(holder3, holder1, 1)}}})
+	require.NoError(err)
+	err = promoteTokenTransfers("logPrefix", tx, 4, 10, time.Millisecond, "", nil)
+	require.NoError(err)
+	check(holder1, 1, 4)
+	check(holder3, 2, 4)
+
+	// Unwind test
+	err = unwindTokenTransfers("logPrefix", tx, 1, nil)
+	require.NoError(err)
+	check(holder1, 1)
+	check(holder2, 1)
+	check(holder3)
+
+	err = unwindTokenTransfers("logPrefix", tx, 0, nil)
+	require.NoError(err)
+	check(holder1)
+	check(holder2)
+}
@@ -13,7 +13,11 @@ import (
 	"github.com/ledgerwatch/turbo-geth/log"
 )
 
-func spawnTxPool(s *StageState, db ethdb.Database, pool *core.TxPool, poolStart func() error, quitCh <-chan struct{}) error {
+// txChanSize is the size of channel listening to NewTxsEvent.
+// The number is referenced from the size of tx pool.
+const txChanSize = 4096
+
+func spawnTxPool(s *StageState, db ethdb.Database, pool *core.TxPool, poolStart func() error, notifier ChainEventNotifier, quitCh <-chan struct{}) error {
 	to, err := s.ExecutionAt(db)
 	if err != nil {
 		return err
@@ -35,6 +39,9 @@ func spawnTxPool(s *StageState, db ethdb.Database, pool *core.TxPool, poolStart
 		if err := poolStart(); err != nil {
 			return fmt.Errorf("%s: start pool phase 2: %w", logPrefix, err)
 		}
+		if notifier != nil {
+			go notifyPendingTxs(pool, notifier, quitCh)
+		}
 	}
 	if pool != nil && pool.IsStarted() && s.BlockNumber > 0 {
 		if err := incrementalTxPoolUpdate(logPrefix, s.BlockNumber, to, pool, db, quitCh); err != nil {
@@ -46,6 +53,23 @@ func spawnTxPool(s *StageState, db ethdb.Database, pool *core.TxPool, poolStart
 	return s.DoneAndUpdate(db, to)
 }
 
+// notifyPendingTxs passes the transactions added to the pool on to the rpc daemon
+func notifyPendingTxs(pool *core.TxPool, notifier ChainEventNotifier, quitCh <-chan struct{}) {
+	txsCh := make(chan core.NewTxsEvent, txChanSize)
+	sub := pool.SubscribeNewTxsEvent(txsCh)
+	defer sub.Unsubscribe()
+	for {
+		select {
+		case e := <-txsCh:
+			notifier.OnNewPendingTxs(e.Txs)
+		case <-sub.Err():
+			return
+		case <-quitCh:
+			return
+		}
+	}
+}
+
 func incrementalTxPoolUpdate(logPrefix string, from, to uint64, pool *core.TxPool, db ethdb.Database, quitCh <-chan struct{}) error {
 	headHash, err := rawdb.ReadCanonicalHash(db, to)
 	if err != nil {
@@ -107,12 +131,15 @@ func incrementalTxPoolUpdate(logPrefix string, from, to uint64, pool *core.TxPoo
 	return nil
 }
 
-func unwindTxPool(u *UnwindState, s *StageState, db ethdb.Database, pool *core.TxPool, quitCh <-chan struct{}) error {
+func unwindTxPool(u *UnwindState, s *StageState, db ethdb.Database, pool *core.TxPool, notifier ChainEventNotifier, quitCh <-chan struct{}) error {
 	if u.UnwindPoint >= s.BlockNumber {
 		s.Done()
 		return nil
 	}
 	logPrefix := s.state.LogPrefix()
+	if err := NotifyRpcDaemonUnwind(u.UnwindPoint, notifier, db); err != nil {
+		return fmt.Errorf("%s: notify rpc daemon: %w", logPrefix, err)
+	}
 	if pool != nil && pool.IsStarted() {
 		if err := unwindTxPoolUpdate(logPrefix, u.UnwindPoint, s.BlockNumber, pool, db, quitCh); err != nil {
 			return err
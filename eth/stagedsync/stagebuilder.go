@@ -16,8 +16,13 @@ import (
 	"github.com/ledgerwatch/turbo-geth/params"
 )
 
+// ChainEventNotifier receives the events streamed to the RPC daemon
 type ChainEventNotifier interface {
 	OnNewHeader(*types.Header)
+	OnNewPendingTxs([]*types.Transaction)
+	OnNewLogs([]*types.Log)
+	// OnUnwind is called with the header of the block the chain was unwound to
+	OnUnwind(*types.Header)
 }
 
 // StageParameters contains the stage that stages receives at runtime when initializes.
@@ -50,6 +55,30 @@ type StageParameters struct {
 	silkwormExecutionFunc unsafe.Pointer
 }
 
+// DB returns the database that staged sync is running on, outside of the current transaction.
+func (world StageParameters) DB() ethdb.Database { return world.db }
+
+// ChainConfig returns the configuration of the chain being synced.
+func (world StageParameters) ChainConfig() *params.ChainConfig { return world.chainConfig }
+
+// ChainContext returns the chain context to use for the block execution.
+func (world StageParameters) ChainContext() *core.TinyChainContext { return world.chainContext }
+
+// VMConfig returns the configuration of the EVM used by the execution stage.
+func (world StageParameters) VMConfig() *vm.Config { return world.vmConfig }
+
+// StorageMode returns which optional data (history, receipts, tx index, call traces) the node keeps.
+func (world StageParameters) StorageMode() ethdb.StorageMode { return world.storageMode }
+
+// TmpDir returns the directory for temporary files, e.g. for the ETL collectors.
+func (world StageParameters) TmpDir() string { return world.tmpdir }
+
+// BatchSize returns the batch size configured for the execution stage.
+func (world StageParameters) BatchSize() int { return world.batchSize }
+
+// CacheSize returns the cache size configured for the execution stage.
+func (world StageParameters) CacheSize() int { return world.cacheSize }
+
 // StageBuilder represent an object to create a single stage for staged sync
 type StageBuilder struct {
 	// ID is the stage identifier. Should be unique. It is recommended to prefix it with reverse domain `com.example.my-stage` to avoid conflicts.
@@ -84,6 +113,44 @@ func (bb StageBuilders) MustReplace(id stages.SyncStage, newBuilder StageBuilder
 	return result
 }
 
+// MustInsertBefore puts the new stage right before the stage with a specific ID.
+// Chainable but panics if it can't find the stage.
+func (bb StageBuilders) MustInsertBefore(id stages.SyncStage, newBuilder StageBuilder) StageBuilders {
+	i := bb.Index(id)
+	if i < 0 {
+		panic(fmt.Sprintf("StageBuilders#InsertBefore can't find the stage with id %s", string(id)))
+	}
+	return bb.insert(i, newBuilder)
+}
+
+// MustInsertAfter puts the new stage right after the stage with a specific ID.
+// Chainable but panics if it can't find the stage.
+func (bb StageBuilders) MustInsertAfter(id stages.SyncStage, newBuilder StageBuilder) StageBuilders {
+	i := bb.Index(id)
+	if i < 0 {
+		panic(fmt.Sprintf("StageBuilders#InsertAfter can't find the stage with id %s", string(id)))
+	}
+	return bb.insert(i+1, newBuilder)
+}
+
+// Index returns the position of the stage with a specific ID, or -1 if there is no such stage.
+// Use it together with `UnwindOrder.InsertStage` to keep the unwind order in sync with the inserted stages.
+func (bb StageBuilders) Index(id stages.SyncStage) int {
+	for i, builder := range bb {
+		if strings.EqualFold(string(builder.ID), string(id)) {
+			return i
+		}
+	}
+	return -1
+}
+
+func (bb StageBuilders) insert(i int, newBuilder StageBuilder) StageBuilders {
+	result := make([]StageBuilder, 0, len(bb)+1)
+	result = append(result, bb[:i]...)
+	result = append(result, newBuilder)
+	return append(result, bb[i:]...)
+}
+
 // Build creates sync states out of builders
 func (bb StageBuilders) Build(world StageParameters) []*Stage {
 	stages := make([]*Stage, len(bb))
@@ -189,7 +256,27 @@ func DefaultStages() StageBuilders {
 							})
 					},
 					UnwindFunc: func(u *UnwindState, s *StageState) error {
-						return UnwindExecutionStage(u, s, world.TX, world.storageMode.Receipts)
+						var removed [][]*types.Log
+						if world.storageMode.Receipts && world.notifier != nil && u.UnwindPoint < s.BlockNumber {
+							var err error
+							if removed, err = ReadRemovedLogs(u.UnwindPoint+1, s.BlockNumber, world.TX); err != nil {
+								return err
+							}
+						}
+						if err := UnwindExecutionStage(u, s, world.TX, world.storageMode.Receipts); err != nil {
+							return err
+						}
+						if len(removed) == 0 {
+							return nil
+						}
+						notify := func() { NotifyRpcDaemonRemovedLogs(removed, world.notifier) }
+						// the logs are removed only if the unwind is committed, the stage commits it itself without the external tx
+						if hasTx, ok := world.TX.(ethdb.HasTx); ok && hasTx.Tx() != nil {
+							s.state.OnCommit(notify)
+						} else {
+							notify()
+						}
+						return nil
 					},
 				}
 			},
@@ -275,6 +362,23 @@ func DefaultStages() StageBuilders {
 				}
 			},
 		},
+		{
+			ID: stages.TokenTransfers,
+			Build: func(world StageParameters) *Stage {
+				return &Stage{
+					ID:                  stages.TokenTransfers,
+					Description:         "Generate token transfers index",
+					Disabled:            !world.storageMode.TokenTransfers,
+					DisabledDescription: "Enable by adding `e` to --storage-mode",
+					ExecFunc: func(s *StageState, u Unwinder) error {
+						return SpawnTokenTransfers(s, world.TX, world.tmpdir, world.QuitCh)
+					},
+					UnwindFunc: func(u *UnwindState, s *StageState) error {
+						return UnwindTokenTransfers(u, s, world.TX, world.QuitCh)
+					},
+				}
+			},
+		},
 		{
 			ID: stages.CallTraces,
 			Build: func(world StageParameters) *Stage {
@@ -300,6 +404,31 @@ func DefaultStages() StageBuilders {
 				}
 			},
 		},
+		{
+			ID: stages.AccountTxs,
+			Build: func(world StageParameters) *Stage {
+				return &Stage{
+					ID:                  stages.AccountTxs,
+					Description:         "Generate account transactions index",
+					Disabled:            !world.storageMode.AccountTxs,
+					DisabledDescription: "Enable by adding `a` to --storage-mode",
+					ExecFunc: func(s *StageState, u Unwinder) error {
+						return SpawnAccountTxs(s, world.TX, world.chainConfig, world.chainContext, world.tmpdir, world.QuitCh,
+							AccountTxsStageParams{
+								CacheSize: world.cacheSize,
+								BatchSize: world.batchSize,
+							})
+					},
+					UnwindFunc: func(u *UnwindState, s *StageState) error {
+						return UnwindAccountTxs(u, s, world.TX, world.chainConfig, world.chainContext, world.QuitCh,
+							AccountTxsStageParams{
+								CacheSize: world.cacheSize,
+								BatchSize: world.batchSize,
+							})
+					},
+				}
+			},
+		},
 		{
 			ID: stages.TxLookup,
 			Build: func(world StageParameters) *Stage {
@@ -324,10 +453,27 @@ func DefaultStages() StageBuilders {
 					ID:          stages.TxPool,
 					Description: "Update transaction pool",
 					ExecFunc: func(s *StageState, _ Unwinder) error {
-						return spawnTxPool(s, world.TX, world.txPool, world.poolStart, world.QuitCh)
+						return spawnTxPool(s, world.TX, world.txPool, world.poolStart, world.notifier, world.QuitCh)
+					},
+					UnwindFunc: func(u *UnwindState, s *StageState) error {
+						return unwindTxPool(u, s, world.TX, world.txPool, world.notifier, world.QuitCh)
+					},
+				}
+			},
+		},
+		{
+			ID: stages.Prune,
+			Build: func(world StageParameters) *Stage {
+				return &Stage{
+					ID:                  stages.Prune,
+					Description:         "Prune history, receipts, call traces and token transfers",
+					Disabled:            world.storageMode.PruneDistance == 0,
+					DisabledDescription: "Enable by setting --prune.distance",
+					ExecFunc: func(s *StageState, _ Unwinder) error {
+						return SpawnPruneStage(s, world.TX, world.storageMode, world.tmpdir, world.QuitCh)
 					},
 					UnwindFunc: func(u *UnwindState, s *StageState) error {
-						return unwindTxPool(u, s, world.TX, world.txPool, world.QuitCh)
+						return UnwindPruneStage(u, s, world.TX)
 					},
 				}
 			},
@@ -375,6 +521,22 @@ func DefaultStages() StageBuilders {
 // is fully unwound (stages 9...3).
 type UnwindOrder []int
 
+// InsertStage returns the unwind order for the list of stages with a new stage inserted at
+// stageIndex (as returned by `StageBuilders.Index`). The indices of the stages that were shifted
+// by the insertion are adjusted. The new stage is unwound first, before any of the existing stages,
+// which is what a stage that only derives its data from the earlier stages needs.
+func (uo UnwindOrder) InsertStage(stageIndex int) UnwindOrder {
+	result := make(UnwindOrder, 0, len(uo)+1)
+	for _, i := range uo {
+		if i >= stageIndex {
+			i++
+		}
+		result = append(result, i)
+	}
+	// the unwind stack is popped from the end, so the last stage here is unwound first
+	return append(result, stageIndex)
+}
+
 // DefaultUnwindOrder contains the default unwind order for `DefaultStages()`.
 // Just adding stages that don't do unwinding, don't require altering the default order.
 func DefaultUnwindOrder() UnwindOrder {
@@ -382,10 +544,12 @@ func DefaultUnwindOrder() UnwindOrder {
 		0, 1, 2,
 		// Unwinding of tx pool (reinjecting transactions into the pool needs to happen after unwinding execution)
 		// also tx pool is before senders because senders unwind is inside cycle transaction
-		12,
+		14,
 		3, 4,
 		// Unwinding of IHashes needs to happen after unwinding HashState
 		6, 5,
-		7, 8, 9, 10, 11,
+		7, 8, 9, 10, 11, 12, 13,
+		// Prune is unwound first, it fails before any data is unwound if the unwind point is pruned
+		15,
 	}
 }
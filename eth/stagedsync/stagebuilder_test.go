@@ -0,0 +1,88 @@
+package stagedsync
+
+import (
+	"testing"
+
+	"github.com/ledgerwatch/turbo-geth/eth/stagedsync/stages"
+	"github.com/ledgerwatch/turbo-geth/ethdb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStageBuildersInsert(t *testing.T) {
+	custom := stages.SyncStage("com.example.custom")
+	builder := StageBuilder{ID: custom}
+
+	bb := DefaultStages().MustInsertAfter(stages.Execution, builder)
+	assert.Equal(t, len(DefaultStages())+1, len(bb))
+	assert.Equal(t, bb.Index(stages.Execution)+1, bb.Index(custom))
+	assert.Equal(t, bb.Index(custom)+1, bb.Index(stages.HashState))
+
+	bb = DefaultStages().MustInsertBefore(stages.Headers, builder)
+	assert.Equal(t, 0, bb.Index(custom))
+	assert.Equal(t, 1, bb.Index(stages.Headers))
+
+	assert.Equal(t, -1, DefaultStages().Index(custom))
+	assert.Panics(t, func() { DefaultStages().MustInsertAfter(custom, builder) })
+	assert.Panics(t, func() { DefaultStages().MustInsertBefore(custom, builder) })
+}
+
+func TestUnwindOrderInsertStage(t *testing.T) {
+	custom := stages.SyncStage("com.example.custom")
+	defaultStages := DefaultStages()
+	bb := defaultStages.MustInsertAfter(stages.Execution, StageBuilder{ID: custom})
+	unwindOrder := DefaultUnwindOrder().InsertStage(bb.Index(custom))
+
+	// the existing stages have to be unwound in the same order as before
+	var expected, actual []stages.SyncStage
+	for _, i := range DefaultUnwindOrder() {
+		expected = append(expected, defaultStages[i].ID)
+	}
+	for _, i := range unwindOrder {
+		actual = append(actual, bb[i].ID)
+	}
+	assert.Equal(t, append(expected, custom), actual)
+}
+
+func TestCustomStageUnwindsFirst(t *testing.T) {
+	var flow []stages.SyncStage
+	stage := func(id stages.SyncStage) StageBuilder {
+		return StageBuilder{
+			ID: id,
+			Build: func(world StageParameters) *Stage {
+				return &Stage{
+					ID: id,
+					ExecFunc: func(s *StageState, u Unwinder) error {
+						return s.DoneAndUpdate(world.TX, 100)
+					},
+					UnwindFunc: func(u *UnwindState, s *StageState) error {
+						flow = append(flow, id)
+						return u.Done(world.TX)
+					},
+				}
+			},
+		}
+	}
+	custom := stages.SyncStage("com.example.custom")
+	bb := StageBuilders{stage(stages.Headers), stage(stages.Bodies), stage(stages.Senders)}.
+		MustInsertAfter(stages.Bodies, stage(custom))
+	unwindOrder := UnwindOrder{0, 1, 2}.InsertStage(bb.Index(custom))
+
+	db := ethdb.NewMemDatabase()
+	defer db.Close()
+	sync := New(bb, unwindOrder, OptionalParameters{})
+	state, err := sync.Prepare(nil, nil, nil, nil, db, db, "", ethdb.DefaultStorageMode, "", 0, 0, nil, nil, nil, nil, nil)
+	assert.NoError(t, err)
+	assert.NoError(t, state.Run(db, db))
+
+	progress, err := stages.GetStageProgress(db, custom)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(100), progress)
+
+	assert.NoError(t, state.UnwindTo(50, db))
+	assert.NoError(t, state.Run(db, db))
+	assert.Equal(t, []stages.SyncStage{custom, stages.Senders, stages.Bodies, stages.Headers}, flow)
+
+	unwindPoint, err := stages.GetStageUnwind(db, custom)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(0), unwindPoint)
+}
@@ -41,9 +41,12 @@ var (
 	AccountHistoryIndex SyncStage = []byte("AccountHistoryIndex") // Generating history index for accounts
 	StorageHistoryIndex SyncStage = []byte("StorageHistoryIndex") // Generating history index for storage
 	LogIndex            SyncStage = []byte("LogIndex")            // Generating logs index (from receipts)
+	TokenTransfers      SyncStage = []byte("TokenTransfers")      // Generating ERC-20/721 transfers index (from receipts)
 	CallTraces          SyncStage = []byte("CallTraces")          // Generating call traces index
+	AccountTxs          SyncStage = []byte("AccountTxs")          // Generating index of transactions by the addresses they touch
 	TxLookup            SyncStage = []byte("TxLookup")            // Generating transactions lookup index
 	TxPool              SyncStage = []byte("TxPool")              // Starts Backend
+	Prune               SyncStage = []byte("Prune")               // Pruning of the changesets, history indexes, receipts and call traces older than the prune distance
 	Finish              SyncStage = []byte("Finish")              // Nominal stage after all other stages
 )
 
@@ -58,9 +61,12 @@ var AllStages = []SyncStage{
 	AccountHistoryIndex,
 	StorageHistoryIndex,
 	LogIndex,
+	TokenTransfers,
 	CallTraces,
+	AccountTxs,
 	TxLookup,
 	TxPool,
+	Prune,
 	Finish,
 }
 
@@ -73,6 +79,15 @@ func GetStageProgress(db ethdb.Getter, stage SyncStage) (uint64, error) {
 	return unmarshalData(v)
 }
 
+// GetStageProgressTx is GetStageProgress which reads in the given transaction
+func GetStageProgressTx(tx ethdb.Tx, stage SyncStage) (uint64, error) {
+	v, err := tx.GetOne(dbutils.SyncStageProgress, stage)
+	if err != nil {
+		return 0, err
+	}
+	return unmarshalData(v)
+}
+
 // SaveStageProgress saves the progress of the given stage in the database
 func SaveStageProgress(db ethdb.Putter, stage SyncStage, progress uint64) error {
 	return db.Put(dbutils.SyncStageProgress, stage, marshalData(progress))
@@ -21,6 +21,7 @@ type State struct {
 	beforeStageRun    map[string]func() error
 	onBeforeUnwind    func(stages.SyncStage) error
 	beforeStageUnwind map[string]func() error
+	onCommit          []func()
 }
 
 func (s *State) Len() int {
@@ -298,3 +299,18 @@ func (s *State) BeforeStageUnwind(id stages.SyncStage, f func() error) {
 func (s *State) OnBeforeUnwind(f func(id stages.SyncStage) error) {
 	s.onBeforeUnwind = f
 }
+
+// OnCommit postpones f until the external transaction of the stages is committed, see Committed
+func (s *State) OnCommit(f func()) {
+	s.onCommit = append(s.onCommit, f)
+}
+
+// Committed must be called by the owner of the external transaction of the stages after it's committed,
+// it runs the functions postponed by OnCommit
+func (s *State) Committed() {
+	onCommit := s.onCommit
+	s.onCommit = nil
+	for _, f := range onCommit {
+		f()
+	}
+}
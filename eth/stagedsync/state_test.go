@@ -693,3 +693,18 @@ func TestStateSyncInterruptLongUnwind(t *testing.T) {
 func unwindOf(s stages.SyncStage) stages.SyncStage {
 	return append(s, 0xF0)
 }
+
+func TestStateOnCommit(t *testing.T) {
+	var flow []int
+	state := NewState(nil)
+	state.OnCommit(func() { flow = append(flow, 1) })
+	state.OnCommit(func() { flow = append(flow, 2) })
+	assert.Empty(t, flow)
+
+	state.Committed()
+	assert.Equal(t, []int{1, 2}, flow)
+
+	// the postponed functions run once
+	state.Committed()
+	assert.Equal(t, []int{1, 2}, flow)
+}
@@ -111,6 +111,46 @@ func TruncateRange(db ethdb.Database, bucket string, key []byte, to uint32) erro
 	})
 }
 
+// PruneRange - removes [0, to) from the existing bitmap in db.
+// starts from cold shard, stops at the first shard which has values >= to
+// !Important: [0, to)
+func PruneRange(db ethdb.Database, bucket string, key []byte, to uint32) error {
+	var updateKey, updateValue []byte
+	if err := db.Walk(bucket, key, len(key)*8, func(k, v []byte) (bool, error) {
+		if binary.BigEndian.Uint32(k[len(k)-4:]) < to {
+			return true, db.Delete(bucket, k, nil)
+		}
+		bm := roaring.New()
+		if _, err := bm.ReadFrom(bytes.NewReader(v)); err != nil {
+			return false, err
+		}
+		if bm.GetCardinality() == 0 || bm.Minimum() >= to {
+			return false, nil
+		}
+		bm.RemoveRange(0, uint64(to))
+		updateKey = common.CopyBytes(k)
+		if bm.GetCardinality() == 0 {
+			return false, nil
+		}
+		buf := bytes.NewBuffer(nil)
+		if _, err := bm.WriteTo(buf); err != nil {
+			return false, err
+		}
+		updateValue = buf.Bytes()
+		return false, nil
+	}); err != nil {
+		return err
+	}
+
+	if updateKey == nil {
+		return nil
+	}
+	if updateValue == nil {
+		return db.Delete(bucket, updateKey, nil)
+	}
+	return db.Put(bucket, updateKey, updateValue)
+}
+
 // Get - reading as much chunks as needed to satisfy [from, to] condition
 // join all chunks to 1 bitmap by Or operator
 func Get(db ethdb.Getter, bucket string, key []byte, from, to uint32) (*roaring.Bitmap, error) {
@@ -249,6 +289,46 @@ func TruncateRange64(db ethdb.Database, bucket string, key []byte, to uint64) er
 	})
 }
 
+// PruneRange64 - removes [0, to) from the existing bitmap in db.
+// starts from cold shard, stops at the first shard which has values >= to
+// !Important: [0, to)
+func PruneRange64(db ethdb.Database, bucket string, key []byte, to uint64) error {
+	var updateKey, updateValue []byte
+	if err := db.Walk(bucket, key, len(key)*8, func(k, v []byte) (bool, error) {
+		if binary.BigEndian.Uint64(k[len(k)-8:]) < to {
+			return true, db.Delete(bucket, k, nil)
+		}
+		bm := roaring64.New()
+		if _, err := bm.ReadFrom(bytes.NewReader(v)); err != nil {
+			return false, err
+		}
+		if bm.GetCardinality() == 0 || bm.Minimum() >= to {
+			return false, nil
+		}
+		bm.RemoveRange(0, to)
+		updateKey = common.CopyBytes(k)
+		if bm.GetCardinality() == 0 {
+			return false, nil
+		}
+		buf := bytes.NewBuffer(nil)
+		if _, err := bm.WriteTo(buf); err != nil {
+			return false, err
+		}
+		updateValue = buf.Bytes()
+		return false, nil
+	}); err != nil {
+		return err
+	}
+
+	if updateKey == nil {
+		return nil
+	}
+	if updateValue == nil {
+		return db.Delete(bucket, updateKey, nil)
+	}
+	return db.Put(bucket, updateKey, updateValue)
+}
+
 // Get - reading as much chunks as needed to satisfy [from, to] condition
 // join all chunks to 1 bitmap by Or operator
 func Get64(db ethdb.Getter, bucket string, key []byte, from, to uint64) (*roaring64.Bitmap, error) {
@@ -0,0 +1,409 @@
+package ethdb
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/c2h5oh/datasize"
+	"github.com/ledgerwatch/turbo-geth/common"
+	"github.com/ledgerwatch/turbo-geth/common/dbutils"
+	"github.com/ledgerwatch/turbo-geth/log"
+	"github.com/ledgerwatch/turbo-geth/metrics"
+)
+
+// CompactionDefaultBatchSize - amount of data copied into the compaction pair by one write transaction
+const CompactionDefaultBatchSize = 64 * datasize.MB
+
+var compactionSavedCounter = metrics.NewRegisteredCounter("db/compaction/saved", nil)
+
+// compactionLogInterval is how often the progress of the compaction of a big bucket is logged
+var compactionLogInterval = 30 * time.Second
+
+// CompactionConfig configures the online compaction of the buckets
+type CompactionConfig struct {
+	Buckets   []string // must be in dbutils.CompactableBuckets
+	BatchSize datasize.ByteSize
+	// Interval between the compactions of the buckets, 0 - the buckets are compacted once after the start
+	Interval time.Duration
+}
+
+func (cfg CompactionConfig) Enabled() bool {
+	return len(cfg.Buckets) > 0
+}
+
+// Compactor reclaims the free pages of the buckets while the database is in use. The bucket is copied in bounded
+// write transactions into its compaction pair (see dbutils.CompactionPair), which is densely packed because the
+// keys are appended in order. The keys written to the bucket in the meantime are copied again. When the copy is
+// complete, the names of the bucket and its pair are swapped in the same transaction, and the old data is deleted.
+//
+// Limitations:
+//   - only the writes of this process are tracked, the other processes must not write to the bucket during the compaction
+//   - the applications which open the buckets by name directly, like silkworm, don't see the swap
+//   - the compaction interrupted by the restart is resumed, but the part of the bucket copied before has to be read
+//     again, because the writes made meanwhile are not tracked
+//   - only LMDB is supported
+type Compactor struct {
+	cfg CompactionConfig
+	kv  *LmdbKV
+
+	afterBatch func() // test hook, called after each committed batch, except the last one
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewCompactor creates the compactor of the LMDB database kv, possibly wrapped by the snapshots
+func NewCompactor(kv KV, cfg CompactionConfig) (*Compactor, error) {
+	if snKV, ok := kv.(*SnapshotKV2); ok {
+		kv = snKV.db
+	}
+	lmdbKV, ok := kv.(*LmdbKV)
+	if !ok {
+		return nil, fmt.Errorf("compaction is supported only for LMDB, got %T", kv)
+	}
+	if cfg.BatchSize == 0 {
+		cfg.BatchSize = CompactionDefaultBatchSize
+	}
+	buckets := lmdbKV.AllBuckets()
+	for _, bucket := range cfg.Buckets {
+		compactable := false
+		for _, name := range dbutils.CompactableBuckets {
+			if name == bucket {
+				compactable = true
+				break
+			}
+		}
+		if !compactable {
+			return nil, fmt.Errorf("bucket %s can't be compacted, supported buckets: %v", bucket, dbutils.CompactableBuckets)
+		}
+		b, ok := buckets[bucket]
+		if !ok || b.DBI == NonExistingDBI {
+			return nil, fmt.Errorf("bucket %s doesn't exist", bucket)
+		}
+		if p, ok := buckets[dbutils.CompactionPair(bucket)]; !ok || p.DBI == NonExistingDBI {
+			return nil, fmt.Errorf("compaction pair of the bucket %s doesn't exist, is the database opened read-only?", bucket)
+		}
+		if b.AutoDupSortKeysConversion {
+			return nil, fmt.Errorf("bucket %s with AutoDupSortKeysConversion can't be compacted", bucket)
+		}
+	}
+	return &Compactor{cfg: cfg, kv: lmdbKV}, nil
+}
+
+// Start compacts the configured buckets in the background, then again every Interval
+func (c *Compactor) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	c.cancel = cancel
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		for {
+			for _, bucket := range c.cfg.Buckets {
+				if err := c.Compact(ctx, bucket); err != nil {
+					if errors.Is(err, common.ErrStopped) {
+						return
+					}
+					log.Error("Bucket compaction failed", "bucket", bucket, "err", err)
+				}
+			}
+			if c.cfg.Interval == 0 {
+				return
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(c.cfg.Interval):
+			}
+		}
+	}()
+}
+
+// Stop interrupts the compaction and waits for it to finish. The interrupted compaction is resumed next time
+func (c *Compactor) Stop() {
+	if c.cancel != nil {
+		c.cancel()
+	}
+	c.wg.Wait()
+}
+
+// Compact rewrites the bucket into its compaction pair and swaps their names
+func (c *Compactor) Compact(ctx context.Context, bucket string) error {
+	if !c.kv.watches.watch(bucket) {
+		return fmt.Errorf("bucket %s is already being compacted", bucket)
+	}
+	defer c.kv.watches.unwatch(bucket)
+
+	t := time.Now()
+	logEvery := time.NewTicker(compactionLogInterval)
+	defer logEvery.Stop()
+	pair := dbutils.CompactionPair(bucket)
+	progressKey := []byte(dbutils.CompactionProgressPrefix + bucket)
+	var last []byte    // last key copied into the pair
+	var resumed []byte // key at which the interrupted compaction stopped, the pair is checked up to it first
+	var checked []byte // last key of the pair checked against the bucket
+	var sizeBefore, sizeAfter uint64
+	started, done := false, false
+	for !done {
+		if err := common.Stopped(ctx.Done()); err != nil {
+			return err
+		}
+		if err := c.kv.Update(ctx, func(tx Tx) error {
+			keys, cleared := c.kv.watches.take(bucket)
+			if !started {
+				v, err := tx.GetOne(dbutils.DatabaseInfoBucket, progressKey)
+				if err != nil {
+					return err
+				}
+				if v != nil && !cleared {
+					log.Info("Resuming interrupted compaction", "bucket", bucket, "key", fmt.Sprintf("%x", v))
+					last, resumed = common.CopyBytes(v), common.CopyBytes(v)
+				}
+			}
+			if cleared || (!started && resumed == nil) {
+				if err := tx.(*lmdbTx).emptyBucket(pair); err != nil {
+					return err
+				}
+				last, resumed, checked = nil, nil, nil
+			} else if err := recopyKeys(tx, bucket, pair, keys, last); err != nil {
+				return err
+			}
+
+			var err error
+			if resumed != nil {
+				var verified bool
+				if checked, verified, err = checkBatch(tx, bucket, pair, checked, resumed, c.cfg.BatchSize); err != nil {
+					return err
+				}
+				if verified {
+					resumed, checked = nil, nil
+				}
+				// the progress is not changed until the pair is copied further
+				return nil
+			}
+			last, done, err = copyBatch(tx, bucket, pair, last, c.cfg.BatchSize)
+			if err != nil {
+				return err
+			}
+			if !done {
+				return tx.Cursor(dbutils.DatabaseInfoBucket).Put(progressKey, last)
+			}
+
+			if sizeBefore, err = tx.BucketSize(bucket); err != nil {
+				return err
+			}
+			if sizeAfter, err = tx.BucketSize(pair); err != nil {
+				return err
+			}
+			if err = tx.(*lmdbTx).swapCompactionPair(bucket); err != nil {
+				return err
+			}
+			// after the swap the pair holds the old data, the transactions started before keep reading it
+			if err = tx.(*lmdbTx).emptyBucket(pair); err != nil {
+				return err
+			}
+			return tx.Cursor(dbutils.DatabaseInfoBucket).Delete(progressKey, nil)
+		}); err != nil {
+			return fmt.Errorf("compaction of %s: %w", bucket, err)
+		}
+		started = true
+
+		if done {
+			break
+		}
+		if c.afterBatch != nil {
+			c.afterBatch()
+		}
+		select {
+		case <-logEvery.C:
+			log.Info("Compacting bucket", "bucket", bucket, "key", fmt.Sprintf("%x", last))
+		default:
+		}
+	}
+
+	saved := int64(sizeBefore) - int64(sizeAfter)
+	if saved > 0 {
+		compactionSavedCounter.Inc(saved)
+	}
+	metrics.GetOrRegisterGauge("db/compaction/"+bucket+"/saved", nil).Update(saved)
+	log.Info("Compacted bucket", "bucket", bucket,
+		"before", datasize.ByteSize(sizeBefore).HR(), "after", datasize.ByteSize(sizeAfter).HR(), "duration", time.Since(t))
+	return nil
+}
+
+// copyBatch appends the keys of the bucket after the key last into the pair, until batchSize bytes are copied.
+// The duplicates of a key are never split between the batches
+func copyBatch(tx Tx, bucket, pair string, last []byte, batchSize datasize.ByteSize) ([]byte, bool, error) {
+	from := tx.Cursor(bucket)
+	defer from.Close()
+	to := tx.Cursor(pair)
+	defer to.Close()
+	dupSort := tx.(*lmdbTx).buckets[bucket].Flags&dbutils.DupSort != 0
+	nextKey, appendKV := from.Next, to.Append
+	if dupSort {
+		nextKey, appendKV = from.(CursorDupSort).NextNoDup, to.(CursorDupSort).AppendDup
+	}
+
+	var k, v []byte
+	var err error
+	if last == nil {
+		k, v, err = from.First()
+	} else {
+		k, v, err = from.Seek(last)
+		if err == nil && k != nil && bytes.Equal(k, last) {
+			k, v, err = nextKey()
+		}
+	}
+	size := 0
+	for ; k != nil; k, v, err = from.Next() {
+		if err != nil {
+			return nil, false, err
+		}
+		if !bytes.Equal(k, last) {
+			if size >= int(batchSize) {
+				return last, false, nil
+			}
+			last = common.CopyBytes(k)
+		}
+		if err = appendKV(k, v); err != nil {
+			return nil, false, err
+		}
+		size += len(k) + len(v)
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return last, true, nil
+}
+
+// checkBatch compares the pair left by the interrupted compaction with the bucket, from the key after checked up to
+// the key resumed, until batchSize bytes are compared, and copies again the keys which differ
+func checkBatch(tx Tx, bucket, pair string, checked, resumed []byte, batchSize datasize.ByteSize) ([]byte, bool, error) {
+	from := tx.Cursor(bucket)
+	defer from.Close()
+	to := tx.Cursor(pair)
+	defer to.Close()
+	seek := func(c Cursor) ([]byte, []byte, error) {
+		if checked == nil {
+			return c.First()
+		}
+		k, v, err := c.Seek(checked)
+		for ; err == nil && k != nil && bytes.Equal(k, checked); k, v, err = c.Next() {
+		}
+		return k, v, err
+	}
+	// skip moves the cursor past the key k
+	skip := func(c Cursor, k1, v1, k []byte) ([]byte, []byte, error) {
+		var err error
+		for ; err == nil && k1 != nil && bytes.Equal(k1, k); k1, v1, err = c.Next() {
+		}
+		return k1, v1, err
+	}
+
+	fromK, fromV, err := seek(from)
+	if err != nil {
+		return nil, false, err
+	}
+	toK, toV, err := seek(to)
+	if err != nil {
+		return nil, false, err
+	}
+	var changed [][]byte
+	size := 0
+	for {
+		if fromK != nil && bytes.Compare(fromK, resumed) > 0 {
+			fromK = nil
+		}
+		if toK != nil && bytes.Compare(toK, resumed) > 0 {
+			toK = nil
+		}
+		if fromK == nil && toK == nil {
+			break
+		}
+		k := fromK
+		if k == nil || (toK != nil && bytes.Compare(toK, fromK) < 0) {
+			k = toK
+		}
+		if !bytes.Equal(k, checked) {
+			if size >= int(batchSize) {
+				return checked, false, recopyKeys(tx, bucket, pair, changed, checked)
+			}
+			checked = common.CopyBytes(k)
+		}
+		if bytes.Equal(fromK, toK) && bytes.Equal(fromV, toV) {
+			size += len(fromK) + len(fromV)
+			if fromK, fromV, err = from.Next(); err != nil {
+				return nil, false, err
+			}
+			if toK, toV, err = to.Next(); err != nil {
+				return nil, false, err
+			}
+			continue
+		}
+		changed = append(changed, checked)
+		if fromK, fromV, err = skip(from, fromK, fromV, checked); err != nil {
+			return nil, false, err
+		}
+		if toK, toV, err = skip(to, toK, toV, checked); err != nil {
+			return nil, false, err
+		}
+	}
+	return resumed, true, recopyKeys(tx, bucket, pair, changed, resumed)
+}
+
+// recopyKeys copies again the keys written to the bucket after they have been copied into the pair
+func recopyKeys(tx Tx, bucket, pair string, keys [][]byte, last []byte) error {
+	dupSort := tx.(*lmdbTx).buckets[bucket].Flags&dbutils.DupSort != 0
+	from := tx.Cursor(bucket)
+	defer from.Close()
+	to := tx.Cursor(pair)
+	defer to.Close()
+	for _, k := range keys {
+		if last == nil || bytes.Compare(k, last) > 0 {
+			continue // will be copied by the next batches
+		}
+		if !dupSort {
+			v, err := tx.GetOne(bucket, k)
+			if err != nil {
+				return err
+			}
+			if v == nil {
+				err = to.Delete(k, nil)
+			} else {
+				err = to.Put(k, common.CopyBytes(v))
+			}
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		toDup := to.(CursorDupSort)
+		k1, _, err := toDup.SeekExact(k)
+		if err != nil {
+			return err
+		}
+		if k1 != nil {
+			if err = toDup.DeleteCurrentDuplicates(); err != nil {
+				return err
+			}
+		}
+		fromDup := from.(CursorDupSort)
+		k1, v, err := fromDup.SeekExact(k)
+		for ; k1 != nil; k1, v, err = fromDup.NextDup() {
+			if err != nil {
+				return err
+			}
+			if err = to.Put(k, common.CopyBytes(v)); err != nil {
+				return err
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
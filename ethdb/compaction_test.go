@@ -0,0 +1,266 @@
+package ethdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/ledgerwatch/turbo-geth/common"
+	"github.com/ledgerwatch/turbo-geth/common/dbutils"
+	"github.com/stretchr/testify/require"
+)
+
+func compactionTestData(t *testing.T, kv KV, bucket string, n int) {
+	t.Helper()
+	require.NoError(t, kv.Update(context.Background(), func(tx Tx) error {
+		c := tx.Cursor(bucket)
+		defer c.Close()
+		for i := 0; i < n; i++ {
+			for j := 0; j < 3; j++ {
+				if err := c.Put([]byte(fmt.Sprintf("key%04d", i)), []byte(fmt.Sprintf("value%04d-%d", i, j))); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}))
+}
+
+func compactionTestContents(t *testing.T, kv KV, bucket string) []string {
+	t.Helper()
+	var res []string
+	require.NoError(t, kv.View(context.Background(), func(tx Tx) error {
+		c := tx.Cursor(bucket)
+		defer c.Close()
+		for k, v, err := c.First(); k != nil; k, v, err = c.Next() {
+			if err != nil {
+				return err
+			}
+			res = append(res, string(k)+"="+string(v))
+		}
+		return nil
+	}))
+	return res
+}
+
+func compactionTestSwapped(t *testing.T, kv KV) string {
+	t.Helper()
+	var res []byte
+	require.NoError(t, kv.View(context.Background(), func(tx Tx) error {
+		v, err := tx.GetOne(dbutils.DatabaseInfoBucket, dbutils.CompactionSwappedKey)
+		res = common.CopyBytes(v)
+		return err
+	}))
+	return string(res)
+}
+
+func TestCompaction(t *testing.T) {
+	kv := NewLMDB().InMem().MustOpen()
+	defer kv.Close()
+	for _, bucket := range []string{dbutils.StorageHistoryBucket, dbutils.PlainStorageChangeSetBucket} {
+		bucket := bucket
+		t.Run(bucket, func(t *testing.T) {
+			compactionTestData(t, kv, bucket, 100)
+			expected := compactionTestContents(t, kv, bucket)
+
+			compactor, err := NewCompactor(kv, CompactionConfig{Buckets: []string{bucket}, BatchSize: 256})
+			require.NoError(t, err)
+			require.NoError(t, compactor.Compact(context.Background(), bucket))
+			require.Equal(t, expected, compactionTestContents(t, kv, bucket))
+			require.Empty(t, compactionTestContents(t, kv, dbutils.CompactionPair(bucket)))
+			require.Contains(t, compactionTestSwapped(t, kv), bucket)
+
+			// the second compaction swaps the names back
+			require.NoError(t, compactor.Compact(context.Background(), bucket))
+			require.Equal(t, expected, compactionTestContents(t, kv, bucket))
+			require.Empty(t, compactionTestContents(t, kv, dbutils.CompactionPair(bucket)))
+			require.NotContains(t, compactionTestSwapped(t, kv), bucket)
+		})
+	}
+
+	_, err := NewCompactor(kv, CompactionConfig{Buckets: []string{dbutils.PlainStateBucket}})
+	require.Error(t, err)
+}
+
+func TestCompactionConcurrentWrites(t *testing.T) {
+	kv := NewLMDB().InMem().MustOpen()
+	defer kv.Close()
+	for _, bucket := range []string{dbutils.AccountsHistoryBucket, dbutils.PlainAccountChangeSetBucket} {
+		bucket := bucket
+		t.Run(bucket, func(t *testing.T) {
+			compactionTestData(t, kv, bucket, 100)
+			compactor, err := NewCompactor(kv, CompactionConfig{Buckets: []string{bucket}, BatchSize: 256})
+			require.NoError(t, err)
+			batch := 0
+			compactor.afterBatch = func() {
+				batch++
+				if batch != 3 {
+					return
+				}
+				// the writes before and after the copied keys
+				require.NoError(t, kv.Update(context.Background(), func(tx Tx) error {
+					c := tx.Cursor(bucket)
+					defer c.Close()
+					for _, k := range []string{"key0000", "key0001", "key0099"} {
+						dup, ok := c.(CursorDupSort)
+						if !ok {
+							if err := c.Delete([]byte(k), nil); err != nil {
+								return err
+							}
+							continue
+						}
+						if _, _, err := dup.SeekExact([]byte(k)); err != nil {
+							return err
+						}
+						if err := dup.DeleteCurrentDuplicates(); err != nil {
+							return err
+						}
+					}
+					for _, k := range []string{"key0002", "key0050", "key0098", "key0200", "a"} {
+						if err := c.Put([]byte(k), []byte("new"+k)); err != nil {
+							return err
+						}
+					}
+					return nil
+				}))
+			}
+			require.NoError(t, compactor.Compact(context.Background(), bucket))
+			require.Greater(t, batch, 3)
+
+			compacted := compactionTestContents(t, kv, bucket)
+			// the writes are applied to the original bucket, which is the compaction pair after the swap
+			require.Equal(t, compactionTestContents(t, kv, bucket), compacted)
+			require.Empty(t, compactionTestContents(t, kv, dbutils.CompactionPair(bucket)))
+			require.NotContains(t, compacted, "key0000=value0000-0")
+			require.Contains(t, compacted, "key0002=newkey0002")
+			require.Contains(t, compacted, "key0200=newkey0200")
+			require.Contains(t, compacted, "a=newa")
+		})
+	}
+}
+
+func TestCompactionResume(t *testing.T) {
+	kv := NewLMDB().InMem().MustOpen()
+	defer kv.Close()
+	for _, bucket := range []string{dbutils.StorageHistoryBucket, dbutils.PlainStorageChangeSetBucket} {
+		bucket := bucket
+		t.Run(bucket, func(t *testing.T) {
+			compactionTestData(t, kv, bucket, 100)
+			compactor, err := NewCompactor(kv, CompactionConfig{Buckets: []string{bucket}, BatchSize: 256})
+			require.NoError(t, err)
+			ctx, cancel := context.WithCancel(context.Background())
+			batch := 0
+			compactor.afterBatch = func() {
+				if batch++; batch == 3 {
+					cancel()
+				}
+			}
+			require.True(t, errors.Is(compactor.Compact(ctx, bucket), common.ErrStopped))
+			var progress []byte
+			require.NoError(t, kv.View(context.Background(), func(tx Tx) error {
+				v, err1 := tx.GetOne(dbutils.DatabaseInfoBucket, []byte(dbutils.CompactionProgressPrefix+bucket))
+				progress = common.CopyBytes(v)
+				return err1
+			}))
+			require.NotNil(t, progress)
+
+			// the writes made while the compaction is stopped are not tracked
+			require.NoError(t, kv.Update(context.Background(), func(tx Tx) error {
+				c := tx.Cursor(bucket)
+				defer c.Close()
+				if dup, ok := c.(CursorDupSort); ok {
+					if _, _, err1 := dup.SeekExact([]byte("key0000")); err1 != nil {
+						return err1
+					}
+					if err1 := dup.DeleteCurrentDuplicates(); err1 != nil {
+						return err1
+					}
+				} else if err1 := c.Delete([]byte("key0000"), nil); err1 != nil {
+					return err1
+				}
+				for _, k := range []string{"key0001", "key0099", "a"} {
+					if err1 := c.Put([]byte(k), []byte("new"+k)); err1 != nil {
+						return err1
+					}
+				}
+				return nil
+			}))
+			expected := compactionTestContents(t, kv, bucket)
+
+			batch = 0
+			compactor.afterBatch = func() {
+				if batch++; batch != 1 {
+					return
+				}
+				// the pair copied before the interruption is kept
+				kept := false
+				for _, item := range compactionTestContents(t, kv, dbutils.CompactionPair(bucket)) {
+					kept = kept || strings.HasPrefix(item, string(progress)+"=")
+				}
+				require.True(t, kept)
+			}
+			require.NoError(t, compactor.Compact(context.Background(), bucket))
+			require.Greater(t, batch, 1)
+			require.Equal(t, expected, compactionTestContents(t, kv, bucket))
+			require.Empty(t, compactionTestContents(t, kv, dbutils.CompactionPair(bucket)))
+		})
+	}
+}
+
+func TestCompactionReopen(t *testing.T) {
+	path, err := ioutil.TempDir(os.TempDir(), "compaction*")
+	require.NoError(t, err)
+	defer os.RemoveAll(path)
+
+	kv := NewLMDB().Path(path).MustOpen()
+	compactionTestData(t, kv, dbutils.LogTopicIndex, 50)
+	expected := compactionTestContents(t, kv, dbutils.LogTopicIndex)
+	compactor, err := NewCompactor(kv, CompactionConfig{Buckets: []string{dbutils.LogTopicIndex}, BatchSize: 128})
+	require.NoError(t, err)
+	require.NoError(t, compactor.Compact(context.Background(), dbutils.LogTopicIndex))
+	kv.Close()
+
+	kv = NewLMDB().Path(path).MustOpen()
+	defer kv.Close()
+	require.Equal(t, expected, compactionTestContents(t, kv, dbutils.LogTopicIndex))
+	require.Empty(t, compactionTestContents(t, kv, dbutils.CompactionPair(dbutils.LogTopicIndex)))
+}
+
+func TestCompactionOpenReadTx(t *testing.T) {
+	kv := NewLMDB().InMem().MustOpen()
+	defer kv.Close()
+	compactionTestData(t, kv, dbutils.CallFromIndex, 50)
+	expected := compactionTestContents(t, kv, dbutils.CallFromIndex)
+
+	opened, swapped, read := make(chan struct{}), make(chan struct{}), make(chan []string)
+	go func() {
+		var res []string
+		_ = kv.View(context.Background(), func(tx Tx) error {
+			close(opened)
+			<-swapped
+			c := tx.Cursor(dbutils.CallFromIndex)
+			defer c.Close()
+			for k, v, err := c.First(); k != nil; k, v, err = c.Next() {
+				if err != nil {
+					return err
+				}
+				res = append(res, string(k)+"="+string(v))
+			}
+			return nil
+		})
+		read <- res
+	}()
+	<-opened
+
+	compactor, err := NewCompactor(kv, CompactionConfig{Buckets: []string{dbutils.CallFromIndex}, BatchSize: 128})
+	require.NoError(t, err)
+	require.NoError(t, compactor.Compact(context.Background(), dbutils.CallFromIndex))
+	close(swapped)
+	// the transaction started before the swap keeps reading the old data
+	require.Equal(t, expected, <-read)
+	require.Equal(t, expected, compactionTestContents(t, kv, dbutils.CallFromIndex))
+}
@@ -201,6 +201,15 @@ type Backend interface {
 	AddLocal([]byte) ([]byte, error)
 	Etherbase() (common.Address, error)
 	NetVersion() (uint64, error)
+	NetPeerCount() (uint64, error)
+	ClientVersion() (string, error)
+	NodeInfo() (*remote.NodeInfoReply, error)
+	Peers() (*remote.PeersReply, error)
+	SyncStatus() (*remote.SyncStatusReply, error)
+	TxPoolContent() (*remote.TxPoolContentReply, error)
+	TxPoolStatus() (*remote.TxPoolStatusReply, error)
+	Nonce(address common.Address) (*remote.NonceReply, error)
+	PendingBlock() (*remote.PendingBlockReply, error)
 	Subscribe(func(*remote.SubscribeReply)) error
 }
 
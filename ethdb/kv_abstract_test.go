@@ -7,6 +7,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/ledgerwatch/turbo-geth/common"
 	"github.com/ledgerwatch/turbo-geth/common/dbutils"
 	"github.com/ledgerwatch/turbo-geth/ethdb"
 	"github.com/ledgerwatch/turbo-geth/ethdb/remote"
@@ -494,3 +495,179 @@ func testMultiCursor(t *testing.T, db ethdb.KV, bucket1, bucket2 string) {
 //		})
 //	}
 //}
+
+func TestRemoteWriteTx(t *testing.T) {
+	writable, readOnly := dbutils.CodeBucket, dbutils.AccountsHistoryBucket
+	db := ethdb.NewLMDB().InMem().MustOpen()
+	defer db.Close()
+
+	conn := bufconn.Listen(1024 * 1024)
+	grpcServer := grpc.NewServer()
+	remote.RegisterKVServer(grpcServer, remotedbserver.NewKvServer(db, writable))
+	go func() {
+		if err := grpcServer.Serve(conn); err != nil {
+			log.Error("private RPC server fail", "err", err)
+		}
+	}()
+	defer grpcServer.Stop()
+	rdb, _ := ethdb.NewRemote().InMem(conn).MustOpen()
+	defer rdb.Close()
+	ctx := context.Background()
+
+	// Committed changes are visible to the server and the other clients
+	require.NoError(t, rdb.Update(ctx, func(tx ethdb.Tx) error {
+		c := tx.Cursor(writable)
+		for i := byte(1); i <= 3; i++ {
+			if err := c.Append([]byte{i}, []byte{i}); err != nil {
+				return err
+			}
+		}
+		if err := c.Put([]byte{4}, []byte{4}); err != nil {
+			return err
+		}
+		if err := c.Delete([]byte{2}, nil); err != nil {
+			return err
+		}
+		// The changes are visible in the same transaction
+		v, err := tx.GetOne(writable, []byte{4})
+		require.Equal(t, []byte{4}, v)
+		return err
+	}))
+	expected := [][]byte{{1}, {3}, {4}}
+	for _, kv := range []ethdb.KV{db, rdb} {
+		require.NoError(t, kv.View(ctx, func(tx ethdb.Tx) error {
+			var keys [][]byte
+			if err := ethdb.ForEach(tx.Cursor(writable), func(k, v []byte) (bool, error) {
+				keys = append(keys, common.CopyBytes(k))
+				return true, nil
+			}); err != nil {
+				return err
+			}
+			require.Equal(t, expected, keys, fmt.Sprintf("%T", kv))
+			return nil
+		}))
+	}
+
+	// Rolled back changes are discarded
+	tx, err := rdb.Begin(ctx, nil, ethdb.RW)
+	require.NoError(t, err)
+	require.NoError(t, tx.Cursor(writable).Put([]byte{5}, []byte{5}))
+	tx.Rollback()
+	require.NoError(t, db.View(ctx, func(tx ethdb.Tx) error {
+		v, err1 := tx.GetOne(writable, []byte{5})
+		require.Nil(t, v)
+		return err1
+	}))
+
+	// Buckets outside of the allow-list and read-only transactions can't be modified
+	tx, err = rdb.Begin(ctx, nil, ethdb.RW)
+	require.NoError(t, err)
+	require.Error(t, tx.Cursor(readOnly).Put([]byte{1}, []byte{1}))
+	tx.Rollback()
+	require.NoError(t, rdb.View(ctx, func(tx ethdb.Tx) error {
+		require.Error(t, tx.Cursor(writable).Put([]byte{1}, []byte{1}))
+		require.Error(t, tx.Commit(ctx))
+		return nil
+	}))
+	require.NoError(t, db.View(ctx, func(tx ethdb.Tx) error {
+		v, err1 := tx.GetOne(readOnly, []byte{1})
+		require.Nil(t, v)
+		return err1
+	}))
+}
+
+func TestRemoteWriteTxTimeout(t *testing.T) {
+	defer func(idle, lifetime time.Duration) {
+		remotedbserver.RwTxIdleTimeout, remotedbserver.RwTxMaxLifetime = idle, lifetime
+	}(remotedbserver.RwTxIdleTimeout, remotedbserver.RwTxMaxLifetime)
+	remotedbserver.RwTxIdleTimeout, remotedbserver.RwTxMaxLifetime = 100*time.Millisecond, 300*time.Millisecond
+
+	writable := dbutils.CodeBucket
+	db := ethdb.NewLMDB().InMem().MustOpen()
+	defer db.Close()
+
+	conn := bufconn.Listen(1024 * 1024)
+	grpcServer := grpc.NewServer()
+	remote.RegisterKVServer(grpcServer, remotedbserver.NewKvServer(db, writable))
+	go func() {
+		if err := grpcServer.Serve(conn); err != nil {
+			log.Error("private RPC server fail", "err", err)
+		}
+	}()
+	defer grpcServer.Stop()
+	rdb, _ := ethdb.NewRemote().InMem(conn).MustOpen()
+	defer rdb.Close()
+	ctx := context.Background()
+
+	writeLocally := func() {
+		t.Helper()
+		done := make(chan error, 1)
+		go func() {
+			done <- db.Update(ctx, func(tx ethdb.Tx) error {
+				return tx.Cursor(writable).Put([]byte{1}, []byte{1})
+			})
+		}()
+		select {
+		case err := <-done:
+			require.NoError(t, err)
+		case <-time.After(5 * time.Second):
+			t.Fatal("the writer of the database is held by the remote transaction")
+		}
+	}
+
+	// the idle transaction is rolled back and releases the writer
+	tx, err := rdb.Begin(ctx, nil, ethdb.RW)
+	require.NoError(t, err)
+	require.NoError(t, tx.Cursor(writable).Put([]byte{2}, []byte{2}))
+	time.Sleep(200 * time.Millisecond)
+	writeLocally()
+	require.Error(t, tx.Commit(ctx))
+
+	// the busy transaction is rolled back after its max lifetime
+	tx, err = rdb.Begin(ctx, nil, ethdb.RW)
+	require.NoError(t, err)
+	c := tx.Cursor(writable)
+	var putErr error
+	for start := time.Now(); putErr == nil && time.Since(start) < 5*time.Second; {
+		putErr = c.Put([]byte{3}, []byte{3})
+		time.Sleep(10 * time.Millisecond)
+	}
+	require.Error(t, putErr)
+	tx.Rollback()
+	writeLocally()
+
+	require.NoError(t, db.View(ctx, func(tx ethdb.Tx) error {
+		for _, k := range [][]byte{{2}, {3}} {
+			v, err1 := tx.GetOne(writable, k)
+			if err1 != nil {
+				return err1
+			}
+			require.Nil(t, v)
+		}
+		return nil
+	}))
+}
+
+func TestRemoteWriteTxNotAllowed(t *testing.T) {
+	db := ethdb.NewLMDB().InMem().MustOpen()
+	defer db.Close()
+
+	conn := bufconn.Listen(1024 * 1024)
+	grpcServer := grpc.NewServer()
+	remote.RegisterKVServer(grpcServer, remotedbserver.NewKvServer(db))
+	go func() {
+		if err := grpcServer.Serve(conn); err != nil {
+			log.Error("private RPC server fail", "err", err)
+		}
+	}()
+	defer grpcServer.Stop()
+	rdb, _ := ethdb.NewRemote().InMem(conn).MustOpen()
+	defer rdb.Close()
+
+	_, err := rdb.Begin(context.Background(), nil, ethdb.RW)
+	require.Error(t, err)
+	require.NoError(t, rdb.View(context.Background(), func(tx ethdb.Tx) error {
+		_, err1 := tx.GetOne(dbutils.Buckets[0], []byte{1})
+		return err1
+	}))
+}
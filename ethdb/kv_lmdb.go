@@ -10,6 +10,7 @@ import (
 	"path"
 	"runtime"
 	"sync"
+	"sync/atomic"
 	"time"
 	"unsafe"
 
@@ -251,6 +252,7 @@ func (opts LmdbOpts) Open() (kv KV, err error) {
 	}); err != nil {
 		return nil, err
 	}
+	db.layout.Store(&bucketsLayout{})
 
 	if !opts.inMem {
 		if staleReaders, err := db.env.ReaderCheck(); err != nil {
@@ -277,6 +279,9 @@ type LmdbKV struct {
 	buckets       dbutils.BucketsCfg
 	wg            *sync.WaitGroup
 	exclusiveLock fileutil.Releaser
+
+	layout  atomic.Value // *bucketsLayout, the buckets with the swapped compaction pairs, see resolveBuckets
+	watches compactionWatches
 }
 
 func (db *LmdbKV) NewDbWithTheSameParameters() *ObjectDatabase {
@@ -350,11 +355,20 @@ func (db *LmdbKV) Begin(_ context.Context, parent Tx, flags TxFlags) (txn Tx, er
 		return nil, err
 	}
 	tx.RawRead = true
+	var buckets dbutils.BucketsCfg
+	if isSubTx {
+		buckets = parent.(*lmdbTx).buckets
+	} else if buckets, err = db.resolveBuckets(tx); err != nil {
+		tx.Abort()
+		runtime.UnlockOSThread()
+		return nil, err
+	}
 	return &lmdbTx{
 		db:      db,
 		tx:      tx,
 		isSubTx: isSubTx,
 		flags:   flags,
+		buckets: buckets,
 	}, nil
 }
 
@@ -363,6 +377,7 @@ type lmdbTx struct {
 	flags   TxFlags
 	tx      *lmdb.Txn
 	db      *LmdbKV
+	buckets dbutils.BucketsCfg // buckets as of the beginning of the transaction, see resolveBuckets
 	cursors []*lmdb.Cursor
 }
 
@@ -393,18 +408,18 @@ func (db *LmdbKV) AllBuckets() dbutils.BucketsCfg {
 }
 
 func (tx *lmdbTx) Comparator(bucket string) dbutils.CmpFunc {
-	b := tx.db.buckets[bucket]
+	b := tx.buckets[bucket]
 	return chooseComparator(tx.tx, lmdb.DBI(b.DBI), b)
 }
 
 // Cmp - this func follow bytes.Compare return style: The result will be 0 if a==b, -1 if a < b, and +1 if a > b.
 func (tx *lmdbTx) Cmp(bucket string, a, b []byte) int {
-	return tx.tx.Cmp(lmdb.DBI(tx.db.buckets[bucket].DBI), a, b)
+	return tx.tx.Cmp(lmdb.DBI(tx.buckets[bucket].DBI), a, b)
 }
 
 // DCmp - this func follow bytes.Compare return style: The result will be 0 if a==b, -1 if a < b, and +1 if a > b.
 func (tx *lmdbTx) DCmp(bucket string, a, b []byte) int {
-	return tx.tx.DCmp(lmdb.DBI(tx.db.buckets[bucket].DBI), a, b)
+	return tx.tx.DCmp(lmdb.DBI(tx.buckets[bucket].DBI), a, b)
 }
 
 // All buckets stored as keys of un-named bucket
@@ -501,7 +516,7 @@ func (tx *lmdbTx) CreateBucket(name string) error {
 
 	tx.db.buckets[name] = cnfCopy
 
-	return nil
+	return tx.refreshBuckets()
 }
 
 func chooseComparator(tx *lmdb.Txn, dbi lmdb.DBI, cnfCopy dbutils.BucketConfigItem) dbutils.CmpFunc {
@@ -553,10 +568,15 @@ func (tx *lmdbTx) dropEvenIfBucketIsNotDeprecated(name string) error {
 	cnfCopy := tx.db.buckets[name]
 	cnfCopy.DBI = NonExistingDBI
 	tx.db.buckets[name] = cnfCopy
-	return nil
+	return tx.refreshBuckets()
 }
 
 func (tx *lmdbTx) ClearBucket(bucket string) error {
+	tx.db.watches.clear(bucket)
+	if tx.isSwapped(bucket) {
+		// the handle of the swapped bucket is shared with its pair, so it's only emptied
+		return tx.emptyBucket(bucket)
+	}
 	if err := tx.dropEvenIfBucketIsNotDeprecated(bucket); err != nil {
 		return err
 	}
@@ -572,7 +592,7 @@ func (tx *lmdbTx) DropBucket(bucket string) error {
 }
 
 func (tx *lmdbTx) ExistsBucket(bucket string) bool {
-	if cfg, ok := tx.db.buckets[bucket]; ok {
+	if cfg, ok := tx.buckets[bucket]; ok {
 		return cfg.DBI != NonExistingDBI
 	}
 	return false
@@ -658,7 +678,7 @@ func (c *LmdbCursor) Prefetch(v uint) Cursor {
 }
 
 func (tx *lmdbTx) GetOne(bucket string, key []byte) ([]byte, error) {
-	b := tx.db.buckets[bucket]
+	b := tx.buckets[bucket]
 	if b.AutoDupSortKeysConversion && len(key) == b.DupFromLen {
 		from, to := b.DupFromLen, b.DupToLen
 		c := tx.Cursor(bucket).(*LmdbCursor)
@@ -690,7 +710,7 @@ func (tx *lmdbTx) GetOne(bucket string, key []byte) ([]byte, error) {
 }
 
 func (tx *lmdbTx) HasOne(bucket string, key []byte) (bool, error) {
-	b := tx.db.buckets[bucket]
+	b := tx.buckets[bucket]
 	if b.AutoDupSortKeysConversion && len(key) == b.DupFromLen {
 		from, to := b.DupFromLen, b.DupToLen
 		c := tx.Cursor(bucket).(*LmdbCursor)
@@ -744,7 +764,7 @@ func (tx *lmdbTx) Sequence(bucket string, amount uint64) (uint64, error) {
 }
 
 func (tx *lmdbTx) BucketSize(name string) (uint64, error) {
-	st, err := tx.tx.Stat(lmdb.DBI(tx.db.buckets[name].DBI))
+	st, err := tx.tx.Stat(lmdb.DBI(tx.buckets[name].DBI))
 	if err != nil {
 		return 0, err
 	}
@@ -758,11 +778,11 @@ func (tx *lmdbTx) BucketStat(name string) (*lmdb.Stat, error) {
 	if name == "root" { //nolint:goconst
 		return tx.tx.Stat(lmdb.DBI(1))
 	}
-	return tx.tx.Stat(lmdb.DBI(tx.db.buckets[name].DBI))
+	return tx.tx.Stat(lmdb.DBI(tx.buckets[name].DBI))
 }
 
 func (tx *lmdbTx) Cursor(bucket string) Cursor {
-	b := tx.db.buckets[bucket]
+	b := tx.buckets[bucket]
 	if b.AutoDupSortKeysConversion {
 		return tx.stdCursor(bucket)
 	}
@@ -779,8 +799,8 @@ func (tx *lmdbTx) Cursor(bucket string) Cursor {
 }
 
 func (tx *lmdbTx) stdCursor(bucket string) Cursor {
-	b := tx.db.buckets[bucket]
-	return &LmdbCursor{bucketName: bucket, tx: tx, bucketCfg: b, dbi: lmdb.DBI(tx.db.buckets[bucket].DBI)}
+	b := tx.buckets[bucket]
+	return &LmdbCursor{bucketName: bucket, tx: tx, bucketCfg: b, dbi: lmdb.DBI(b.DBI)}
 }
 
 func (tx *lmdbTx) CursorDupSort(bucket string) CursorDupSort {
@@ -1093,6 +1113,7 @@ func (c *LmdbCursor) Delete(k, v []byte) error {
 			return err
 		}
 	}
+	c.touch(k)
 
 	if c.bucketCfg.AutoDupSortKeysConversion {
 		return c.deleteDupSort(k)
@@ -1132,6 +1153,7 @@ func (c *LmdbCursor) DeleteCurrent() error {
 		}
 	}
 
+	c.touchCurrent()
 	return c.delCurrent()
 }
 
@@ -1142,6 +1164,7 @@ func (c *LmdbCursor) Reserve(k []byte, n int) ([]byte, error) {
 		}
 	}
 
+	c.touch(k)
 	return c.reserve(k, n)
 }
 
@@ -1191,6 +1214,7 @@ func (c *LmdbCursor) PutNoOverwrite(key []byte, value []byte) error {
 		panic("not implemented")
 	}
 
+	c.touch(key)
 	return c.putNoOverwrite(key, value)
 }
 
@@ -1204,6 +1228,7 @@ func (c *LmdbCursor) Put(key []byte, value []byte) error {
 		}
 	}
 
+	c.touch(key)
 	b := c.bucketCfg
 	if b.AutoDupSortKeysConversion {
 		return c.putDupSort(key, value)
@@ -1263,6 +1288,7 @@ func (c *LmdbCursor) PutCurrent(key []byte, value []byte) error {
 		}
 	}
 
+	c.touch(key)
 	b := c.bucketCfg
 	if b.AutoDupSortKeysConversion && len(key) == b.DupFromLen {
 		value = append(key[b.DupToLen:], value...)
@@ -1318,6 +1344,7 @@ func (c *LmdbCursor) Append(k []byte, v []byte) error {
 			return err
 		}
 	}
+	c.touch(k)
 	b := c.bucketCfg
 	if b.AutoDupSortKeysConversion {
 		from, to := b.DupFromLen, b.DupToLen
@@ -1392,6 +1419,7 @@ func (c *LmdbDupSortCursor) DeleteExact(k1, k2 []byte) error {
 			return err
 		}
 	}
+	c.touch(k1)
 
 	_, _, err := c.getBoth(k1, k2)
 	if err != nil { // if key not found, or found another one - then nothing to delete
@@ -1547,6 +1575,7 @@ func (c *LmdbDupSortCursor) Append(k []byte, v []byte) error {
 			return err
 		}
 	}
+	c.touch(k)
 
 	if err := c.c.Put(k, v, lmdb.Append|lmdb.AppendDup); err != nil {
 		return fmt.Errorf("in Append: %w", err)
@@ -1560,6 +1589,7 @@ func (c *LmdbDupSortCursor) AppendDup(k []byte, v []byte) error {
 			return err
 		}
 	}
+	c.touch(k)
 
 	if err := c.appendDup(k, v); err != nil {
 		return fmt.Errorf("in AppendDup: %w", err)
@@ -1573,6 +1603,7 @@ func (c *LmdbDupSortCursor) PutNoDupData(key, value []byte) error {
 			return err
 		}
 	}
+	c.touch(key)
 	if err := c.putNoDupData(key, value); err != nil {
 		return fmt.Errorf("in PutNoDupData: %w", err)
 	}
@@ -1587,6 +1618,7 @@ func (c *LmdbDupSortCursor) DeleteCurrentDuplicates() error {
 			return err
 		}
 	}
+	c.touchCurrent()
 	if err := c.delNoDupData(); err != nil {
 		return fmt.Errorf("in DeleteCurrentDuplicates: %w", err)
 	}
@@ -1662,5 +1694,6 @@ func (c *LmdbDupFixedCursor) PutMulti(key []byte, page []byte, stride int) error
 		}
 	}
 
+	c.touch(key)
 	return c.c.PutMulti(key, page, stride, 0)
 }
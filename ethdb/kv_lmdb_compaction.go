@@ -0,0 +1,225 @@
+package ethdb
+
+import (
+	"bytes"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/ledgerwatch/lmdb-go/lmdb"
+	"github.com/ledgerwatch/turbo-geth/common"
+	"github.com/ledgerwatch/turbo-geth/common/dbutils"
+)
+
+// bucketsLayout - buckets of the database with the DBIs of the swapped compaction pairs exchanged
+type bucketsLayout struct {
+	swapped []byte // value of dbutils.CompactionSwappedKey
+	buckets dbutils.BucketsCfg
+}
+
+// resolveBuckets returns the buckets as of the transaction. The compaction swaps the names of the bucket and its
+// pair (see dbutils.CompactionPair) by exchanging their DBIs. The list of the swapped buckets is stored in the
+// database, so every transaction of every process which opened it sees the swap atomically
+func (db *LmdbKV) resolveBuckets(tx *lmdb.Txn) (dbutils.BucketsCfg, error) {
+	info, ok := db.buckets[dbutils.DatabaseInfoBucket]
+	if !ok || info.DBI == 0 || info.DBI == NonExistingDBI {
+		return db.buckets, nil
+	}
+	swapped, err := tx.Get(lmdb.DBI(info.DBI), dbutils.CompactionSwappedKey)
+	if err != nil {
+		if lmdb.IsNotFound(err) {
+			return db.buckets, nil
+		}
+		return nil, err
+	}
+	if len(swapped) == 0 {
+		return db.buckets, nil
+	}
+	if l, _ := db.layout.Load().(*bucketsLayout); l != nil && l.buckets != nil && bytes.Equal(l.swapped, swapped) {
+		return l.buckets, nil
+	}
+	l := &bucketsLayout{swapped: common.CopyBytes(swapped), buckets: swapCompactionPairs(db.buckets, swapped)}
+	db.layout.Store(l)
+	return l.buckets, nil
+}
+
+func swapCompactionPairs(buckets dbutils.BucketsCfg, swapped []byte) dbutils.BucketsCfg {
+	res := make(dbutils.BucketsCfg, len(buckets))
+	for name, cfg := range buckets {
+		res[name] = cfg
+	}
+	for _, name := range strings.Split(string(swapped), ",") {
+		pair := dbutils.CompactionPair(name)
+		b, ok := res[name]
+		if !ok || b.DBI == 0 || b.DBI == NonExistingDBI {
+			continue
+		}
+		p, ok := res[pair]
+		if !ok || p.DBI == 0 || p.DBI == NonExistingDBI {
+			continue
+		}
+		b.DBI, p.DBI = p.DBI, b.DBI
+		res[name], res[pair] = b, p
+	}
+	return res
+}
+
+// refreshBuckets is called after the DBIs of the buckets are changed
+func (tx *lmdbTx) refreshBuckets() error {
+	tx.db.layout.Store(&bucketsLayout{})
+	buckets, err := tx.db.resolveBuckets(tx.tx)
+	if err != nil {
+		return err
+	}
+	tx.buckets = buckets
+	return nil
+}
+
+func (tx *lmdbTx) isSwapped(bucket string) bool {
+	return tx.buckets[bucket].DBI != tx.db.buckets[bucket].DBI
+}
+
+// emptyBucket deletes all the data of the bucket, but keeps its handle open: the transactions which
+// have started before keep reading their snapshot of it
+func (tx *lmdbTx) emptyBucket(bucket string) error {
+	return tx.tx.Drop(lmdb.DBI(tx.buckets[bucket].DBI), false)
+}
+
+// swapCompactionPair swaps the names of the bucket and its compaction pair, the rest of the transaction
+// and the transactions started after its commit see the swapped buckets
+func (tx *lmdbTx) swapCompactionPair(bucket string) error {
+	v, err := tx.GetOne(dbutils.DatabaseInfoBucket, dbutils.CompactionSwappedKey)
+	if err != nil {
+		return err
+	}
+	var swapped []string
+	found := false
+	if len(v) > 0 {
+		for _, name := range strings.Split(string(v), ",") {
+			if name == bucket {
+				found = true
+				continue
+			}
+			swapped = append(swapped, name)
+		}
+	}
+	if !found {
+		swapped = append(swapped, bucket)
+	}
+	sort.Strings(swapped)
+
+	c := tx.Cursor(dbutils.DatabaseInfoBucket)
+	defer c.Close()
+	newV := []byte(strings.Join(swapped, ","))
+	if len(newV) == 0 {
+		err = c.Delete(dbutils.CompactionSwappedKey, nil)
+	} else {
+		err = c.Put(dbutils.CompactionSwappedKey, newV)
+	}
+	if err != nil {
+		return err
+	}
+	tx.buckets = swapCompactionPairs(tx.db.buckets, newV)
+	return nil
+}
+
+// compactionWatches collects the keys written to the buckets under compaction, see Compactor
+type compactionWatches struct {
+	active  int32 // amount of the watched buckets, the writes don't take the lock when there are none
+	mu      sync.Mutex
+	buckets map[string]*compactionWatch
+}
+
+type compactionWatch struct {
+	keys    map[string]struct{}
+	cleared bool
+}
+
+func (w *compactionWatches) watch(bucket string) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if _, ok := w.buckets[bucket]; ok {
+		return false
+	}
+	if w.buckets == nil {
+		w.buckets = make(map[string]*compactionWatch)
+	}
+	w.buckets[bucket] = &compactionWatch{keys: make(map[string]struct{})}
+	atomic.AddInt32(&w.active, 1)
+	return true
+}
+
+func (w *compactionWatches) unwatch(bucket string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if _, ok := w.buckets[bucket]; ok {
+		delete(w.buckets, bucket)
+		atomic.AddInt32(&w.active, -1)
+	}
+}
+
+func (w *compactionWatches) watched(bucket string) bool {
+	if atomic.LoadInt32(&w.active) == 0 {
+		return false
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	_, ok := w.buckets[bucket]
+	return ok
+}
+
+func (w *compactionWatches) touch(bucket string, k []byte) {
+	if atomic.LoadInt32(&w.active) == 0 {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if b, ok := w.buckets[bucket]; ok && !b.cleared {
+		b.keys[string(k)] = struct{}{}
+	}
+}
+
+func (w *compactionWatches) clear(bucket string) {
+	if atomic.LoadInt32(&w.active) == 0 {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if b, ok := w.buckets[bucket]; ok {
+		b.cleared = true
+		b.keys = make(map[string]struct{})
+	}
+}
+
+// take returns the keys written since the previous call, cleared is true if the whole bucket was cleared
+func (w *compactionWatches) take(bucket string) (keys [][]byte, cleared bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	b, ok := w.buckets[bucket]
+	if !ok {
+		return nil, false
+	}
+	keys = make([][]byte, 0, len(b.keys))
+	for k := range b.keys {
+		keys = append(keys, []byte(k))
+	}
+	cleared = b.cleared
+	b.keys, b.cleared = make(map[string]struct{}), false
+	return keys, cleared
+}
+
+// touch remembers the key written by the cursor, if its bucket is under compaction
+func (c *LmdbCursor) touch(k []byte) {
+	c.tx.db.watches.touch(c.bucketName, k)
+}
+
+// touchCurrent remembers the key at the current position of the cursor, if its bucket is under compaction
+func (c *LmdbCursor) touchCurrent() {
+	if !c.tx.db.watches.watched(c.bucketName) {
+		return
+	}
+	if k, _, err := c.getCurrent(); err == nil {
+		c.touch(k)
+	}
+}
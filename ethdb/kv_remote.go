@@ -57,6 +57,7 @@ type remoteTx struct {
 	stream             remote.KV_TxClient
 	streamCancelFn     context.CancelFunc
 	streamingRequested bool
+	rw                 bool // write transaction, the server has to allow writes to the modified buckets
 }
 
 type remoteCursor struct {
@@ -221,6 +222,8 @@ func (db *RemoteKV) DiskSize(ctx context.Context) (uint64, error) {
 	return sizeReply.Size, nil
 }
 
+// Begin - creates remote transaction. Write transaction (flags without RO) is opened only if the server allows writes,
+// see remotedbserver.NewKvServer
 func (db *RemoteKV) Begin(ctx context.Context, parent Tx, flags TxFlags) (Tx, error) {
 	streamCtx, streamCancelFn := context.WithCancel(ctx) // We create child context for the stream so we can cancel it to prevent leak
 	stream, err := db.remoteKV.Tx(streamCtx)
@@ -228,7 +231,17 @@ func (db *RemoteKV) Begin(ctx context.Context, parent Tx, flags TxFlags) (Tx, er
 		streamCancelFn()
 		return nil, err
 	}
-	return &remoteTx{ctx: ctx, db: db, stream: stream, streamCancelFn: streamCancelFn}, nil
+	tx := &remoteTx{ctx: ctx, db: db, stream: stream, streamCancelFn: streamCancelFn, rw: flags&RO == 0}
+	if tx.rw {
+		if err = stream.Send(&remote.Cursor{Op: remote.Op_BEGIN_RW}); err == nil {
+			_, err = stream.Recv()
+		}
+		if err != nil {
+			streamCancelFn()
+			return nil, fmt.Errorf("begin remote write transaction: %w", err)
+		}
+	}
+	return tx, nil
 }
 
 func (db *RemoteKV) View(ctx context.Context, f func(tx Tx) error) (err error) {
@@ -242,7 +255,16 @@ func (db *RemoteKV) View(ctx context.Context, f func(tx Tx) error) (err error) {
 }
 
 func (db *RemoteKV) Update(ctx context.Context, f func(tx Tx) error) (err error) {
-	return fmt.Errorf("remote db provider doesn't support .Update method")
+	tx, err := db.Begin(ctx, nil, RW)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err = f(tx); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
 }
 
 func (tx *remoteTx) Comparator(bucket string) dbutils.CmpFunc { panic("not implemented yet") }
@@ -255,16 +277,44 @@ func (tx *remoteTx) Sequence(bucket string, amount uint64) (uint64, error) {
 }
 
 func (tx *remoteTx) Commit(ctx context.Context) error {
-	panic("remote db is read-only")
+	if !tx.rw {
+		return fmt.Errorf("remote read-only transaction can't be committed")
+	}
+	if tx.stream == nil {
+		return fmt.Errorf("remote transaction is already finished")
+	}
+	return tx.finish(remote.Op_COMMIT)
 }
 
 func (tx *remoteTx) Rollback() {
+	if tx.rw {
+		if tx.stream != nil {
+			if err := tx.finish(remote.Op_ROLLBACK); err != nil {
+				log.Warn("remote rollback failed", "err", err)
+			}
+		}
+		return
+	}
 	for _, c := range tx.cursors {
 		c.Close()
 	}
 	tx.closeGrpcStream()
 }
 
+// finish sends COMMIT or ROLLBACK of the write transaction, after which the server closes the stream
+func (tx *remoteTx) finish(op remote.Op) error {
+	defer func() {
+		tx.streamCancelFn()
+		tx.stream = nil
+		tx.cursors = nil
+	}()
+	if err := tx.stream.Send(&remote.Cursor{Op: op}); err != nil {
+		return err
+	}
+	_, err := tx.stream.Recv()
+	return err
+}
+
 func (c *remoteCursor) Prefix(v []byte) Cursor {
 	return c
 }
@@ -335,15 +385,44 @@ func (c *remoteCursor) initCursor() error {
 	return nil
 }
 
-func (c *remoteCursor) Put(key []byte, value []byte) error            { panic("not supported") }
 func (c *remoteCursor) PutNoOverwrite(key []byte, value []byte) error { panic("not supported") }
 func (c *remoteCursor) PutCurrent(key, value []byte) error            { panic("not supported") }
-func (c *remoteCursor) Append(key []byte, value []byte) error         { panic("not supported") }
-func (c *remoteCursor) Delete(k, v []byte) error                      { panic("not supported") }
 func (c *remoteCursor) DeleteCurrent() error                          { panic("not supported") }
 func (c *remoteCursor) Count() (uint64, error)                        { panic("not supported") }
 func (c *remoteCursor) Reserve(k []byte, n int) ([]byte, error)       { panic("not supported") }
 
+func (c *remoteCursor) Put(key []byte, value []byte) error {
+	if err := c.initCursor(); err != nil {
+		return err
+	}
+	return c.write(remote.Op_PUT, key, value)
+}
+
+func (c *remoteCursor) Append(key []byte, value []byte) error {
+	if err := c.initCursor(); err != nil {
+		return err
+	}
+	return c.write(remote.Op_APPEND, key, value)
+}
+
+func (c *remoteCursor) Delete(k, v []byte) error {
+	if err := c.initCursor(); err != nil {
+		return err
+	}
+	return c.write(remote.Op_DELETE, k, v)
+}
+
+func (c *remoteCursor) write(op remote.Op, k, v []byte) error {
+	if !c.tx.rw {
+		return fmt.Errorf("%s in remote read-only transaction", op)
+	}
+	if err := c.stream.Send(&remote.Cursor{Cursor: c.id, Op: op, K: k, V: v}); err != nil {
+		return err
+	}
+	_, err := c.stream.Recv()
+	return err
+}
+
 func (c *remoteCursor) first() ([]byte, []byte, error) {
 	if err := c.stream.Send(&remote.Cursor{Cursor: c.id, Op: remote.Op_FIRST}); err != nil {
 		return []byte{}, nil, err
@@ -717,6 +796,52 @@ func (back *RemoteBackend) NetVersion() (uint64, error) {
 	return res.Id, nil
 }
 
+func (back *RemoteBackend) NetPeerCount() (uint64, error) {
+	res, err := back.remoteEthBackend.NetPeerCount(context.Background(), &remote.NetPeerCountRequest{})
+	if err != nil {
+		return 0, err
+	}
+
+	return res.Count, nil
+}
+
+func (back *RemoteBackend) ClientVersion() (string, error) {
+	res, err := back.remoteEthBackend.ClientVersion(context.Background(), &remote.ClientVersionRequest{})
+	if err != nil {
+		return "", err
+	}
+
+	return res.Nodename, nil
+}
+
+func (back *RemoteBackend) NodeInfo() (*remote.NodeInfoReply, error) {
+	return back.remoteEthBackend.NodeInfo(context.Background(), &remote.NodeInfoRequest{})
+}
+
+func (back *RemoteBackend) Peers() (*remote.PeersReply, error) {
+	return back.remoteEthBackend.Peers(context.Background(), &remote.PeersRequest{})
+}
+
+func (back *RemoteBackend) SyncStatus() (*remote.SyncStatusReply, error) {
+	return back.remoteEthBackend.SyncStatus(context.Background(), &remote.SyncStatusRequest{})
+}
+
+func (back *RemoteBackend) TxPoolContent() (*remote.TxPoolContentReply, error) {
+	return back.remoteEthBackend.TxPoolContent(context.Background(), &remote.TxPoolContentRequest{})
+}
+
+func (back *RemoteBackend) TxPoolStatus() (*remote.TxPoolStatusReply, error) {
+	return back.remoteEthBackend.TxPoolStatus(context.Background(), &remote.TxPoolStatusRequest{})
+}
+
+func (back *RemoteBackend) Nonce(address common.Address) (*remote.NonceReply, error) {
+	return back.remoteEthBackend.Nonce(context.Background(), &remote.NonceRequest{Address: address.Bytes()})
+}
+
+func (back *RemoteBackend) PendingBlock() (*remote.PendingBlockReply, error) {
+	return back.remoteEthBackend.PendingBlock(context.Background(), &remote.PendingBlockRequest{})
+}
+
 func (back *RemoteBackend) Subscribe(onNewEvent func(*remote.SubscribeReply)) error {
 	subscription, err := back.remoteEthBackend.Subscribe(context.Background(), &remote.SubscribeRequest{})
 	if err != nil {
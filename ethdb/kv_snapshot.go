@@ -4,10 +4,13 @@ import (
 	"bytes"
 	"context"
 	"errors"
-	"fmt"
+	"sync"
+	"unsafe"
+
 	"github.com/ledgerwatch/turbo-geth/common"
 	"github.com/ledgerwatch/turbo-geth/common/dbutils"
-	"unsafe"
+	"github.com/ledgerwatch/turbo-geth/log"
+	"github.com/willf/bloom"
 )
 
 var (
@@ -15,8 +18,13 @@ var (
 	_ Tx             = &sn2TX{}
 	_ BucketMigrator = &sn2TX{}
 	_ Cursor         = &snCursor2{}
+	_ CursorDupSort  = &snCursor2Dup{}
 )
 
+// snapshotBloomFalsePositiveRate is the share of the point lookups of the keys missing in a snapshot segment,
+// which aren't skipped by its bloom filter. It takes about 10 bits per key
+const snapshotBloomFalsePositiveRate = 0.01
+
 func NewSnapshot2KV() snapshotOpts2 {
 	return snapshotOpts2{}
 }
@@ -30,6 +38,8 @@ type snapshotOpts2 struct {
 	snapshots []snapshotData
 }
 
+// SnapshotDB adds the snapshot of the buckets on top of the snapshots added before, the snapshots of the same
+// bucket are stacked: the later ones hide the keys of the earlier ones, the main database hides all of them
 func (opts snapshotOpts2) SnapshotDB(buckets []string, db KV) snapshotOpts2 {
 	opts.snapshots = append(opts.snapshots, snapshotData{
 		buckets:  buckets,
@@ -44,21 +54,187 @@ func (opts snapshotOpts2) DB(db KV) snapshotOpts2 {
 }
 
 func (opts snapshotOpts2) MustOpen() KV {
-	snapshots := make(map[string]snapshotData)
-	for i, v := range opts.snapshots {
+	snapshots := make(map[string][]*snapshotSegment)
+	for _, v := range opts.snapshots {
+		segment := newSnapshotSegment(v.buckets, v.snapshot)
 		for _, bucket := range v.buckets {
-			snapshots[bucket] = opts.snapshots[i]
+			snapshots[bucket] = append(snapshots[bucket], segment)
 		}
 	}
 	return &SnapshotKV2{
-		snapshots: snapshots,
-		db:        opts.db,
+		snapshots:   snapshots,
+		snapshotsWG: &sync.WaitGroup{},
+		db:          opts.db,
+	}
+}
+
+// snapshotSegment is a read-only snapshot database in the stacks of its buckets. The point lookups skip it when
+// its bloom filter of the bucket keys doesn't contain the key, the filters are built in the background after opening
+type snapshotSegment struct {
+	snapshotData
+
+	bloomsMtx   sync.RWMutex
+	blooms      map[string]*bloom.BloomFilter
+	quit        chan struct{}
+	bloomsBuilt chan struct{}
+	closeOnce   sync.Once
+}
+
+func newSnapshotSegment(buckets []string, snapshot KV) *snapshotSegment {
+	segment := &snapshotSegment{
+		snapshotData: snapshotData{
+			buckets:  buckets,
+			snapshot: snapshot,
+		},
+		blooms:      make(map[string]*bloom.BloomFilter, len(buckets)),
+		quit:        make(chan struct{}),
+		bloomsBuilt: make(chan struct{}),
+	}
+	go segment.buildBlooms()
+	return segment
+}
+
+// mayContain is false only if the segment surely doesn't have the key in the bucket
+func (sn *snapshotSegment) mayContain(bucket string, key []byte) bool {
+	sn.bloomsMtx.RLock()
+	defer sn.bloomsMtx.RUnlock()
+	filter, ok := sn.blooms[bucket]
+	return !ok || filter.Test(key)
+}
+
+func (sn *snapshotSegment) buildBlooms() {
+	defer close(sn.bloomsBuilt)
+	for _, bucket := range sn.buckets {
+		filter, err := sn.buildBloom(bucket)
+		if err != nil {
+			if !errors.Is(err, common.ErrStopped) {
+				log.Warn("Can't build bloom filter of snapshot", "bucket", bucket, "err", err)
+			}
+			return
+		}
+		sn.bloomsMtx.Lock()
+		sn.blooms[bucket] = filter
+		sn.bloomsMtx.Unlock()
 	}
 }
 
+func (sn *snapshotSegment) buildBloom(bucket string) (*bloom.BloomFilter, error) {
+	var filter *bloom.BloomFilter
+	err := sn.snapshot.View(context.Background(), func(tx Tx) error {
+		c := tx.Cursor(bucket)
+		defer c.Close()
+		n, err := c.Count()
+		if err != nil {
+			return err
+		}
+		filter = bloom.NewWithEstimates(uint(n)+1, snapshotBloomFalsePositiveRate)
+		for k, _, err := c.First(); k != nil; k, _, err = c.Next() {
+			if err != nil {
+				return err
+			}
+			select {
+			case <-sn.quit:
+				return common.ErrStopped
+			default:
+			}
+			filter.Add(k)
+		}
+		return nil
+	})
+	return filter, err
+}
+
+// close stops building of the bloom filters and closes the snapshot database
+func (sn *snapshotSegment) close() {
+	sn.closeOnce.Do(func() {
+		close(sn.quit)
+		<-sn.bloomsBuilt
+		sn.snapshot.Close()
+	})
+}
+
 type SnapshotKV2 struct {
-	db        KV
-	snapshots map[string]snapshotData
+	db KV
+
+	mtx sync.RWMutex // protects snapshots and snapshotsWG, which are replaced by UpdateSnapshots and AppendSnapshot
+	// stacks of the snapshot segments of the buckets, from the oldest to the newest
+	snapshots map[string][]*snapshotSegment
+	// transactions which have been started with the current snapshots
+	snapshotsWG *sync.WaitGroup
+}
+
+// UpdateSnapshots replaces the snapshots of the buckets by snapshotKV without blocking readers and writers.
+// tx, if not nil, must be a read-write transaction of s, it is committed together with the replacement: the
+// transactions started before see neither the changes of tx nor snapshotKV, the ones started after see both.
+// The transactions which are already started keep reading the old snapshots. The old snapshots which don't
+// serve any bucket anymore are closed once all those transactions are finished, the returned channel is
+// closed after that. The deletion marks of the keys which snapshotKV doesn't have are removed from the main
+// database together with the replacement, if tx is given.
+func (s *SnapshotKV2) UpdateSnapshots(ctx context.Context, tx Tx, buckets []string, snapshotKV KV) (<-chan struct{}, error) {
+	return s.swapSnapshots(ctx, tx, buckets, snapshotKV, true)
+}
+
+// AppendSnapshot puts snapshotKV on top of the snapshots of the buckets, so an incremental snapshot of the recent
+// data is added without regenerating the previous ones. It is atomic in the same way as UpdateSnapshots
+func (s *SnapshotKV2) AppendSnapshot(ctx context.Context, tx Tx, buckets []string, snapshotKV KV) error {
+	_, err := s.swapSnapshots(ctx, tx, buckets, snapshotKV, false)
+	return err
+}
+
+func (s *SnapshotKV2) swapSnapshots(ctx context.Context, tx Tx, buckets []string, snapshotKV KV, replace bool) (<-chan struct{}, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if tx != nil {
+		if replace {
+			dbTx := tx
+			if snTx, ok := tx.(*sn2TX); ok {
+				dbTx = snTx.dbTX
+			}
+			if err := removeSupersededDeletions(dbTx, buckets, snapshotKV); err != nil {
+				return nil, err
+			}
+		}
+		if err := tx.Commit(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	snapshots := make(map[string][]*snapshotSegment, len(s.snapshots)+len(buckets))
+	replaced := make(map[*snapshotSegment]struct{})
+	for bucket, stack := range s.snapshots {
+		snapshots[bucket] = stack
+		for _, segment := range stack {
+			replaced[segment] = struct{}{}
+		}
+	}
+	segment := newSnapshotSegment(buckets, snapshotKV)
+	for _, bucket := range buckets {
+		if replace {
+			snapshots[bucket] = []*snapshotSegment{segment}
+			continue
+		}
+		// the running transactions keep reading the old stack, so it isn't modified in place
+		stack := snapshots[bucket]
+		snapshots[bucket] = append(stack[:len(stack):len(stack)], segment)
+	}
+	for _, stack := range snapshots {
+		for _, sn := range stack {
+			delete(replaced, sn)
+		}
+	}
+	oldWG := s.snapshotsWG
+	s.snapshots = snapshots
+	s.snapshotsWG = &sync.WaitGroup{}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		oldWG.Wait()
+		for sn := range replaced {
+			sn.close()
+		}
+	}()
+	return done, nil
 }
 
 func (s *SnapshotKV2) View(ctx context.Context, f func(tx Tx) error) error {
@@ -86,20 +262,38 @@ func (s *SnapshotKV2) Update(ctx context.Context, f func(tx Tx) error) error {
 
 func (s *SnapshotKV2) Close() {
 	s.db.Close()
-	for i := range s.snapshots {
-		s.snapshots[i].snapshot.Close()
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+	for _, stack := range s.snapshots {
+		for _, sn := range stack {
+			sn.close()
+		}
 	}
 }
 
 func (s *SnapshotKV2) Begin(ctx context.Context, parent Tx, flags TxFlags) (Tx, error) {
+	// read-only transactions pick the snapshots and the state of the main database together, see UpdateSnapshots.
+	// read-write ones take the lock only after the database lock, which is held by UpdateSnapshots on commit
+	if flags&RO != 0 {
+		s.mtx.RLock()
+		defer s.mtx.RUnlock()
+	}
 	dbTx, err := s.db.Begin(ctx, parent, flags)
 	if err != nil {
 		return nil, err
 	}
+	if flags&RO == 0 {
+		s.mtx.RLock()
+		defer s.mtx.RUnlock()
+	}
+	s.snapshotsWG.Add(1)
 	return &sn2TX{
-		dbTX:      dbTx,
-		snapshots: s.snapshots,
-		snTX:      map[string]Tx{},
+		dbTX:        dbTx,
+		readOnly:    flags&RO != 0,
+		buckets:     s.db.AllBuckets(),
+		snapshots:   s.snapshots,
+		snapshotsWG: s.snapshotsWG,
+		snTX:        map[*snapshotSegment]Tx{},
 	}, nil
 }
 
@@ -110,9 +304,12 @@ func (s *SnapshotKV2) AllBuckets() dbutils.BucketsCfg {
 var ErrUnavailableSnapshot = errors.New("unavailable snapshot")
 
 type sn2TX struct {
-	dbTX      Tx
-	snapshots map[string]snapshotData
-	snTX      map[string]Tx
+	dbTX        Tx
+	readOnly    bool
+	buckets     dbutils.BucketsCfg
+	snapshots   map[string][]*snapshotSegment
+	snapshotsWG *sync.WaitGroup // nil once the transaction is finished
+	snTX        map[*snapshotSegment]Tx
 }
 
 func (s *sn2TX) DropBucket(bucket string) error {
@@ -133,138 +330,151 @@ func (s *sn2TX) ClearBucket(bucket string) error {
 }
 
 func (s *sn2TX) ExistingBuckets() ([]string, error) {
-	panic("implement me")
+	return s.dbTX.(BucketMigrator).ExistingBuckets()
+}
+
+// isDupSort is true if the cursors iterate over the duplicates of the keys in the bucket, then the pairs of
+// the key and the value are merged, otherwise only the keys
+func (s *sn2TX) isDupSort(bucket string) bool {
+	cfg := s.buckets[bucket]
+	return cfg.Flags&dbutils.DupSort != 0 && !cfg.AutoDupSortKeysConversion
 }
 
 func (s *sn2TX) Cursor(bucket string) Cursor {
-	tx, err := s.getSnapshotTX(bucket)
-	if err != nil && !errors.Is(err, ErrUnavailableSnapshot) {
-		panic(err.Error())
-	}
+	stack := s.snapshots[bucket]
 	//process only db buckets
-	if errors.Is(err, ErrUnavailableSnapshot) {
+	if len(stack) == 0 {
 		return s.dbTX.Cursor(bucket)
 	}
-	return &snCursor2{
-		dbCursor: s.dbTX.Cursor(bucket),
-		snCursor: tx.Cursor(bucket),
+	if s.isDupSort(bucket) {
+		return s.CursorDupSort(bucket)
+	}
+	c, err := s.newCursor(bucket, stack, func(tx Tx) Cursor { return tx.Cursor(bucket) })
+	if err != nil {
+		panic(err.Error())
 	}
+	return c
 }
 
 func (s *sn2TX) CursorDupSort(bucket string) CursorDupSort {
-	tx, err := s.getSnapshotTX(bucket)
-	if err != nil && !errors.Is(err, ErrUnavailableSnapshot) {
-		panic(err.Error())
-	}
+	stack := s.snapshots[bucket]
 	//process only db buckets
-	if errors.Is(err, ErrUnavailableSnapshot) {
+	if len(stack) == 0 {
 		return s.dbTX.CursorDupSort(bucket)
 	}
-	dbc := s.dbTX.CursorDupSort(bucket)
-	sncbc := tx.CursorDupSort(bucket)
-	return &snCursor2Dup{
-		snCursor2{
-			dbCursor: dbc,
-			snCursor: sncbc,
-		},
-		dbc,
-		sncbc,
+	c, err := s.newCursor(bucket, stack, func(tx Tx) Cursor { return tx.CursorDupSort(bucket) })
+	if err != nil {
+		panic(err.Error())
 	}
+	c.dupSort = true
+	for _, src := range c.sources {
+		src.dup = src.c.(CursorDupSort)
+	}
+	return &snCursor2Dup{*c}
+}
+
+func (s *sn2TX) newCursor(bucket string, stack []*snapshotSegment, open func(tx Tx) Cursor) (*snCursor2, error) {
+	c := &snCursor2{
+		bucket:   bucket,
+		dbTX:     s.dbTX,
+		sources:  make([]*snCursorSource, 0, len(stack)+1),
+		segments: make([]*snapshotSegment, 0, len(stack)),
+	}
+	c.sources = append(c.sources, &snCursorSource{c: open(s.dbTX), mutable: !s.readOnly})
+	for i := len(stack) - 1; i >= 0; i-- {
+		tx, err := s.getSnapshotTX(stack[i])
+		if err != nil {
+			c.Close()
+			return nil, err
+		}
+		c.sources = append(c.sources, &snCursorSource{c: open(tx)})
+		c.segments = append(c.segments, stack[i])
+	}
+	return c, nil
 }
 
 func (s *sn2TX) CursorDupFixed(bucket string) CursorDupFixed {
 	panic("implement me")
 }
 
+// GetOne reads the key from the main database, then from the snapshots of the bucket, from the newest to the oldest
 func (s *sn2TX) GetOne(bucket string, key []byte) (val []byte, err error) {
 	v, err := s.dbTX.GetOne(bucket, key)
 	if err != nil {
 		return nil, err
 	}
-	if len(v) == 0 {
-		snTx, innerErr := s.getSnapshotTX(bucket)
-		if innerErr != nil && !errors.Is(innerErr, ErrUnavailableSnapshot) {
-			return nil, innerErr
+	stack := s.snapshots[bucket]
+	for i := len(stack) - 1; i >= 0 && len(v) == 0; i-- {
+		if !stack[i].mayContain(bucket, key) {
+			continue
 		}
-		//process only db buckets
-		if errors.Is(innerErr, ErrUnavailableSnapshot) {
-			return v, nil
+		snTx, innerErr := s.getSnapshotTX(stack[i])
+		if innerErr != nil {
+			return nil, innerErr
 		}
 		v, err = snTx.GetOne(bucket, key)
 		if err != nil {
 			return nil, err
 		}
-		if bytes.Equal(v, DeletedValue) {
-			return nil, nil
-		}
-		return v, nil
+	}
+	if bytes.Equal(v, DeletedValue) {
+		return nil, nil
 	}
 	return v, nil
 }
-func (s *sn2TX) getSnapshotTX(bucket string) (Tx, error) {
-	tx, ok := s.snTX[bucket]
+
+func (s *sn2TX) getSnapshotTX(sn *snapshotSegment) (Tx, error) {
+	tx, ok := s.snTX[sn]
 	if ok {
 		return tx, nil
 	}
-	sn, ok := s.snapshots[bucket]
-	if !ok {
-		return nil, fmt.Errorf("%s  %w", bucket, ErrUnavailableSnapshot)
-	}
 	var err error
 	tx, err = sn.snapshot.Begin(context.TODO(), nil, RO)
 	if err != nil {
 		return nil, err
 	}
 
-	s.snTX[bucket] = tx
+	s.snTX[sn] = tx
 	return tx, nil
 }
 
 func (s *sn2TX) HasOne(bucket string, key []byte) (bool, error) {
-	v, err := s.dbTX.HasOne(bucket, key)
+	if len(s.snapshots[bucket]) == 0 {
+		return s.dbTX.HasOne(bucket, key)
+	}
+	v, err := s.GetOne(bucket, key)
 	if err != nil {
 		return false, err
 	}
-	if !v {
-		snTx, err := s.getSnapshotTX(bucket)
-		if err != nil && !errors.Is(err, ErrUnavailableSnapshot) {
-			return false, err
-		}
-		//process only db buckets
-		if errors.Is(err, ErrUnavailableSnapshot) {
-			return v, nil
-		}
-
-		v, err := snTx.GetOne(bucket, key)
-		if err != nil {
-			return false, err
-		}
-		if bytes.Equal(v, DeletedValue) {
-			return false, nil
-		}
-
-		return true, nil
-	}
-	return v, nil
+	return v != nil, nil
 }
 
 func (s *sn2TX) Commit(ctx context.Context) error {
-	for i := range s.snTX {
-		defer s.snTX[i].Rollback()
-	}
+	defer s.releaseSnapshots()
 	return s.dbTX.Commit(ctx)
 }
 
 func (s *sn2TX) Rollback() {
-	for i := range s.snTX {
-		defer s.snTX[i].Rollback()
-	}
+	defer s.releaseSnapshots()
 	s.dbTX.Rollback()
 
 }
 
+// releaseSnapshots finishes the transactions of the snapshots and lets UpdateSnapshots close the snapshots
+// replaced after the transaction had been started. Once they are released, they may be closed at any moment
+func (s *sn2TX) releaseSnapshots() {
+	for sn, tx := range s.snTX {
+		tx.Rollback()
+		delete(s.snTX, sn)
+	}
+	if s.snapshotsWG != nil {
+		s.snapshotsWG.Done()
+		s.snapshotsWG = nil
+	}
+}
+
 func (s *sn2TX) BucketSize(name string) (uint64, error) {
-	panic("implement me")
+	return s.dbTX.BucketSize(name)
 }
 
 func (s *sn2TX) Comparator(bucket string) dbutils.CmpFunc {
@@ -272,260 +482,400 @@ func (s *sn2TX) Comparator(bucket string) dbutils.CmpFunc {
 }
 
 func (s *sn2TX) Cmp(bucket string, a, b []byte) int {
-	panic("implement me")
+	return s.dbTX.Cmp(bucket, a, b)
 }
 
 func (s *sn2TX) DCmp(bucket string, a, b []byte) int {
-	panic("implement me")
+	return s.dbTX.DCmp(bucket, a, b)
 }
 
 func (s *sn2TX) Sequence(bucket string, amount uint64) (uint64, error) {
-	panic("implement me")
+	return s.dbTX.Sequence(bucket, amount)
 }
 
 func (s *sn2TX) CHandle() unsafe.Pointer {
 	return s.dbTX.CHandle()
 }
 
+// DeletedValue in the main database or in a snapshot hides the key from the snapshots below it
 var DeletedValue = []byte("it is deleted value")
 
+// DeletionMarker is implemented by the transactions of SnapshotKV2
+type DeletionMarker interface {
+	// MarkDeleted hides the key of the bucket from the snapshots, including the ones which are added later
+	MarkDeleted(bucket string, k []byte) error
+}
+
+func (s *sn2TX) MarkDeleted(bucket string, k []byte) error {
+	c := s.dbTX.Cursor(bucket)
+	defer c.Close()
+	return markDeleted(s.dbTX, c, bucket, k)
+}
+
+// markDeleted puts DeletedValue of the key into the main database by the cursor c, and remembers the key in
+// dbutils.SnapshotDeletedKeysBucket, so the mark is removed once the snapshots don't have the key anymore
+func markDeleted(dbTx Tx, c Cursor, bucket string, k []byte) error {
+	if err := c.Put(k, DeletedValue); err != nil {
+		return err
+	}
+	deleted := dbTx.Cursor(dbutils.SnapshotDeletedKeysBucket)
+	defer deleted.Close()
+	return deleted.Put(snapshotDeletedKey(bucket, k), []byte{})
+}
+
+func snapshotDeletedKey(bucket string, k []byte) []byte {
+	key := make([]byte, len(bucket)+1+len(k))
+	copy(key, bucket)
+	copy(key[len(bucket)+1:], k)
+	return key
+}
+
+// removeSupersededDeletions removes DeletedValue of the keys of the buckets, which snapshotKV doesn't have,
+// from the main database. It is called when snapshotKV replaces the snapshots of the buckets
+func removeSupersededDeletions(dbTx Tx, buckets []string, snapshotKV KV) error {
+	deleted := dbTx.Cursor(dbutils.SnapshotDeletedKeysBucket)
+	defer deleted.Close()
+	return snapshotKV.View(context.Background(), func(snTx Tx) error {
+		for _, bucket := range buckets {
+			prefix := snapshotDeletedKey(bucket, nil)
+			for k, _, err := deleted.Seek(prefix); k != nil; k, _, err = deleted.Next() {
+				if err != nil {
+					return err
+				}
+				if !bytes.HasPrefix(k, prefix) {
+					break
+				}
+				key := common.CopyBytes(k[len(prefix):])
+				v, err := dbTx.GetOne(bucket, key)
+				if err != nil {
+					return err
+				}
+				if bytes.Equal(v, DeletedValue) {
+					inSnapshot, err := snTx.HasOne(bucket, key)
+					if err != nil {
+						return err
+					}
+					if inSnapshot {
+						continue // still hides the key of the new snapshot
+					}
+					c := dbTx.Cursor(bucket)
+					err = c.Delete(key, nil)
+					c.Close()
+					if err != nil {
+						return err
+					}
+				}
+				// the mark is removed or overwritten by the new value, the next entry becomes the current one
+				if err = deleted.DeleteCurrent(); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// snCursorSource is the cursor of the main database or of a snapshot segment with the entry it is positioned at
+type snCursorSource struct {
+	c   Cursor
+	dup CursorDupSort // the same cursor, set for the DupSort buckets
+	// mutable sources are written by the transaction, so they are positioned again before each step
+	mutable bool
+	k, v    []byte
+}
+
+// snCursor2 is a k-way merge of the cursors of the main database and of the snapshot segments of the bucket.
+// The entries are the keys, or the pairs of the key and the value for the DupSort buckets. When several sources
+// have the same entry, the main database wins, then the newest segment. The entries with DeletedValue are skipped.
+// Writes go to the main database only, the cursor keeps its position after them.
 type snCursor2 struct {
-	dbCursor Cursor
-	snCursor Cursor
+	bucket   string
+	dbTX     Tx                // transaction of the main database
+	sources  []*snCursorSource // the main database, then the segments from the newest to the oldest
+	segments []*snapshotSegment
+	dupSort  bool
+	prefix   []byte
 
-	currentKey []byte
+	// direction the sources are positioned in relative to the current entry: 1 after it, -1 before it, 0 unknown
+	dir          int
+	hasCurrent   bool
+	currentKey   []byte
+	currentValue []byte
 }
 
 func (s *snCursor2) Prefix(v []byte) Cursor {
-	s.dbCursor.Prefix(v)
-	s.snCursor.Prefix(v)
+	s.prefix = v
+	for _, src := range s.sources {
+		src.c.Prefix(v)
+	}
 	return s
 }
 
 func (s *snCursor2) Prefetch(v uint) Cursor {
-	panic("implement me")
+	for _, src := range s.sources {
+		src.c.Prefetch(v)
+	}
+	return s
 }
 
-func (s *snCursor2) First() ([]byte, []byte, error) {
-	var err error
-	lastDBKey, lastDBVal, err := s.dbCursor.First()
-	if err != nil {
-		return nil, nil, err
+func (s *snCursor2) cmp(k1, v1, k2, v2 []byte) int {
+	if c := bytes.Compare(k1, k2); c != 0 || !s.dupSort {
+		return c
 	}
+	return bytes.Compare(v1, v2)
+}
 
-	lastSNDBKey, lastSNDBVal, err := s.snCursor.First()
-	if err != nil {
-		return nil, nil, err
-	}
-	cmp, br := common.KeyCmp(lastDBKey, lastSNDBKey)
-	if br {
-		return nil, nil, nil
-	}
+func (s *snCursor2) atCurrent(src *snCursorSource) bool {
+	return src.k != nil && s.cmp(src.k, src.v, s.currentKey, s.currentValue) == 0
+}
 
-	if cmp <= 0 {
-		s.saveCurrent(lastDBKey)
-		return lastDBKey, lastDBVal, nil
-	}
-	s.saveCurrent(lastSNDBKey)
-	return lastSNDBKey, lastSNDBVal, nil
+func (s *snCursor2) setCurrent(k, v []byte) {
+	s.hasCurrent = true
+	s.currentKey = common.CopyBytes(k)
+	s.currentValue = common.CopyBytes(v)
 }
 
-func (s *snCursor2) Seek(seek []byte) ([]byte, []byte, error) {
-	dbKey, dbVal, err := s.dbCursor.Seek(seek)
-	if err != nil && !errors.Is(err, ErrKeyNotFound) {
-		return nil, nil, err
+// seek positions src at the first entry not less than k, and not less than v among the duplicates of k if v isn't nil
+func (s *snCursor2) seek(src *snCursorSource, k, v []byte) (err error) {
+	if !s.dupSort || v == nil {
+		src.k, src.v, err = src.c.Seek(k)
+		return err
 	}
-	sndbKey, sndbVal, err := s.snCursor.Seek(seek)
-	if err != nil && !errors.Is(err, ErrKeyNotFound) {
-		return nil, nil, err
+	if src.k, src.v, err = src.dup.SeekBothRange(k, v); err != nil || src.k != nil {
+		return err
+	}
+	// all the duplicates of k are less than v
+	if src.k, src.v, err = src.c.Seek(k); err != nil || !bytes.Equal(src.k, k) {
+		return err
 	}
+	src.k, src.v, err = src.dup.NextNoDup()
+	return err
+}
 
-	if bytes.Equal(dbKey, seek) && dbVal != nil {
-		return dbKey, dbVal, err
+// reposition positions the sources at the entries next to the current one in the given direction
+func (s *snCursor2) reposition(src *snCursorSource, forward bool) (err error) {
+	if err = s.seek(src, s.currentKey, s.currentValue); err != nil {
+		return err
 	}
-	if bytes.Equal(sndbKey, seek) && sndbVal != nil {
-		return sndbKey, sndbVal, err
+	switch {
+	case forward && s.atCurrent(src):
+		src.k, src.v, err = src.c.Next()
+	case !forward && src.k == nil:
+		src.k, src.v, err = src.c.Last()
+	case !forward:
+		src.k, src.v, err = src.c.Prev()
 	}
-	cmp, _ := common.KeyCmp(dbKey, sndbKey)
-	if cmp <= 0 {
-		s.saveCurrent(dbKey)
-		return dbKey, dbVal, nil
+	return err
+}
+
+// best returns the source with the next entry in the given direction, nil if there are no more entries
+func (s *snCursor2) best(forward bool) *snCursorSource {
+	var best *snCursorSource
+	for _, src := range s.sources {
+		if src.k == nil || (s.prefix != nil && !bytes.HasPrefix(src.k, s.prefix)) {
+			continue
+		}
+		if best == nil {
+			best = src
+			continue
+		}
+		cmp := s.cmp(src.k, src.v, best.k, best.v)
+		if (forward && cmp < 0) || (!forward && cmp > 0) {
+			best = src
+		}
 	}
-	s.saveCurrent(sndbKey)
-	return sndbKey, sndbVal, nil
+	return best
 }
 
-func (s *snCursor2) SeekExact(key []byte) ([]byte, []byte, error) {
-	k, v, err := s.dbCursor.SeekExact(key)
-	if err != nil {
-		return nil, nil, err
+func direction(forward bool) int {
+	if forward {
+		return 1
 	}
-	if bytes.Equal(v, DeletedValue) {
+	return -1
+}
+
+// pick takes the first entry from the sources just positioned by First, Last or Seek
+func (s *snCursor2) pick(forward bool) ([]byte, []byte, error) {
+	s.dir = direction(forward)
+	best := s.best(forward)
+	if best == nil {
+		s.hasCurrent = false
 		return nil, nil, nil
 	}
-	if v == nil {
-		k, v, err = s.snCursor.SeekExact(key)
-		s.saveCurrent(k)
+	s.setCurrent(best.k, best.v)
+	if bytes.Equal(best.v, DeletedValue) {
+		k, v, err := s.move(forward)
+		if err == nil && k == nil {
+			s.hasCurrent = false
+		}
 		return k, v, err
 	}
-	s.saveCurrent(k)
-	return k, v, err
+	return best.k, best.v, nil
 }
 
-func (s *snCursor2) iteration(dbNextElement func() ([]byte, []byte, error), sndbNextElement func() ([]byte, []byte, error), cmpFunc func(kdb, ksndb []byte) (int, bool)) ([]byte, []byte, error) {
-	var err error
-	//current returns error on empty bucket
-	lastDBKey, lastDBVal, err := s.dbCursor.Current()
-	if err != nil {
-		var innerErr error
-		lastDBKey, lastDBVal, innerErr = dbNextElement()
-		if innerErr != nil {
-			return nil, nil, fmt.Errorf("get current from db %w inner %v", err, innerErr)
+// move steps to the next entry in the given direction. At the end the cursor stays at the last entry
+func (s *snCursor2) move(forward bool) ([]byte, []byte, error) {
+	if !s.hasCurrent {
+		if forward {
+			return s.First()
 		}
+		return s.Last()
 	}
-
-	lastSNDBKey, lastSNDBVal, err := s.snCursor.Current()
-	if err != nil {
-		return nil, nil, err
-	}
-
-	cmp, br := cmpFunc(lastDBKey, lastSNDBKey)
-	if br {
-		return nil, nil, nil
+	key, value := s.currentKey, s.currentValue
+	for {
+		for _, src := range s.sources {
+			var err error
+			switch {
+			case s.dir != direction(forward) || src.mutable:
+				err = s.reposition(src, forward)
+			case s.atCurrent(src) && forward:
+				src.k, src.v, err = src.c.Next()
+			case s.atCurrent(src):
+				src.k, src.v, err = src.c.Prev()
+			}
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+		s.dir = direction(forward)
+		best := s.best(forward)
+		if best == nil {
+			// the deleted entries passed on the way don't count as the last one
+			if s.cmp(key, value, s.currentKey, s.currentValue) != 0 {
+				s.setCurrent(key, value)
+				s.dir = 0
+			}
+			return nil, nil, nil
+		}
+		s.setCurrent(best.k, best.v)
+		if !bytes.Equal(best.v, DeletedValue) {
+			return best.k, best.v, nil
+		}
 	}
+}
 
-	//todo Seek fastpath
-	if cmp > 0 {
-		lastSNDBKey, lastSNDBVal, err = sndbNextElement()
-		if err != nil {
+func (s *snCursor2) First() ([]byte, []byte, error) {
+	for _, src := range s.sources {
+		var err error
+		if src.k, src.v, err = src.c.First(); err != nil {
 			return nil, nil, err
 		}
-		//todo
-		if currentKeyCmp, _ := common.KeyCmp(s.currentKey, lastDBKey); len(lastSNDBKey) == 0 && currentKeyCmp >= 0 && len(s.currentKey) > 0 {
-			lastDBKey, lastDBVal, err = dbNextElement()
-		}
-		if err != nil {
+	}
+	return s.pick(true)
+}
+
+func (s *snCursor2) Seek(seek []byte) ([]byte, []byte, error) {
+	for _, src := range s.sources {
+		if err := s.seek(src, seek, nil); err != nil {
 			return nil, nil, err
 		}
 	}
+	return s.pick(true)
+}
 
-	//current receives last acceptable key. If it is empty
-	if cmp < 0 {
-		lastDBKey, lastDBVal, err = dbNextElement()
-		if err != nil {
+// SeekExact looks the key up in the sources one by one, skipping the segments which surely don't have it
+func (s *snCursor2) SeekExact(key []byte) ([]byte, []byte, error) {
+	if s.dupSort {
+		k, v, err := s.Seek(key)
+		if err != nil || !bytes.Equal(k, key) {
 			return nil, nil, err
 		}
-		if currentKeyCmp, _ := common.KeyCmp(s.currentKey, lastSNDBKey); len(lastDBKey) == 0 && currentKeyCmp >= 0 && len(s.currentKey) > 0 {
-			lastSNDBKey, lastSNDBVal, err = sndbNextElement()
+		return k, v, nil
+	}
+	s.dir = 0
+	for i, src := range s.sources {
+		if i > 0 && !s.segments[i-1].mayContain(s.bucket, key) {
+			continue
 		}
+		k, v, err := src.c.SeekExact(key)
 		if err != nil {
 			return nil, nil, err
 		}
-	}
-	if cmp == 0 {
-		lastDBKey, lastDBVal, err = dbNextElement()
-		if err != nil {
-			return nil, nil, err
+		if v == nil {
+			continue
 		}
-		lastSNDBKey, lastSNDBVal, err = sndbNextElement()
-		if err != nil {
-			return nil, nil, err
+		s.setCurrent(k, v)
+		if bytes.Equal(v, DeletedValue) {
+			return nil, nil, nil
 		}
+		return k, v, nil
 	}
-
-	cmp, br = cmpFunc(lastDBKey, lastSNDBKey)
-	if br {
-		return nil, nil, nil
-	}
-	if cmp <= 0 {
-		return lastDBKey, lastDBVal, nil
-	}
-
-	return lastSNDBKey, lastSNDBVal, nil
+	return nil, nil, nil
 }
 
 func (s *snCursor2) Next() ([]byte, []byte, error) {
-	k, v, err := s.iteration(s.dbCursor.Next, s.snCursor.Next, common.KeyCmp) //f(s.dbCursor.Next, s.snCursor.Next)
-	if err != nil {
-		return nil, nil, err
-	}
-	for bytes.Equal(v, DeletedValue) {
-		k, v, err = s.iteration(s.dbCursor.Next, s.snCursor.Next, common.KeyCmp) // f(s.dbCursor.Next, s.snCursor.Next)
-		if err != nil {
-			return nil, nil, err
-		}
-
-	}
-	s.saveCurrent(k)
-	return k, v, nil
+	return s.move(true)
 }
 
 func (s *snCursor2) Prev() ([]byte, []byte, error) {
-	k, v, err := s.iteration(s.dbCursor.Prev, s.snCursor.Prev, func(kdb, ksndb []byte) (int, bool) {
-		cmp, br := KeyCmpBackward(kdb, ksndb)
-		return -1 * cmp, br
-	})
-	if err != nil {
-		return nil, nil, err
-	}
-	for cmp, _ := KeyCmpBackward(k, s.currentKey); bytes.Equal(v, DeletedValue) || cmp >= 0; cmp, _ = KeyCmpBackward(k, s.currentKey) {
-		k, v, err = s.iteration(s.dbCursor.Prev, s.snCursor.Prev, func(kdb, ksndb []byte) (int, bool) {
-			cmp, br := KeyCmpBackward(kdb, ksndb)
-			return -1 * cmp, br
-		})
-		if err != nil {
-			return nil, nil, err
-		}
-	}
-	s.saveCurrent(k)
-	return k, v, nil
+	return s.move(false)
 }
 
 func (s *snCursor2) Last() ([]byte, []byte, error) {
-	var err error
-	lastSNDBKey, lastSNDBVal, err := s.snCursor.Last()
-	if err != nil {
-		return nil, nil, err
-	}
-	lastDBKey, lastDBVal, err := s.dbCursor.Last()
-	if err != nil {
-		return nil, nil, err
-	}
-	cmp, br := KeyCmpBackward(lastDBKey, lastSNDBKey)
-	if br {
-		return nil, nil, nil
-	}
-
-	if cmp >= 0 {
-		s.saveCurrent(lastDBKey)
-		return lastDBKey, lastDBVal, nil
+	for _, src := range s.sources {
+		var err error
+		if src.k, src.v, err = src.c.Last(); err != nil {
+			return nil, nil, err
+		}
 	}
-	s.saveCurrent(lastSNDBKey)
-	return lastSNDBKey, lastSNDBVal, nil
+	return s.pick(false)
 }
 
 func (s *snCursor2) Current() ([]byte, []byte, error) {
-	k, v, err := s.dbCursor.Current()
-	if bytes.Equal(k, s.currentKey) {
-		return k, v, err
+	if !s.hasCurrent {
+		return nil, nil, nil
 	}
-	return s.snCursor.Current()
+	return s.currentKey, s.currentValue, nil
 }
 
 func (s *snCursor2) Put(k, v []byte) error {
-	return s.dbCursor.Put(k, v)
+	return s.sources[0].c.Put(k, v)
 }
 
 func (s *snCursor2) Append(k []byte, v []byte) error {
-	return s.dbCursor.Append(k, v)
+	return s.sources[0].c.Append(k, v)
 }
 
+// Delete puts DeletedValue to hide the key from the snapshots which have it, otherwise the key is deleted from the
+// main database. The duplicates of the DupSort buckets are deleted from the main database only
 func (s *snCursor2) Delete(k, v []byte) error {
-	return s.dbCursor.Put(k, DeletedValue)
+	if s.dupSort {
+		return s.sources[0].c.Delete(k, v)
+	}
+	inSnapshots, err := s.inSnapshots(k)
+	if err != nil {
+		return err
+	}
+	if !inSnapshots {
+		return s.sources[0].c.Delete(k, nil)
+	}
+	return markDeleted(s.dbTX, s.sources[0].c, s.bucket, k)
+}
+
+// inSnapshots is true if the newest snapshot segment which has the key holds its value, not DeletedValue
+func (s *snCursor2) inSnapshots(k []byte) (bool, error) {
+	// the segments are positioned at the key, the next step positions all the sources again
+	s.dir = 0
+	for i, src := range s.sources[1:] {
+		if !s.segments[i].mayContain(s.bucket, k) {
+			continue
+		}
+		_, v, err := src.c.SeekExact(k)
+		if err != nil {
+			return false, err
+		}
+		if v != nil {
+			return !bytes.Equal(v, DeletedValue), nil
+		}
+	}
+	return false, nil
 }
 
 func (s *snCursor2) DeleteCurrent() error {
-	panic("implement me")
+	if !s.hasCurrent {
+		return nil
+	}
+	return s.Delete(s.currentKey, s.currentValue)
 }
 
 func (s *snCursor2) Reserve(k []byte, n int) ([]byte, error) {
@@ -541,85 +891,158 @@ func (s *snCursor2) Count() (uint64, error) {
 }
 
 func (s *snCursor2) Close() {
-	s.dbCursor.Close()
-	s.snCursor.Close()
+	for _, src := range s.sources {
+		src.c.Close()
+	}
 }
 
 type snCursor2Dup struct {
 	snCursor2
-	dbCursorDup   CursorDupSort
-	sndbCursorDup CursorDupSort
 }
 
 func (c *snCursor2Dup) SeekBothExact(key, value []byte) ([]byte, []byte, error) {
-	k, v, err := c.dbCursorDup.SeekBothExact(key, value)
-	if err != nil {
-		return nil, nil, err
-	}
-	if v == nil {
-		k, v, err = c.sndbCursorDup.SeekBothExact(key, value)
-		c.saveCurrent(k)
-		return k, v, err
+	c.dir = 0
+	for i, src := range c.sources {
+		if i > 0 && !c.segments[i-1].mayContain(c.bucket, key) {
+			continue
+		}
+		k, v, err := src.dup.SeekBothExact(key, value)
+		if err != nil {
+			return nil, nil, err
+		}
+		if k == nil {
+			continue
+		}
+		c.setCurrent(k, v)
+		if bytes.Equal(v, DeletedValue) {
+			return nil, nil, nil
+		}
+		return k, v, nil
 	}
-	c.saveCurrent(k)
-	return k, v, err
-
+	return nil, nil, nil
 }
 
 func (c *snCursor2Dup) SeekBothRange(key, value []byte) ([]byte, []byte, error) {
-	dbKey, dbVal, err := c.dbCursorDup.SeekBothRange(key, value)
-	if err != nil {
-		return nil, nil, err
+	for _, src := range c.sources {
+		if err := c.seek(src, key, value); err != nil {
+			return nil, nil, err
+		}
 	}
-	snDBKey, snDBVal, err := c.sndbCursorDup.SeekBothRange(key, value)
-	if err != nil {
+	k, v, err := c.pick(true)
+	if err != nil || !bytes.Equal(k, key) {
 		return nil, nil, err
 	}
-
-	//todo Is it correct comparison
-	cmp, br := common.KeyCmp(dbKey, snDBKey)
-	if br {
-		return nil, nil, nil
-	}
-	if cmp >= 0 {
-		c.saveCurrent(dbKey)
-		return dbKey, dbVal, nil
-	}
-	return snDBKey, snDBVal, nil
+	return k, v, nil
 }
 
 func (c *snCursor2Dup) FirstDup() ([]byte, error) {
-	panic("implement me")
+	if !c.hasCurrent {
+		return nil, nil
+	}
+	_, v, err := c.SeekBothRange(common.CopyBytes(c.currentKey), nil)
+	return v, err
 }
 
 func (c *snCursor2Dup) NextDup() ([]byte, []byte, error) {
-	panic("implement me")
+	if !c.hasCurrent {
+		return nil, nil, nil
+	}
+	key, value := c.currentKey, c.currentValue
+	k, v, err := c.move(true)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !bytes.Equal(k, key) {
+		// no more duplicates, stay at the last one
+		c.setCurrent(key, value)
+		c.dir = 0
+		return nil, nil, nil
+	}
+	return k, v, nil
 }
 
 func (c *snCursor2Dup) NextNoDup() ([]byte, []byte, error) {
-	panic("implement me")
+	if !c.hasCurrent {
+		return c.First()
+	}
+	for _, src := range c.sources {
+		var err error
+		if src.k, src.v, err = src.c.Seek(c.currentKey); err != nil {
+			return nil, nil, err
+		}
+		if bytes.Equal(src.k, c.currentKey) {
+			if src.k, src.v, err = src.dup.NextNoDup(); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+	return c.pick(true)
 }
 
+// LastDup positions the cursor at the last duplicate of k, or of the current key if k is nil
 func (c *snCursor2Dup) LastDup(k []byte) ([]byte, error) {
-	panic("implement me")
+	if k == nil {
+		if !c.hasCurrent {
+			return nil, nil
+		}
+		k = common.CopyBytes(c.currentKey)
+	}
+	c.dir = 0
+	var last []byte
+	for _, src := range c.sources {
+		key, _, err := src.c.SeekExact(k)
+		if err != nil {
+			return nil, err
+		}
+		if key == nil {
+			continue
+		}
+		v, err := src.dup.LastDup(k)
+		if err != nil {
+			return nil, err
+		}
+		if v != nil && (last == nil || bytes.Compare(v, last) > 0) {
+			last = v
+		}
+	}
+	if last == nil {
+		return nil, nil
+	}
+	c.setCurrent(k, last)
+	return last, nil
 }
 
+// CountDuplicates counts the distinct duplicates of the current key over all the sources
 func (c *snCursor2Dup) CountDuplicates() (uint64, error) {
-	panic("implement me")
+	if !c.hasCurrent {
+		return 0, nil
+	}
+	key, value := c.currentKey, c.currentValue
+	var count uint64
+	v, err := c.FirstDup()
+	for ; v != nil && err == nil; _, v, err = c.NextDup() {
+		count++
+	}
+	c.setCurrent(key, value)
+	c.dir = 0
+	return count, err
 }
 
+// DeleteCurrentDuplicates deletes the duplicates of the current key from the main database only
 func (c *snCursor2Dup) DeleteCurrentDuplicates() error {
-	panic("implement me")
+	if !c.hasCurrent {
+		return nil
+	}
+	main := c.sources[0].dup
+	k, _, err := main.SeekExact(c.currentKey)
+	if err != nil || k == nil {
+		return err
+	}
+	return main.DeleteCurrentDuplicates()
 }
 
 func (c *snCursor2Dup) AppendDup(key, value []byte) error {
-	panic("implement me")
-}
-
-func (s *snCursor2) saveCurrent(k []byte) {
-	if k != nil {
-		s.currentKey = common.CopyBytes(k)
-	}
+	return c.sources[0].dup.AppendDup(key, value)
 }
 
 func KeyCmpBackward(key1, key2 []byte) (int, bool) {
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"github.com/ledgerwatch/turbo-geth/common"
 	"github.com/ledgerwatch/turbo-geth/common/dbutils"
+	"github.com/stretchr/testify/require"
 	"testing"
 )
 
@@ -1281,6 +1282,460 @@ func TestSnapshot2WritableTxNextAndPrevWithDeleteAndPutKeys(t *testing.T) {
 
 }
 
+func TestSnapshot2UpdateSnapshots(t *testing.T) {
+	newSnapshot := func(val byte) KV {
+		sn := NewLMDB().WithBucketsConfig(func(defaultBuckets dbutils.BucketsCfg) dbutils.BucketsCfg {
+			return dbutils.BucketsCfg{
+				dbutils.HeaderPrefix: dbutils.BucketConfigItem{},
+			}
+		}).InMem().MustOpen()
+		err := sn.Update(context.Background(), func(tx Tx) error {
+			return tx.Cursor(dbutils.HeaderPrefix).Put(dbutils.HeaderKey(1, common.Hash{1}), []byte{val})
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return sn
+	}
+	sn1, sn2 := newSnapshot(1), newSnapshot(2)
+	mainDB := NewLMDB().InMem().MustOpen()
+	kv := NewSnapshot2KV().DB(mainDB).SnapshotDB([]string{dbutils.HeaderPrefix}, sn1).MustOpen().(*SnapshotKV2)
+	defer kv.Close()
+
+	checkHeader := func(tx Tx, expected byte) {
+		t.Helper()
+		v, err := tx.GetOne(dbutils.HeaderPrefix, dbutils.HeaderKey(1, common.Hash{1}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(v, []byte{expected}) {
+			t.Fatalf("expected %x, got %x", expected, v)
+		}
+	}
+
+	oldTx, err := kv.Begin(context.Background(), nil, RO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	done, err := kv.UpdateSnapshots(context.Background(), nil, []string{dbutils.HeaderPrefix}, sn2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newTx, err := kv.Begin(context.Background(), nil, RO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkHeader(newTx, 2)
+	newTx.Rollback()
+	// the transaction started before the update keeps reading the old snapshot
+	checkHeader(oldTx, 1)
+
+	select {
+	case <-done:
+		t.Fatal("the old snapshot is closed while it is in use")
+	default:
+	}
+	oldTx.Rollback()
+	<-done
+	if _, err = sn1.Begin(context.Background(), nil, RO); err == nil {
+		t.Fatal("the old snapshot is expected to be closed")
+	}
+	err = kv.View(context.Background(), func(tx Tx) error {
+		checkHeader(tx, 2)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the changes of the write transaction become visible together with the new snapshot
+	rwTx, err := kv.Begin(context.Background(), nil, RW)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rwTx.Rollback()
+	if err = rwTx.Cursor(dbutils.HeaderPrefix).Put(dbutils.HeaderKey(2, common.Hash{2}), []byte{22}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = kv.UpdateSnapshots(context.Background(), rwTx, []string{dbutils.HeaderPrefix}, newSnapshot(3)); err != nil {
+		t.Fatal(err)
+	}
+	err = kv.View(context.Background(), func(tx Tx) error {
+		checkHeader(tx, 3)
+		v, innerErr := tx.GetOne(dbutils.HeaderPrefix, dbutils.HeaderKey(2, common.Hash{2}))
+		if innerErr != nil {
+			return innerErr
+		}
+		if !bytes.Equal(v, []byte{22}) {
+			t.Fatalf("expected the value written together with the update, got %x", v)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSnapshot2DeletionMarks(t *testing.T) {
+	sn1, err := GenStateData([]KvData{
+		{K: []byte{1}, V: []byte{1}},
+		{K: []byte{2}, V: []byte{2}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mainDB := NewLMDB().InMem().MustOpen()
+	kv := NewSnapshot2KV().DB(mainDB).SnapshotDB([]string{dbutils.PlainStateBucket}, sn1).MustOpen().(*SnapshotKV2)
+	defer kv.Close()
+
+	mainContents := func(bucket string) []KvData {
+		t.Helper()
+		var res []KvData
+		if err := mainDB.View(context.Background(), func(tx Tx) error {
+			return Walk(tx.Cursor(bucket), nil, 0, func(k, v []byte) (bool, error) {
+				res = append(res, KvData{K: common.CopyBytes(k), V: common.CopyBytes(v)})
+				return true, nil
+			})
+		}); err != nil {
+			t.Fatal(err)
+		}
+		return res
+	}
+
+	err = kv.Update(context.Background(), func(tx Tx) error {
+		c := tx.Cursor(dbutils.PlainStateBucket)
+		defer c.Close()
+		if innerErr := c.Put([]byte{3}, []byte{3}); innerErr != nil {
+			return innerErr
+		}
+		// only the keys of the snapshot get the deletion marks
+		for _, k := range [][]byte{{1}, {2}, {3}} {
+			if innerErr := c.Delete(k, nil); innerErr != nil {
+				return innerErr
+			}
+		}
+		// the key deleted from the snapshot is written again
+		if innerErr := c.Put([]byte{2}, []byte{22}); innerErr != nil {
+			return innerErr
+		}
+		// the key of the next snapshot, which is deleted after its block
+		return tx.(DeletionMarker).MarkDeleted(dbutils.PlainStateBucket, []byte{4})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	require.Equal(t, []KvData{{K: []byte{1}, V: DeletedValue}, {K: []byte{2}, V: []byte{22}}, {K: []byte{4}, V: DeletedValue}}, mainContents(dbutils.PlainStateBucket))
+	require.Len(t, mainContents(dbutils.SnapshotDeletedKeysBucket), 3)
+
+	sn2, err := GenStateData([]KvData{
+		{K: []byte{4}, V: []byte{4}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx, err := kv.Begin(context.Background(), nil, RW)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+	if _, err = kv.UpdateSnapshots(context.Background(), tx, []string{dbutils.PlainStateBucket}, sn2); err != nil {
+		t.Fatal(err)
+	}
+	// the mark of the key missing in the new snapshot is removed, the one of the key it has is kept
+	require.Equal(t, []KvData{{K: []byte{2}, V: []byte{22}}, {K: []byte{4}, V: DeletedValue}}, mainContents(dbutils.PlainStateBucket))
+	marks := mainContents(dbutils.SnapshotDeletedKeysBucket)
+	require.Len(t, marks, 1)
+	require.Equal(t, snapshotDeletedKey(dbutils.PlainStateBucket, []byte{4}), marks[0].K)
+	err = kv.View(context.Background(), func(tx Tx) error {
+		v, innerErr := tx.GetOne(dbutils.PlainStateBucket, []byte{4})
+		require.Nil(t, v)
+		return innerErr
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSnapshot2StackedSnapshots(t *testing.T) {
+	sn1, err := GenStateData([]KvData{
+		{K: []byte{1}, V: []byte{1}},
+		{K: []byte{2}, V: []byte{2}},
+		{K: []byte{3}, V: []byte{3}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sn2, err := GenStateData([]KvData{
+		{K: []byte{2}, V: []byte{2, 2}},
+		{K: []byte{3}, V: DeletedValue},
+		{K: []byte{4}, V: []byte{4}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sn3, err := GenStateData([]KvData{
+		{K: []byte{1}, V: []byte{1, 1}},
+		{K: []byte{5}, V: []byte{5}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mainDB, err := GenStateData([]KvData{
+		{K: []byte{5}, V: []byte{5, 5, 5}},
+		{K: []byte{6}, V: []byte{6}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	kv := NewSnapshot2KV().DB(mainDB).
+		SnapshotDB([]string{dbutils.PlainStateBucket}, sn1).
+		SnapshotDB([]string{dbutils.PlainStateBucket}, sn2).
+		MustOpen().(*SnapshotKV2)
+	defer kv.Close()
+	// the incremental snapshot goes on top of the previous ones
+	if err = kv.AppendSnapshot(context.Background(), nil, []string{dbutils.PlainStateBucket}, sn3); err != nil {
+		t.Fatal(err)
+	}
+
+	data := []KvData{
+		{K: []byte{1}, V: []byte{1, 1}},
+		{K: []byte{2}, V: []byte{2, 2}},
+		{K: []byte{4}, V: []byte{4}},
+		{K: []byte{5}, V: []byte{5, 5, 5}},
+		{K: []byte{6}, V: []byte{6}},
+	}
+	tx, err := kv.Begin(context.Background(), nil, RO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+	c := tx.Cursor(dbutils.PlainStateBucket)
+	defer c.Close()
+
+	i := 0
+	for k, v, err := c.First(); k != nil; k, v, err = c.Next() {
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkKV(t, k, v, data[i].K, data[i].V)
+		i++
+	}
+	if i != len(data) {
+		t.Fatalf("expected %d keys, got %d", len(data), i)
+	}
+	i = len(data) - 1
+	for k, v, err := c.Last(); k != nil; k, v, err = c.Prev() {
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkKV(t, k, v, data[i].K, data[i].V)
+		i--
+	}
+	if i != -1 {
+		t.Fatalf("expected %d keys backward, got %d", len(data), len(data)-1-i)
+	}
+
+	k, v, err := c.Seek([]byte{3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkKV(t, k, v, data[2].K, data[2].V)
+	k, v, err = c.SeekExact([]byte{3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkKV(t, k, v, nil, nil)
+	k, v, err = c.SeekExact([]byte{2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkKV(t, k, v, data[1].K, data[1].V)
+	k, v, err = c.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkKV(t, k, v, data[2].K, data[2].V)
+
+	for _, kv := range append(data, KvData{K: []byte{3}}, KvData{K: []byte{7}}) {
+		v, err := tx.GetOne(dbutils.PlainStateBucket, kv.K)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(v, kv.V) {
+			t.Fatalf("key %x: expected %x, got %x", kv.K, kv.V, v)
+		}
+		has, err := tx.HasOne(dbutils.PlainStateBucket, kv.K)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if has != (kv.V != nil) {
+			t.Fatalf("key %x: unexpected HasOne %t", kv.K, has)
+		}
+	}
+}
+
+func TestSnapshot2BloomSkipsSnapshots(t *testing.T) {
+	data := make([]KvData, 0, 100)
+	for i := 0; i < 100; i++ {
+		data = append(data, KvData{K: []byte{1, byte(i)}, V: []byte{byte(i)}})
+	}
+	snapshotDB, err := GenStateData(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mainDB := NewLMDB().InMem().MustOpen()
+	kv := NewSnapshot2KV().DB(mainDB).SnapshotDB([]string{dbutils.PlainStateBucket}, snapshotDB).MustOpen().(*SnapshotKV2)
+	defer kv.Close()
+	segment := kv.snapshots[dbutils.PlainStateBucket][0]
+	<-segment.bloomsBuilt
+	for i := range data {
+		if !segment.mayContain(dbutils.PlainStateBucket, data[i].K) {
+			t.Fatalf("bloom filter excludes the key %x of the snapshot", data[i].K)
+		}
+	}
+
+	err = kv.View(context.Background(), func(tx Tx) error {
+		v, innerErr := tx.GetOne(dbutils.PlainStateBucket, []byte{7, 7, 7})
+		if innerErr != nil {
+			return innerErr
+		}
+		if v != nil {
+			t.Fatalf("unexpected value %x", v)
+		}
+		if len(tx.(*sn2TX).snTX) != 0 {
+			t.Fatal("the snapshot is read for the key it doesn't have")
+		}
+		v, innerErr = tx.GetOne(dbutils.PlainStateBucket, data[10].K)
+		if innerErr != nil {
+			return innerErr
+		}
+		if !bytes.Equal(v, data[10].V) {
+			t.Fatalf("expected %x, got %x", data[10].V, v)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSnapshot2DupSortStackedSnapshots(t *testing.T) {
+	bucket := dbutils.PlainAccountChangeSetBucket
+	genDupSortData := func(data []KvData) KV {
+		sn := NewLMDB().WithBucketsConfig(func(defaultBuckets dbutils.BucketsCfg) dbutils.BucketsCfg {
+			return dbutils.BucketsCfg{
+				bucket: dbutils.BucketConfigItem{Flags: dbutils.DupSort},
+			}
+		}).InMem().MustOpen()
+		err := sn.Update(context.Background(), func(tx Tx) error {
+			c := tx.Cursor(bucket)
+			for i := range data {
+				if innerErr := c.Put(data[i].K, data[i].V); innerErr != nil {
+					return innerErr
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return sn
+	}
+	sn1 := genDupSortData([]KvData{
+		{K: []byte{1}, V: []byte{1}},
+		{K: []byte{1}, V: []byte{3}},
+		{K: []byte{2}, V: []byte{1}},
+	})
+	sn2 := genDupSortData([]KvData{
+		{K: []byte{1}, V: []byte{2}},
+		{K: []byte{1}, V: []byte{3}},
+		{K: []byte{3}, V: []byte{1}},
+	})
+	mainDB := NewLMDB().InMem().MustOpen()
+	err := mainDB.Update(context.Background(), func(tx Tx) error {
+		return tx.Cursor(bucket).Put([]byte{1}, []byte{4})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	kv := NewSnapshot2KV().DB(mainDB).SnapshotDB([]string{bucket}, sn1).SnapshotDB([]string{bucket}, sn2).MustOpen()
+	defer kv.Close()
+
+	data := []KvData{
+		{K: []byte{1}, V: []byte{1}},
+		{K: []byte{1}, V: []byte{2}},
+		{K: []byte{1}, V: []byte{3}},
+		{K: []byte{1}, V: []byte{4}},
+		{K: []byte{2}, V: []byte{1}},
+		{K: []byte{3}, V: []byte{1}},
+	}
+	tx, err := kv.Begin(context.Background(), nil, RO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+	c := tx.CursorDupSort(bucket)
+	defer c.Close()
+
+	i := 0
+	for k, v, err := c.First(); k != nil; k, v, err = c.Next() {
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkKV(t, k, v, data[i].K, data[i].V)
+		i++
+	}
+	if i != len(data) {
+		t.Fatalf("expected %d pairs, got %d", len(data), i)
+	}
+
+	k, v, err := c.SeekBothRange([]byte{1}, []byte{2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkKV(t, k, v, data[1].K, data[1].V)
+	k, v, err = c.NextDup()
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkKV(t, k, v, data[2].K, data[2].V)
+	count, err := c.CountDuplicates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 4 {
+		t.Fatalf("expected 4 duplicates, got %d", count)
+	}
+	last, err := c.LastDup([]byte{1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(last, data[3].V) {
+		t.Fatalf("expected the last duplicate %x, got %x", data[3].V, last)
+	}
+	k, v, err = c.NextDup()
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkKV(t, k, v, nil, nil)
+	k, v, err = c.NextNoDup()
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkKV(t, k, v, data[4].K, data[4].V)
+	k, v, err = c.SeekBothExact([]byte{3}, []byte{1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkKV(t, k, v, data[5].K, data[5].V)
+	k, v, err = c.Prev()
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkKV(t, k, v, data[4].K, data[4].V)
+}
+
 func printBucket(kv KV, bucket string) {
 	fmt.Println("+Print bucket", bucket)
 	defer func() {
@@ -20,6 +20,15 @@ type ETHBACKENDClient interface {
 	Add(ctx context.Context, in *TxRequest, opts ...grpc.CallOption) (*AddReply, error)
 	Etherbase(ctx context.Context, in *EtherbaseRequest, opts ...grpc.CallOption) (*EtherbaseReply, error)
 	NetVersion(ctx context.Context, in *NetVersionRequest, opts ...grpc.CallOption) (*NetVersionReply, error)
+	NetPeerCount(ctx context.Context, in *NetPeerCountRequest, opts ...grpc.CallOption) (*NetPeerCountReply, error)
+	ClientVersion(ctx context.Context, in *ClientVersionRequest, opts ...grpc.CallOption) (*ClientVersionReply, error)
+	NodeInfo(ctx context.Context, in *NodeInfoRequest, opts ...grpc.CallOption) (*NodeInfoReply, error)
+	Peers(ctx context.Context, in *PeersRequest, opts ...grpc.CallOption) (*PeersReply, error)
+	SyncStatus(ctx context.Context, in *SyncStatusRequest, opts ...grpc.CallOption) (*SyncStatusReply, error)
+	TxPoolContent(ctx context.Context, in *TxPoolContentRequest, opts ...grpc.CallOption) (*TxPoolContentReply, error)
+	TxPoolStatus(ctx context.Context, in *TxPoolStatusRequest, opts ...grpc.CallOption) (*TxPoolStatusReply, error)
+	Nonce(ctx context.Context, in *NonceRequest, opts ...grpc.CallOption) (*NonceReply, error)
+	PendingBlock(ctx context.Context, in *PendingBlockRequest, opts ...grpc.CallOption) (*PendingBlockReply, error)
 	Subscribe(ctx context.Context, in *SubscribeRequest, opts ...grpc.CallOption) (ETHBACKEND_SubscribeClient, error)
 }
 
@@ -58,6 +67,87 @@ func (c *eTHBACKENDClient) NetVersion(ctx context.Context, in *NetVersionRequest
 	return out, nil
 }
 
+func (c *eTHBACKENDClient) NetPeerCount(ctx context.Context, in *NetPeerCountRequest, opts ...grpc.CallOption) (*NetPeerCountReply, error) {
+	out := new(NetPeerCountReply)
+	err := c.cc.Invoke(ctx, "/remote.ETHBACKEND/NetPeerCount", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *eTHBACKENDClient) ClientVersion(ctx context.Context, in *ClientVersionRequest, opts ...grpc.CallOption) (*ClientVersionReply, error) {
+	out := new(ClientVersionReply)
+	err := c.cc.Invoke(ctx, "/remote.ETHBACKEND/ClientVersion", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *eTHBACKENDClient) NodeInfo(ctx context.Context, in *NodeInfoRequest, opts ...grpc.CallOption) (*NodeInfoReply, error) {
+	out := new(NodeInfoReply)
+	err := c.cc.Invoke(ctx, "/remote.ETHBACKEND/NodeInfo", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *eTHBACKENDClient) Peers(ctx context.Context, in *PeersRequest, opts ...grpc.CallOption) (*PeersReply, error) {
+	out := new(PeersReply)
+	err := c.cc.Invoke(ctx, "/remote.ETHBACKEND/Peers", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *eTHBACKENDClient) SyncStatus(ctx context.Context, in *SyncStatusRequest, opts ...grpc.CallOption) (*SyncStatusReply, error) {
+	out := new(SyncStatusReply)
+	err := c.cc.Invoke(ctx, "/remote.ETHBACKEND/SyncStatus", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *eTHBACKENDClient) TxPoolContent(ctx context.Context, in *TxPoolContentRequest, opts ...grpc.CallOption) (*TxPoolContentReply, error) {
+	out := new(TxPoolContentReply)
+	err := c.cc.Invoke(ctx, "/remote.ETHBACKEND/TxPoolContent", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *eTHBACKENDClient) TxPoolStatus(ctx context.Context, in *TxPoolStatusRequest, opts ...grpc.CallOption) (*TxPoolStatusReply, error) {
+	out := new(TxPoolStatusReply)
+	err := c.cc.Invoke(ctx, "/remote.ETHBACKEND/TxPoolStatus", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *eTHBACKENDClient) Nonce(ctx context.Context, in *NonceRequest, opts ...grpc.CallOption) (*NonceReply, error) {
+	out := new(NonceReply)
+	err := c.cc.Invoke(ctx, "/remote.ETHBACKEND/Nonce", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *eTHBACKENDClient) PendingBlock(ctx context.Context, in *PendingBlockRequest, opts ...grpc.CallOption) (*PendingBlockReply, error) {
+	out := new(PendingBlockReply)
+	err := c.cc.Invoke(ctx, "/remote.ETHBACKEND/PendingBlock", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *eTHBACKENDClient) Subscribe(ctx context.Context, in *SubscribeRequest, opts ...grpc.CallOption) (ETHBACKEND_SubscribeClient, error) {
 	stream, err := c.cc.NewStream(ctx, &_ETHBACKEND_serviceDesc.Streams[0], "/remote.ETHBACKEND/Subscribe", opts...)
 	if err != nil {
@@ -97,6 +187,15 @@ type ETHBACKENDServer interface {
 	Add(context.Context, *TxRequest) (*AddReply, error)
 	Etherbase(context.Context, *EtherbaseRequest) (*EtherbaseReply, error)
 	NetVersion(context.Context, *NetVersionRequest) (*NetVersionReply, error)
+	NetPeerCount(context.Context, *NetPeerCountRequest) (*NetPeerCountReply, error)
+	ClientVersion(context.Context, *ClientVersionRequest) (*ClientVersionReply, error)
+	NodeInfo(context.Context, *NodeInfoRequest) (*NodeInfoReply, error)
+	Peers(context.Context, *PeersRequest) (*PeersReply, error)
+	SyncStatus(context.Context, *SyncStatusRequest) (*SyncStatusReply, error)
+	TxPoolContent(context.Context, *TxPoolContentRequest) (*TxPoolContentReply, error)
+	TxPoolStatus(context.Context, *TxPoolStatusRequest) (*TxPoolStatusReply, error)
+	Nonce(context.Context, *NonceRequest) (*NonceReply, error)
+	PendingBlock(context.Context, *PendingBlockRequest) (*PendingBlockReply, error)
 	Subscribe(*SubscribeRequest, ETHBACKEND_SubscribeServer) error
 	mustEmbedUnimplementedETHBACKENDServer()
 }
@@ -114,6 +213,33 @@ func (UnimplementedETHBACKENDServer) Etherbase(context.Context, *EtherbaseReques
 func (UnimplementedETHBACKENDServer) NetVersion(context.Context, *NetVersionRequest) (*NetVersionReply, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method NetVersion not implemented")
 }
+func (UnimplementedETHBACKENDServer) NetPeerCount(context.Context, *NetPeerCountRequest) (*NetPeerCountReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method NetPeerCount not implemented")
+}
+func (UnimplementedETHBACKENDServer) ClientVersion(context.Context, *ClientVersionRequest) (*ClientVersionReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ClientVersion not implemented")
+}
+func (UnimplementedETHBACKENDServer) NodeInfo(context.Context, *NodeInfoRequest) (*NodeInfoReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method NodeInfo not implemented")
+}
+func (UnimplementedETHBACKENDServer) Peers(context.Context, *PeersRequest) (*PeersReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Peers not implemented")
+}
+func (UnimplementedETHBACKENDServer) SyncStatus(context.Context, *SyncStatusRequest) (*SyncStatusReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SyncStatus not implemented")
+}
+func (UnimplementedETHBACKENDServer) TxPoolContent(context.Context, *TxPoolContentRequest) (*TxPoolContentReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method TxPoolContent not implemented")
+}
+func (UnimplementedETHBACKENDServer) TxPoolStatus(context.Context, *TxPoolStatusRequest) (*TxPoolStatusReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method TxPoolStatus not implemented")
+}
+func (UnimplementedETHBACKENDServer) Nonce(context.Context, *NonceRequest) (*NonceReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Nonce not implemented")
+}
+func (UnimplementedETHBACKENDServer) PendingBlock(context.Context, *PendingBlockRequest) (*PendingBlockReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PendingBlock not implemented")
+}
 func (UnimplementedETHBACKENDServer) Subscribe(*SubscribeRequest, ETHBACKEND_SubscribeServer) error {
 	return status.Errorf(codes.Unimplemented, "method Subscribe not implemented")
 }
@@ -184,6 +310,168 @@ func _ETHBACKEND_NetVersion_Handler(srv interface{}, ctx context.Context, dec fu
 	return interceptor(ctx, in, info, handler)
 }
 
+func _ETHBACKEND_NetPeerCount_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(NetPeerCountRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ETHBACKENDServer).NetPeerCount(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/remote.ETHBACKEND/NetPeerCount",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ETHBACKENDServer).NetPeerCount(ctx, req.(*NetPeerCountRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ETHBACKEND_ClientVersion_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ClientVersionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ETHBACKENDServer).ClientVersion(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/remote.ETHBACKEND/ClientVersion",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ETHBACKENDServer).ClientVersion(ctx, req.(*ClientVersionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ETHBACKEND_NodeInfo_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(NodeInfoRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ETHBACKENDServer).NodeInfo(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/remote.ETHBACKEND/NodeInfo",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ETHBACKENDServer).NodeInfo(ctx, req.(*NodeInfoRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ETHBACKEND_Peers_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PeersRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ETHBACKENDServer).Peers(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/remote.ETHBACKEND/Peers",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ETHBACKENDServer).Peers(ctx, req.(*PeersRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ETHBACKEND_SyncStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SyncStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ETHBACKENDServer).SyncStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/remote.ETHBACKEND/SyncStatus",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ETHBACKENDServer).SyncStatus(ctx, req.(*SyncStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ETHBACKEND_TxPoolContent_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TxPoolContentRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ETHBACKENDServer).TxPoolContent(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/remote.ETHBACKEND/TxPoolContent",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ETHBACKENDServer).TxPoolContent(ctx, req.(*TxPoolContentRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ETHBACKEND_TxPoolStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TxPoolStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ETHBACKENDServer).TxPoolStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/remote.ETHBACKEND/TxPoolStatus",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ETHBACKENDServer).TxPoolStatus(ctx, req.(*TxPoolStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ETHBACKEND_Nonce_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(NonceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ETHBACKENDServer).Nonce(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/remote.ETHBACKEND/Nonce",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ETHBACKENDServer).Nonce(ctx, req.(*NonceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ETHBACKEND_PendingBlock_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PendingBlockRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ETHBACKENDServer).PendingBlock(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/remote.ETHBACKEND/PendingBlock",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ETHBACKENDServer).PendingBlock(ctx, req.(*PendingBlockRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _ETHBACKEND_Subscribe_Handler(srv interface{}, stream grpc.ServerStream) error {
 	m := new(SubscribeRequest)
 	if err := stream.RecvMsg(m); err != nil {
@@ -221,6 +509,42 @@ var _ETHBACKEND_serviceDesc = grpc.ServiceDesc{
 			MethodName: "NetVersion",
 			Handler:    _ETHBACKEND_NetVersion_Handler,
 		},
+		{
+			MethodName: "NetPeerCount",
+			Handler:    _ETHBACKEND_NetPeerCount_Handler,
+		},
+		{
+			MethodName: "ClientVersion",
+			Handler:    _ETHBACKEND_ClientVersion_Handler,
+		},
+		{
+			MethodName: "NodeInfo",
+			Handler:    _ETHBACKEND_NodeInfo_Handler,
+		},
+		{
+			MethodName: "Peers",
+			Handler:    _ETHBACKEND_Peers_Handler,
+		},
+		{
+			MethodName: "SyncStatus",
+			Handler:    _ETHBACKEND_SyncStatus_Handler,
+		},
+		{
+			MethodName: "TxPoolContent",
+			Handler:    _ETHBACKEND_TxPoolContent_Handler,
+		},
+		{
+			MethodName: "TxPoolStatus",
+			Handler:    _ETHBACKEND_TxPoolStatus_Handler,
+		},
+		{
+			MethodName: "Nonce",
+			Handler:    _ETHBACKEND_Nonce_Handler,
+		},
+		{
+			MethodName: "PendingBlock",
+			Handler:    _ETHBACKEND_PendingBlock_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{
@@ -45,8 +45,17 @@ const (
 	Op_PREV_NO_DUP     Op = 14
 	Op_SEEK_EXACT      Op = 15
 	Op_SEEK_BOTH_EXACT Op = 16
-	Op_OPEN            Op = 30
-	Op_CLOSE           Op = 31
+	// Write operations, allowed only in the write transactions and only for the buckets which the server allows to modify
+	Op_PUT    Op = 20
+	Op_DELETE Op = 21
+	Op_APPEND Op = 22
+	// Finish the transaction, the server closes the stream after replying
+	Op_COMMIT   Op = 25
+	Op_ROLLBACK Op = 26
+	Op_OPEN     Op = 30
+	Op_CLOSE    Op = 31
+	// Must be the first message of the stream to make it a write transaction
+	Op_BEGIN_RW Op = 32
 )
 
 // Enum value maps for Op.
@@ -69,8 +78,14 @@ var (
 		14: "PREV_NO_DUP",
 		15: "SEEK_EXACT",
 		16: "SEEK_BOTH_EXACT",
+		20: "PUT",
+		21: "DELETE",
+		22: "APPEND",
+		25: "COMMIT",
+		26: "ROLLBACK",
 		30: "OPEN",
 		31: "CLOSE",
+		32: "BEGIN_RW",
 	}
 	Op_value = map[string]int32{
 		"FIRST":           0,
@@ -90,8 +105,14 @@ var (
 		"PREV_NO_DUP":     14,
 		"SEEK_EXACT":      15,
 		"SEEK_BOTH_EXACT": 16,
+		"PUT":             20,
+		"DELETE":          21,
+		"APPEND":          22,
+		"COMMIT":          25,
+		"ROLLBACK":        26,
 		"OPEN":            30,
 		"CLOSE":           31,
+		"BEGIN_RW":        32,
 	}
 )
 
@@ -280,7 +301,7 @@ var file_remote_kv_proto_rawDesc = []byte{
 	0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x01, 0x6b, 0x12, 0x0c, 0x0a, 0x01, 0x76, 0x18, 0x02,
 	0x20, 0x01, 0x28, 0x0c, 0x52, 0x01, 0x76, 0x12, 0x1a, 0x0a, 0x08, 0x63, 0x75, 0x72, 0x73, 0x6f,
 	0x72, 0x49, 0x44, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x08, 0x63, 0x75, 0x72, 0x73, 0x6f,
-	0x72, 0x49, 0x44, 0x2a, 0x8d, 0x02, 0x0a, 0x02, 0x4f, 0x70, 0x12, 0x09, 0x0a, 0x05, 0x46, 0x49,
+	0x72, 0x49, 0x44, 0x2a, 0xd6, 0x02, 0x0a, 0x02, 0x4f, 0x70, 0x12, 0x09, 0x0a, 0x05, 0x46, 0x49,
 	0x52, 0x53, 0x54, 0x10, 0x00, 0x12, 0x0d, 0x0a, 0x09, 0x46, 0x49, 0x52, 0x53, 0x54, 0x5f, 0x44,
 	0x55, 0x50, 0x10, 0x01, 0x12, 0x08, 0x0a, 0x04, 0x53, 0x45, 0x45, 0x4b, 0x10, 0x02, 0x12, 0x0d,
 	0x0a, 0x09, 0x53, 0x45, 0x45, 0x4b, 0x5f, 0x42, 0x4f, 0x54, 0x48, 0x10, 0x03, 0x12, 0x0b, 0x0a,
@@ -295,15 +316,19 @@ var file_remote_kv_proto_rawDesc = []byte{
 	0x45, 0x56, 0x5f, 0x44, 0x55, 0x50, 0x10, 0x0d, 0x12, 0x0f, 0x0a, 0x0b, 0x50, 0x52, 0x45, 0x56,
 	0x5f, 0x4e, 0x4f, 0x5f, 0x44, 0x55, 0x50, 0x10, 0x0e, 0x12, 0x0e, 0x0a, 0x0a, 0x53, 0x45, 0x45,
 	0x4b, 0x5f, 0x45, 0x58, 0x41, 0x43, 0x54, 0x10, 0x0f, 0x12, 0x13, 0x0a, 0x0f, 0x53, 0x45, 0x45,
-	0x4b, 0x5f, 0x42, 0x4f, 0x54, 0x48, 0x5f, 0x45, 0x58, 0x41, 0x43, 0x54, 0x10, 0x10, 0x12, 0x08,
-	0x0a, 0x04, 0x4f, 0x50, 0x45, 0x4e, 0x10, 0x1e, 0x12, 0x09, 0x0a, 0x05, 0x43, 0x4c, 0x4f, 0x53,
-	0x45, 0x10, 0x1f, 0x32, 0x2c, 0x0a, 0x02, 0x4b, 0x56, 0x12, 0x26, 0x0a, 0x02, 0x54, 0x78, 0x12,
-	0x0e, 0x2e, 0x72, 0x65, 0x6d, 0x6f, 0x74, 0x65, 0x2e, 0x43, 0x75, 0x72, 0x73, 0x6f, 0x72, 0x1a,
-	0x0c, 0x2e, 0x72, 0x65, 0x6d, 0x6f, 0x74, 0x65, 0x2e, 0x50, 0x61, 0x69, 0x72, 0x28, 0x01, 0x30,
-	0x01, 0x42, 0x29, 0x0a, 0x10, 0x69, 0x6f, 0x2e, 0x74, 0x75, 0x72, 0x62, 0x6f, 0x2d, 0x67, 0x65,
-	0x74, 0x68, 0x2e, 0x64, 0x62, 0x42, 0x02, 0x4b, 0x56, 0x50, 0x01, 0x5a, 0x0f, 0x2e, 0x2f, 0x72,
-	0x65, 0x6d, 0x6f, 0x74, 0x65, 0x3b, 0x72, 0x65, 0x6d, 0x6f, 0x74, 0x65, 0x62, 0x06, 0x70, 0x72,
-	0x6f, 0x74, 0x6f, 0x33,
+	0x4b, 0x5f, 0x42, 0x4f, 0x54, 0x48, 0x5f, 0x45, 0x58, 0x41, 0x43, 0x54, 0x10, 0x10, 0x12, 0x07,
+	0x0a, 0x03, 0x50, 0x55, 0x54, 0x10, 0x14, 0x12, 0x0a, 0x0a, 0x06, 0x44, 0x45, 0x4c, 0x45, 0x54,
+	0x45, 0x10, 0x15, 0x12, 0x0a, 0x0a, 0x06, 0x41, 0x50, 0x50, 0x45, 0x4e, 0x44, 0x10, 0x16, 0x12,
+	0x0a, 0x0a, 0x06, 0x43, 0x4f, 0x4d, 0x4d, 0x49, 0x54, 0x10, 0x19, 0x12, 0x0c, 0x0a, 0x08, 0x52,
+	0x4f, 0x4c, 0x4c, 0x42, 0x41, 0x43, 0x4b, 0x10, 0x1a, 0x12, 0x08, 0x0a, 0x04, 0x4f, 0x50, 0x45,
+	0x4e, 0x10, 0x1e, 0x12, 0x09, 0x0a, 0x05, 0x43, 0x4c, 0x4f, 0x53, 0x45, 0x10, 0x1f, 0x12, 0x0c,
+	0x0a, 0x08, 0x42, 0x45, 0x47, 0x49, 0x4e, 0x5f, 0x52, 0x57, 0x10, 0x20, 0x32, 0x2c, 0x0a, 0x02,
+	0x4b, 0x56, 0x12, 0x26, 0x0a, 0x02, 0x54, 0x78, 0x12, 0x0e, 0x2e, 0x72, 0x65, 0x6d, 0x6f, 0x74,
+	0x65, 0x2e, 0x43, 0x75, 0x72, 0x73, 0x6f, 0x72, 0x1a, 0x0c, 0x2e, 0x72, 0x65, 0x6d, 0x6f, 0x74,
+	0x65, 0x2e, 0x50, 0x61, 0x69, 0x72, 0x28, 0x01, 0x30, 0x01, 0x42, 0x29, 0x0a, 0x10, 0x69, 0x6f,
+	0x2e, 0x74, 0x75, 0x72, 0x62, 0x6f, 0x2d, 0x67, 0x65, 0x74, 0x68, 0x2e, 0x64, 0x62, 0x42, 0x02,
+	0x4b, 0x56, 0x50, 0x01, 0x5a, 0x0f, 0x2e, 0x2f, 0x72, 0x65, 0x6d, 0x6f, 0x74, 0x65, 0x3b, 0x72,
+	0x65, 0x6d, 0x6f, 0x74, 0x65, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
 }
 
 var (
@@ -6,29 +6,29 @@ import (
 	"sync"
 
 	"github.com/ledgerwatch/turbo-geth/common"
-	"github.com/ledgerwatch/turbo-geth/core"
 	"github.com/ledgerwatch/turbo-geth/core/types"
+	"github.com/ledgerwatch/turbo-geth/eth/ethbackend"
 	"github.com/ledgerwatch/turbo-geth/ethdb/remote"
 	"github.com/ledgerwatch/turbo-geth/log"
-	"github.com/ledgerwatch/turbo-geth/rlp"
 )
 
 type EthBackendServer struct {
 	remote.UnimplementedETHBACKENDServer // must be embedded to have forward compatible implementations.
 
-	eth    core.Backend
+	eth    ethbackend.Backend
+	back   *ethbackend.EthBackend // converts the p2p and sync state of eth to the replies
 	events *Events
 }
 
-func NewEthBackendServer(eth core.Backend, events *Events) *EthBackendServer {
-	return &EthBackendServer{eth: eth, events: events}
+func NewEthBackendServer(eth ethbackend.Backend, events *Events) *EthBackendServer {
+	return &EthBackendServer{eth: eth, back: ethbackend.New(eth), events: events}
 }
 
 func (s *EthBackendServer) Add(_ context.Context, in *remote.TxRequest) (*remote.AddReply, error) {
 	signedTx := new(types.Transaction)
 	out := &remote.AddReply{Hash: common.Hash{}.Bytes()}
 
-	if err := rlp.DecodeBytes(in.Signedtx, signedTx); err != nil {
+	if err := signedTx.UnmarshalBinary(in.Signedtx); err != nil {
 		return out, err
 	}
 
@@ -60,28 +60,77 @@ func (s *EthBackendServer) NetVersion(_ context.Context, _ *remote.NetVersionReq
 	return &remote.NetVersionReply{Id: id}, nil
 }
 
+func (s *EthBackendServer) NetPeerCount(_ context.Context, _ *remote.NetPeerCountRequest) (*remote.NetPeerCountReply, error) {
+	count, err := s.eth.NetPeerCount()
+	if err != nil {
+		return &remote.NetPeerCountReply{}, err
+	}
+	return &remote.NetPeerCountReply{Count: count}, nil
+}
+
+func (s *EthBackendServer) ClientVersion(_ context.Context, _ *remote.ClientVersionRequest) (*remote.ClientVersionReply, error) {
+	nodename, err := s.eth.ClientVersion()
+	if err != nil {
+		return &remote.ClientVersionReply{}, err
+	}
+	return &remote.ClientVersionReply{Nodename: nodename}, nil
+}
+
+func (s *EthBackendServer) NodeInfo(_ context.Context, _ *remote.NodeInfoRequest) (*remote.NodeInfoReply, error) {
+	return s.back.NodeInfo()
+}
+
+func (s *EthBackendServer) Peers(_ context.Context, _ *remote.PeersRequest) (*remote.PeersReply, error) {
+	return s.back.Peers()
+}
+
+func (s *EthBackendServer) SyncStatus(_ context.Context, _ *remote.SyncStatusRequest) (*remote.SyncStatusReply, error) {
+	return s.back.SyncStatus()
+}
+
+func (s *EthBackendServer) TxPoolContent(_ context.Context, _ *remote.TxPoolContentRequest) (*remote.TxPoolContentReply, error) {
+	return s.back.TxPoolContent()
+}
+
+func (s *EthBackendServer) TxPoolStatus(_ context.Context, _ *remote.TxPoolStatusRequest) (*remote.TxPoolStatusReply, error) {
+	return s.back.TxPoolStatus()
+}
+
+func (s *EthBackendServer) Nonce(_ context.Context, in *remote.NonceRequest) (*remote.NonceReply, error) {
+	return s.back.Nonce(common.BytesToAddress(in.Address))
+}
+
+func (s *EthBackendServer) PendingBlock(_ context.Context, _ *remote.PendingBlockRequest) (*remote.PendingBlockReply, error) {
+	return s.back.PendingBlock()
+}
+
 func (s *EthBackendServer) Subscribe(r *remote.SubscribeRequest, subscribeServer remote.ETHBACKEND_SubscribeServer) error {
 	log.Debug("establishing event subscription channel with the RPC daemon")
 	wg := sync.WaitGroup{}
 	wg.Add(1)
-	s.events.AddHeaderSubscription(func(h *types.Header) error {
+	var closeOnce sync.Once
+	// events come from the staged sync and from the tx pool, while the stream can only be sent to from one goroutine at a time
+	var sendLock sync.Mutex
+	send := func(eventType RpcEventType, v interface{}) error {
 		select {
 		case <-subscribeServer.Context().Done():
-			wg.Done()
+			closeOnce.Do(wg.Done)
 			return subscribeServer.Context().Err()
 		default:
 		}
 
-		payload, err := json.Marshal(h)
+		payload, err := json.Marshal(v)
 		if err != nil {
-			log.Warn("error while marshaling a header", "err", err)
+			log.Warn("error while marshaling an event", "type", eventType, "err", err)
 			return err
 		}
 
+		sendLock.Lock()
 		err = subscribeServer.Send(&remote.SubscribeReply{
-			Type: uint64(EventTypeHeader),
+			Type: uint64(eventType),
 			Data: payload,
 		})
+		sendLock.Unlock()
 
 		// we only close the wg on error because if we successfully sent an event,
 		// that means that the channel wasn't closed and is ready to
@@ -92,6 +141,19 @@ func (s *EthBackendServer) Subscribe(r *remote.SubscribeRequest, subscribeServer
 			log.Info("event subscription channel was closed", "reason", err)
 		}
 		return err
+	}
+
+	s.events.AddHeaderSubscription(func(h *types.Header) error {
+		return send(EventTypeHeader, h)
+	})
+	s.events.AddPendingTxsSubscription(func(txs []*types.Transaction) error {
+		return send(EventTypePendingTxs, txs)
+	})
+	s.events.AddLogsSubscription(func(logs []*types.Log) error {
+		return send(EventTypeLogs, logs)
+	})
+	s.events.AddUnwindSubscription(func(h *types.Header) error {
+		return send(EventTypeUnwind, h)
 	})
 
 	log.Info("event subscription channel established with the RPC daemon")
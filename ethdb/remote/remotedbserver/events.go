@@ -1,6 +1,8 @@
 package remotedbserver
 
 import (
+	"sync"
+
 	"github.com/ledgerwatch/turbo-geth/core/types"
 )
 
@@ -8,12 +10,26 @@ type RpcEventType uint64
 
 const (
 	EventTypeHeader = RpcEventType(iota)
+	EventTypePendingTxs
+	EventTypeLogs
+	EventTypeUnwind
 )
 
 type HeaderSubscription func(*types.Header) error
+type PendingTxsSubscription func([]*types.Transaction) error
+type LogsSubscription func([]*types.Log) error
+
+// UnwindSubscription receives the header of the block the chain was unwound to
+type UnwindSubscription func(*types.Header) error
 
+// Events is the ChainEventNotifier of the staged sync, it passes the events on to the RPC daemon subscription.
+// Subscriptions are dropped after their first error
 type Events struct {
-	headerSubscription HeaderSubscription
+	lock                   sync.RWMutex
+	headerSubscription     HeaderSubscription
+	pendingTxsSubscription PendingTxsSubscription
+	logsSubscription       LogsSubscription
+	unwindSubscription     UnwindSubscription
 }
 
 func NewEvents() *Events {
@@ -21,15 +37,73 @@ func NewEvents() *Events {
 }
 
 func (e *Events) AddHeaderSubscription(s HeaderSubscription) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
 	e.headerSubscription = s
 }
 
+func (e *Events) AddPendingTxsSubscription(s PendingTxsSubscription) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	e.pendingTxsSubscription = s
+}
+
+func (e *Events) AddLogsSubscription(s LogsSubscription) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	e.logsSubscription = s
+}
+
+func (e *Events) AddUnwindSubscription(s UnwindSubscription) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	e.unwindSubscription = s
+}
+
 func (e *Events) OnNewHeader(newHeader *types.Header) {
-	if e.headerSubscription == nil {
+	e.lock.RLock()
+	s := e.headerSubscription
+	e.lock.RUnlock()
+	if s == nil {
+		return
+	}
+	if err := s(newHeader); err != nil {
+		e.AddHeaderSubscription(nil)
+	}
+}
+
+func (e *Events) OnNewPendingTxs(txs []*types.Transaction) {
+	e.lock.RLock()
+	s := e.pendingTxsSubscription
+	e.lock.RUnlock()
+	if s == nil {
+		return
+	}
+	if err := s(txs); err != nil {
+		e.AddPendingTxsSubscription(nil)
+	}
+}
+
+func (e *Events) OnNewLogs(logs []*types.Log) {
+	e.lock.RLock()
+	s := e.logsSubscription
+	e.lock.RUnlock()
+	if s == nil {
+		return
+	}
+	if err := s(logs); err != nil {
+		e.AddLogsSubscription(nil)
+	}
+}
+
+func (e *Events) OnUnwind(unwindTo *types.Header) {
+	e.lock.RLock()
+	s := e.unwindSubscription
+	e.lock.RUnlock()
+	if s == nil {
 		return
 	}
-	err := e.headerSubscription(newHeader)
-	if err != nil {
-		e.headerSubscription = nil
+	if err := s(unwindTo); err != nil {
+		e.AddUnwindSubscription(nil)
 	}
 }
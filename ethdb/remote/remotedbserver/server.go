@@ -11,7 +11,7 @@ import (
 	grpc_recovery "github.com/grpc-ecosystem/go-grpc-middleware/recovery"
 	grpc_prometheus "github.com/grpc-ecosystem/go-grpc-prometheus"
 	"github.com/ledgerwatch/turbo-geth/common"
-	"github.com/ledgerwatch/turbo-geth/core"
+	"github.com/ledgerwatch/turbo-geth/eth/ethbackend"
 	"github.com/ledgerwatch/turbo-geth/ethdb"
 	"github.com/ledgerwatch/turbo-geth/ethdb/remote"
 	"github.com/ledgerwatch/turbo-geth/log"
@@ -23,20 +23,37 @@ import (
 
 const MaxTxTTL = 30 * time.Second
 
+var (
+	// RwTxIdleTimeout - the write transaction of the client which sends no requests for this long is rolled back,
+	// because it holds the only writer of the database and stalls the sync
+	RwTxIdleTimeout = 30 * time.Second
+	// RwTxMaxLifetime - the write transaction which is not committed in this time is rolled back
+	RwTxMaxLifetime = 5 * time.Minute
+)
+
 type KvServer struct {
 	remote.UnimplementedKVServer // must be embedded to have forward compatible implementations.
 
-	kv ethdb.KV
+	kv              ethdb.KV
+	writableBuckets map[string]struct{} // buckets which the clients can modify in the write transactions
 }
 
-func StartGrpc(kv ethdb.KV, eth core.Backend, addr string, creds *credentials.TransportCredentials, events *Events) (*grpc.Server, error) {
-	log.Info("Starting private RPC server", "on", addr)
+// StartGrpc starts the private api server. The clients can open the write transactions only if writableBuckets is not empty,
+// and can modify only these buckets
+func StartGrpc(kv ethdb.KV, eth ethbackend.Backend, addr string, creds *credentials.TransportCredentials, events *Events, writableBuckets []string) (*grpc.Server, error) {
+	log.Info("Starting private RPC server", "on", addr, "writable buckets", writableBuckets)
+	buckets := kv.AllBuckets()
+	for _, name := range writableBuckets {
+		if _, ok := buckets[name]; !ok {
+			return nil, fmt.Errorf("%w: %s", ethdb.ErrUnknownBucket, name)
+		}
+	}
 	lis, err := net.Listen("tcp", addr)
 	if err != nil {
 		return nil, fmt.Errorf("could not create listener: %w, addr=%s", err, addr)
 	}
 
-	kv2Srv := NewKvServer(kv)
+	kv2Srv := NewKvServer(kv, writableBuckets...)
 	dbSrv := NewDBServer(kv)
 	ethBackendSrv := NewEthBackendServer(eth, events)
 	var (
@@ -88,12 +105,34 @@ func StartGrpc(kv ethdb.KV, eth core.Backend, addr string, creds *credentials.Tr
 	return grpcServer, nil
 }
 
-func NewKvServer(kv ethdb.KV) *KvServer {
-	return &KvServer{kv: kv}
+// NewKvServer creates the server of the remote KV. The clients can modify only the writable buckets, and only in the
+// transactions which start with BEGIN_RW. Without writable buckets the server is read-only
+func NewKvServer(kv ethdb.KV, writableBuckets ...string) *KvServer {
+	s := &KvServer{kv: kv, writableBuckets: map[string]struct{}{}}
+	for _, name := range writableBuckets {
+		s.writableBuckets[name] = struct{}{}
+	}
+	return s
 }
 
 func (s *KvServer) Tx(stream remote.KV_TxServer) error {
-	tx, errBegin := s.kv.Begin(stream.Context(), nil, ethdb.RO)
+	first, recvErr := stream.Recv()
+	if recvErr != nil {
+		if recvErr == io.EOF { // termination
+			return nil
+		}
+		return fmt.Errorf("server-side error: %w", recvErr)
+	}
+	rw := first.Op == remote.Op_BEGIN_RW
+	if rw && len(s.writableBuckets) == 0 {
+		return fmt.Errorf("server-side error: write transactions are not allowed")
+	}
+	flags := ethdb.RO
+	if rw {
+		flags = ethdb.RW
+	}
+
+	tx, errBegin := s.kv.Begin(stream.Context(), nil, flags)
 	if errBegin != nil {
 		return fmt.Errorf("server-side error: %w", errBegin)
 	}
@@ -101,6 +140,12 @@ func (s *KvServer) Tx(stream remote.KV_TxServer) error {
 		tx.Rollback()
 	}
 	defer rollback()
+	if rw {
+		if err := stream.Send(&remote.Pair{}); err != nil {
+			return fmt.Errorf("server-side error: %w", err)
+		}
+		first = nil
+	}
 
 	var CursorID uint32
 	type CursorInfo struct {
@@ -110,23 +155,33 @@ func (s *KvServer) Tx(stream remote.KV_TxServer) error {
 	}
 	cursors := map[uint32]*CursorInfo{}
 
-	txTicker := time.NewTicker(MaxTxTTL)
-	defer txTicker.Stop()
+	// The read-only transactions are periodically reopened, but the write transaction can't be reopened without losing the changes
+	var txTTL <-chan time.Time
+	recv := stream.Recv
+	if rw {
+		recv = newRwTxReceiver(stream)
+	} else {
+		txTicker := time.NewTicker(MaxTxTTL)
+		defer txTicker.Stop()
+		txTTL = txTicker.C
+	}
 
 	// send all items to client, if k==nil - still send it to client and break loop
 	for {
-		in, recvErr := stream.Recv()
-		if recvErr != nil {
-			if recvErr == io.EOF { // termination
-				return nil
+		in := first
+		first = nil
+		if in == nil {
+			if in, recvErr = recv(); recvErr != nil {
+				if recvErr == io.EOF { // termination
+					return nil
+				}
+				return fmt.Errorf("server-side error: %w", recvErr)
 			}
-			return fmt.Errorf("server-side error: %w", recvErr)
 		}
 
-		//TODO: protect against client - which doesn't send any requests
 		select {
 		default:
-		case <-txTicker.C:
+		case <-txTTL:
 			for _, c := range cursors { // save positions of cursor, will restore after Tx reopening
 				k, v, err := c.c.Current()
 				if err != nil {
@@ -175,6 +230,22 @@ func (s *KvServer) Tx(stream remote.KV_TxServer) error {
 			}
 		}
 
+		switch in.Op {
+		case remote.Op_COMMIT:
+			if !rw {
+				return fmt.Errorf("server-side error: commit of the read-only transaction")
+			}
+			if err := tx.Commit(stream.Context()); err != nil {
+				return fmt.Errorf("server-side error: %w", err)
+			}
+			return stream.Send(&remote.Pair{})
+		case remote.Op_ROLLBACK:
+			tx.Rollback()
+			return stream.Send(&remote.Pair{})
+		case remote.Op_BEGIN_RW:
+			return fmt.Errorf("server-side error: BEGIN_RW must be the first message of the transaction")
+		}
+
 		var c ethdb.Cursor
 		if in.BucketName == "" {
 			cInfo, ok := cursors[in.Cursor]
@@ -182,6 +253,14 @@ func (s *KvServer) Tx(stream remote.KV_TxServer) error {
 				return fmt.Errorf("server-side error: unknown Cursor=%d, Op=%s", in.Cursor, in.Op)
 			}
 			c = cInfo.c
+			if in.Op == remote.Op_PUT || in.Op == remote.Op_DELETE || in.Op == remote.Op_APPEND {
+				if !rw {
+					return fmt.Errorf("server-side error: %s in the read-only transaction", in.Op)
+				}
+				if _, writable := s.writableBuckets[cInfo.bucket]; !writable {
+					return fmt.Errorf("server-side error: bucket %s is not writable", cInfo.bucket)
+				}
+			}
 		}
 
 		switch in.Op {
@@ -215,6 +294,52 @@ func (s *KvServer) Tx(stream remote.KV_TxServer) error {
 	}
 }
 
+// newRwTxReceiver returns the receiver of the requests of the write transaction, which fails if the client doesn't send
+// the next request in RwTxIdleTimeout or the transaction lasts longer than RwTxMaxLifetime. Then the transaction is
+// rolled back and the stream is closed
+func newRwTxReceiver(stream remote.KV_TxServer) func() (*remote.Cursor, error) {
+	type request struct {
+		in  *remote.Cursor
+		err error
+	}
+	requests := make(chan request)
+	go func() {
+		for {
+			in, err := stream.Recv()
+			select {
+			case requests <- request{in, err}:
+			case <-stream.Context().Done():
+				return
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	deadline := time.Now().Add(RwTxMaxLifetime)
+	return func() (*remote.Cursor, error) {
+		idle := RwTxIdleTimeout
+		if left := time.Until(deadline); left < idle {
+			if left <= 0 {
+				return nil, fmt.Errorf("write transaction exceeded max lifetime %s, rolled back", RwTxMaxLifetime)
+			}
+			idle = left
+		}
+		timer := time.NewTimer(idle)
+		defer timer.Stop()
+		select {
+		case r := <-requests:
+			return r.in, r.err
+		case <-timer.C:
+			if time.Now().Before(deadline) {
+				return nil, fmt.Errorf("write transaction idle for %s, rolled back", RwTxIdleTimeout)
+			}
+			return nil, fmt.Errorf("write transaction exceeded max lifetime %s, rolled back", RwTxMaxLifetime)
+		}
+	}
+}
+
 func handleOp(c ethdb.Cursor, stream remote.KV_TxServer, in *remote.Cursor) error {
 	var k, v []byte
 	var err error
@@ -259,6 +384,12 @@ func handleOp(c ethdb.Cursor, stream remote.KV_TxServer, in *remote.Cursor) erro
 		k, v, err = c.SeekExact(in.K)
 	case remote.Op_SEEK_BOTH_EXACT:
 		k, v, err = c.(ethdb.CursorDupSort).SeekBothExact(in.K, in.V)
+	case remote.Op_PUT:
+		err = c.Put(in.K, in.V)
+	case remote.Op_DELETE:
+		err = c.Delete(in.K, in.V)
+	case remote.Op_APPEND:
+		err = c.Append(in.K, in.V)
 	default:
 		return fmt.Errorf("unknown operation: %s", in.Op)
 	}
@@ -1,6 +1,7 @@
 package ethdb
 
 import (
+	"encoding/binary"
 	"errors"
 	"fmt"
 
@@ -12,6 +13,13 @@ type StorageMode struct {
 	Receipts   bool
 	TxIndex    bool
 	CallTraces bool
+	// TokenTransfers enables the index of ERC-20/721 transfers by holder, it is built from the receipts
+	TokenTransfers bool
+	// AccountTxs enables the index of transactions by the addresses they touch, including the internal calls
+	AccountTxs bool
+	// PruneDistance is the number of most recent blocks for which changesets, history indexes,
+	// receipts and call traces are kept. 0 means that nothing is pruned.
+	PruneDistance uint64
 }
 
 var DefaultStorageMode = StorageMode{History: true, Receipts: true, TxIndex: true, CallTraces: false}
@@ -30,6 +38,12 @@ func (m StorageMode) ToString() string {
 	if m.CallTraces {
 		modeString += "c"
 	}
+	if m.TokenTransfers {
+		modeString += "e"
+	}
+	if m.AccountTxs {
+		modeString += "a"
+	}
 	return modeString
 }
 
@@ -45,10 +59,17 @@ func StorageModeFromString(flags string) (StorageMode, error) {
 			mode.TxIndex = true
 		case 'c':
 			mode.CallTraces = true
+		case 'e':
+			mode.TokenTransfers = true
+		case 'a':
+			mode.AccountTxs = true
 		default:
 			return mode, fmt.Errorf("unexpected flag found: %c", flag)
 		}
 	}
+	if mode.TokenTransfers && !mode.Receipts {
+		return mode, errors.New("token transfers index (e) is built from the receipts, add r to the storage mode")
+	}
 
 	return mode, nil
 }
@@ -83,6 +104,26 @@ func GetStorageModeFromDB(db Database) (StorageMode, error) {
 	}
 	sm.CallTraces = len(v) == 1 && v[0] == 1
 
+	v, err = db.Get(dbutils.DatabaseInfoBucket, dbutils.StorageModeTokenTransfers)
+	if err != nil && !errors.Is(err, ErrKeyNotFound) {
+		return StorageMode{}, err
+	}
+	sm.TokenTransfers = len(v) == 1 && v[0] == 1
+
+	v, err = db.Get(dbutils.DatabaseInfoBucket, dbutils.StorageModeAccountTxs)
+	if err != nil && !errors.Is(err, ErrKeyNotFound) {
+		return StorageMode{}, err
+	}
+	sm.AccountTxs = len(v) == 1 && v[0] == 1
+
+	v, err = db.Get(dbutils.DatabaseInfoBucket, dbutils.StorageModePruneDistance)
+	if err != nil && !errors.Is(err, ErrKeyNotFound) {
+		return StorageMode{}, err
+	}
+	if len(v) == 8 {
+		sm.PruneDistance = binary.BigEndian.Uint64(v)
+	}
+
 	return sm, nil
 }
 
@@ -110,6 +151,21 @@ func SetStorageModeIfNotExist(db Database, sm StorageMode) error {
 		return err
 	}
 
+	err = setModeOnEmpty(db, dbutils.StorageModeTokenTransfers, sm.TokenTransfers)
+	if err != nil {
+		return err
+	}
+
+	err = setModeOnEmpty(db, dbutils.StorageModeAccountTxs, sm.AccountTxs)
+	if err != nil {
+		return err
+	}
+
+	err = setPruneDistanceOnEmpty(db, sm.PruneDistance)
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -130,3 +186,19 @@ func setModeOnEmpty(db Database, key []byte, currentValue bool) error {
 
 	return nil
 }
+
+func setPruneDistanceOnEmpty(db Database, distance uint64) error {
+	_, err := db.Get(dbutils.DatabaseInfoBucket, dbutils.StorageModePruneDistance)
+	if err != nil && !errors.Is(err, ErrKeyNotFound) {
+		return err
+	}
+	if errors.Is(err, ErrKeyNotFound) {
+		val := make([]byte, 8)
+		binary.BigEndian.PutUint64(val, distance)
+		if err = db.Put(dbutils.DatabaseInfoBucket, dbutils.StorageModePruneDistance, val); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
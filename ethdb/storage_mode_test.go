@@ -23,6 +23,9 @@ func TestSetStorageModeIfNotExist(t *testing.T) {
 		true,
 		true,
 		true,
+		true,
+		true,
+		90000,
 	})
 	if err != nil {
 		t.Fatal(err)
@@ -38,8 +41,26 @@ func TestSetStorageModeIfNotExist(t *testing.T) {
 		true,
 		true,
 		true,
+		true,
+		true,
+		90000,
 	}) {
 		spew.Dump(sm)
 		t.Fatal("not equal")
 	}
 }
+
+func TestStorageModeFromString(t *testing.T) {
+	sm, err := StorageModeFromString("hrte")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(sm, StorageMode{History: true, Receipts: true, TxIndex: true, TokenTransfers: true}) {
+		spew.Dump(sm)
+		t.Fatal("not equal")
+	}
+
+	if _, err = StorageModeFromString("hte"); err == nil {
+		t.Fatal("expected the token transfers without the receipts to be rejected")
+	}
+}
@@ -16,7 +16,8 @@
 
 package graphql
 
-const schema string = `
+// Schema is the GraphQL schema of Ethereum node data, it is served by the node and by rpcdaemon
+const Schema string = `
     # Bytes32 is a 32 byte binary string, represented as 0x-prefixed hexadecimal.
     scalar Bytes32
     # Address is a 20 byte Ethereum address, represented as 0x-prefixed hexadecimal.
@@ -37,7 +37,7 @@ func New(stack *node.Node, backend ethapi.Backend, cors, vhosts []string) error
 func newHandler(stack *node.Node, backend ethapi.Backend, cors, vhosts []string) error {
 	q := Resolver{backend}
 
-	s, err := graphql.ParseSchema(schema, &q)
+	s, err := graphql.ParseSchema(Schema, &q)
 	if err != nil {
 		return err
 	}
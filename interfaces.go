@@ -121,6 +121,8 @@ type CallMsg struct {
 	GasPrice *uint256.Int    // wei <-> gas exchange ratio
 	Value    *uint256.Int    // amount of wei sent along with the call
 	Data     []byte          // input data, usually an ABI-encoded contract method invocation
+
+	AccessList types.AccessList // EIP-2930 access list.
 }
 
 // A ContractCaller provides contract calls, essentially transactions that are executed by
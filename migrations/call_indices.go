@@ -0,0 +1,37 @@
+package migrations
+
+import (
+	"github.com/ledgerwatch/turbo-geth/common/dbutils"
+	"github.com/ledgerwatch/turbo-geth/common/etl"
+	"github.com/ledgerwatch/turbo-geth/eth/stagedsync/stages"
+	"github.com/ledgerwatch/turbo-geth/ethdb"
+)
+
+// clearCallIndices - the call indices used to be written empty, they are rebuilt by the CallTraces stage.
+// The blocks already pruned by the Prune stage can't be re-executed, the stage restarts from the first block which isn't pruned
+var clearCallIndices = Migration{
+	Name:    "clear_call_indices",
+	Buckets: []string{dbutils.CallFromIndex, dbutils.CallToIndex, dbutils.SyncStageProgress, dbutils.SyncStageUnwind},
+	Up: func(db ethdb.Database, tmpdir string, progress []byte, OnLoadCommit etl.LoadCommitHandler) error {
+		if err := db.(ethdb.BucketsMigrator).ClearBuckets(dbutils.CallFromIndex, dbutils.CallToIndex); err != nil {
+			return err
+		}
+
+		pruned, err := stages.GetStageProgress(db, stages.Prune)
+		if err != nil {
+			return err
+		}
+		var restartFrom uint64
+		if pruned > 0 {
+			restartFrom = pruned - 1
+		}
+		if err = stages.SaveStageProgress(db, stages.CallTraces, restartFrom); err != nil {
+			return err
+		}
+		if err = stages.SaveStageUnwind(db, stages.CallTraces, 0); err != nil {
+			return err
+		}
+
+		return OnLoadCommit(db, nil, true)
+	},
+}
@@ -0,0 +1,42 @@
+package migrations
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ledgerwatch/turbo-geth/common/dbutils"
+	"github.com/ledgerwatch/turbo-geth/eth/stagedsync/stages"
+	"github.com/ledgerwatch/turbo-geth/ethdb"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClearCallIndices(t *testing.T) {
+	for _, tc := range []struct {
+		pruned, expected uint64
+	}{{0, 0}, {50, 49}} {
+		require, db := require.New(t), ethdb.NewMemDatabase()
+		require.NoError(db.Put(dbutils.CallFromIndex, []byte{1}, []byte{1}))
+		require.NoError(db.Put(dbutils.CallToIndex, []byte{1}, []byte{1}))
+		require.NoError(stages.SaveStageProgress(db, stages.CallTraces, 100))
+		require.NoError(stages.SaveStageProgress(db, stages.Prune, tc.pruned))
+
+		migrator := NewMigrator()
+		migrator.Migrations = []Migration{clearCallIndices}
+		require.NoError(migrator.Apply(db, ""))
+
+		for _, bucket := range []string{dbutils.CallFromIndex, dbutils.CallToIndex} {
+			_, err := db.Get(bucket, []byte{1})
+			require.True(errors.Is(err, ethdb.ErrKeyNotFound), bucket)
+		}
+		progress, err := stages.GetStageProgress(db, stages.CallTraces)
+		require.NoError(err)
+		require.Equal(tc.expected, progress)
+
+		// the applied migration doesn't run again
+		require.NoError(stages.SaveStageProgress(db, stages.CallTraces, 100))
+		require.NoError(migrator.Apply(db, ""))
+		progress, err = stages.GetStageProgress(db, stages.CallTraces)
+		require.NoError(err)
+		require.Equal(uint64(100), progress)
+	}
+}
@@ -71,19 +71,36 @@ var migrations = []Migration{
 	transactionsTable,
 	historyAccBitmap,
 	historyStorageBitmap,
+	clearCallIndices,
 }
 
 type Migration struct {
 	Name string
 	Up   func(db ethdb.Database, tmpdir string, progress []byte, OnLoadCommitOnLoadCommit etl.LoadCommitHandler) error
+	// Down reverts Up, it's optional. It has the same contract as Up, see Migrator.Revert
+	Down func(db ethdb.Database, tmpdir string, progress []byte, OnLoadCommitOnLoadCommit etl.LoadCommitHandler) error
+	// Buckets changed by the migration, they are reported by the dry run and restored from the checkpoint
+	Buckets []string
 }
 
 var (
 	ErrMigrationNonUniqueName   = fmt.Errorf("please provide unique migration name")
 	ErrMigrationCommitNotCalled = fmt.Errorf("migraion commit function was not called")
 	ErrMigrationETLFilesDeleted = fmt.Errorf("db migration progress was interrupted after extraction step and ETL files was deleted, please contact development team for help or re-sync from scratch")
+	ErrMigrationNotReversible   = fmt.Errorf("migration has no Down function")
+	ErrMigrationNotApplied      = fmt.Errorf("migration is not applied")
+	ErrMigrationUnknown         = fmt.Errorf("unknown migration")
 )
 
+// Checkpoint is a copy of the database made before the migrations. If they fail, their buckets are restored from it
+type Checkpoint interface {
+	// Save copies the buckets of db, it's called out of any transaction. The buckets which don't exist in db
+	// mustn't exist in the copy
+	Save(db ethdb.Database, buckets []string) error
+	// Open opens the copy made by Save for reading
+	Open() (ethdb.KV, error)
+}
+
 func NewMigrator() *Migrator {
 	return &Migrator{
 		Migrations: migrations,
@@ -92,6 +109,22 @@ func NewMigrator() *Migrator {
 
 type Migrator struct {
 	Migrations []Migration
+	// Checkpoint, if set, is saved before applying or reverting the migrations
+	Checkpoint Checkpoint
+}
+
+// BucketInfo describes the bucket which is going to be changed by a migration
+type BucketInfo struct {
+	Name   string
+	Exists bool
+	Size   uint64
+}
+
+// MigrationPlan is what a migration is going to change, as reported by the dry run
+type MigrationPlan struct {
+	Name       string
+	Reversible bool
+	Buckets    []BucketInfo
 }
 
 func AppliedMigrations(db ethdb.Database, withPayload bool) (map[string][]byte, error) {
@@ -164,6 +197,26 @@ func (m *Migrator) Apply(db ethdb.Database, tmpdir string) error {
 		uniqueNameCheck[m.Migrations[i].Name] = true
 	}
 
+	if m.Checkpoint != nil {
+		pending, err := m.PendingMigrations(db)
+		if err != nil {
+			return err
+		}
+		if len(pending) > 0 {
+			if err = m.Checkpoint.Save(db, checkpointBuckets(pending)); err != nil {
+				return fmt.Errorf("saving checkpoint: %w", err)
+			}
+		}
+	}
+	var started []Migration
+	err := m.apply(db, tmpdir, applied, &started)
+	if err != nil && m.Checkpoint != nil && len(started) > 0 {
+		return m.restore(db, started, err)
+	}
+	return err
+}
+
+func (m *Migrator) apply(db ethdb.Database, tmpdir string, applied map[string][]byte, started *[]Migration) error {
 	tx, err1 := db.Begin(context.Background(), ethdb.RW)
 	if err1 != nil {
 		return err1
@@ -179,57 +232,257 @@ func (m *Migrator) Apply(db ethdb.Database, tmpdir string) error {
 		commitFuncCalled := false // commit function must be called if no error, protection against people's mistake
 
 		log.Info("Apply migration", "name", v.Name)
-		progress, err := tx.Get(dbutils.Migrations, []byte("_progress_"+v.Name))
+		progressKey := []byte("_progress_" + v.Name)
+		progress, err := tx.Get(dbutils.Migrations, progressKey)
 		if err != nil && !errors.Is(err, ethdb.ErrKeyNotFound) {
 			return err
 		}
 
-		if err = v.Up(tx, path.Join(tmpdir, "migrations", v.Name), progress, func(_ ethdb.Putter, key []byte, isDone bool) error {
-			if !isDone {
-				if key != nil {
-					err = tx.Put(dbutils.Migrations, []byte("_progress_"+v.Name), key)
-					if err != nil {
-						return err
-					}
-				}
-				// do commit, but don't save partial progress
-				if err := tx.CommitAndBegin(context.Background()); err != nil {
-					return err
-				}
-				return nil
-			}
-			commitFuncCalled = true
-
+		*started = append(*started, v)
+		if err = v.Up(tx, path.Join(tmpdir, "migrations", v.Name), progress, loadCommitHandler(tx, progressKey, &commitFuncCalled, func() error {
 			stagesProgress, err := MarshalMigrationPayload(tx)
 			if err != nil {
 				return err
 			}
-			err = tx.Put(dbutils.Migrations, []byte(v.Name), stagesProgress)
-			if err != nil {
-				return err
+			return tx.Put(dbutils.Migrations, []byte(v.Name), stagesProgress)
+		})); err != nil {
+			return err
+		}
+
+		if !commitFuncCalled {
+			return fmt.Errorf("%w: %s", ErrMigrationCommitNotCalled, v.Name)
+		}
+		log.Info("Applied migration", "name", v.Name)
+	}
+	return nil
+}
+
+// Revert runs Down of the applied migration and forgets it, so Apply runs it again. The progress of Down is kept
+// separately from the progress of Up
+func (m *Migrator) Revert(db ethdb.Database, tmpdir string, name string) error {
+	v, err := m.find(name)
+	if err != nil {
+		return err
+	}
+	if v.Down == nil {
+		return fmt.Errorf("%w: %s", ErrMigrationNotReversible, name)
+	}
+	applied, err := AppliedMigrations(db, false)
+	if err != nil {
+		return err
+	}
+	if _, ok := applied[name]; !ok {
+		return fmt.Errorf("%w: %s", ErrMigrationNotApplied, name)
+	}
+
+	if m.Checkpoint != nil {
+		if err = m.Checkpoint.Save(db, checkpointBuckets([]Migration{v})); err != nil {
+			return fmt.Errorf("saving checkpoint: %w", err)
+		}
+	}
+	if err = m.revert(db, tmpdir, v); err != nil && m.Checkpoint != nil {
+		return m.restore(db, []Migration{v}, err)
+	}
+	return err
+}
+
+func (m *Migrator) revert(db ethdb.Database, tmpdir string, v Migration) error {
+	tx, err := db.Begin(context.Background(), ethdb.RW)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	log.Info("Revert migration", "name", v.Name)
+	progressKey := []byte("_progress_revert_" + v.Name)
+	progress, err := tx.Get(dbutils.Migrations, progressKey)
+	if err != nil && !errors.Is(err, ethdb.ErrKeyNotFound) {
+		return err
+	}
+	commitFuncCalled := false
+	if err = v.Down(tx, path.Join(tmpdir, "migrations", v.Name+"_revert"), progress, loadCommitHandler(tx, progressKey, &commitFuncCalled, func() error {
+		return tx.Delete(dbutils.Migrations, []byte(v.Name), nil)
+	})); err != nil {
+		return err
+	}
+	if !commitFuncCalled {
+		return fmt.Errorf("%w: %s", ErrMigrationCommitNotCalled, v.Name)
+	}
+	log.Info("Reverted migration", "name", v.Name)
+	return nil
+}
+
+// loadCommitHandler commits the progress of the migration saved under progressKey, done is called
+// in the same transaction when the migration is finished
+func loadCommitHandler(tx ethdb.DbWithPendingMutations, progressKey []byte, commitFuncCalled *bool, done func() error) etl.LoadCommitHandler {
+	return func(_ ethdb.Putter, key []byte, isDone bool) error {
+		if !isDone {
+			if key != nil {
+				if err := tx.Put(dbutils.Migrations, progressKey, key); err != nil {
+					return err
+				}
 			}
+			// do commit, but don't save partial progress
+			return tx.CommitAndBegin(context.Background())
+		}
+		*commitFuncCalled = true
 
-			err = tx.Delete(dbutils.Migrations, []byte("_progress_"+v.Name), nil)
-			if err != nil {
-				return err
+		if err := done(); err != nil {
+			return err
+		}
+		if err := tx.Delete(dbutils.Migrations, progressKey, nil); err != nil {
+			return err
+		}
+		return tx.CommitAndBegin(context.Background())
+	}
+}
+
+// restore brings back the buckets of the failed migrations and the migration records from the checkpoint
+// checkpointBuckets - the buckets changed by the migrations, dbutils.Migrations goes first
+func checkpointBuckets(ms []Migration) []string {
+	buckets := []string{dbutils.Migrations}
+	seen := map[string]bool{dbutils.Migrations: true}
+	for _, v := range ms {
+		for _, bucket := range v.Buckets {
+			if !seen[bucket] {
+				seen[bucket] = true
+				buckets = append(buckets, bucket)
 			}
+		}
+	}
+	return buckets
+}
 
-			if err := tx.CommitAndBegin(context.Background()); err != nil {
+func (m *Migrator) restore(db ethdb.Database, failed []Migration, cause error) error {
+	buckets := checkpointBuckets(failed)
+	names := make([]string, 0, len(failed))
+	for _, v := range failed {
+		if len(v.Buckets) == 0 {
+			log.Warn("Migration doesn't declare its buckets, they aren't restored", "name", v.Name)
+		}
+		names = append(names, v.Name)
+	}
+	log.Warn("Restoring buckets from checkpoint", "migrations", names, "buckets", buckets, "err", cause)
+	checkpoint, err := m.Checkpoint.Open()
+	if err != nil {
+		return fmt.Errorf("%w, opening checkpoint: %v", cause, err)
+	}
+	defer checkpoint.Close()
+	if err = restoreBuckets(db, checkpoint, buckets); err != nil {
+		return fmt.Errorf("%w, restoring checkpoint: %v", cause, err)
+	}
+	return cause
+}
+
+func restoreBuckets(db ethdb.Database, checkpoint ethdb.KV, buckets []string) error {
+	tx, err := db.Begin(context.Background(), ethdb.RW)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	err = checkpoint.View(context.Background(), func(cpTx ethdb.Tx) error {
+		for _, bucket := range buckets {
+			inCheckpoint := cpTx.(ethdb.BucketMigrator).ExistsBucket(bucket)
+			if !inCheckpoint {
+				// the bucket is created by the migration, only the deprecated ones can be dropped
+				if exists, err := tx.(ethdb.BucketsMigrator).BucketExists(bucket); err != nil || !exists {
+					return err
+				}
+				if dbutils.BucketsConfigs[bucket].IsDeprecated {
+					if err := tx.(ethdb.BucketsMigrator).DropBuckets(bucket); err != nil {
+						return err
+					}
+					continue
+				}
+			}
+			if err := tx.(ethdb.BucketsMigrator).ClearBuckets(bucket); err != nil {
+				return err
+			}
+			if !inCheckpoint {
+				continue
+			}
+			if err := copyBucket(tx, cpTx, bucket); err != nil {
 				return err
 			}
-			return nil
-		}); err != nil {
-			return err
 		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	_, err = tx.Commit()
+	return err
+}
 
-		if !commitFuncCalled {
-			return fmt.Errorf("%w: %s", ErrMigrationCommitNotCalled, v.Name)
+// copyBucket puts the whole bucket into tx, the buckets are restored in one transaction so the restore is atomic
+func copyBucket(tx ethdb.DbWithPendingMutations, from ethdb.Tx, bucket string) error {
+	c := from.Cursor(bucket)
+	defer c.Close()
+	for k, v, err := c.First(); k != nil; k, v, err = c.Next() {
+		if err != nil {
+			return err
+		}
+		if err = tx.Put(bucket, common.CopyBytes(k), common.CopyBytes(v)); err != nil {
+			return err
 		}
-		log.Info("Applied migration", "name", v.Name)
 	}
 	return nil
 }
 
+// DryRun reports the buckets which the pending migrations are going to change
+func (m *Migrator) DryRun(db ethdb.Database) ([]MigrationPlan, error) {
+	pending, err := m.PendingMigrations(db)
+	if err != nil {
+		return nil, err
+	}
+	return plan(db, pending)
+}
+
+// RevertDryRun reports the buckets which reverting of the migration is going to change
+func (m *Migrator) RevertDryRun(db ethdb.Database, name string) (MigrationPlan, error) {
+	v, err := m.find(name)
+	if err != nil {
+		return MigrationPlan{}, err
+	}
+	plans, err := plan(db, []Migration{v})
+	if err != nil {
+		return MigrationPlan{}, err
+	}
+	return plans[0], nil
+}
+
+func (m *Migrator) find(name string) (Migration, error) {
+	for i := range m.Migrations {
+		if m.Migrations[i].Name == name {
+			return m.Migrations[i], nil
+		}
+	}
+	return Migration{}, fmt.Errorf("%w: %s", ErrMigrationUnknown, name)
+}
+
+func plan(db ethdb.Database, migrations []Migration) ([]MigrationPlan, error) {
+	plans := make([]MigrationPlan, 0, len(migrations))
+	err := db.(ethdb.HasKV).KV().View(context.Background(), func(tx ethdb.Tx) error {
+		for _, v := range migrations {
+			p := MigrationPlan{Name: v.Name, Reversible: v.Down != nil}
+			for _, bucket := range v.Buckets {
+				info := BucketInfo{Name: bucket, Exists: tx.(ethdb.BucketMigrator).ExistsBucket(bucket)}
+				if info.Exists {
+					size, err := tx.BucketSize(bucket)
+					if err != nil {
+						return err
+					}
+					info.Size = size
+				}
+				p.Buckets = append(p.Buckets, info)
+			}
+			plans = append(plans, p)
+		}
+		return nil
+	})
+	return plans, err
+}
+
 func MarshalMigrationPayload(db ethdb.Getter) ([]byte, error) {
 	s := map[string][]byte{}
 
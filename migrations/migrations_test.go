@@ -1,11 +1,13 @@
 package migrations
 
 import (
+	"context"
 	"errors"
 	"testing"
 
 	"github.com/ledgerwatch/turbo-geth/eth/stagedsync/stages"
 
+	"github.com/ledgerwatch/turbo-geth/common"
 	"github.com/ledgerwatch/turbo-geth/common/dbutils"
 	"github.com/ledgerwatch/turbo-geth/common/etl"
 	"github.com/ledgerwatch/turbo-geth/ethdb"
@@ -16,14 +18,14 @@ func TestApplyWithInit(t *testing.T) {
 	require, db := require.New(t), ethdb.NewMemDatabase()
 	migrations = []Migration{
 		{
-			"one",
-			func(db ethdb.Database, tmpdir string, progress []byte, OnLoadCommit etl.LoadCommitHandler) error {
+			Name: "one",
+			Up: func(db ethdb.Database, tmpdir string, progress []byte, OnLoadCommit etl.LoadCommitHandler) error {
 				return OnLoadCommit(db, nil, true)
 			},
 		},
 		{
-			"two",
-			func(db ethdb.Database, tmpdir string, progress []byte, OnLoadCommit etl.LoadCommitHandler) error {
+			Name: "two",
+			Up: func(db ethdb.Database, tmpdir string, progress []byte, OnLoadCommit etl.LoadCommitHandler) error {
 				return OnLoadCommit(db, nil, true)
 			},
 		},
@@ -55,15 +57,15 @@ func TestApplyWithoutInit(t *testing.T) {
 	require, db := require.New(t), ethdb.NewMemDatabase()
 	migrations = []Migration{
 		{
-			"one",
-			func(db ethdb.Database, tmpdir string, progress []byte, OnLoadCommit etl.LoadCommitHandler) error {
+			Name: "one",
+			Up: func(db ethdb.Database, tmpdir string, progress []byte, OnLoadCommit etl.LoadCommitHandler) error {
 				t.Fatal("shouldn't been executed")
 				return nil
 			},
 		},
 		{
-			"two",
-			func(db ethdb.Database, tmpdir string, progress []byte, OnLoadCommit etl.LoadCommitHandler) error {
+			Name: "two",
+			Up: func(db ethdb.Database, tmpdir string, progress []byte, OnLoadCommit etl.LoadCommitHandler) error {
 				return OnLoadCommit(db, nil, true)
 			},
 		},
@@ -98,14 +100,14 @@ func TestWhenNonFirstMigrationAlreadyApplied(t *testing.T) {
 	require, db := require.New(t), ethdb.NewMemDatabase()
 	migrations = []Migration{
 		{
-			"one",
-			func(db ethdb.Database, tmpdir string, progress []byte, OnLoadCommit etl.LoadCommitHandler) error {
+			Name: "one",
+			Up: func(db ethdb.Database, tmpdir string, progress []byte, OnLoadCommit etl.LoadCommitHandler) error {
 				return OnLoadCommit(db, nil, true)
 			},
 		},
 		{
-			"two",
-			func(db ethdb.Database, tmpdir string, progress []byte, OnLoadCommit etl.LoadCommitHandler) error {
+			Name: "two",
+			Up: func(db ethdb.Database, tmpdir string, progress []byte, OnLoadCommit etl.LoadCommitHandler) error {
 				t.Fatal("shouldn't been executed")
 				return nil
 			},
@@ -200,3 +202,180 @@ func TestCommitCallRequired(t *testing.T) {
 	require.NoError(err)
 	require.Equal(0, len(applied))
 }
+
+func TestRevert(t *testing.T) {
+	require, db := require.New(t), ethdb.NewMemDatabase()
+	migrations = []Migration{
+		{
+			Name:    "one",
+			Buckets: []string{dbutils.DatabaseInfoBucket},
+			Up: func(db ethdb.Database, tmpdir string, progress []byte, OnLoadCommit etl.LoadCommitHandler) error {
+				if err := db.Put(dbutils.DatabaseInfoBucket, []byte("key"), []byte("value")); err != nil {
+					return err
+				}
+				return OnLoadCommit(db, nil, true)
+			},
+			Down: func(db ethdb.Database, tmpdir string, progress []byte, OnLoadCommit etl.LoadCommitHandler) error {
+				if err := db.Delete(dbutils.DatabaseInfoBucket, []byte("key"), nil); err != nil {
+					return err
+				}
+				return OnLoadCommit(db, nil, true)
+			},
+		},
+		{
+			Name: "two",
+			Up: func(db ethdb.Database, tmpdir string, progress []byte, OnLoadCommit etl.LoadCommitHandler) error {
+				return OnLoadCommit(db, nil, true)
+			},
+		},
+	}
+	migrator := NewMigrator()
+	migrator.Migrations = migrations
+
+	err := migrator.Revert(db, "", "one")
+	require.True(errors.Is(err, ErrMigrationNotApplied))
+	err = migrator.Apply(db, "")
+	require.NoError(err)
+
+	err = migrator.Revert(db, "", "two")
+	require.True(errors.Is(err, ErrMigrationNotReversible))
+	err = migrator.Revert(db, "", "three")
+	require.True(errors.Is(err, ErrMigrationUnknown))
+
+	err = migrator.Revert(db, "", "one")
+	require.NoError(err)
+	_, err = db.Get(dbutils.DatabaseInfoBucket, []byte("key"))
+	require.True(errors.Is(err, ethdb.ErrKeyNotFound))
+	applied, err := AppliedMigrations(db, false)
+	require.NoError(err)
+	_, ok := applied["one"]
+	require.False(ok)
+	_, ok = applied["two"]
+	require.True(ok)
+
+	// reverted migration is applied again
+	err = migrator.Apply(db, "")
+	require.NoError(err)
+	v, err := db.Get(dbutils.DatabaseInfoBucket, []byte("key"))
+	require.NoError(err)
+	require.Equal([]byte("value"), v)
+}
+
+func TestDryRun(t *testing.T) {
+	require, db := require.New(t), ethdb.NewMemDatabase()
+	migrations = []Migration{
+		{
+			Name:    "one",
+			Buckets: []string{dbutils.DatabaseInfoBucket},
+			Up: func(db ethdb.Database, tmpdir string, progress []byte, OnLoadCommit etl.LoadCommitHandler) error {
+				t.Fatal("shouldn't been executed")
+				return nil
+			},
+			Down: func(db ethdb.Database, tmpdir string, progress []byte, OnLoadCommit etl.LoadCommitHandler) error {
+				t.Fatal("shouldn't been executed")
+				return nil
+			},
+		},
+	}
+	err := db.Put(dbutils.DatabaseInfoBucket, []byte("key"), []byte("value"))
+	require.NoError(err)
+
+	migrator := NewMigrator()
+	migrator.Migrations = migrations
+	plans, err := migrator.DryRun(db)
+	require.NoError(err)
+	require.Equal(1, len(plans))
+	require.Equal("one", plans[0].Name)
+	require.True(plans[0].Reversible)
+	require.Equal(1, len(plans[0].Buckets))
+	require.Equal(dbutils.DatabaseInfoBucket, plans[0].Buckets[0].Name)
+	require.True(plans[0].Buckets[0].Exists)
+	require.True(plans[0].Buckets[0].Size > 0)
+
+	p, err := migrator.RevertDryRun(db, "one")
+	require.NoError(err)
+	require.Equal(plans[0], p)
+
+	applied, err := AppliedMigrations(db, false)
+	require.NoError(err)
+	require.Equal(0, len(applied))
+}
+
+type testCheckpoint struct {
+	buckets []string
+	kv      ethdb.KV
+}
+
+func (c *testCheckpoint) Save(db ethdb.Database, buckets []string) error {
+	c.buckets = buckets
+	c.kv = ethdb.NewLMDB().InMem().MustOpen()
+	return c.kv.Update(context.Background(), func(tx ethdb.Tx) error {
+		for _, bucket := range buckets {
+			if err := db.Walk(bucket, nil, 0, func(k, v []byte) (bool, error) {
+				return true, tx.Cursor(bucket).Put(common.CopyBytes(k), common.CopyBytes(v))
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (c *testCheckpoint) Open() (ethdb.KV, error) {
+	return c.kv, nil
+}
+
+func TestCheckpointRestore(t *testing.T) {
+	require, db := require.New(t), ethdb.NewMemDatabase()
+	errFailed := errors.New("failed")
+	migrations = []Migration{
+		{
+			Name:    "one",
+			Buckets: []string{dbutils.DatabaseInfoBucket},
+			Up: func(db ethdb.Database, tmpdir string, progress []byte, OnLoadCommit etl.LoadCommitHandler) error {
+				if err := db.Put(dbutils.DatabaseInfoBucket, []byte("a"), []byte{2}); err != nil {
+					return err
+				}
+				return OnLoadCommit(db, nil, true)
+			},
+		},
+		{
+			Name:    "two",
+			Buckets: []string{dbutils.DatabaseInfoBucket},
+			Up: func(db ethdb.Database, tmpdir string, progress []byte, OnLoadCommit etl.LoadCommitHandler) error {
+				if err := db.Put(dbutils.DatabaseInfoBucket, []byte("b"), []byte{1}); err != nil {
+					return err
+				}
+				// the partial progress is committed before the failure
+				if err := OnLoadCommit(db, []byte("b"), false); err != nil {
+					return err
+				}
+				return errFailed
+			},
+		},
+	}
+	err := db.Put(dbutils.DatabaseInfoBucket, []byte("a"), []byte{1})
+	require.NoError(err)
+
+	migrator := NewMigrator()
+	migrator.Migrations = migrations
+	checkpoint := &testCheckpoint{}
+	migrator.Checkpoint = checkpoint
+	err = migrator.Apply(db, "")
+	require.True(errors.Is(err, errFailed))
+	// only the buckets of the pending migrations are saved
+	require.Equal([]string{dbutils.Migrations, dbutils.DatabaseInfoBucket}, checkpoint.buckets)
+
+	v, err := db.Get(dbutils.DatabaseInfoBucket, []byte("a"))
+	require.NoError(err)
+	require.Equal([]byte{1}, v)
+	_, err = db.Get(dbutils.DatabaseInfoBucket, []byte("b"))
+	require.True(errors.Is(err, ethdb.ErrKeyNotFound))
+
+	// the records of the migrations and their progress are restored too, so all of them are pending again
+	applied, err := AppliedMigrations(db, false)
+	require.NoError(err)
+	require.Equal(0, len(applied))
+	_, err = db.Get(dbutils.Migrations, []byte("_progress_two"))
+	require.True(errors.Is(err, ethdb.ErrKeyNotFound))
+}
@@ -1,13 +1,15 @@
 package migrations
 
 import (
+	"github.com/ledgerwatch/turbo-geth/common/dbutils"
 	"github.com/ledgerwatch/turbo-geth/common/etl"
 	"github.com/ledgerwatch/turbo-geth/eth/stagedsync/stages"
 	"github.com/ledgerwatch/turbo-geth/ethdb"
 )
 
 var stagedsyncToUseStageBlockhashes = Migration{
-	Name: "stagedsync_to_use_stage_blockhashes",
+	Name:    "stagedsync_to_use_stage_blockhashes",
+	Buckets: []string{dbutils.SyncStageProgress},
 	Up: func(db ethdb.Database, tmpdir string, progress []byte, OnLoadCommit etl.LoadCommitHandler) error {
 
 		var stageProgress uint64
@@ -26,10 +28,17 @@ var stagedsyncToUseStageBlockhashes = Migration{
 
 		return nil
 	},
+	Down: func(db ethdb.Database, tmpdir string, progress []byte, OnLoadCommit etl.LoadCommitHandler) error {
+		if err := db.Delete(dbutils.SyncStageProgress, stages.BlockHashes, nil); err != nil {
+			return err
+		}
+		return OnLoadCommit(db, nil, true)
+	},
 }
 
 var unwindStagedsyncToUseStageBlockhashes = Migration{
-	Name: "unwind_stagedsync_to_use_stage_blockhashes",
+	Name:    "unwind_stagedsync_to_use_stage_blockhashes",
+	Buckets: []string{dbutils.SyncStageUnwind},
 	Up: func(db ethdb.Database, tmpdir string, progress []byte, OnLoadCommit etl.LoadCommitHandler) error {
 
 		var stageProgress uint64
@@ -48,4 +57,10 @@ var unwindStagedsyncToUseStageBlockhashes = Migration{
 
 		return nil
 	},
+	Down: func(db ethdb.Database, tmpdir string, progress []byte, OnLoadCommit etl.LoadCommitHandler) error {
+		if err := db.Delete(dbutils.SyncStageUnwind, stages.BlockHashes, nil); err != nil {
+			return err
+		}
+		return OnLoadCommit(db, nil, true)
+	},
 }
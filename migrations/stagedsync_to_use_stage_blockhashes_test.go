@@ -25,6 +25,12 @@ func TestStagedsyncToUseStageBlockhashes(t *testing.T) {
 	actual, err := stages.GetStageProgress(db, stages.BlockHashes)
 	assert.NoError(t, err)
 	assert.Equal(t, expected, actual)
+
+	err = migrator.Revert(db, "", stagedsyncToUseStageBlockhashes.Name)
+	require.NoError(err)
+	actual, err = stages.GetStageProgress(db, stages.BlockHashes)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(0), actual)
 }
 
 func TestUnwindStagedsyncToUseStageBlockhashes(t *testing.T) {
@@ -23,7 +23,8 @@ var dbKeys = []stages.SyncStage{
 }
 
 var stagesToUseNamedKeys = Migration{
-	Name: "stages_to_use_named_keys",
+	Name:    "stages_to_use_named_keys",
+	Buckets: []string{dbutils.SyncStageProgressOld1, dbutils.SyncStageProgress},
 	Up: func(db ethdb.Database, tmpdir string, progress []byte, OnLoadCommit etl.LoadCommitHandler) error {
 
 		if exists, err := db.(ethdb.BucketsMigrator).BucketExists(dbutils.SyncStageProgressOld1); err != nil {
@@ -69,7 +70,8 @@ var stagesToUseNamedKeys = Migration{
 }
 
 var unwindStagesToUseNamedKeys = Migration{
-	Name: "unwind_stages_to_use_named_keys",
+	Name:    "unwind_stages_to_use_named_keys",
+	Buckets: []string{dbutils.SyncStageUnwindOld1, dbutils.SyncStageUnwind},
 	Up: func(db ethdb.Database, tmpdir string, progress []byte, OnLoadCommit etl.LoadCommitHandler) error {
 		if exists, err := db.(ethdb.BucketsMigrator).BucketExists(dbutils.SyncStageUnwindOld1); err != nil {
 			return err
@@ -17,7 +17,8 @@ import (
 )
 
 var transactionsTable = Migration{
-	Name: "tx_table_4",
+	Name:    "tx_table_4",
+	Buckets: []string{dbutils.BlockBodyPrefix, dbutils.EthTx},
 	Up: func(db ethdb.Database, tmpdir string, progress []byte, CommitProgress etl.LoadCommitHandler) (err error) {
 		logEvery := time.NewTicker(30 * time.Second)
 		defer logEvery.Stop()
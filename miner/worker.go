@@ -757,7 +757,7 @@ func (w *worker) updateSnapshot() {
 		w.current.receipts,
 	)
 
-	w.snapshotState = w.current.state
+	w.snapshotState = w.current.state.Copy()
 	w.snapshotTds = w.current.tds.WithNewBuffer()
 }
 
@@ -191,6 +191,9 @@ type Config struct {
 	// empty string means not to start the listener
 	PrivateApiAddr string
 
+	// Buckets which the clients of the remote database access can modify, empty means the access is read-only
+	PrivateApiWritableBuckets []string
+
 	staticNodesWarning     bool
 	trustedNodesWarning    bool
 	oldGethResourceWarning bool
@@ -49,6 +49,16 @@ will be pointing to the given third argument. Since the optional argument is the
 argument the RPC package will also accept 2 integers as arguments. It will pass the mod
 argument as nil to the RPC method.
 
+Methods with big results can write them to a stream instead of returning them. Such method
+takes *jsoniter.Stream as the last argument, which is not a parameter of the call, and
+returns only an error:
+
+ func (s *CalcService) Range(n int, stream *jsoniter.Stream) error
+
+The result of a single call is sent to the client as the method flushes the stream, if the
+transport allows it. The error returned before anything is flushed is sent as the usual
+error response.
+
 The server offers the ServeCodec method which accepts a ServerCodec instance. It will read
 requests from the codec, process the request and sends the response back to the client
 using the codec. The server can execute requests concurrently. Responses can be sent back
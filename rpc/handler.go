@@ -17,14 +17,17 @@
 package rpc
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"io"
 	"reflect"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
 
+	jsoniter "github.com/json-iterator/go"
 	"github.com/ledgerwatch/turbo-geth/log"
 )
 
@@ -71,6 +74,7 @@ type handler struct {
 type callProc struct {
 	ctx       context.Context
 	notifiers []*Notifier
+	stream    bool // the result of the call may be written to the connection while it's produced, see streamWriter
 }
 
 func newHandler(connCtx context.Context, conn jsonWriter, idgen func() ID, reg *serviceRegistry, allowList AllowList) *handler {
@@ -139,9 +143,10 @@ func (h *handler) handleMsg(msg *jsonrpcMessage) {
 		return
 	}
 	h.startCallProc(func(cp *callProc) {
+		cp.stream = true
 		answer := h.handleCallMsg(cp, msg)
 		h.addSubscriptions(cp.notifiers)
-		if answer != nil {
+		if answer != nil && !answer.streamed {
 			h.conn.writeJSON(cp.ctx, answer)
 		}
 		for _, n := range cp.notifiers {
@@ -344,7 +349,7 @@ func (h *handler) handleCall(cp *callProc, msg *jsonrpcMessage) *jsonrpcMessage
 		return msg.errorResponse(&invalidParamsError{err.Error()})
 	}
 	start := time.Now()
-	answer := h.runMethod(cp.ctx, msg, callb, args)
+	answer := h.runMethod(cp.ctx, msg, callb, args, cp.stream && msg.isCall())
 
 	// Collect the statistics for RPC calls if metrics is enabled.
 	// We only care about pure rpc call. Filter out subscription.
@@ -391,18 +396,92 @@ func (h *handler) handleSubscribe(cp *callProc, msg *jsonrpcMessage) *jsonrpcMes
 	cp.notifiers = append(cp.notifiers, n)
 	ctx := context.WithValue(cp.ctx, notifierKey{}, n)
 
-	return h.runMethod(ctx, msg, callb, args)
+	return h.runMethod(ctx, msg, callb, args, false)
 }
 
 // runMethod runs the Go callback for an RPC method.
-func (h *handler) runMethod(ctx context.Context, msg *jsonrpcMessage, callb *callback, args []reflect.Value) *jsonrpcMessage {
-	result, err := callb.call(ctx, msg.Method, args)
+func (h *handler) runMethod(ctx context.Context, msg *jsonrpcMessage, callb *callback, args []reflect.Value, stream bool) *jsonrpcMessage {
+	if callb.streamable {
+		return h.runStreamMethod(ctx, msg, callb, args, stream)
+	}
+	result, err := callb.call(ctx, msg.Method, args, nil)
 	if err != nil {
 		return msg.errorResponse(err)
 	}
 	return msg.response(result)
 }
 
+// runStreamMethod runs the Go callback which writes its result to a stream. If stream is set and the connection
+// supports it, the response is written to the connection while the result is produced, otherwise the result is
+// buffered. The error returned before any part of the result is written is sent as the usual error response.
+func (h *handler) runStreamMethod(ctx context.Context, msg *jsonrpcMessage, callb *callback, args []reflect.Value, stream bool) *jsonrpcMessage {
+	sw, ok := h.conn.(streamWriter)
+	if !stream || !ok {
+		var buf bytes.Buffer
+		s := jsoniter.NewStream(jsoniter.ConfigCompatibleWithStandardLibrary, &buf, 4096)
+		if _, err := callb.call(ctx, msg.Method, args, s); err != nil {
+			return msg.errorResponse(err)
+		}
+		if err := s.Flush(); err != nil {
+			return msg.errorResponse(err)
+		}
+		if buf.Len() == 0 {
+			return msg.response(nil)
+		}
+		return &jsonrpcMessage{Version: vsn, ID: msg.ID, Result: buf.Bytes()}
+	}
+
+	var answer *jsonrpcMessage
+	if err := sw.writeStream(ctx, func(w io.Writer) error {
+		resp := &streamResponse{w: w, id: msg.ID}
+		s := jsoniter.NewStream(jsoniter.ConfigCompatibleWithStandardLibrary, resp, 4096)
+		_, err := callb.call(ctx, msg.Method, args, s)
+		if err == nil {
+			err = s.Flush()
+		}
+		if err != nil {
+			answer = msg.errorResponse(err)
+			if !resp.started {
+				return nil
+			}
+			// The beginning of the result is already sent, the error is added to the response
+			answer.streamed = true
+			enc, _ := json.Marshal(answer.Error)
+			_, werr := w.Write(append(append([]byte(`,"error":`), enc...), "}\n"...))
+			return werr
+		}
+		answer = &jsonrpcMessage{Version: vsn, ID: msg.ID, streamed: true}
+		if !resp.started {
+			if _, err = resp.Write([]byte("null")); err != nil {
+				return err
+			}
+		}
+		_, err = w.Write([]byte("}\n"))
+		return err
+	}); err != nil {
+		h.log.Debug("Failed to write the streamed response", "method", msg.Method, "err", err)
+	}
+	return answer
+}
+
+// streamResponse writes the beginning of the response before the first part of the result
+type streamResponse struct {
+	w       io.Writer
+	id      json.RawMessage
+	started bool
+}
+
+func (r *streamResponse) Write(p []byte) (int, error) {
+	if !r.started {
+		r.started = true
+		head := append(append([]byte(`{"jsonrpc":"`+vsn+`","id":`), r.id...), `,"result":`...)
+		if _, err := r.w.Write(head); err != nil {
+			return 0, err
+		}
+	}
+	return r.w.Write(p)
+}
+
 // unsubscribe is the callback function for all *_unsubscribe calls.
 func (h *handler) unsubscribe(ctx context.Context, id ID) (bool, error) {
 	h.subLock.Lock()
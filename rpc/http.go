@@ -223,6 +223,13 @@ func (t *httpServerConn) RemoteAddr() string {
 // SetWriteDeadline does nothing and always returns nil.
 func (t *httpServerConn) SetWriteDeadline(time.Time) error { return nil }
 
+// Flush sends the buffered part of the response to the client.
+func (t *httpServerConn) Flush() {
+	if flusher, ok := t.Writer.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
 // ServeHTTP serves JSON-RPC requests over HTTP.
 func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	// Permit dumb empty requests for remote health-checks (AWS)
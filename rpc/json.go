@@ -55,6 +55,8 @@ type jsonrpcMessage struct {
 	Params  json.RawMessage `json:"params,omitempty"`
 	Error   *jsonError      `json:"error,omitempty"`
 	Result  json.RawMessage `json:"result,omitempty"`
+
+	streamed bool // the response is already written to the connection, see handler.runStreamMethod
 }
 
 func (msg *jsonrpcMessage) isNotification() bool {
@@ -195,7 +197,7 @@ func NewCodec(conn Conn) ServerCodec {
 	enc := json.NewEncoder(conn)
 	dec := json.NewDecoder(conn)
 	dec.UseNumber()
-	return NewFuncCodec(conn, enc.Encode, dec.Decode)
+	return &streamCodec{jsonCodec: NewFuncCodec(conn, enc.Encode, dec.Decode).(*jsonCodec), w: conn}
 }
 
 func (c *jsonCodec) remoteAddr() string {
@@ -244,6 +246,38 @@ func (c *jsonCodec) closed() <-chan interface{} {
 	return c.closeCh
 }
 
+// streamCodec is the codec of the connection which gives access to its writer, so the responses
+// can be written to it while they are produced, see streamWriter
+type streamCodec struct {
+	*jsonCodec
+	w io.Writer
+}
+
+func (c *streamCodec) writeStream(ctx context.Context, write func(w io.Writer) error) error {
+	c.encMu.Lock()
+	defer c.encMu.Unlock()
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(defaultWriteTimeout)
+	}
+	c.conn.SetWriteDeadline(deadline)
+	return write(flushWriter{c.w})
+}
+
+// flushWriter sends the written data to the peer right away if the writer buffers it
+type flushWriter struct {
+	w io.Writer
+}
+
+func (f flushWriter) Write(p []byte) (int, error) {
+	n, err := f.w.Write(p)
+	if flusher, ok := f.w.(interface{ Flush() }); ok && err == nil {
+		flusher.Flush()
+	}
+	return n, err
+}
+
 // parseMessage parses raw bytes as a (batch of) JSON-RPC message(s). There are no error
 // checks in this function because the raw message has already been syntax-checked when it
 // is called. Any non-JSON-RPC messages in the input return the zero value of
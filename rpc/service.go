@@ -26,6 +26,7 @@ import (
 	"sync"
 	"unicode"
 
+	jsoniter "github.com/json-iterator/go"
 	"github.com/ledgerwatch/turbo-geth/log"
 )
 
@@ -34,6 +35,7 @@ var (
 	errorType        = reflect.TypeOf((*error)(nil)).Elem()
 	subscriptionType = reflect.TypeOf(Subscription{})
 	stringType       = reflect.TypeOf("")
+	streamType       = reflect.TypeOf((*jsoniter.Stream)(nil))
 )
 
 type serviceRegistry struct {
@@ -56,6 +58,7 @@ type callback struct {
 	hasCtx      bool           // method's first argument is a context (not included in argTypes)
 	errPos      int            // err return idx, of -1 when method cannot return error
 	isSubscribe bool           // true if this is a subscription callback
+	streamable  bool           // method's last argument is a *jsoniter.Stream the result is written to (not included in argTypes)
 }
 
 func (r *serviceRegistry) registerName(name string, rcvr interface{}) error {
@@ -173,17 +176,23 @@ func (c *callback) makeArgTypes() {
 		c.hasCtx = true
 		firstArg++
 	}
+	// Skip the stream the result is written to (if present).
+	lastArg := fntype.NumIn()
+	if lastArg > firstArg && fntype.In(lastArg-1) == streamType {
+		c.streamable = true
+		lastArg--
+	}
 	// Add all remaining parameters.
-	c.argTypes = make([]reflect.Type, fntype.NumIn()-firstArg)
-	for i := firstArg; i < fntype.NumIn(); i++ {
+	c.argTypes = make([]reflect.Type, lastArg-firstArg)
+	for i := firstArg; i < lastArg; i++ {
 		c.argTypes[i-firstArg] = fntype.In(i)
 	}
 }
 
-// call invokes the callback.
-func (c *callback) call(ctx context.Context, method string, args []reflect.Value) (res interface{}, errRes error) {
+// call invokes the callback. The stream is passed to the streamable callbacks only.
+func (c *callback) call(ctx context.Context, method string, args []reflect.Value, stream *jsoniter.Stream) (res interface{}, errRes error) {
 	// Create the argument slice.
-	fullargs := make([]reflect.Value, 0, 2+len(args))
+	fullargs := make([]reflect.Value, 0, 3+len(args))
 	if c.rcvr.IsValid() {
 		fullargs = append(fullargs, c.rcvr)
 	}
@@ -191,6 +200,9 @@ func (c *callback) call(ctx context.Context, method string, args []reflect.Value
 		fullargs = append(fullargs, reflect.ValueOf(ctx))
 	}
 	fullargs = append(fullargs, args...)
+	if c.streamable {
+		fullargs = append(fullargs, reflect.ValueOf(stream))
+	}
 
 	// Catch panic while running the callback.
 	defer func() {
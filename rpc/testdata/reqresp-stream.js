@@ -0,0 +1,13 @@
+// This test calls the test_stream method, which writes its result to a stream.
+
+--> {"jsonrpc": "2.0", "id": 2, "method": "test_stream", "params": [3]}
+<-- {"jsonrpc":"2.0","id":2,"result":[0,1,2]}
+
+--> {"jsonrpc": "2.0", "id": 2, "method": "test_stream", "params": [0]}
+<-- {"jsonrpc":"2.0","id":2,"result":[]}
+
+--> {"jsonrpc": "2.0", "id": 2, "method": "test_stream", "params": [-1]}
+<-- {"jsonrpc":"2.0","id":2,"error":{"code":-32000,"message":"negative length"}}
+
+--> [{"jsonrpc": "2.0", "id": 3, "method": "test_stream", "params": [2]}, {"jsonrpc": "2.0", "id": 4, "method": "test_stream", "params": [1]}]
+<-- [{"jsonrpc":"2.0","id":3,"result":[0,1]},{"jsonrpc":"2.0","id":4,"result":[0]}]
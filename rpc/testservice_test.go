@@ -22,6 +22,8 @@ import (
 	"errors"
 	"sync"
 	"time"
+
+	jsoniter "github.com/json-iterator/go"
 )
 
 func newTestServer() *Server {
@@ -109,6 +111,25 @@ func (s *testService) ReturnError() error {
 	return testError{}
 }
 
+// Stream writes the array of the numbers [0, n) to the stream
+func (s *testService) Stream(ctx context.Context, n int, stream *jsoniter.Stream) error {
+	if n < 0 {
+		return errors.New("negative length")
+	}
+	stream.WriteArrayStart()
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			stream.WriteMore()
+		}
+		stream.WriteInt(i)
+		if err := stream.Flush(); err != nil {
+			return err
+		}
+	}
+	stream.WriteArrayEnd()
+	return nil
+}
+
 func (s *testService) CallMeBack(ctx context.Context, method string, args []interface{}) (interface{}, error) {
 	c, ok := ClientFromContext(ctx)
 	if !ok {
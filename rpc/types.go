@@ -20,6 +20,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"math"
 	"strconv"
 	"strings"
@@ -67,6 +68,13 @@ type jsonWriter interface {
 	remoteAddr() string
 }
 
+// streamWriter can write a response to its underlying connection while the response is produced.
+// Implementations must be safe for concurrent use.
+type streamWriter interface {
+	// writeStream calls write with the writer of the connection, nothing else is written to the connection until it returns
+	writeStream(ctx context.Context, write func(w io.Writer) error) error
+}
+
 type BlockNumber int64
 
 const (
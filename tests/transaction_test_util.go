@@ -24,7 +24,6 @@ import (
 	"github.com/ledgerwatch/turbo-geth/common/hexutil"
 	"github.com/ledgerwatch/turbo-geth/core/types"
 	"github.com/ledgerwatch/turbo-geth/params"
-	"github.com/ledgerwatch/turbo-geth/rlp"
 )
 
 // TransactionTest checks RLP decoding and sender derivation of transactions.
@@ -47,7 +46,7 @@ type ttFork struct {
 func (tt *TransactionTest) Run(config *params.ChainConfig) error {
 	validateTx := func(rlpData hexutil.Bytes, signer types.Signer, isHomestead bool, isIstanbul bool) (*common.Address, *common.Hash, error) {
 		tx := new(types.Transaction)
-		if err := rlp.DecodeBytes(rlpData, tx); err != nil {
+		if err := tx.UnmarshalBinary(rlpData); err != nil {
 			return nil, nil, err
 		}
 		sender, err := types.Sender(signer, tx)
@@ -55,7 +54,7 @@ func (tt *TransactionTest) Run(config *params.ChainConfig) error {
 			return nil, nil, err
 		}
 		// Intrinsic gas
-		requiredGas, err := core.IntrinsicGas(tx.Data(), tx.To() == nil, isHomestead, isIstanbul)
+		requiredGas, err := core.IntrinsicGas(tx.Data(), tx.AccessList(), tx.To() == nil, isHomestead, isIstanbul)
 		if err != nil {
 			return nil, nil, err
 		}
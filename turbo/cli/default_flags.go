@@ -24,13 +24,21 @@ var DefaultFlags = []cli.Flag{
 	utils.TxPoolLifetimeFlag,
 	utils.TxLookupLimitFlag,
 	StorageModeFlag,
+	PruneDistanceFlag,
 	SnapshotModeFlag,
 	SeedSnapshotsFlag,
 	ExternalSnapshotDownloaderAddrFlag,
+	SnapshotProduceFlag,
+	SnapshotProduceEveryFlag,
+	SnapshotProduceFinalityFlag,
+	CompactionBucketsFlag,
+	CompactionBatchSizeFlag,
+	CompactionIntervalFlag,
 	CacheSizeFlag,
 	BatchSizeFlag,
 	DatabaseFlag,
 	PrivateApiAddr,
+	PrivateApiWritableBuckets,
 	EtlBufferSizeFlag,
 	LMDBMapSizeFlag,
 	LMDBMaxFreelistReuseFlag,
@@ -6,6 +6,7 @@ import (
 
 	"github.com/c2h5oh/datasize"
 	"github.com/ledgerwatch/turbo-geth/cmd/utils"
+	"github.com/ledgerwatch/turbo-geth/common/dbutils"
 	"github.com/ledgerwatch/turbo-geth/common/etl"
 	"github.com/ledgerwatch/turbo-geth/eth"
 	"github.com/ledgerwatch/turbo-geth/ethdb"
@@ -43,14 +44,26 @@ var (
 		Value: "",
 	}
 
+	PrivateApiWritableBuckets = cli.StringSliceFlag{
+		Name:  "private.api.writable",
+		Usage: "comma separated list of the buckets which the clients of the private api can modify, for example the buckets of the custom stages running in a separate process. empty means the private api is read-only",
+	}
+
 	StorageModeFlag = cli.StringFlag{
 		Name: "storage-mode",
 		Usage: `Configures the storage mode of the app:
 * h - write history to the DB
 * r - write receipts to the DB
-* t - write tx lookup index to the DB`,
+* t - write tx lookup index to the DB
+* e - write index of ERC-20/721 token transfers to the DB (requires r)
+* a - write index of transactions by the addresses they touch, including internal calls, to the DB (requires h)`,
 		Value: ethdb.DefaultStorageMode.ToString(),
 	}
+	PruneDistanceFlag = cli.Uint64Flag{
+		Name:  "prune.distance",
+		Usage: "Keep changesets, history indexes, receipts and call traces only for this amount of the most recent blocks (0 - keep everything, otherwise at least 90000 - the max reorg depth)",
+		Value: ethdb.DefaultStorageMode.PruneDistance,
+	}
 	SnapshotModeFlag = cli.StringFlag{
 		Name: "snapshot.mode",
 		Usage: `Configures the storage mode of the app:
@@ -70,6 +83,39 @@ var (
 		Name:  "snapshot.downloader.addr",
 		Usage: `enable external snapshot downloader`,
 	}
+	SnapshotProduceFlag = cli.StringFlag{
+		Name: "snapshot.produce",
+		Usage: `Produce the snapshots of the synced blocks in the background and seed them:
+* h - headers snapshot
+* b - bodies snapshot
+* s - state snapshot (requires the full history)
+`,
+		Value: "",
+	}
+	SnapshotProduceEveryFlag = cli.Uint64Flag{
+		Name:  "snapshot.produce.every",
+		Usage: "Produce the snapshots at the blocks multiple of this number",
+		Value: 500_000,
+	}
+	SnapshotProduceFinalityFlag = cli.Uint64Flag{
+		Name:  "snapshot.produce.finality",
+		Usage: "Number of the most recent blocks which are not put into the produced snapshots, because they may be reorganised",
+		Value: 1_000,
+	}
+
+	CompactionBucketsFlag = cli.StringSliceFlag{
+		Name:  "compaction.buckets",
+		Usage: "comma separated list of the buckets which are compacted in the background to reclaim the free pages of the database, supported buckets: " + strings.Join(dbutils.CompactableBuckets, ","),
+	}
+	CompactionBatchSizeFlag = cli.StringFlag{
+		Name:  "compaction.batchSize",
+		Usage: "Amount of data copied by one transaction of the compaction",
+		Value: ethdb.CompactionDefaultBatchSize.String(),
+	}
+	CompactionIntervalFlag = cli.DurationFlag{
+		Name:  "compaction.interval",
+		Usage: "Interval between the compactions of the buckets (0 - compact once after the start)",
+	}
 
 	// LMDB flags
 	LMDBMapSizeFlag = cli.StringFlag{
@@ -115,6 +161,7 @@ func ApplyFlagsForEthConfig(ctx *cli.Context, cfg *eth.Config) {
 	if err != nil {
 		utils.Fatalf(fmt.Sprintf("error while parsing mode: %v", err))
 	}
+	mode.PruneDistance = ctx.GlobalUint64(PruneDistanceFlag.Name)
 	cfg.StorageMode = mode
 	snMode, err := snapshotsync.SnapshotModeFromString(ctx.GlobalString(SnapshotModeFlag.Name))
 	if err != nil {
@@ -122,6 +169,15 @@ func ApplyFlagsForEthConfig(ctx *cli.Context, cfg *eth.Config) {
 	}
 	cfg.SnapshotMode = snMode
 	cfg.SnapshotSeeding = ctx.GlobalBool(SeedSnapshotsFlag.Name)
+	produceMode, err := snapshotsync.SnapshotModeFromString(ctx.GlobalString(SnapshotProduceFlag.Name))
+	if err != nil {
+		utils.Fatalf(fmt.Sprintf("error while parsing mode: %v", err))
+	}
+	cfg.SnapshotProducer = snapshotsync.ProducerConfig{
+		Mode:     produceMode,
+		Every:    ctx.GlobalUint64(SnapshotProduceEveryFlag.Name),
+		Finality: ctx.GlobalUint64(SnapshotProduceFinalityFlag.Name),
+	}
 
 	if ctx.GlobalString(CacheSizeFlag.Name) != "" {
 		err := cfg.CacheSize.UnmarshalText([]byte(ctx.GlobalString(CacheSizeFlag.Name)))
@@ -149,6 +205,17 @@ func ApplyFlagsForEthConfig(ctx *cli.Context, cfg *eth.Config) {
 		etl.BufferOptimalSize = *size
 	}
 
+	cfg.Compaction = ethdb.CompactionConfig{
+		Buckets:  ctx.GlobalStringSlice(CompactionBucketsFlag.Name),
+		Interval: ctx.GlobalDuration(CompactionIntervalFlag.Name),
+	}
+	if ctx.GlobalString(CompactionBatchSizeFlag.Name) != "" {
+		err := cfg.Compaction.BatchSize.UnmarshalText([]byte(ctx.GlobalString(CompactionBatchSizeFlag.Name)))
+		if err != nil {
+			utils.Fatalf("Invalid compaction.batchSize provided: %v", err)
+		}
+	}
+
 	cfg.ExternalSnapshotDownloaderAddr = ctx.GlobalString(ExternalSnapshotDownloaderAddrFlag.Name)
 }
 
@@ -188,9 +255,10 @@ func ApplyFlagsForNodeConfig(ctx *cli.Context, cfg *node.Config) {
 }
 
 // setPrivateApi populates configuration fields related to the remote
-// read-only interface to the databae
+// interface to the databae
 func setPrivateApi(ctx *cli.Context, cfg *node.Config) {
 	cfg.PrivateApiAddr = ctx.GlobalString(PrivateApiAddr.Name)
+	cfg.PrivateApiWritableBuckets = ctx.GlobalStringSlice(PrivateApiWritableBuckets.Name)
 	if ctx.GlobalBool(TLSFlag.Name) {
 		certFile := ctx.GlobalString(TLSCertFlag.Name)
 		keyFile := ctx.GlobalString(TLSKeyFlag.Name)
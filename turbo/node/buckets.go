@@ -1,8 +1,6 @@
 package node
 
 import (
-	"fmt"
-
 	"github.com/ledgerwatch/turbo-geth/common/dbutils"
 )
 
@@ -11,14 +9,7 @@ func prepareBuckets(customBuckets dbutils.BucketsCfg) {
 		return
 	}
 
-	currentBuckets := dbutils.DefaultBuckets()
-
-	for k, v := range customBuckets {
-		if _, ok := currentBuckets[k]; ok {
-			panic(fmt.Errorf("overriding existing buckets is not supported (bucket key=%s)", k))
-		}
-		currentBuckets[k] = v
+	if err := dbutils.RegisterBuckets(customBuckets); err != nil {
+		panic(err)
 	}
-
-	dbutils.UpdateBucketsList(currentBuckets)
 }
@@ -1,6 +1,7 @@
 package rpchelper
 
 import (
+	"errors"
 	"fmt"
 
 	"github.com/ledgerwatch/turbo-geth/common"
@@ -12,6 +13,38 @@ import (
 	"github.com/ledgerwatch/turbo-geth/turbo/adapter"
 )
 
+// ErrBlockPruned is returned for the blocks whose history, receipts and call traces are deleted by the Prune stage
+var ErrBlockPruned = errors.New("block pruned")
+
+// CheckPruned returns ErrBlockPruned if the block is pruned: neither the state as of the block, nor its receipts
+// and call traces can be read anymore
+func CheckPruned(dbReader ethdb.Getter, blockNumber uint64) error {
+	pruned, err := stages.GetStageProgress(dbReader, stages.Prune)
+	if err != nil {
+		return fmt.Errorf("getting pruned block number: %v", err)
+	}
+	if blockNumber < pruned {
+		return fmt.Errorf("%w: %d, the history is kept from block %d", ErrBlockPruned, blockNumber, pruned)
+	}
+	return nil
+}
+
+// FirstNotPruned returns the first block whose history is not pruned, see CheckPruned
+func FirstNotPruned(dbReader ethdb.Getter) (uint64, error) {
+	return stages.GetStageProgress(dbReader, stages.Prune)
+}
+
+// LatestInsteadOfPending replaces the `pending` tag with the latest block. It's used by the methods which apply
+// the changes of the pending block on top of the latest state, see PendingBlock
+func LatestInsteadOfPending(blockNrOrHash rpc.BlockNumberOrHash) rpc.BlockNumberOrHash {
+	if number, ok := blockNrOrHash.Number(); ok && number == rpc.PendingBlockNumber {
+		return rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
+	}
+	return blockNrOrHash
+}
+
+// GetBlockNumber resolves the block number or hash, the state of the block is read by the caller,
+// so ErrBlockPruned is returned for the pruned blocks
 func GetBlockNumber(blockNrOrHash rpc.BlockNumberOrHash, dbReader ethdb.Database) (uint64, common.Hash, error) {
 	var blockNumber uint64
 	var err error
@@ -52,10 +85,16 @@ func GetBlockNumber(blockNrOrHash rpc.BlockNumberOrHash, dbReader ethdb.Database
 			return 0, common.Hash{}, fmt.Errorf("hash %q is not currently canonical", hash.String())
 		}
 	}
+	if err = CheckPruned(dbReader, blockNumber); err != nil {
+		return 0, common.Hash{}, err
+	}
 	return blockNumber, hash, nil
 }
 
 func GetAccount(tx ethdb.Database, blockNumber uint64, address common.Address) (*accounts.Account, error) {
+	if err := CheckPruned(tx, blockNumber); err != nil {
+		return nil, err
+	}
 	reader := adapter.NewStateReader(tx.(ethdb.HasTx).Tx(), blockNumber)
 	return reader.ReadAccountData(address)
 }
@@ -0,0 +1,129 @@
+package rpchelper
+
+import (
+	"fmt"
+
+	"github.com/ledgerwatch/turbo-geth/common"
+	"github.com/ledgerwatch/turbo-geth/core/state"
+	"github.com/ledgerwatch/turbo-geth/core/types"
+	"github.com/ledgerwatch/turbo-geth/core/types/accounts"
+	"github.com/ledgerwatch/turbo-geth/ethdb"
+	"github.com/ledgerwatch/turbo-geth/ethdb/remote"
+	"github.com/ledgerwatch/turbo-geth/rlp"
+	"github.com/ledgerwatch/turbo-geth/rpc"
+)
+
+// PendingBlock is the block which the miner of the node is working on, together with the changes
+// it makes on top of the state of its parent
+type PendingBlock struct {
+	Block    *types.Block
+	accounts map[common.Address]*pendingAccount
+}
+
+type pendingAccount struct {
+	account *accounts.Account // nil if the account is deleted
+	code    []byte
+	storage map[common.Hash][]byte
+}
+
+// DecodePendingBlock decodes the reply of the PendingBlock call of ETHBACKEND, returns nil if the node has no pending block
+func DecodePendingBlock(reply *remote.PendingBlockReply) (*PendingBlock, error) {
+	if !reply.Found {
+		return nil, nil
+	}
+	block := new(types.Block)
+	if err := rlp.DecodeBytes(reply.BlockRlp, block); err != nil {
+		return nil, fmt.Errorf("decoding pending block: %w", err)
+	}
+	pending := &PendingBlock{Block: block, accounts: make(map[common.Address]*pendingAccount, len(reply.StateDiff))}
+	for _, diff := range reply.StateDiff {
+		address := common.BytesToAddress(diff.Address)
+		acc := &pendingAccount{code: diff.Code, storage: make(map[common.Hash][]byte, len(diff.Storage))}
+		if !diff.Deleted {
+			acc.account = new(accounts.Account)
+			if err := acc.account.DecodeForStorage(diff.Account); err != nil {
+				return nil, fmt.Errorf("decoding pending account %x: %w", address, err)
+			}
+		}
+		for _, storage := range diff.Storage {
+			acc.storage[common.BytesToHash(storage.Location)] = storage.Value
+		}
+		pending.accounts[address] = acc
+	}
+	return pending, nil
+}
+
+// ReadPendingBlock returns the pending block of the node. Returns nil if the node doesn't know the pending block
+// or the block isn't built on top of the latest block of the database, the latest state is used then.
+func ReadPendingBlock(backend ethdb.Backend, tx ethdb.Database) (*PendingBlock, error) {
+	reply, err := backend.PendingBlock()
+	if err != nil {
+		return nil, err
+	}
+	pending, err := DecodePendingBlock(reply)
+	if err != nil || pending == nil {
+		return nil, err
+	}
+	latest, latestHash, err := GetBlockNumber(rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber), tx)
+	if err != nil {
+		return nil, err
+	}
+	if pending.Block.NumberU64() != latest+1 || pending.Block.ParentHash() != latestHash {
+		return nil, nil
+	}
+	return pending, nil
+}
+
+// StateReader returns the reader of the pending state, the accounts not touched by the pending block are read from parent
+func (b *PendingBlock) StateReader(parent state.StateReader) state.StateReader {
+	return &pendingStateReader{pending: b, parent: parent}
+}
+
+type pendingStateReader struct {
+	pending *PendingBlock
+	parent  state.StateReader
+}
+
+func (r *pendingStateReader) ReadAccountData(address common.Address) (*accounts.Account, error) {
+	if acc, ok := r.pending.accounts[address]; ok {
+		if acc.account == nil {
+			return nil, nil
+		}
+		return acc.account.SelfCopy(), nil
+	}
+	return r.parent.ReadAccountData(address)
+}
+
+func (r *pendingStateReader) ReadAccountStorage(address common.Address, incarnation uint64, key *common.Hash) ([]byte, error) {
+	if acc, ok := r.pending.accounts[address]; ok {
+		if acc.account == nil {
+			return nil, nil
+		}
+		if value, ok := acc.storage[*key]; ok {
+			if len(value) == 0 {
+				return nil, nil
+			}
+			return common.CopyBytes(value), nil
+		}
+	}
+	// the storage of a re-created contract is not found in the parent, because its incarnation is new
+	return r.parent.ReadAccountStorage(address, incarnation, key)
+}
+
+func (r *pendingStateReader) ReadAccountCode(address common.Address, incarnation uint64, codeHash common.Hash) ([]byte, error) {
+	if acc, ok := r.pending.accounts[address]; ok && acc.code != nil {
+		return common.CopyBytes(acc.code), nil
+	}
+	return r.parent.ReadAccountCode(address, incarnation, codeHash)
+}
+
+func (r *pendingStateReader) ReadAccountCodeSize(address common.Address, incarnation uint64, codeHash common.Hash) (int, error) {
+	if acc, ok := r.pending.accounts[address]; ok && acc.code != nil {
+		return len(acc.code), nil
+	}
+	return r.parent.ReadAccountCodeSize(address, incarnation, codeHash)
+}
+
+func (r *pendingStateReader) ReadAccountIncarnation(address common.Address) (uint64, error) {
+	return r.parent.ReadAccountIncarnation(address)
+}
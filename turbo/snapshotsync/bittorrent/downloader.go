@@ -11,6 +11,7 @@ import (
 
 	lg "github.com/anacrolix/log"
 	"github.com/anacrolix/torrent"
+	"github.com/anacrolix/torrent/bencode"
 	"github.com/anacrolix/torrent/metainfo"
 	"github.com/ledgerwatch/turbo-geth/common"
 	"github.com/ledgerwatch/turbo-geth/common/dbutils"
@@ -20,6 +21,8 @@ import (
 	"golang.org/x/sync/errgroup"
 )
 
+var _ snapshotsync.Seeder = &Client{}
+
 type Client struct {
 	Cli          *torrent.Client
 	snapshotsDir string
@@ -91,6 +94,50 @@ func (cli *Client) AddTorrentSpec(snapshotName string, snapshotHash metainfo.Has
 	return t, err
 }
 
+// SeedSnapshot starts seeding the LMDB snapshot at path, which must be a subdirectory of the snapshots directory
+// of the client, and returns the info hash of its torrent
+func (cli *Client) SeedSnapshot(name string, path string) ([]byte, error) {
+	if cli.Cli == nil {
+		return nil, errors.New("torrent client is not started")
+	}
+	info, err := BuildInfoBytesForLMDBSnapshot(path)
+	if err != nil {
+		return nil, err
+	}
+	infoBytes, err := bencode.Marshal(info)
+	if err != nil {
+		return nil, err
+	}
+	t, _, err := cli.Cli.AddTorrentSpec(&torrent.TorrentSpec{
+		Trackers:    Trackers,
+		InfoHash:    metainfo.HashBytes(infoBytes),
+		DisplayName: name,
+		InfoBytes:   infoBytes,
+		ChunkSize:   DefaultChunkSize,
+	})
+	if err != nil {
+		return nil, err
+	}
+	t.VerifyData()
+	if !t.Seeding() {
+		log.Warn("Snapshot is not seeding", "snapshot", name, "path", path)
+	}
+	return t.InfoHash().Bytes(), nil
+}
+
+// StopSeeding drops the torrent with the given info hash
+func (cli *Client) StopSeeding(infoHash []byte) error {
+	if cli.Cli == nil {
+		return errors.New("torrent client is not started")
+	}
+	var hash metainfo.Hash
+	copy(hash[:], infoHash)
+	if t, ok := cli.Cli.Torrent(hash); ok {
+		t.Drop()
+	}
+	return nil
+}
+
 func (cli *Client) AddTorrent(ctx context.Context, db ethdb.Database, snapshotType snapshotsync.SnapshotType, networkID uint64) error { //nolint: interfacer
 	infoHashBytes, infoBytes, err := getTorrentSpec(db, snapshotType.String(), networkID)
 	if err != nil {
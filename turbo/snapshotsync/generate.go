@@ -0,0 +1,356 @@
+package snapshotsync
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"time"
+
+	"github.com/ledgerwatch/lmdb-go/lmdb"
+	"github.com/ledgerwatch/turbo-geth/common"
+	"github.com/ledgerwatch/turbo-geth/common/dbutils"
+	"github.com/ledgerwatch/turbo-geth/core/rawdb"
+	"github.com/ledgerwatch/turbo-geth/core/state"
+	"github.com/ledgerwatch/turbo-geth/core/types/accounts"
+	"github.com/ledgerwatch/turbo-geth/eth/stagedsync"
+	"github.com/ledgerwatch/turbo-geth/ethdb"
+	"github.com/ledgerwatch/turbo-geth/log"
+)
+
+// blocksSnapshot describes the snapshot of a bucket keyed by the block number and the canonical hash
+type blocksSnapshot struct {
+	bucket     string
+	key        func(number uint64, hash common.Hash) []byte
+	read       func(db ethdb.Database, hash common.Hash, number uint64) []byte
+	infoBucket string
+	headNumber string
+	headHash   string
+}
+
+var blocksSnapshots = map[SnapshotType]blocksSnapshot{
+	SnapshotType_headers: {
+		bucket: dbutils.HeaderPrefix,
+		key:    dbutils.HeaderKey,
+		read: func(db ethdb.Database, hash common.Hash, number uint64) []byte {
+			return rawdb.ReadHeaderRLP(db, hash, number)
+		},
+		infoBucket: dbutils.HeadersSnapshotInfoBucket,
+		headNumber: dbutils.SnapshotHeadersHeadNumber,
+		headHash:   dbutils.SnapshotHeadersHeadHash,
+	},
+	SnapshotType_bodies: {
+		bucket: dbutils.BlockBodyPrefix,
+		key:    dbutils.BlockBodyKey,
+		read: func(db ethdb.Database, hash common.Hash, number uint64) []byte {
+			return rawdb.ReadBodyRLP(db, hash, number)
+		},
+		infoBucket: dbutils.BodiesSnapshotInfoBucket,
+		headNumber: dbutils.SnapshotBodyHeadNumber,
+		headHash:   dbutils.SnapshotBodyHeadHash,
+	},
+}
+
+// snapshotReadTxDuration is how long the generation of a snapshot keeps one read transaction of the database open.
+// LMDB can't reuse the pages freed while a reader is open, so the database would grow during the whole generation
+var snapshotReadTxDuration = time.Minute
+
+// GenerateSnapshot writes the snapshot of the given type at the block toBlock of db into a new LMDB database at
+// snapshotPath, and checks it against db. The data of the blocks up to toBlock doesn't change, so db is read in
+// the bounded transactions, each one continues from where the previous one stopped
+func GenerateSnapshot(ctx context.Context, db ethdb.Database, snapshotType SnapshotType, snapshotPath string, toBlock uint64, tmpdir string) error {
+	if err := os.RemoveAll(snapshotPath); err != nil {
+		return err
+	}
+	snKV, err := ethdb.NewLMDB().WithBucketsConfig(func(defaultBuckets dbutils.BucketsCfg) dbutils.BucketsCfg {
+		return bucketConfigs[snapshotType]
+	}).Path(snapshotPath).Open()
+	if err != nil {
+		return err
+	}
+	snDB := ethdb.NewObjectDatabase(snKV)
+
+	switch snapshotType {
+	case SnapshotType_headers, SnapshotType_bodies:
+		err = generateBlocksSnapshot(ctx, db, snDB, blocksSnapshots[snapshotType], toBlock)
+	case SnapshotType_state:
+		err = generateStateSnapshot(ctx, db, snDB, toBlock)
+	default:
+		err = fmt.Errorf("generation of %s snapshot is not supported", snapshotType)
+	}
+	snDB.Close()
+	if err != nil {
+		return err
+	}
+	// the lock file is not part of the snapshot, the seeder shares only the data file
+	if err = os.Remove(snapshotPath + "/lock.mdb"); err != nil {
+		return err
+	}
+
+	snKV, err = openSnapshot(snapshotType, snapshotPath)
+	if err != nil {
+		return err
+	}
+	defer snKV.Close()
+	switch snapshotType {
+	case SnapshotType_headers, SnapshotType_bodies:
+		return verifyBlocksSnapshot(ctx, db, snKV, blocksSnapshots[snapshotType], toBlock)
+	default:
+		return verifyStateSnapshot(ctx, db, snKV, toBlock, tmpdir)
+	}
+}
+
+// walkInReadTxs calls walk with the consecutive read transactions of db, until it reports that it is done.
+// walk should stop after the deadline, but not before it makes some progress. The next call continues from where it stopped
+func walkInReadTxs(ctx context.Context, db ethdb.Database, walk func(tx ethdb.Database, deadline time.Time) (bool, error)) error {
+	for {
+		if common.IsCanceled(ctx) {
+			return common.ErrStopped
+		}
+		tx, err := db.Begin(ctx, ethdb.RO)
+		if err != nil {
+			return err
+		}
+		done, err := walk(tx, time.Now().Add(snapshotReadTxDuration))
+		tx.Rollback()
+		if err != nil || done {
+			return err
+		}
+	}
+}
+
+// openSnapshot opens the snapshot of the given type read-only
+func openSnapshot(snapshotType SnapshotType, snapshotPath string) (ethdb.KV, error) {
+	return ethdb.NewLMDB().Flags(func(flags uint) uint { return flags | lmdb.Readonly }).Path(snapshotPath).WithBucketsConfig(func(defaultBuckets dbutils.BucketsCfg) dbutils.BucketsCfg {
+		return bucketConfigs[snapshotType]
+	}).Open()
+}
+
+func generateBlocksSnapshot(ctx context.Context, db ethdb.Database, snDB ethdb.Database, sn blocksSnapshot, toBlock uint64) error {
+	chunkFile := 30000
+	tuples := make(ethdb.MultiPutTuples, 0, chunkFile*3+100)
+	var hash common.Hash
+	next := uint64(1)
+	err := walkInReadTxs(ctx, db, func(tx ethdb.Database, deadline time.Time) (bool, error) {
+		for from := next; next <= toBlock; next++ {
+			if common.IsCanceled(ctx) {
+				return false, common.ErrStopped
+			}
+			if next > from && time.Now().After(deadline) {
+				return false, nil
+			}
+			var err error
+			hash, err = rawdb.ReadCanonicalHash(tx, next)
+			if err != nil {
+				return false, fmt.Errorf("getting canonical hash for block %d: %w", next, err)
+			}
+			v := sn.read(tx, hash, next)
+			if len(v) == 0 {
+				return false, fmt.Errorf("empty %s for block %d", sn.bucket, next)
+			}
+			tuples = append(tuples, []byte(sn.bucket), sn.key(next, hash), v)
+			if len(tuples) >= chunkFile {
+				log.Info("Committed", "bucket", sn.bucket, "block", next)
+				if _, err = snDB.MultiPut(tuples...); err != nil {
+					return false, err
+				}
+				tuples = tuples[:0]
+			}
+		}
+		return true, nil
+	})
+	if err != nil {
+		return err
+	}
+	if len(tuples) > 0 {
+		if _, err = snDB.MultiPut(tuples...); err != nil {
+			return err
+		}
+	}
+
+	if err = snDB.Put(sn.infoBucket, []byte(sn.headNumber), big.NewInt(0).SetUint64(toBlock).Bytes()); err != nil {
+		return err
+	}
+	return snDB.Put(sn.infoBucket, []byte(sn.headHash), hash.Bytes())
+}
+
+// verifyBlocksSnapshot checks that the snapshot holds exactly the records of the canonical blocks 1..toBlock of db
+func verifyBlocksSnapshot(ctx context.Context, db ethdb.Database, snKV ethdb.KV, sn blocksSnapshot, toBlock uint64) error {
+	return snKV.View(ctx, func(snTx ethdb.Tx) error {
+		var hash common.Hash
+		next := uint64(1)
+		err := walkInReadTxs(ctx, db, func(tx ethdb.Database, deadline time.Time) (bool, error) {
+			for from := next; next <= toBlock; next++ {
+				if common.IsCanceled(ctx) {
+					return false, common.ErrStopped
+				}
+				if next > from && time.Now().After(deadline) {
+					return false, nil
+				}
+				var err error
+				hash, err = rawdb.ReadCanonicalHash(tx, next)
+				if err != nil {
+					return false, err
+				}
+				v, err := snTx.GetOne(sn.bucket, sn.key(next, hash))
+				if err != nil {
+					return false, err
+				}
+				if !bytes.Equal(v, sn.read(tx, hash, next)) {
+					return false, fmt.Errorf("%s: mismatch for the block %d", sn.bucket, next)
+				}
+			}
+			return true, nil
+		})
+		if err != nil {
+			return err
+		}
+		var count uint64
+		if err = ethdb.Walk(snTx.Cursor(sn.bucket), nil, 0, func(k, v []byte) (bool, error) {
+			count++
+			return true, nil
+		}); err != nil {
+			return err
+		}
+		if count != toBlock {
+			return fmt.Errorf("%s: expected %d records in the snapshot, got %d", sn.bucket, toBlock, count)
+		}
+		headHash, err := snTx.GetOne(sn.infoBucket, []byte(sn.headHash))
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(headHash, hash.Bytes()) {
+			return fmt.Errorf("%s: snapshot head hash %x, expected %x", sn.bucket, headHash, hash)
+		}
+		return nil
+	})
+}
+
+// generateStateSnapshot writes the plain state as of the end of the block toBlock, the storage and the code of the contracts
+func generateStateSnapshot(ctx context.Context, db ethdb.Database, snDB ethdb.Database, toBlock uint64) error {
+	hash, err := rawdb.ReadCanonicalHash(db, toBlock)
+	if err != nil {
+		return err
+	}
+	batch := snDB.NewBatch()
+	defer batch.Rollback()
+
+	i := 0
+	var startAddress common.Address
+	err = walkInReadTxs(ctx, db, func(roDB ethdb.Database, deadline time.Time) (bool, error) {
+		tx := roDB.(ethdb.HasTx).Tx()
+		done := true
+		from := startAddress
+		err := state.WalkAsOfAccounts(tx, from, toBlock+1, func(k []byte, v []byte) (bool, error) {
+			if len(k) != common.AddressLength {
+				return true, nil
+			}
+			if !bytes.Equal(k, from[:]) && time.Now().After(deadline) {
+				// the next transaction continues from this account
+				startAddress = common.BytesToAddress(k)
+				done = false
+				return false, nil
+			}
+			i++
+			if i%100000 == 0 {
+				log.Info("Generating state snapshot", "block", toBlock, "accounts", i, "current", common.Bytes2Hex(k))
+				if common.IsCanceled(ctx) {
+					return false, common.ErrStopped
+				}
+			}
+			var acc accounts.Account
+			if err := acc.DecodeForStorage(v); err != nil {
+				return false, fmt.Errorf("decoding %x for %x: %w", v, k, err)
+			}
+			if acc.Incarnation > 0 {
+				err := state.WalkAsOfStorage(tx, common.BytesToAddress(k), acc.Incarnation, common.Hash{}, toBlock+1, func(k1, k2 []byte, vv []byte) (bool, error) {
+					return true, batch.Put(dbutils.PlainStateBucket, dbutils.PlainGenerateCompositeStorageKey(k1, acc.Incarnation, k2), common.CopyBytes(vv))
+				})
+				if err != nil {
+					return false, err
+				}
+				storagePrefix := dbutils.PlainGenerateStoragePrefix(k, acc.Incarnation)
+				codeHash, err := roDB.Get(dbutils.PlainContractCodeBucket, storagePrefix)
+				if err != nil && !errors.Is(err, ethdb.ErrKeyNotFound) {
+					return false, fmt.Errorf("getting code hash for %x: %w", k, err)
+				}
+				if len(codeHash) > 0 {
+					if err = batch.Put(dbutils.PlainContractCodeBucket, storagePrefix, codeHash); err != nil {
+						return false, err
+					}
+				}
+			}
+			if !acc.IsEmptyCodeHash() {
+				code, err := roDB.Get(dbutils.CodeBucket, acc.CodeHash[:])
+				if err != nil {
+					return false, fmt.Errorf("getting code %x: %w", acc.CodeHash, err)
+				}
+				if err = batch.Put(dbutils.CodeBucket, acc.CodeHash[:], code); err != nil {
+					return false, err
+				}
+			}
+			if err := batch.Put(dbutils.PlainStateBucket, common.CopyBytes(k), common.CopyBytes(v)); err != nil {
+				return false, err
+			}
+			if batch.BatchSize() >= batch.IdealBatchSize() {
+				if err := batch.CommitAndBegin(ctx); err != nil {
+					return false, err
+				}
+			}
+			return true, nil
+		})
+		return done, err
+	})
+	if err != nil {
+		return err
+	}
+	if err = batch.Put(dbutils.StateSnapshotInfoBucket, []byte(dbutils.SnapshotStateHeadNumber), big.NewInt(0).SetUint64(toBlock).Bytes()); err != nil {
+		return err
+	}
+	if err = batch.Put(dbutils.StateSnapshotInfoBucket, []byte(dbutils.SnapshotStateHeadHash), hash.Bytes()); err != nil {
+		return err
+	}
+	_, err = batch.Commit()
+	return err
+}
+
+// verifyStateSnapshot checks that the state root of the snapshot is the one of the header of the block toBlock
+func verifyStateSnapshot(ctx context.Context, db ethdb.Database, snKV ethdb.KV, toBlock uint64, tmpdir string) error {
+	hash, err := rawdb.ReadCanonicalHash(db, toBlock)
+	if err != nil {
+		return err
+	}
+	header := rawdb.ReadHeader(db, hash, toBlock)
+	if header == nil {
+		return fmt.Errorf("header of the block %d is not found", toBlock)
+	}
+
+	tmpPath, err := ioutil.TempDir(tmpdir, "snverify")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpPath)
+	tmpKV, err := ethdb.NewLMDB().Path(tmpPath).Open()
+	if err != nil {
+		return err
+	}
+	defer tmpKV.Close()
+
+	// the wrapper is not closed, because it would close the snapshot, which is owned by the caller
+	snDB := ethdb.NewObjectDatabase(ethdb.NewSnapshot2KV().DB(tmpKV).SnapshotDB([]string{dbutils.PlainStateBucket, dbutils.PlainContractCodeBucket, dbutils.CodeBucket}, snKV).MustOpen())
+	tx, err := snDB.Begin(ctx, ethdb.RW)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err = stagedsync.PromoteHashedStateCleanly("Snapshot", tx, tmpdir, ctx.Done()); err != nil {
+		return fmt.Errorf("promote state: %w", err)
+	}
+	if err = stagedsync.RegenerateIntermediateHashes("Snapshot", tx, true, tmpdir, header.Root, ctx.Done()); err != nil {
+		return fmt.Errorf("regenerate intermediate hashes: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package snapshotsync
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/turbo-geth/common"
+	"github.com/ledgerwatch/turbo-geth/common/dbutils"
+	"github.com/ledgerwatch/turbo-geth/core/rawdb"
+	"github.com/ledgerwatch/turbo-geth/core/types/accounts"
+	"github.com/ledgerwatch/turbo-geth/ethdb"
+)
+
+func TestGenerateStateSnapshotInReadTxs(t *testing.T) {
+	// every account is read in its own transaction
+	defer func(d time.Duration) { snapshotReadTxDuration = d }(snapshotReadTxDuration)
+	snapshotReadTxDuration = 0
+
+	db := ethdb.NewMemDatabase()
+	defer db.Close()
+	if err := rawdb.WriteCanonicalHash(db, common.Hash{1}, 10); err != nil {
+		t.Fatal(err)
+	}
+	for i := byte(1); i <= 5; i++ {
+		acc := accounts.NewAccount()
+		acc.Balance = *uint256.NewInt().SetUint64(uint64(i))
+		v := make([]byte, acc.EncodingLengthForStorage())
+		acc.EncodeForStorage(v)
+		if err := db.Put(dbutils.PlainStateBucket, common.Address{i}.Bytes(), v); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	snDB := ethdb.NewMemDatabase()
+	defer snDB.Close()
+	if err := generateStateSnapshot(context.Background(), db, snDB, 10); err != nil {
+		t.Fatal(err)
+	}
+	for i := byte(1); i <= 5; i++ {
+		expected, err := db.Get(dbutils.PlainStateBucket, common.Address{i}.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		v, err := snDB.Get(dbutils.PlainStateBucket, common.Address{i}.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(v, expected) {
+			t.Fatalf("account %d: expected %x, got %x", i, expected, v)
+		}
+	}
+}
@@ -45,6 +45,13 @@ func PostProcessing(db ethdb.Database, mode SnapshotMode, downloadedSnapshots ma
 			return err
 		}
 	}
+
+	if mode.Receipts {
+		err := PostProcessReceipts(db)
+		if err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -92,6 +99,33 @@ func PostProcessState(db ethdb.GetterPutter, info *SnapshotsInfo) error {
 	return nil
 }
 
+// PostProcessReceipts checks that the receipts snapshot belongs to the canonical chain of the node. The receipts
+// are read through the snapshot, so there is nothing to generate.
+func PostProcessReceipts(db ethdb.Database) error {
+	headNumberBytes, err := db.Get(dbutils.ReceiptsSnapshotInfoBucket, []byte(dbutils.SnapshotReceiptsHeadNumber))
+	if err != nil {
+		return fmt.Errorf("reading receipts snapshot head number: %w", err)
+	}
+	headHashBytes, err := db.Get(dbutils.ReceiptsSnapshotInfoBucket, []byte(dbutils.SnapshotReceiptsHeadHash))
+	if err != nil {
+		return fmt.Errorf("reading receipts snapshot head hash: %w", err)
+	}
+	headNumber := big.NewInt(0).SetBytes(headNumberBytes).Uint64()
+	canonicalHash, err := rawdb.ReadCanonicalHash(db, headNumber)
+	if err != nil {
+		return err
+	}
+	if canonicalHash == (common.Hash{}) {
+		log.Warn("Receipts snapshot can't be checked before the headers are downloaded", "head", headNumber)
+		return nil
+	}
+	if canonicalHash != common.BytesToHash(headHashBytes) {
+		return fmt.Errorf("receipts snapshot head %d(%x) is not canonical, expected %x", headNumber, headHashBytes, canonicalHash)
+	}
+	log.Info("Receipts snapshot", "head", headNumber)
+	return nil
+}
+
 func GenerateHeaderIndexes(ctx context.Context, db ethdb.Database) error {
 	var hash common.Hash
 	var number uint64
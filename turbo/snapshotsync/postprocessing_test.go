@@ -2,11 +2,13 @@ package snapshotsync
 
 import (
 	"context"
+	"io/ioutil"
 	"math/big"
 	"os"
 	"testing"
 
 	"github.com/ledgerwatch/lmdb-go/lmdb"
+	"github.com/ledgerwatch/turbo-geth/common"
 	"github.com/ledgerwatch/turbo-geth/common/dbutils"
 	"github.com/ledgerwatch/turbo-geth/core/rawdb"
 	"github.com/ledgerwatch/turbo-geth/core/types"
@@ -103,3 +105,60 @@ func generateHeaders(n int) []types.Header {
 	}
 	return headers
 }
+
+func TestReceiptsSnapshot(t *testing.T) {
+	snapshotDir, err := ioutil.TempDir(os.TempDir(), "snapshots*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(snapshotDir)
+	headers := generateHeaders(3)
+	receipts := types.Receipts{{
+		Status:            types.ReceiptStatusSuccessful,
+		CumulativeGasUsed: 21000,
+		Logs:              []*types.Log{{Address: common.Address{1}, Topics: []common.Hash{{2}}, Data: []byte{3}}},
+	}}
+
+	snKV := ethdb.NewLMDB().Path(snapshotDir + "/receipts").MustOpen()
+	snDB := ethdb.NewObjectDatabase(snKV)
+	if err = rawdb.WriteReceipts(snDB, 1, receipts); err != nil {
+		t.Fatal(err)
+	}
+	if err = snDB.Put(dbutils.ReceiptsSnapshotInfoBucket, []byte(dbutils.SnapshotReceiptsHeadNumber), headers[2].Number.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	if err = snDB.Put(dbutils.ReceiptsSnapshotInfoBucket, []byte(dbutils.SnapshotReceiptsHeadHash), headers[2].Hash().Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	snDB.Close()
+
+	kv, err := WrapBySnapshotsFromDir(ethdb.NewLMDB().InMem().MustOpen(), snapshotDir, SnapshotMode{Receipts: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := ethdb.NewObjectDatabase(kv)
+	defer db.Close()
+
+	// the receipts of the old blocks are read through the snapshot
+	got := rawdb.ReadRawReceipts(db, headers[1].Hash(), 1)
+	if len(got) != 1 || got[0].CumulativeGasUsed != 21000 || len(got[0].Logs) != 1 || got[0].Logs[0].Address != (common.Address{1}) {
+		t.Fatalf("unexpected receipts %v", got)
+	}
+
+	// the snapshot is checked against the canonical chain once the headers are known
+	if err = PostProcessReceipts(db); err != nil {
+		t.Fatal(err)
+	}
+	if err = rawdb.WriteCanonicalHash(db, headers[2].Hash(), 2); err != nil {
+		t.Fatal(err)
+	}
+	if err = PostProcessReceipts(db); err != nil {
+		t.Fatal(err)
+	}
+	if err = rawdb.WriteCanonicalHash(db, headers[1].Hash(), 2); err != nil {
+		t.Fatal(err)
+	}
+	if err = PostProcessReceipts(db); err == nil {
+		t.Fatal("expected error for the snapshot which is not canonical")
+	}
+}
@@ -0,0 +1,376 @@
+package snapshotsync
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/ledgerwatch/turbo-geth/common"
+	"github.com/ledgerwatch/turbo-geth/common/changeset"
+	"github.com/ledgerwatch/turbo-geth/common/dbutils"
+	"github.com/ledgerwatch/turbo-geth/common/etl"
+	"github.com/ledgerwatch/turbo-geth/eth/stagedsync/stages"
+	"github.com/ledgerwatch/turbo-geth/ethdb"
+	"github.com/ledgerwatch/turbo-geth/log"
+)
+
+// producerCheckInterval is how often the producer checks whether the sync has got far enough for a new snapshot
+var producerCheckInterval = time.Minute
+
+// Seeder shares the snapshots produced by the node with the other peers
+type Seeder interface {
+	// SeedSnapshot starts seeding the snapshot at path and returns the info hash of its torrent
+	SeedSnapshot(name string, path string) ([]byte, error)
+	StopSeeding(infoHash []byte) error
+}
+
+// ProducerConfig configures the snapshots which the node produces by itself
+type ProducerConfig struct {
+	Mode SnapshotMode // only headers, bodies and state are supported
+	// Every is the distance in blocks between the snapshots, they are produced at the blocks multiple of it
+	Every uint64
+	// Finality is the number of the most recent blocks which may be reorganised, they don't get into the snapshots
+	Finality uint64
+}
+
+func (cfg ProducerConfig) Enabled() bool {
+	return cfg.Mode != (SnapshotMode{})
+}
+
+// Producer periodically freezes the blocks past finality into new snapshots, checks them, gives them to the seeder
+// and replaces the snapshots of the read path of the database by them. The data isn't removed from the database.
+type Producer struct {
+	cfg    ProducerConfig
+	db     ethdb.Database
+	kv     *ethdb.SnapshotKV2
+	dir    string
+	tmpdir string
+	seeder Seeder
+
+	infoHashes map[SnapshotType][]byte
+	cancel     context.CancelFunc
+	wg         sync.WaitGroup
+}
+
+// NewProducer creates the producer of the snapshots of db in dir, the KV of db must be *ethdb.SnapshotKV2.
+// The snapshots are given to seeder, which serves the files from dir
+func NewProducer(db ethdb.Database, dir, tmpdir string, seeder Seeder, cfg ProducerConfig) (*Producer, error) {
+	if cfg.Mode.Receipts {
+		return nil, errors.New("production of receipts snapshots is not supported")
+	}
+	if cfg.Every == 0 {
+		return nil, errors.New("distance between the snapshots must be positive")
+	}
+	kv, ok := db.(ethdb.HasKV).KV().(*ethdb.SnapshotKV2)
+	if !ok {
+		return nil, errors.New("producer requires the database wrapped by the snapshots")
+	}
+	if cfg.Mode.State {
+		// the state as of the snapshot block is read from the history, and the accounts deleted after it are found in the changesets
+		sm, err := ethdb.GetStorageModeFromDB(db)
+		if err != nil {
+			return nil, err
+		}
+		if !sm.History || sm.PruneDistance != 0 {
+			return nil, errors.New("production of state snapshots requires the full history")
+		}
+	}
+	return &Producer{
+		cfg:        cfg,
+		db:         db,
+		kv:         kv,
+		dir:        dir,
+		tmpdir:     tmpdir,
+		seeder:     seeder,
+		infoHashes: make(map[SnapshotType][]byte),
+	}, nil
+}
+
+// Start brings the snapshots produced before the restart back into the read path and the seeder,
+// then starts producing the new ones in the background
+func (p *Producer) Start() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	p.cancel = cancel
+	for _, snapshotType := range p.cfg.Mode.ToSnapshotTypes() {
+		block, err := p.lastProduced(snapshotType)
+		if err != nil {
+			return err
+		}
+		if err = p.removeStaleSnapshots(snapshotType, block); err != nil {
+			return err
+		}
+		if block == 0 {
+			continue
+		}
+		snKV, err := openSnapshot(snapshotType, p.snapshotPath(snapshotType, block))
+		if err != nil {
+			return fmt.Errorf("opening produced %s snapshot: %w", snapshotType, err)
+		}
+		if _, err = p.kv.UpdateSnapshots(ctx, nil, snapshotBuckets(snapshotType), snKV); err != nil {
+			return err
+		}
+		p.seed(snapshotType, block)
+	}
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		ticker := time.NewTicker(producerCheckInterval)
+		defer ticker.Stop()
+		for {
+			if err := p.Produce(ctx); err != nil && !errors.Is(err, common.ErrStopped) {
+				log.Error("Snapshot production failed", "err", err)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return nil
+}
+
+// Stop interrupts the production and waits for it to finish
+func (p *Producer) Stop() {
+	if p.cancel != nil {
+		p.cancel()
+	}
+	p.wg.Wait()
+}
+
+// Produce makes the snapshots of the latest block multiple of Every which is past finality, if they aren't made yet
+func (p *Producer) Produce(ctx context.Context) error {
+	head, err := stages.GetStageProgress(p.db, stages.Finish)
+	if err != nil {
+		return err
+	}
+	if head < p.cfg.Finality {
+		return nil
+	}
+	toBlock := (head - p.cfg.Finality) / p.cfg.Every * p.cfg.Every
+	if toBlock == 0 {
+		return nil
+	}
+	for _, snapshotType := range p.cfg.Mode.ToSnapshotTypes() {
+		last, err := p.lastProduced(snapshotType)
+		if err != nil {
+			return err
+		}
+		if toBlock <= last {
+			continue
+		}
+		if err = p.produce(ctx, snapshotType, toBlock, last); err != nil {
+			return fmt.Errorf("%s snapshot at block %d: %w", snapshotType, toBlock, err)
+		}
+	}
+	return nil
+}
+
+func (p *Producer) produce(ctx context.Context, snapshotType SnapshotType, toBlock, previous uint64) error {
+	t := time.Now()
+	log.Info("Producing snapshot", "type", snapshotType, "block", toBlock)
+	path := p.snapshotPath(snapshotType, toBlock)
+	if err := GenerateSnapshot(ctx, p.db, snapshotType, path, toBlock, p.tmpdir); err != nil {
+		if removeErr := os.RemoveAll(path); removeErr != nil {
+			log.Warn("Can't remove failed snapshot", "path", path, "err", removeErr)
+		}
+		return err
+	}
+	snKV, err := openSnapshot(snapshotType, path)
+	if err != nil {
+		return err
+	}
+
+	// The deleted state is searched for in a read-only transaction, the write transaction only puts the marks
+	var deleted *etl.Collector
+	var deletedTo uint64
+	if snapshotType == SnapshotType_state {
+		if deleted, deletedTo, err = collectDeletedState(ctx, p.db, snKV, toBlock, p.tmpdir); err != nil {
+			snKV.Close()
+			return err
+		}
+	}
+
+	tx, err := p.db.Begin(ctx, ethdb.RW)
+	if err != nil {
+		snKV.Close()
+		return err
+	}
+	defer tx.Rollback()
+	if snapshotType == SnapshotType_state {
+		if err = markDeletedState(tx, snKV, deleted, deletedTo); err != nil {
+			snKV.Close()
+			return err
+		}
+	}
+	if err = tx.Put(dbutils.SnapshotInfoBucket, producedBlockKey(snapshotType), dbutils.EncodeBlockNumber(toBlock)); err != nil {
+		snKV.Close()
+		return err
+	}
+	// the new snapshot becomes visible together with the deletion marks and the produced block
+	replaced, err := p.kv.UpdateSnapshots(ctx, tx.(ethdb.HasTx).Tx(), snapshotBuckets(snapshotType), snKV)
+	if err != nil {
+		snKV.Close()
+		return err
+	}
+	log.Info("Snapshot produced", "type", snapshotType, "block", toBlock, "duration", time.Since(t))
+
+	previousHash := p.infoHashes[snapshotType]
+	p.seed(snapshotType, toBlock)
+	if previous == 0 {
+		return nil
+	}
+	if previousHash != nil {
+		if err = p.seeder.StopSeeding(previousHash); err != nil {
+			log.Warn("Can't stop seeding snapshot", "type", snapshotType, "block", previous, "err", err)
+		}
+	}
+	select {
+	case <-replaced:
+	case <-ctx.Done():
+		return nil
+	}
+	return os.RemoveAll(p.snapshotPath(snapshotType, previous))
+}
+
+func (p *Producer) seed(snapshotType SnapshotType, block uint64) {
+	infoHash, err := p.seeder.SeedSnapshot(snapshotType.String(), p.snapshotPath(snapshotType, block))
+	if err != nil {
+		log.Warn("Can't seed snapshot", "type", snapshotType, "block", block, "err", err)
+		return
+	}
+	p.infoHashes[snapshotType] = infoHash
+	log.Info("Seeding snapshot", "type", snapshotType, "block", block, "hash", common.Bytes2Hex(infoHash))
+}
+
+func (p *Producer) lastProduced(snapshotType SnapshotType) (uint64, error) {
+	v, err := p.db.Get(dbutils.SnapshotInfoBucket, producedBlockKey(snapshotType))
+	if err != nil && !errors.Is(err, ethdb.ErrKeyNotFound) {
+		return 0, err
+	}
+	if len(v) != 8 {
+		return 0, nil
+	}
+	return binary.BigEndian.Uint64(v), nil
+}
+
+// removeStaleSnapshots removes the snapshots of the given type left by the interrupted production or replacement
+func (p *Producer) removeStaleSnapshots(snapshotType SnapshotType, current uint64) error {
+	paths, err := filepath.Glob(filepath.Join(p.dir, snapshotType.String()+"-*"))
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		if current != 0 && path == p.snapshotPath(snapshotType, current) {
+			continue
+		}
+		log.Info("Removing stale snapshot", "path", path)
+		if err = os.RemoveAll(path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *Producer) snapshotPath(snapshotType SnapshotType, block uint64) string {
+	return filepath.Join(p.dir, fmt.Sprintf("%s-%d", snapshotType, block))
+}
+
+func producedBlockKey(snapshotType SnapshotType) []byte {
+	return []byte(dbutils.SnapshotProducedBlockPrefix + snapshotType.String())
+}
+
+// collectDeletedState collects the keys of the accounts and the storage which are in the state snapshot of the block
+// toBlock, but deleted from the state after it, in a read-only transaction. Returns the last block it has looked at.
+func collectDeletedState(ctx context.Context, db ethdb.Database, snKV ethdb.KV, toBlock uint64, tmpdir string) (*etl.Collector, uint64, error) {
+	tx, err := db.Begin(ctx, ethdb.RO)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer tx.Rollback()
+
+	head, err := stages.GetStageProgress(tx, stages.Execution)
+	if err != nil {
+		return nil, 0, err
+	}
+	if head < toBlock {
+		head = toBlock
+	}
+	collector := etl.NewCollector(tmpdir, etl.NewSortableBuffer(etl.BufferOptimalSize))
+	if err = snKV.View(ctx, func(snTx ethdb.Tx) error {
+		return walkDeletedState(tx, snTx, toBlock, head, func(key []byte) error {
+			return collector.Collect(key, nil)
+		})
+	}); err != nil {
+		collector.Close("snapshot producer")
+		return nil, 0, err
+	}
+	return collector, head, nil
+}
+
+// markDeletedState puts the deletion marks for the keys collected by collectDeletedState, and for the keys
+// deleted by the blocks executed after it. Otherwise the reads would find them in the snapshot
+func markDeletedState(db ethdb.DbWithPendingMutations, snKV ethdb.KV, deleted *etl.Collector, from uint64) error {
+	tx := db.(ethdb.HasTx).Tx()
+	marker := tx.(ethdb.DeletionMarker)
+	mark := func(key []byte) error {
+		return marker.MarkDeleted(dbutils.PlainStateBucket, key)
+	}
+
+	head, err := stages.GetStageProgress(db, stages.Execution)
+	if err != nil {
+		return err
+	}
+	if head > from {
+		if err = snKV.View(context.Background(), func(snTx ethdb.Tx) error {
+			return walkDeletedState(db, snTx, from, head, mark)
+		}); err != nil {
+			return err
+		}
+	}
+	return deleted.Load("snapshot producer", db, "", func(k []byte, _ []byte, _ etl.CurrentTableReader, _ etl.LoadNextFunc) error {
+		// the key may be put back to the state after it's collected
+		v, err := tx.GetOne(dbutils.PlainStateBucket, k)
+		if err != nil {
+			return err
+		}
+		if len(v) > 0 {
+			return nil
+		}
+		return mark(k)
+	}, etl.TransformArgs{})
+}
+
+// walkDeletedState calls f for the keys changed by the blocks (from, to], which are in the state snapshot, but not in the state
+func walkDeletedState(db ethdb.Database, snTx ethdb.Tx, from, to uint64, f func(key []byte) error) error {
+	for _, csBucket := range []string{dbutils.PlainAccountChangeSetBucket, dbutils.PlainStorageChangeSetBucket} {
+		if err := changeset.Walk(db, csBucket, dbutils.EncodeBlockNumber(from+1), 0, func(blockN uint64, k, _ []byte) (bool, error) {
+			if blockN > to {
+				return false, nil
+			}
+			v, err := db.Get(dbutils.PlainStateBucket, k)
+			if err != nil && !errors.Is(err, ethdb.ErrKeyNotFound) {
+				return false, err
+			}
+			if len(v) > 0 {
+				return true, nil
+			}
+			if v, err = snTx.GetOne(dbutils.PlainStateBucket, k); err != nil {
+				return false, err
+			}
+			if len(v) == 0 {
+				return true, nil
+			}
+			return true, f(common.CopyBytes(k))
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
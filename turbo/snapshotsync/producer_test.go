@@ -0,0 +1,234 @@
+package snapshotsync
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ledgerwatch/turbo-geth/common"
+	"github.com/ledgerwatch/turbo-geth/common/changeset"
+	"github.com/ledgerwatch/turbo-geth/common/dbutils"
+	"github.com/ledgerwatch/turbo-geth/core/rawdb"
+	"github.com/ledgerwatch/turbo-geth/core/types"
+	"github.com/ledgerwatch/turbo-geth/eth/stagedsync/stages"
+	"github.com/ledgerwatch/turbo-geth/ethdb"
+)
+
+type testSeeder struct {
+	seeded  map[string]string
+	stopped [][]byte
+}
+
+func (s *testSeeder) SeedSnapshot(name string, path string) ([]byte, error) {
+	s.seeded[name] = path
+	return []byte(path), nil
+}
+
+func (s *testSeeder) StopSeeding(infoHash []byte) error {
+	s.stopped = append(s.stopped, infoHash)
+	return nil
+}
+
+func TestProducer(t *testing.T) {
+	// every block is read in its own transaction
+	defer func(d time.Duration) { snapshotReadTxDuration = d }(snapshotReadTxDuration)
+	snapshotReadTxDuration = 0
+
+	snapshotDir, err := ioutil.TempDir(os.TempDir(), "snapshots*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(snapshotDir)
+
+	mainKV := ethdb.NewLMDB().InMem().MustOpen()
+	db := ethdb.NewObjectDatabase(ethdb.NewSnapshot2KV().DB(mainKV).MustOpen())
+	defer db.Close()
+	headers := generateHeaders(15)
+	for i := range headers {
+		header := &headers[i]
+		rawdb.WriteHeader(context.Background(), db, header)
+		if err = rawdb.WriteCanonicalHash(db, header.Hash(), header.Number.Uint64()); err != nil {
+			t.Fatal(err)
+		}
+		if err = rawdb.WriteBody(db, header.Hash(), header.Number.Uint64(), &types.Body{Uncles: []*types.Header{{Number: header.Number}}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = stages.SaveStageProgress(db, stages.Finish, 10); err != nil {
+		t.Fatal(err)
+	}
+
+	seeder := &testSeeder{seeded: make(map[string]string)}
+	producer, err := NewProducer(db, snapshotDir, os.TempDir(), seeder, ProducerConfig{
+		Mode:     SnapshotMode{Headers: true, Bodies: true},
+		Every:    4,
+		Finality: 2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = producer.Produce(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	for _, snapshotType := range []SnapshotType{SnapshotType_headers, SnapshotType_bodies} {
+		if seeder.seeded[snapshotType.String()] != filepath.Join(snapshotDir, snapshotType.String()+"-8") {
+			t.Fatalf("%s snapshot of the block 8 is not seeded: %v", snapshotType, seeder.seeded)
+		}
+		if last, innerErr := producer.lastProduced(snapshotType); innerErr != nil || last != 8 {
+			t.Fatalf("expected the last produced %s snapshot 8, got %d, %v", snapshotType, last, innerErr)
+		}
+	}
+
+	// the blocks removed from the main database are read from the snapshots, up to the snapshot block
+	deleteHeaders := func(from, to uint64) {
+		t.Helper()
+		innerErr := mainKV.Update(context.Background(), func(tx ethdb.Tx) error {
+			for i := from; i <= to; i++ {
+				if err := tx.Cursor(dbutils.HeaderPrefix).Delete(dbutils.HeaderKey(i, headers[i].Hash()), nil); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if innerErr != nil {
+			t.Fatal(innerErr)
+		}
+	}
+	checkHeader := func(number uint64, expected bool) {
+		t.Helper()
+		v := rawdb.ReadHeaderRLP(db, headers[number].Hash(), number)
+		if expected && len(v) == 0 {
+			t.Fatalf("header %d is not found", number)
+		}
+		if !expected && len(v) != 0 {
+			t.Fatalf("header %d is not expected in the snapshot", number)
+		}
+	}
+	deleteHeaders(1, 8)
+	checkHeader(1, true)
+	checkHeader(8, true)
+
+	// nothing new to produce
+	if err = producer.Produce(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(seeder.stopped) != 0 {
+		t.Fatalf("unexpected replacement of the snapshots")
+	}
+
+	if err = stages.SaveStageProgress(db, stages.Finish, 14); err != nil {
+		t.Fatal(err)
+	}
+	if err = producer.Produce(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	// the new snapshot is made of the blocks from the previous snapshot and from the main database
+	deleteHeaders(9, 13)
+	checkHeader(5, true)
+	checkHeader(12, true)
+	checkHeader(13, false)
+	if len(seeder.stopped) != 2 || !bytes.Equal(seeder.stopped[0], []byte(filepath.Join(snapshotDir, "headers-8"))) {
+		t.Fatalf("expected the previous snapshots to stop seeding, got %q", seeder.stopped)
+	}
+	for _, name := range []string{"headers-8", "bodies-8"} {
+		if _, err = os.Stat(filepath.Join(snapshotDir, name)); !os.IsNotExist(err) {
+			t.Fatalf("the replaced snapshot %s is not removed: %v", name, err)
+		}
+	}
+
+	// after the restart the produced snapshots are back in the read path, the leftovers are removed
+	if err = os.Mkdir(filepath.Join(snapshotDir, "headers-4"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	restarted := ethdb.NewObjectDatabase(ethdb.NewSnapshot2KV().DB(mainKV).MustOpen())
+	defer restarted.Close()
+	producer, err = NewProducer(restarted, snapshotDir, os.TempDir(), seeder, ProducerConfig{
+		Mode:     SnapshotMode{Headers: true},
+		Every:    4,
+		Finality: 2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = producer.Start(); err != nil {
+		t.Fatal(err)
+	}
+	producer.Stop()
+	if v := rawdb.ReadHeaderRLP(restarted, headers[12].Hash(), 12); len(v) == 0 {
+		t.Fatal("header 12 is not found after the restart")
+	}
+	if _, err = os.Stat(filepath.Join(snapshotDir, "headers-4")); !os.IsNotExist(err) {
+		t.Fatalf("the stale snapshot is not removed: %v", err)
+	}
+}
+
+func TestMarkDeletedState(t *testing.T) {
+	mainKV := ethdb.NewLMDB().InMem().MustOpen()
+	db := ethdb.NewObjectDatabase(ethdb.NewSnapshot2KV().DB(mainKV).MustOpen())
+	defer db.Close()
+	deleted, recreated, deletedLater := common.Address{1}, common.Address{2}, common.Address{3}
+	snKV, err := ethdb.GenStateData([]ethdb.KvData{
+		{K: deleted.Bytes(), V: []byte{1}},
+		{K: recreated.Bytes(), V: []byte{2}},
+		{K: deletedLater.Bytes(), V: []byte{3}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer snKV.Close()
+
+	csInfo := changeset.Mapper[dbutils.PlainAccountChangeSetBucket]
+	writeChangeSet := func(blockNumber uint64, addrs ...common.Address) {
+		t.Helper()
+		cs := csInfo.New()
+		for _, addr := range addrs {
+			if innerErr := cs.Add(addr.Bytes(), []byte{1}); innerErr != nil {
+				t.Fatal(innerErr)
+			}
+		}
+		if innerErr := csInfo.Encode(blockNumber, cs, func(k, v []byte) error {
+			return db.Put(dbutils.PlainAccountChangeSetBucket, k, v)
+		}); innerErr != nil {
+			t.Fatal(innerErr)
+		}
+		if innerErr := stages.SaveStageProgress(db, stages.Execution, blockNumber); innerErr != nil {
+			t.Fatal(innerErr)
+		}
+	}
+	writeChangeSet(6, deleted, recreated)
+	collector, collectedTo, err := collectDeletedState(context.Background(), db, snKV, 5, os.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if collectedTo != 6 {
+		t.Fatalf("expected the deleted state to be collected up to the block 6, got %d", collectedTo)
+	}
+
+	// the changes made between the read-only and the write transactions
+	if err = db.Put(dbutils.PlainStateBucket, recreated.Bytes(), []byte{22}); err != nil {
+		t.Fatal(err)
+	}
+	writeChangeSet(7, deletedLater)
+
+	tx, err := db.Begin(context.Background(), ethdb.RW)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+	if err = markDeletedState(tx, snKV, collector, collectedTo); err != nil {
+		t.Fatal(err)
+	}
+	for _, addr := range []common.Address{deleted, recreated, deletedLater} {
+		v, innerErr := tx.Get(dbutils.PlainStateBucket, addr.Bytes())
+		if innerErr != nil {
+			t.Fatal(innerErr)
+		}
+		if marked := bytes.Equal(v, ethdb.DeletedValue); marked != (addr != recreated) {
+			t.Errorf("wrong deletion mark of %x: %x", addr, v)
+		}
+	}
+}
@@ -28,46 +28,30 @@ var (
 			dbutils.CodeBucket:              dbutils.BucketConfigItem{},
 			dbutils.StateSnapshotInfoBucket: dbutils.BucketConfigItem{},
 		},
+		SnapshotType_receipts: {
+			dbutils.BlockReceiptsPrefix:        dbutils.BucketConfigItem{},
+			dbutils.Log:                        dbutils.BucketConfigItem{},
+			dbutils.ReceiptsSnapshotInfoBucket: dbutils.BucketConfigItem{},
+		},
 	}
 )
 
+// WrapBySnapshotsFromDir wraps kv by the snapshots of mode found in snapshotDir, each snapshot type is in the
+// subdirectory named after it: headers, bodies, state and receipts
 func WrapBySnapshotsFromDir(kv ethdb.KV, snapshotDir string, mode SnapshotMode) (ethdb.KV, error) {
 	log.Info("Wrap db to snapshots", "dir", snapshotDir, "mode", mode.ToString())
 	snkv := ethdb.NewSnapshot2KV().DB(kv)
 
-	if mode.Bodies {
-		snapshotKV, err := ethdb.NewLMDB().Flags(func(flags uint) uint { return flags | lmdb.Readonly }).Path(snapshotDir + "/bodies").WithBucketsConfig(func(defaultBuckets dbutils.BucketsCfg) dbutils.BucketsCfg {
-			return bucketConfigs[SnapshotType_bodies]
-		}).Open()
-		if err != nil {
-			log.Error("Can't open body snapshot", "err", err)
-			return nil, err
-		} else { //nolint
-			snkv.SnapshotDB([]string{dbutils.BlockBodyPrefix, dbutils.BodiesSnapshotInfoBucket}, snapshotKV)
-		}
-	}
-
-	if mode.Headers {
-		snapshotKV, err := ethdb.NewLMDB().Flags(func(flags uint) uint { return flags | lmdb.Readonly }).Path(snapshotDir + "/headers").WithBucketsConfig(func(defaultBuckets dbutils.BucketsCfg) dbutils.BucketsCfg {
-			return bucketConfigs[SnapshotType_headers]
-		}).Open()
-		if err != nil {
-			log.Error("Can't open headers snapshot", "err", err)
-			return nil, err
-		} else { //nolint
-			snkv.SnapshotDB([]string{dbutils.HeaderPrefix, dbutils.HeadersSnapshotInfoBucket}, snapshotKV)
-		}
-	}
-	if mode.State {
-		snapshotKV, err := ethdb.NewLMDB().Flags(func(flags uint) uint { return flags | lmdb.Readonly }).Path(snapshotDir + "/headers").WithBucketsConfig(func(defaultBuckets dbutils.BucketsCfg) dbutils.BucketsCfg {
-			return bucketConfigs[SnapshotType_headers]
+	for _, snapshotType := range mode.ToSnapshotTypes() {
+		cfg := bucketConfigs[snapshotType]
+		snapshotKV, err := ethdb.NewLMDB().Flags(func(flags uint) uint { return flags | lmdb.Readonly }).Path(snapshotDir + "/" + snapshotType.String()).WithBucketsConfig(func(defaultBuckets dbutils.BucketsCfg) dbutils.BucketsCfg {
+			return cfg
 		}).Open()
 		if err != nil {
-			log.Error("Can't open headers snapshot", "err", err)
+			log.Error("Can't open snapshot", "type", snapshotType.String(), "err", err)
 			return nil, err
-		} else { //nolint
-			snkv.SnapshotDB([]string{dbutils.StateSnapshotInfoBucket, dbutils.PlainStateBucket, dbutils.PlainContractCodeBucket, dbutils.CodeBucket}, snapshotKV)
 		}
+		snkv = snkv.SnapshotDB(snapshotBuckets(snapshotType), snapshotKV)
 	}
 	return snkv.MustOpen(), nil
 }
@@ -85,14 +69,18 @@ func WrapBySnapshotsFromDownloader(kv ethdb.KV, snapshots map[SnapshotType]*Snap
 			log.Error("Can't open snapshot", "err", err)
 			return nil, err
 		} else { //nolint
-			buckets := make([]string, 0, 1)
-			for bucket := range bucketConfigs[k] {
-				buckets = append(buckets, bucket)
-			}
-
-			snKV.SnapshotDB(buckets, snapshotKV)
+			snKV = snKV.SnapshotDB(snapshotBuckets(k), snapshotKV)
 		}
 	}
 
 	return snKV.MustOpen(), nil
 }
+
+// snapshotBuckets returns the buckets which are read from the snapshot of the given type
+func snapshotBuckets(snapshotType SnapshotType) []string {
+	buckets := make([]string, 0, len(bucketConfigs[snapshotType]))
+	for bucket := range bucketConfigs[snapshotType] {
+		buckets = append(buckets, bucket)
+	}
+	return buckets
+}
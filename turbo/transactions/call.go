@@ -23,31 +23,15 @@ import (
 
 const callTimeout = 5 * time.Minute
 
-func DoCall(ctx context.Context, args ethapi.CallArgs, tx ethdb.Database, blockNrOrHash rpc.BlockNumberOrHash, overrides *map[common.Address]ethapi.Account, GasCap uint64, chainConfig *params.ChainConfig) (*core.ExecutionResult, error) {
-	// todo: Pending state is only known by the miner
-	/*
-		if blockNrOrHash.BlockNumber != nil && *blockNrOrHash.BlockNumber == rpc.PendingBlockNumber {
-			block, state, _ := b.eth.miner.Pending()
-			return state, block.Header(), nil
-		}
-	*/
-	blockNumber, hash, err := rpchelper.GetBlockNumber(blockNrOrHash, tx)
+// DoCall executes the call on top of the state of the given block. With the `pending` tag the call is executed
+// on top of pendingBlock if it's known, or on top of the latest block otherwise.
+func DoCall(ctx context.Context, args ethapi.CallArgs, tx ethdb.Database, blockNrOrHash rpc.BlockNumberOrHash, pendingBlock *rpchelper.PendingBlock, overrides *map[common.Address]ethapi.Account, GasCap uint64, chainConfig *params.ChainConfig) (*core.ExecutionResult, error) {
+	stateReader, header, err := CallStateReader(tx, blockNrOrHash, pendingBlock)
 	if err != nil {
 		return nil, err
 	}
-	var stateReader state.StateReader
-	if num, ok := blockNrOrHash.Number(); ok && num == rpc.LatestBlockNumber {
-		stateReader = state.NewPlainStateReader(tx)
-	} else {
-		stateReader = state.NewPlainDBState(tx, blockNumber)
-	}
 	state := state.New(stateReader)
 
-	header := rawdb.ReadHeader(tx, hash, blockNumber)
-	if header == nil {
-		return nil, fmt.Errorf("block %d(%x) not found", blockNumber, hash)
-	}
-
 	// Override the fields of specified contracts before execution.
 	if overrides != nil {
 		for addr, account := range *overrides {
@@ -121,6 +105,30 @@ func DoCall(ctx context.Context, args ethapi.CallArgs, tx ethdb.Database, blockN
 	return result, nil
 }
 
+// CallStateReader returns the reader of the state the call on blockNrOrHash is executed on, and the header of the block
+// which is used for the execution. With the `pending` tag these are pendingBlock if it's known, or the latest block otherwise.
+func CallStateReader(tx ethdb.Database, blockNrOrHash rpc.BlockNumberOrHash, pendingBlock *rpchelper.PendingBlock) (state.StateReader, *types.Header, error) {
+	num, isNumber := blockNrOrHash.Number()
+	if isNumber && num == rpc.PendingBlockNumber && pendingBlock != nil {
+		return pendingBlock.StateReader(state.NewPlainStateReader(tx)), pendingBlock.Block.Header(), nil
+	}
+	blockNumber, hash, err := rpchelper.GetBlockNumber(rpchelper.LatestInsteadOfPending(blockNrOrHash), tx)
+	if err != nil {
+		return nil, nil, err
+	}
+	var stateReader state.StateReader
+	if isNumber && (num == rpc.LatestBlockNumber || num == rpc.PendingBlockNumber) {
+		stateReader = state.NewPlainStateReader(tx)
+	} else {
+		stateReader = state.NewPlainDBState(tx, blockNumber)
+	}
+	header := rawdb.ReadHeader(tx, hash, blockNumber)
+	if header == nil {
+		return nil, nil, fmt.Errorf("block %d(%x) not found", blockNumber, hash)
+	}
+	return stateReader, header, nil
+}
+
 func GetEvmContext(msg core.Message, header *types.Header, requireCanonical bool, db ethdb.Database) vm.Context {
 	return vm.Context{
 		CanTransfer: core.CanTransfer,
@@ -146,3 +146,44 @@ func TraceTx(ctx context.Context, message core.Message, vmctx vm.Context, ibs vm
 		panic(fmt.Sprintf("bad tracer type %T", tracer))
 	}
 }
+
+// TraceBlock executes all the transactions of the block once, on top of the state of its parent, and traces each of
+// them according to the provided configuration. The result of each transaction trace, or the tracer failure, is passed
+// to emit as soon as the transaction is executed, so that the results can be streamed to the caller
+func TraceBlock(ctx context.Context, block *types.Block, cfg *params.ChainConfig, chain core.ChainContext, blockGetter BlockGetter, tx ethdb.Tx, config *eth.TraceConfig, emit func(txIndex int, result interface{}, err error) error) error {
+	parent := blockGetter.GetBlock(block.ParentHash(), block.NumberU64()-1)
+	if parent == nil {
+		return fmt.Errorf("parent %x not found", block.ParentHash())
+	}
+	statedb, reader := state2.ComputeIntraBlockState(tx, parent)
+	signer := types.MakeSigner(cfg, block.Number())
+	// Without refunds the traced execution does not give the canonical state, so it has to be repeated without tracing
+	noRefunds := config != nil && config.NoRefunds != nil && *config.NoRefunds
+
+	for idx, txn := range block.Transactions() {
+		select {
+		default:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		statedb.Prepare(txn.Hash(), block.Hash(), idx)
+		msg, _ := txn.AsMessage(signer)
+		vmctx := core.NewEVMContext(msg, block.Header(), chain, nil)
+
+		snapshot := statedb.Snapshot()
+		result, err := TraceTx(ctx, msg, vmctx, statedb, config, cfg)
+		if err = emit(idx, result, err); err != nil {
+			return err
+		}
+		if noRefunds {
+			statedb.RevertToSnapshot(snapshot)
+			vmenv := vm.NewEVM(vmctx, statedb, cfg, vm.Config{})
+			if _, err = core.ApplyMessage(vmenv, msg, new(core.GasPool).AddGas(txn.Gas()), true /* refunds */); err != nil {
+				return fmt.Errorf("transaction %x failed: %v", txn.Hash(), err)
+			}
+		}
+		// Only delete empty objects if EIP158/161 (a.k.a Spurious Dragon) is in effect
+		_ = statedb.FinalizeTx(cfg.WithEIPsFlags(context.Background(), block.Number()), reader)
+	}
+	return nil
+}
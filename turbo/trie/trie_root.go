@@ -98,6 +98,7 @@ type FlatDBTrieLoader struct {
 	receiver        StreamReceiver
 	defaultReceiver *RootHashAggregator
 	hc              HashCollector
+	readOnly        bool // if true, intermediate hashes rejected by RetainDecider are skipped instead of being deleted
 }
 
 // RootHashAggregator - calculates Merkle trie root hash from incoming data stream
@@ -106,6 +107,8 @@ type RootHashAggregator struct {
 	wasIH        bool
 	wasIHStorage bool
 	root         common.Hash
+	rootNode     node          // Root of the trie with the nodes retained by rd, only set when rd is not nil
+	rd           RetainDecider // Decides which nodes are built in memory instead of being hashed, nil means none
 	hc           HashCollector
 	currStorage  bytes.Buffer // Current key for the structure generation algorithm, as well as the input tape for the hash builder
 	succStorage  bytes.Buffer
@@ -156,6 +159,12 @@ func (l *FlatDBTrieLoader) SetStreamReceiver(receiver StreamReceiver) {
 	l.receiver = receiver
 }
 
+// SetReadOnly makes the loader leave the intermediate hashes rejected by the RetainDecider in the database,
+// so that the root can be calculated inside read-only transactions (for example, to produce proofs)
+func (l *FlatDBTrieLoader) SetReadOnly(readOnly bool) {
+	l.readOnly = readOnly
+}
+
 // iteration moves through the database buckets and creates at most
 // one stream item, which is indicated by setting the field fstl.itemPresent to true
 func (l *FlatDBTrieLoader) iteration(c *StateCursor, ih *IHCursor, first bool) error {
@@ -386,6 +395,7 @@ func (l *FlatDBTrieLoader) CalcTrieRoot(db ethdb.Database, quit <-chan struct{})
 		return !l.rd.Retain(k)
 	}
 	ih := IH(filter, tx.CursorDupSort(l.intermediateHashesBucket))
+	ih.readOnly = l.readOnly
 	if err := l.iteration(c, ih, true /* first */); err != nil {
 		return EmptyRoot, err
 	}
@@ -441,6 +451,19 @@ func (r *RootHashAggregator) RetainNothing(_ []byte) bool {
 	return false
 }
 
+// SetRetainDecider makes the aggregator build the nodes on the paths retained by rd in memory,
+// they are available via Result after the root has been calculated
+func (r *RootHashAggregator) SetRetainDecider(rd RetainDecider) {
+	r.rd = rd
+}
+
+func (r *RootHashAggregator) retain(prefix []byte) bool {
+	if r.rd == nil {
+		return false
+	}
+	return r.rd.Retain(prefix)
+}
+
 func (r *RootHashAggregator) Reset(hc HashCollector, trace bool) {
 	r.hc = hc
 	r.curr.Reset()
@@ -455,6 +478,7 @@ func (r *RootHashAggregator) Reset(hc HashCollector, trace bool) {
 	r.valueStorage = nil
 	r.wasIHStorage = false
 	r.root = common.Hash{}
+	r.rootNode = nil
 	r.trace = trace
 	r.hb.trace = trace
 }
@@ -588,6 +612,9 @@ func (r *RootHashAggregator) Receive(itemType StreamItem,
 		}
 		if r.hb.hasRoot() {
 			r.root = r.hb.rootHash()
+			if r.rd != nil {
+				r.rootNode = r.hb.root()
+			}
 		} else {
 			r.root = EmptyRoot
 		}
@@ -604,7 +631,10 @@ func (r *RootHashAggregator) Receive(itemType StreamItem,
 }
 
 func (r *RootHashAggregator) Result() SubTries {
-	panic("don't call me")
+	if r.rd == nil {
+		panic("don't call me without RetainDecider")
+	}
+	return SubTries{Hashes: []common.Hash{r.root}, roots: []node{r.rootNode}}
 }
 
 func (r *RootHashAggregator) Root() common.Hash {
@@ -643,7 +673,7 @@ func (r *RootHashAggregator) genStructStorage() error {
 		r.leafData.Value = rlphacks.RlpSerializableBytes(r.valueStorage)
 		data = &r.leafData
 	}
-	r.groups, err = GenStructStep(r.RetainNothing, r.currStorage.Bytes(), r.succStorage.Bytes(), r.hb, r.hc, data, r.groups, r.trace)
+	r.groups, err = GenStructStep(r.retain, r.currStorage.Bytes(), r.succStorage.Bytes(), r.hb, r.hc, data, r.groups, r.trace)
 	if err != nil {
 		return err
 	}
@@ -702,7 +732,7 @@ func (r *RootHashAggregator) genStructAccount() error {
 	r.currStorage.Reset()
 	r.succStorage.Reset()
 	var err error
-	if r.groups, err = GenStructStep(r.RetainNothing, r.curr.Bytes(), r.succ.Bytes(), r.hb, r.hc, data, r.groups, r.trace); err != nil {
+	if r.groups, err = GenStructStep(r.retain, r.curr.Bytes(), r.succ.Bytes(), r.hb, r.hc, data, r.groups, r.trace); err != nil {
 		return err
 	}
 	r.accData.FieldSet = 0
@@ -733,8 +763,9 @@ const IHDupKeyLen = 2 * (common.HashLength + common.IncarnationLength)
 
 // IHCursor - holds logic related to iteration over IH bucket
 type IHCursor struct {
-	c      ethdb.CursorDupSort
-	filter Filter
+	c        ethdb.CursorDupSort
+	filter   Filter
+	readOnly bool
 }
 
 func IH(f Filter, c ethdb.CursorDupSort) *IHCursor {
@@ -773,9 +804,11 @@ func (c *IHCursor) _seek(seek []byte) (k, v []byte, err error) {
 		return k, v, nil
 	}
 
-	err = c.c.DeleteCurrent()
-	if err != nil {
-		return []byte{}, nil, err
+	if !c.readOnly {
+		err = c.c.DeleteCurrent()
+		if err != nil {
+			return []byte{}, nil, err
+		}
 	}
 
 	return c._next()
@@ -801,9 +834,11 @@ func (c *IHCursor) _next() (k, v []byte, err error) {
 			return k, v, nil
 		}
 
-		err = c.c.DeleteCurrent()
-		if err != nil {
-			return []byte{}, nil, err
+		if !c.readOnly {
+			err = c.c.DeleteCurrent()
+			if err != nil {
+				return []byte{}, nil, err
+			}
 		}
 
 		k, v, err = c.c.Next()